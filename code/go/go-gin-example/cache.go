@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/cache_service"
+)
+
+const cacheUsage = "usage: go-gin-example cache flush [--tenant name] [--all]"
+
+// runCache 处理 cache 子命令
+func runCache(args []string) int {
+	return subcommand(cacheUsage, map[string]func([]string) int{
+		"flush": runCacheFlush,
+	}, args)
+}
+
+// runCacheFlush 清除站点的缓存，--all 时清除所有站点的缓存
+//
+// 只删除站点命名空间下的键，未写入数据库的浏览数、限流与登录锁定等计数不受影响
+func runCacheFlush(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("cache flush", flag.ExitOnError)
+	flags.bind(fs)
+	all := fs.Bool("all", false, "清除所有站点的缓存")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, cacheUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(true)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	prefix, target := cache_service.Namespace(tenant.ID)+"_", "site "+tenant.Name
+	if *all {
+		prefix, target = e.CACHE_TENANT+"_", "all sites"
+	}
+	if err := service.Cache.LikeDeletes(context.Background(), prefix); err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("flushed the cache of %s\n", target)
+	return 0
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service/audit_service"
+)
+
+// CLI_USER 子命令作为操作人写入审计日志，以及新建标签、导入文章时的创建人
+const CLI_USER = "cli"
+
+// commands 子命令，不带子命令或第一个参数为 - 开头的参数时按 serve 处理
+var commands = map[string]func(args []string) int{
+	"serve":         runServe,
+	"config":        runConfig,
+	"export-site":   runExportSite,
+	"user":          runUser,
+	"tag":           runTag,
+	"article":       runArticle,
+	"cache":         runCache,
+	"purge-deleted": runPurgeDeleted,
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: go-gin-example [command] [flags]\n\ncommands: %s\n", strings.Join(names, ", "))
+}
+
+// subcommand 按第一个参数分派到 subs 中的子命令，如 user create
+func subcommand(usage string, subs map[string]func(args []string) int, args []string) int {
+	if len(args) == 0 || subs[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	return subs[args[0]](args[1:])
+}
+
+// commandFlags 管理子命令共用的参数
+type commandFlags struct {
+	opts   setting.Options
+	tenant string
+}
+
+func (f *commandFlags) bind(fs *flag.FlagSet) {
+	f.opts.Bind(fs)
+	fs.StringVar(&f.tenant, "tenant", "", "要操作的站点名称，默认为 [tenant] Default")
+}
+
+// setup 加载配置、连接数据库，redis 为 true 时同时连接 Redis，返回 --tenant 指定的启用的站点
+//
+// 修改标签、文章等会清除 Redis 中的缓存，这类子命令需要连接 Redis，以免服务继续返回旧的缓存
+func (f *commandFlags) setup(redis bool) (*models.Tenant, error) {
+	if err := setting.Setup(f.opts); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(setting.AppSetting.RuntimeRootPath+setting.AppSetting.LogSavePath, 0755); err != nil {
+		return nil, err
+	}
+	logging.Setup()
+	if err := models.Setup(); err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	if redis {
+		if err := gredis.Setup(); err != nil {
+			return nil, fmt.Errorf("connect to redis: %w", err)
+		}
+	}
+
+	name := f.tenant
+	if name == "" {
+		name = setting.TenantSetting.Default
+	}
+	tenant, err := models.GetTenantByName(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.ID == 0 {
+		return nil, fmt.Errorf("site %q does not exist or is disabled", name)
+	}
+
+	return tenant, nil
+}
+
+// audit 记录子命令的一次写操作，记录失败只输出警告
+func audit(tenantID int, action, entityType string, entityID interface{}, before, after interface{}) {
+	id := ""
+	if entityID != nil {
+		id = fmt.Sprint(entityID)
+	}
+
+	if err := audit_service.Record(context.Background(), tenantID, CLI_USER, "", action, entityType, id, before, after); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: audit:", err)
+	}
+}
+
+// fail 输出错误并返回退出码 1
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
+
+// readPassword 未通过参数指定密码时从标准输入读取一行，避免密码出现在命令历史中
+func readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
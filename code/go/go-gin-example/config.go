@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+const configUsage = "usage: go-gin-example config print [--config path] [--set section.key=value] [--redact]"
+
+// runConfig 处理 config 子命令，print 输出合并默认值、配置文件、环境变量与命令行参数后的最终配置
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return 2
+	}
+
+	var opts setting.Options
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	opts.Bind(fs)
+	redact := fs.Bool("redact", false, "隐藏密钥、密码等敏感配置项")
+	fs.Parse(args[1:])
+
+	if err := setting.Setup(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	setting.Print(os.Stdout, *redact)
+	return 0
+}
@@ -33,3 +33,197 @@ CREATE TABLE `blog_auth` (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
 INSERT INTO `blog`.`blog_auth` (`id`, `username`, `password`) VALUES (null, 'test', 'test123456');
+
+ALTER TABLE `blog_auth` ADD COLUMN `role` varchar(20) DEFAULT 'editor' COMMENT '角色 admin、editor';
+
+UPDATE `blog`.`blog_auth` SET `role` = 'admin' WHERE `username` = 'test';
+
+CREATE TABLE `blog_job` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(50) NOT NULL DEFAULT '' COMMENT '任务名称',
+  `paused` tinyint(3) unsigned DEFAULT '0' COMMENT '是否暂停 0为否、1为是',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '创建时间',
+  `modified_on` int(10) unsigned DEFAULT '0' COMMENT '修改时间',
+  `modified_by` varchar(100) DEFAULT '' COMMENT '修改人',
+  `deleted_on` int(10) unsigned DEFAULT '0',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='定时任务';
+
+CREATE TABLE `blog_job_run` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `job` varchar(50) NOT NULL DEFAULT '' COMMENT '任务名称',
+  `trigger` varchar(20) DEFAULT '' COMMENT '触发方式 schedule、manual',
+  `trigger_by` varchar(100) DEFAULT '' COMMENT '手动触发人',
+  `instance` varchar(100) DEFAULT '' COMMENT '执行实例',
+  `status` varchar(20) DEFAULT '' COMMENT '状态 running、success、failed',
+  `error` text COMMENT '错误信息',
+  `started_on` int(10) unsigned DEFAULT '0' COMMENT '开始时间',
+  `duration` int(10) unsigned DEFAULT '0' COMMENT '耗时（毫秒）',
+  PRIMARY KEY (`id`),
+  KEY `idx_job` (`job`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='定时任务执行记录';
+
+CREATE TABLE `blog_audit` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `username` varchar(50) DEFAULT '' COMMENT '操作人，取自 token',
+  `action` varchar(20) DEFAULT '' COMMENT '操作 create、edit、delete、restore、import、export、run、pause、resume',
+  `entity_type` varchar(20) DEFAULT '' COMMENT '对象类型 tag、article、job',
+  `entity_id` varchar(50) DEFAULT '' COMMENT '对象ID',
+  `ip` varchar(50) DEFAULT '' COMMENT '客户端IP',
+  `before` mediumtext COMMENT '操作前的数据',
+  `after` mediumtext COMMENT '操作后的数据',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_entity` (`entity_type`, `entity_id`),
+  KEY `idx_username` (`username`),
+  KEY `idx_created_on` (`created_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='审计日志';
+
+CREATE TABLE `blog_webhook` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `url` varchar(255) DEFAULT '' COMMENT '回调地址',
+  `secret` varchar(100) DEFAULT '' COMMENT '签名密钥',
+  `events` varchar(255) DEFAULT '' COMMENT '订阅的事件，逗号分隔，* 表示所有事件',
+  `state` tinyint(3) unsigned DEFAULT '1' COMMENT '状态 0为禁用、1为启用',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '创建时间',
+  `created_by` varchar(100) DEFAULT '' COMMENT '创建人',
+  `modified_on` int(10) unsigned DEFAULT '0' COMMENT '修改时间',
+  `modified_by` varchar(100) DEFAULT '' COMMENT '修改人',
+  `deleted_on` int(10) unsigned DEFAULT '0',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='webhook 订阅';
+
+CREATE TABLE `blog_webhook_delivery` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `webhook_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '订阅ID',
+  `event` varchar(50) DEFAULT '' COMMENT '事件',
+  `payload` mediumtext COMMENT '请求体',
+  `status` varchar(20) DEFAULT '' COMMENT '状态 pending、sending、success、failed',
+  `attempts` int(10) unsigned DEFAULT '0' COMMENT '已投递次数',
+  `response_code` int(10) unsigned DEFAULT '0' COMMENT '最近一次响应状态码',
+  `response_body` text COMMENT '最近一次响应内容',
+  `error` text COMMENT '最近一次错误',
+  `next_retry_on` int(10) unsigned DEFAULT '0' COMMENT '下次投递时间',
+  `replay_of` int(10) unsigned DEFAULT '0' COMMENT '重放的原投递ID',
+  `replayed` tinyint(3) unsigned DEFAULT '0' COMMENT '是否已被重放',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '创建时间',
+  `modified_on` int(10) unsigned DEFAULT '0' COMMENT '修改时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_webhook` (`webhook_id`),
+  KEY `idx_due` (`status`, `next_retry_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='webhook 投递记录';
+
+ALTER TABLE `blog_article` ADD COLUMN `slug` varchar(255) NOT NULL DEFAULT '' COMMENT 'URL 中使用的唯一标识，由标题生成' AFTER `title`;
+
+UPDATE `blog_article` SET `slug` = CONCAT('article-', `id`) WHERE `slug` = '';
+
+ALTER TABLE `blog_article` ADD UNIQUE KEY `uk_slug` (`slug`);
+
+ALTER TABLE `blog_article` ADD COLUMN `version` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '版本号，每次修改加一，用于乐观锁';
+
+ALTER TABLE `blog_tag` ADD COLUMN `version` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '版本号，每次修改加一，用于乐观锁';
+
+CREATE TABLE `blog_tenant` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(50) NOT NULL DEFAULT '' COMMENT '站点名称，用于 X-Tenant 请求头',
+  `host` varchar(255) NOT NULL DEFAULT '' COMMENT '站点域名，为空时只能通过 X-Tenant 请求头访问',
+  `title` varchar(100) DEFAULT '' COMMENT '订阅源标题，为空时使用 [feed] Title',
+  `description` varchar(255) DEFAULT '' COMMENT '订阅源描述，为空时使用 [feed] Description',
+  `link` varchar(255) DEFAULT '' COMMENT '站点地址，为空时使用 [feed] Link',
+  `state` tinyint(3) unsigned DEFAULT '1' COMMENT '状态 0为禁用、1为启用',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '创建时间',
+  `created_by` varchar(100) DEFAULT '' COMMENT '创建人',
+  `modified_on` int(10) unsigned DEFAULT '0' COMMENT '修改时间',
+  `modified_by` varchar(100) DEFAULT '' COMMENT '修改人',
+  `deleted_on` int(10) unsigned DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `idx_name` (`name`),
+  KEY `idx_host` (`host`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='站点';
+
+INSERT INTO `blog`.`blog_tenant` (`id`, `name`, `state`) VALUES (1, 'default', 1);
+
+ALTER TABLE `blog_tag` ADD COLUMN `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID' AFTER `id`, ADD KEY `idx_tenant` (`tenant_id`);
+
+ALTER TABLE `blog_article` ADD COLUMN `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID' AFTER `id`, ADD KEY `idx_tenant` (`tenant_id`);
+
+ALTER TABLE `blog_article` DROP INDEX `uk_slug`, ADD UNIQUE KEY `uk_tenant_slug` (`tenant_id`, `slug`);
+
+ALTER TABLE `blog_auth` ADD COLUMN `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID' AFTER `id`, ADD UNIQUE KEY `uk_tenant_username` (`tenant_id`, `username`);
+
+ALTER TABLE `blog_audit` ADD COLUMN `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID' AFTER `id`, ADD KEY `idx_tenant` (`tenant_id`);
+
+ALTER TABLE `blog_webhook` ADD COLUMN `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID' AFTER `id`, ADD KEY `idx_tenant` (`tenant_id`);
+
+ALTER TABLE `blog_article` ADD COLUMN `view_count` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '浏览数，由定时任务从 Redis 写入', ADD COLUMN `like_count` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '点赞数';
+
+CREATE TABLE `blog_article_view` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID',
+  `article_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '文章ID',
+  `day` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '当天 0 点（UTC）的时间戳',
+  `views` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '当天的浏览数',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_article_day` (`article_id`, `day`),
+  KEY `idx_tenant_day` (`tenant_id`, `day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='文章每天的浏览数';
+
+CREATE TABLE `blog_article_like` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID',
+  `article_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '文章ID',
+  `username` varchar(50) NOT NULL DEFAULT '' COMMENT '点赞的用户',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '点赞时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_article_username` (`article_id`, `username`),
+  KEY `idx_tenant_created_on` (`tenant_id`, `created_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='文章点赞';
+
+CREATE TABLE `blog_series` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID',
+  `title` varchar(100) DEFAULT '' COMMENT '系列标题',
+  `desc` varchar(255) DEFAULT '' COMMENT '简述',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '创建时间',
+  `created_by` varchar(100) DEFAULT '' COMMENT '创建人',
+  `modified_on` int(10) unsigned DEFAULT '0' COMMENT '修改时间',
+  `modified_by` varchar(100) DEFAULT '' COMMENT '修改人',
+  `deleted_on` int(10) unsigned DEFAULT '0',
+  PRIMARY KEY (`id`),
+  KEY `idx_tenant` (`tenant_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='文章系列';
+
+CREATE TABLE `blog_series_article` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID',
+  `series_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '系列ID',
+  `article_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '文章ID',
+  `position` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '在系列中的位置，从 1 开始',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_article` (`article_id`),
+  KEY `idx_series` (`series_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='系列中的文章，一篇文章最多属于一个系列';
+
+CREATE TABLE `blog_api_key` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `tenant_id` int(10) unsigned NOT NULL DEFAULT '1' COMMENT '站点ID',
+  `username` varchar(50) DEFAULT '' COMMENT '所属账号，通过 API Key 鉴权的请求以该账号的身份执行',
+  `name` varchar(100) DEFAULT '' COMMENT '用途说明',
+  `prefix` varchar(20) DEFAULT '' COMMENT 'key 的前几位，用于辨认',
+  `key_hash` char(64) NOT NULL DEFAULT '' COMMENT 'key 的 SHA-256，不保存 key 本身',
+  `scope` varchar(20) DEFAULT '' COMMENT '权限范围 read、articles:write 或 admin',
+  `expires_on` int(10) unsigned DEFAULT '0' COMMENT '过期时间，0 表示不过期',
+  `last_used_on` int(10) unsigned DEFAULT '0' COMMENT '最近使用时间',
+  `created_on` int(10) unsigned DEFAULT '0' COMMENT '创建时间',
+  `modified_on` int(10) unsigned DEFAULT '0' COMMENT '修改时间',
+  `deleted_on` int(10) unsigned DEFAULT '0' COMMENT '吊销时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_key_hash` (`key_hash`),
+  KEY `idx_tenant_username` (`tenant_id`, `username`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='API Key';
+
+ALTER TABLE `blog_auth` ADD COLUMN `subject` varchar(255) NOT NULL DEFAULT '' COMMENT '通过 OIDC 登录时绑定的身份提供方用户 sub';
+ALTER TABLE `blog_auth` ADD KEY `idx_tenant_subject` (`tenant_id`, `subject`);
+
+ALTER TABLE `blog_webhook_delivery` ADD COLUMN `traceparent` varchar(55) NOT NULL DEFAULT '' COMMENT '创建投递的请求的 W3C traceparent，投递时沿用该 trace' AFTER `replayed`;
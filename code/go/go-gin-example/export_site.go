@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service/site_service"
+)
+
+const exportSiteUsage = "usage: go-gin-example export-site [--config path] [--set section.key=value] [--tenant name] [--out dir] [--templates dir]"
+
+// runExportSite 处理 export-site 子命令，将站点已发布的文章与标签导出为静态页面，无需启动服务与 Redis
+func runExportSite(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("export-site", flag.ExitOnError)
+	flags.bind(fs)
+	out := fs.String("out", "", "输出目录，默认为 [site] OutputPath")
+	templates := fs.String("templates", "", "自定义模板目录，默认为 [site] TemplatePath")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, exportSiteUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(false)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	if *out == "" {
+		*out = setting.SiteSetting.OutputPath
+	}
+	if *templates == "" {
+		*templates = setting.SiteSetting.TemplatePath
+	}
+
+	result, err := site_service.Export(context.Background(), tenant, *out, *templates)
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("exported %d pages (%d articles, %d tags) and %d images to %s\n", result.Pages, result.Articles, result.Tags, result.Images, *out)
+	for _, image := range result.Missing {
+		fmt.Fprintf(os.Stderr, "warning: image %s not found in %s\n", image, setting.AppSetting.RuntimeRootPath+setting.AppSetting.ImageSavePath)
+	}
+	return 0
+}
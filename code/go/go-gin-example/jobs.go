@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/scheduler"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service/article_service"
+)
+
+// registerJobs 注册所有定时任务，执行时间在配置 [jobs] 中设置
+func registerJobs() {
+	// 物理删除回收站中超过保留时长的标签
+	scheduler.Register("clean_tags", func(ctx context.Context) error {
+		_, err := models.CleanAllTag(ctx, purgeBefore())
+		return err
+	})
+	// 物理删除回收站中超过保留时长的文章
+	scheduler.Register("clean_articles", func(ctx context.Context) error {
+		_, err := models.CleanAllArticle(ctx, purgeBefore())
+		return err
+	})
+	// 将 Redis 中累计的文章浏览数写入数据库
+	scheduler.Register("flush_views", article_service.FlushViews)
+}
+
+// purgeBefore 在此时间之前删除的数据已超过回收站保留时长
+func purgeBefore() int {
+	return int(time.Now().Add(-setting.TrashSetting.Retention).Unix())
+}
@@ -1,23 +1,77 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
-	_ "github.com/fzzv/go-gin-example/docs"
+	"github.com/fzzv/go-gin-example/middleware/ratelimit"
 	"github.com/fzzv/go-gin-example/models"
 	"github.com/fzzv/go-gin-example/pkg/gredis"
 	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/scheduler"
 	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/telemetry"
 	"github.com/fzzv/go-gin-example/routers"
+	"github.com/fzzv/go-gin-example/service/related_service"
+	"github.com/fzzv/go-gin-example/service/webhook_service"
 )
 
 func main() {
-	setting.Setup()
-	models.Setup()
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		os.Exit(runServe(args))
+	}
+
+	run, ok := commands[args[0]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(run(args[1:]))
+}
+
+// runServe 启动 HTTP 服务与后台任务
+func runServe(args []string) int {
+	var opts setting.Options
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	opts.Bind(fs)
+	fs.Parse(args)
+
+	if err := setting.Setup(opts); err != nil {
+		log.Fatalf("setting.Setup err: %v", err)
+	}
+	shutdownTracing, err := telemetry.Setup()
+	if err != nil {
+		log.Fatalf("telemetry.Setup err: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownTracing(ctx)
+	}()
+	if err := models.Setup(); err != nil {
+		log.Println(err)
+	}
 	logging.Setup()
 	gredis.Setup()
+	if err := ratelimit.Setup(); err != nil {
+		log.Fatalf("ratelimit.Setup err: %v", err)
+	}
+	webhook_service.Setup()
+	defer webhook_service.Stop()
+	related_service.Setup()
+	defer related_service.Stop()
+	registerJobs()
+	if err := scheduler.Setup(); err != nil {
+		log.Fatalf("scheduler.Setup err: %v", err)
+	}
+	defer scheduler.Stop()
 	router := routers.InitRouter()
 
 	s := &http.Server{
@@ -28,15 +82,18 @@ func main() {
 		MaxHeaderBytes: 1 << 20,
 	}
 
-	err := s.ListenAndServe()
+	err = s.ListenAndServe()
 	if err != nil {
 		log.Printf("Server err: %v", err)
+		return 1
 	}
+
+	return 0
 }
 
 // endless 实现优雅重启
 // func main() {
-// 	setting.Setup()
+// 	setting.Setup(setting.Options{})
 // 	models.Setup()
 // 	logging.Setup()
 
@@ -0,0 +1,129 @@
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+const (
+	// ALLOW_HEADERS 允许跨域请求携带的请求头，traceparent、tracestate 用于前端透传链路追踪
+	ALLOW_HEADERS = "Content-Type, Accept-Language, If-Match, If-None-Match, X-Tenant, X-Api-Version, X-Api-Key, X-CSRF-Token, traceparent, tracestate"
+	// EXPOSE_HEADERS 允许前端读取的响应头，修改文章时需将 ETag 作为 If-Match 发回，导出文件的文件名在 Content-Disposition 中
+	EXPOSE_HEADERS = "Content-Disposition, ETag, Last-Modified, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset"
+)
+
+// Policy 跨域策略
+type Policy struct {
+	Origins     []string // 允许的来源，* 表示任意来源
+	Methods     []string
+	Credentials bool // 是否允许明确列出的来源携带 Cookie，* 匹配的来源始终不允许
+	MaxAge      time.Duration
+}
+
+// allow 判断来源是否允许跨域访问，credentials 表示是否允许该来源携带 Cookie
+func (p *Policy) allow(origin string) (ok, credentials bool) {
+	wildcard := false
+	for _, o := range p.Origins {
+		if strings.EqualFold(o, origin) {
+			return true, p.Credentials
+		}
+		if o == "*" {
+			wildcard = true
+		}
+	}
+
+	return wildcard, false
+}
+
+// Api 管理接口的跨域策略，来源为 [cors] Api
+func Api() *Policy {
+	return &Policy{
+		Origins:     setting.CORSSetting.Api,
+		Methods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		Credentials: true,
+		MaxAge:      setting.CORSSetting.MaxAge,
+	}
+}
+
+// Public 公开内容的跨域策略，来源为 [cors] Public，只允许读取
+func Public() *Policy {
+	return &Policy{
+		Origins: setting.CORSSetting.Public,
+		Methods: []string{http.MethodGet, http.MethodHead},
+		MaxAge:  setting.CORSSetting.MaxAge,
+	}
+}
+
+// Rule 路径以 Prefix 开头的请求使用 Policy
+type Rule struct {
+	Prefix string
+	Policy *Policy
+}
+
+// CORS 按路径选择第一条匹配的规则处理跨域请求，需在注册路由之前通过 r.Use 注册，
+// 这样没有对应路由的 OPTIONS 预检请求也会经过它。没有匹配的规则或来源不被允许时不设置任何跨域响应头
+func CORS(rules ...Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		var policy *Policy
+		for _, rule := range rules {
+			if strings.HasPrefix(c.Request.URL.Path, rule.Prefix) {
+				policy = rule.Policy
+				break
+			}
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		ok, credentials := false, false
+		if policy != nil {
+			ok, credentials = policy.allow(origin)
+		}
+		if !ok {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if credentials {
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Set("Access-Control-Allow-Credentials", "true")
+		} else if policy.Credentials {
+			// 通过 * 允许的来源，响应不能随 Cookie 共享，也不能用 * 以免浏览器缓存给其他来源
+			header.Set("Access-Control-Allow-Origin", origin)
+		} else {
+			header.Set("Access-Control-Allow-Origin", "*")
+		}
+
+		if !preflight {
+			header.Set("Access-Control-Expose-Headers", EXPOSE_HEADERS)
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", strings.Join(policy.Methods, ", "))
+		header.Set("Access-Control-Allow-Headers", ALLOW_HEADERS)
+		if policy.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
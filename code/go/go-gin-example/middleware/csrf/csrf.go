@@ -0,0 +1,55 @@
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// HEADER 前端提交写请求时携带 CSRF token 的请求头
+const HEADER = "X-CSRF-Token"
+
+// COOKIE_NAME 保存 CSRF token 的 Cookie，不设置 HttpOnly，供前端读取后放入 HEADER
+const COOKIE_NAME = "csrf_token"
+
+// Token 由登录 Cookie 中的 token 计算 CSRF token，与登录状态绑定，其他站点既读不到也无法预先设置
+func Token(token string) string {
+	mac := hmac.New(sha256.New, []byte(setting.AppSetting.JwtSecret))
+	mac.Write([]byte("csrf:" + token))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Protect 校验通过 Cookie 登录的写请求，请求头 HEADER 必须与 Token 一致，需放在 JWT 中间件之后。
+// 通过 token 参数鉴权的请求不会被浏览器自动带上凭据，无需校验
+func Protect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if !jwt.FromCookie(c) {
+			c.Next()
+			return
+		}
+
+		token, _ := jwt.Token(c)
+		if got := c.GetHeader(HEADER); got == "" || !hmac.Equal([]byte(got), []byte(Token(token))) {
+			appG := app.Gin{C: c}
+			appG.Response(http.StatusForbidden, e.ERROR_CSRF_TOKEN, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
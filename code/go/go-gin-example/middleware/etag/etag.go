@@ -0,0 +1,85 @@
+package etag
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/app"
+)
+
+// bufferWriter 暂存响应内容，待 handler 执行完毕后再计算 ETag 并写出
+type bufferWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferWriter) WriteHeaderNow() {}
+
+func (w *bufferWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferWriter) Written() bool {
+	return false
+}
+
+// ETag 为 GET、HEAD 请求的 200 响应生成弱 ETag，请求头 If-None-Match 匹配时返回 304，
+// 响应内容会完整缓存在内存中，只应用于只读且响应不大的接口
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &bufferWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.status != http.StatusOK {
+			w.ResponseWriter.WriteHeader(w.status)
+			w.ResponseWriter.Write(w.body.Bytes())
+			return
+		}
+
+		sum := sha1.Sum(w.body.Bytes())
+		tag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+		c.Header("ETag", tag)
+		if c.Writer.Header().Get("Cache-Control") == "" {
+			c.Header("Cache-Control", "no-cache")
+		}
+
+		if app.NotModified(c.Request, tag, time.Time{}) {
+			c.Writer.Header().Del("Content-Type")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
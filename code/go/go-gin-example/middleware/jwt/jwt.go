@@ -1,47 +1,186 @@
 package jwt
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/pkg/app"
 	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
 	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/api_key_service"
 )
 
-// jwt中间件
+// CLAIMS_KEY 解析出的 token 声明在 gin.Context 中的键
+const CLAIMS_KEY = "claims"
+
+// COOKIE_NAME 保存 token 的 Cookie，/auth 指定 cookie=true 时设置
+const COOKIE_NAME = "token"
+
+// API_KEY_HEADER 携带 API Key 的请求头，与限流的 apikey 维度使用同一个请求头
+const API_KEY_HEADER = "X-Api-Key"
+
+// FROM_COOKIE_KEY token 取自 Cookie 时在 gin.Context 中设置的键，这类请求需经过 CSRF 校验
+const FROM_COOKIE_KEY = "token_from_cookie"
+
+// jwt中间件，依次取 token 参数、X-Api-Key 请求头与 Cookie 中的凭据
 func JWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var code int
 		var data interface{}
+		var claims *util.Claims
 
 		code = e.SUCCESS
-		token := c.Query("token")
-		if token == "" {
+		token, fromCookie := Token(c)
+		apiKey := c.GetHeader(API_KEY_HEADER)
+		if apiKey != "" && (token == "" || fromCookie) {
+			// API Key 需由客户端显式放入请求头，不会被浏览器自动带上，无需 CSRF 校验
+			fromCookie = false
+			var err error
+			claims, err = apiKeyClaims(c.Request.Context(), tenant.GetID(c), apiKey)
+			if err != nil {
+				logging.Error(err)
+				appG := app.Gin{C: c}
+				appG.Response(http.StatusInternalServerError, e.ERROR, nil)
+				c.Abort()
+				return
+			}
+			if claims == nil {
+				code = e.ERROR_AUTH_CHECK_API_KEY_FAIL
+			}
+		} else if token == "" {
 			code = e.INVALID_PARAMS
 		} else {
+			var err error
 			// 解析 token
-			claims, err := util.ParseToken(token)
+			claims, err = util.ParseToken(token)
 			if err != nil {
 				code = e.ERROR_AUTH_CHECK_TOKEN_FAIL
 			} else if time.Now().Unix() > claims.ExpiresAt {
 				// 判断token是否过期
 				code = e.ERROR_AUTH_CHECK_TOKEN_TIMEOUT
+			} else if claims.TenantID != tenant.GetID(c) {
+				// 其他站点签发的 token
+				code = e.ERROR_AUTH_CHECK_TOKEN_FAIL
 			}
 		}
 
 		if code != e.SUCCESS {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code": code,
-				"msg":  e.GetMsg(code),
-				"data": data,
-			})
+			appG := app.Gin{C: c}
+			appG.Response(http.StatusUnauthorized, code, data)
 
 			c.Abort()
 			return
 		}
 
+		c.Set(CLAIMS_KEY, claims)
+		if fromCookie {
+			c.Set(FROM_COOKIE_KEY, true)
+		}
 		c.Next()
 	}
 }
+
+// apiKeyClaims 校验 API Key，角色取所属账号当前的角色，key 无效时返回 nil
+func apiKeyClaims(ctx context.Context, tenantID int, key string) (*util.Claims, error) {
+	apiKey, auth, err := api_key_service.Authenticate(ctx, tenantID, key)
+	if err != nil || apiKey == nil {
+		return nil, err
+	}
+
+	return &util.Claims{
+		TenantID: tenantID,
+		Username: auth.Username,
+		Role:     auth.Role,
+		ApiKeyID: apiKey.ID,
+		Scope:    apiKey.Scope,
+	}, nil
+}
+
+// Token 获取请求携带的 token，fromCookie 表示取自 Cookie
+func Token(c *gin.Context) (token string, fromCookie bool) {
+	if token = c.Query("token"); token != "" {
+		return token, false
+	}
+	if token, _ = c.Cookie(COOKIE_NAME); token != "" {
+		return token, true
+	}
+
+	return "", false
+}
+
+// FromCookie 通过 JWT 中间件的 token 是否取自 Cookie
+func FromCookie(c *gin.Context) bool {
+	return c.GetBool(FROM_COOKIE_KEY)
+}
+
+// RequireScope 通过 API Key 鉴权的请求，GET、HEAD 请求需具有 read 权限范围，其他请求需具有 write 权限范围，
+// 更高的权限范围包含较低的。通过 token 登录的请求不受限制，需放在 JWT 中间件之后
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			required = read
+		}
+		if HasScope(c, required) {
+			c.Next()
+			return
+		}
+
+		appG := app.Gin{C: c}
+		appG.Response(http.StatusForbidden, e.ERROR_API_KEY_SCOPE, nil)
+		c.Abort()
+	}
+}
+
+// HasScope 当前请求是否具有权限范围 scope，通过 token 登录的请求始终具有
+func HasScope(c *gin.Context, scope string) bool {
+	claims := GetClaims(c)
+	if claims == nil {
+		return false
+	}
+
+	return claims.ApiKeyID == 0 || api_key_service.Allows(claims.Scope, scope)
+}
+
+// RequireRole 只允许指定角色访问，需放在 JWT 中间件之后
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims := GetClaims(c); claims != nil {
+			for _, role := range roles {
+				if claims.Role == role {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		appG := app.Gin{C: c}
+		appG.Response(http.StatusForbidden, e.ERROR_AUTH_FORBIDDEN, nil)
+		c.Abort()
+	}
+}
+
+// GetClaims 获取 JWT 中间件解析出的 token 声明，未经过 JWT 中间件时返回 nil
+func GetClaims(c *gin.Context) *util.Claims {
+	if v, ok := c.Get(CLAIMS_KEY); ok {
+		if claims, ok := v.(*util.Claims); ok {
+			return claims
+		}
+	}
+
+	return nil
+}
+
+// GetUsername 获取当前登录用户名，未经过 JWT 中间件时返回空字符串
+func GetUsername(c *gin.Context) string {
+	if claims := GetClaims(c); claims != nil {
+		return claims.Username
+	}
+
+	return ""
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// 同一用户名连续登录失败达到阈值后被锁定，此后每次失败锁定时间翻倍，直到上限，登录成功后清零
+
+func failKey(username string) string {
+	return e.CACHE_LOCKOUT + "_FAIL_" + username
+}
+
+func lockKey(username string) string {
+	return e.CACHE_LOCKOUT + "_LOCK_" + username
+}
+
+// Locked 返回用户名剩余的锁定时间，未锁定时返回 0
+func Locked(ctx context.Context, username string) time.Duration {
+	if store == nil || setting.RateLimitSetting.LockoutThreshold <= 0 {
+		return 0
+	}
+
+	d, err := store.TTL(ctx, lockKey(username))
+	if err != nil {
+		logging.Warn(err)
+		return 0
+	}
+
+	return d
+}
+
+// AuthFailed 记录一次登录失败，返回因此产生的锁定时间
+func AuthFailed(ctx context.Context, username string) time.Duration {
+	cfg := setting.RateLimitSetting
+	if store == nil || cfg.LockoutThreshold <= 0 {
+		return 0
+	}
+
+	failures, err := store.Incr(ctx, failKey(username), cfg.LockoutMaxDuration)
+	if err != nil {
+		logging.Warn(err)
+		return 0
+	}
+	if failures < cfg.LockoutThreshold {
+		return 0
+	}
+
+	d := cfg.LockoutDuration
+	for i := cfg.LockoutThreshold; i < failures && d < cfg.LockoutMaxDuration; i++ {
+		d *= 2
+	}
+	if d > cfg.LockoutMaxDuration {
+		d = cfg.LockoutMaxDuration
+	}
+
+	if err := store.Lock(ctx, lockKey(username), d); err != nil {
+		logging.Warn(err)
+		return 0
+	}
+	logging.Warn("auth locked", username, failures, d)
+
+	return d
+}
+
+// AuthSucceeded 登录成功后清除失败记录
+func AuthSucceeded(ctx context.Context, username string) {
+	if store == nil {
+		return
+	}
+
+	if err := store.Delete(ctx, failKey(username)); err != nil {
+		logging.Warn(err)
+	}
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+const (
+	KEY_IP     = "ip"
+	KEY_USER   = "user"
+	KEY_APIKEY = "apikey"
+)
+
+// Policy 限流策略，每个 Period 内最多 Limit 次请求，令牌匀速补充
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Key    string // ip、user 或 apikey
+}
+
+// Rate 每秒补充的令牌数
+func (p *Policy) Rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+var (
+	store    Store
+	policies = make(map[string]*Policy)
+)
+
+// Setup 根据配置初始化存储与限流策略
+func Setup() error {
+	switch setting.RateLimitSetting.Store {
+	case "redis":
+		store = NewRedisStore()
+	default:
+		store = NewMemoryStore()
+	}
+
+	specs := map[string]string{
+		"auth":  setting.RateLimitSetting.Auth,
+		"write": setting.RateLimitSetting.Write,
+	}
+	for name, spec := range specs {
+		p, err := ParsePolicy(name, spec)
+		if err != nil {
+			return err
+		}
+		policies[name] = p
+	}
+
+	return nil
+}
+
+// ParsePolicy 解析形如 5/m、100/1h,user 的策略，逗号后为限流维度，默认按 IP，空字符串表示不限流
+func ParsePolicy(name, spec string) (*Policy, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	rate, key, _ := strings.Cut(spec, ",")
+	count, period, ok := strings.Cut(rate, "/")
+	if !ok {
+		return nil, fmt.Errorf("ratelimit.%s: %q must look like 10/m or 10/m,user", name, spec)
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("ratelimit.%s: invalid limit %q", name, count)
+	}
+
+	p := &Policy{Name: name, Limit: limit, Key: KEY_IP}
+	switch period = strings.TrimSpace(period); period {
+	case "s":
+		p.Period = time.Second
+	case "m":
+		p.Period = time.Minute
+	case "h":
+		p.Period = time.Hour
+	default:
+		if p.Period, err = time.ParseDuration(period); err != nil || p.Period <= 0 {
+			return nil, fmt.Errorf("ratelimit.%s: invalid period %q", name, period)
+		}
+	}
+
+	if key = strings.TrimSpace(key); key != "" {
+		if key != KEY_IP && key != KEY_USER && key != KEY_APIKEY {
+			return nil, fmt.Errorf("ratelimit.%s: key must be ip, user or apikey, got %q", name, key)
+		}
+		p.Key = key
+	}
+
+	return p, nil
+}
+
+// Limit 按名称对应的策略限流，策略未配置时不做限制
+func Limit(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit(c, policies[name])
+	}
+}
+
+// LimitWrites 与 Limit 相同，但只限制 POST、PUT、PATCH、DELETE 等写请求
+func LimitWrites(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+		default:
+			limit(c, policies[name])
+		}
+	}
+}
+
+func limit(c *gin.Context, p *Policy) {
+	if p == nil || store == nil {
+		c.Next()
+		return
+	}
+
+	key := e.CACHE_RATELIMIT + "_" + p.Name + "_" + clientKey(c, p.Key)
+	result, err := store.Take(c.Request.Context(), key, p.Rate(), p.Limit)
+	if err != nil {
+		// 存储不可用时放行，避免限流组件故障导致整个服务不可用
+		logging.Warn(err)
+		c.Next()
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(p.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+
+	if !result.Allowed {
+		SetRetryAfter(c, result.RetryAfter)
+		appG := app.Gin{C: c}
+		appG.Response(http.StatusTooManyRequests, e.TOO_MANY_REQUESTS, nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// clientKey 获取限流维度对应的标识，取不到用户或 API Key 时退回到 IP
+func clientKey(c *gin.Context, key string) string {
+	switch key {
+	case KEY_USER:
+		if claims := jwt.GetClaims(c); claims != nil {
+			return "user:" + strconv.Itoa(claims.TenantID) + ":" + claims.Username
+		}
+	case KEY_APIKEY:
+		if apiKey := c.GetHeader(jwt.API_KEY_HEADER); apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			return "apikey:" + hex.EncodeToString(sum[:8])
+		}
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+// SetRetryAfter 设置 Retry-After 响应头，单位为秒
+func SetRetryAfter(c *gin.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(seconds(d)))
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,234 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// clock 测试中手动推进的时钟
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newClock() *clock {
+	return &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *Policy
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"  ", nil, false},
+		{"5/s", &Policy{Name: "p", Limit: 5, Period: time.Second, Key: KEY_IP}, false},
+		{"10/m", &Policy{Name: "p", Limit: 10, Period: time.Minute, Key: KEY_IP}, false},
+		{"100/h,user", &Policy{Name: "p", Limit: 100, Period: time.Hour, Key: KEY_USER}, false},
+		{" 20 / 30s , apikey ", &Policy{Name: "p", Limit: 20, Period: 30 * time.Second, Key: KEY_APIKEY}, false},
+		{"10", nil, true},
+		{"0/m", nil, true},
+		{"-1/m", nil, true},
+		{"x/m", nil, true},
+		{"10/day", nil, true},
+		{"10/-1s", nil, true},
+		{"10/m,tenant", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePolicy("p", tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePolicy(%q) err = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePolicy(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	p, _ := ParsePolicy("p", "30/m")
+	if rate := p.Rate(); rate != 0.5 {
+		t.Errorf("Rate() = %g, want 0.5", rate)
+	}
+}
+
+func TestTake(t *testing.T) {
+	c := newClock()
+	s := newMemoryStore(c.now)
+
+	take := func(key string) Result {
+		t.Helper()
+		r, err := s.Take(context.Background(), key, 1, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// 新建的桶是满的，可以连续取 burst 个令牌
+	for want := 2; want >= 0; want-- {
+		if r := take("a"); !r.Allowed || r.Remaining != want {
+			t.Fatalf("take = %+v, want allowed with %d remaining", r, want)
+		}
+	}
+	r := take("a")
+	if r.Allowed || r.RetryAfter != time.Second || r.Reset != 3*time.Second {
+		t.Fatalf("take from empty bucket = %+v", r)
+	}
+
+	// 其他 key 不受影响
+	if r := take("b"); !r.Allowed || r.Remaining != 2 {
+		t.Fatalf("take b = %+v", r)
+	}
+
+	// 令牌按 rate 匀速补充
+	c.advance(500 * time.Millisecond)
+	if r := take("a"); r.Allowed || r.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("take after 500ms = %+v", r)
+	}
+	c.advance(500 * time.Millisecond)
+	if r := take("a"); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("take after 1s = %+v", r)
+	}
+
+	// 补充的令牌不超过 burst
+	c.advance(time.Hour)
+	if r := take("a"); !r.Allowed || r.Remaining != 2 || r.Reset != time.Second {
+		t.Fatalf("take after an hour = %+v", r)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	c := newClock()
+	s := newMemoryStore(c.now)
+
+	// fast 每秒补充 1 个，slow 每 100 秒补充 1 个，各取一个令牌后 fast 1 秒即装满
+	s.Take(context.Background(), "fast", 1, 10)
+	s.Take(context.Background(), "slow", 0.01, 10)
+	s.Incr(context.Background(), "counter", time.Minute)
+	s.Lock(context.Background(), "lock", time.Hour)
+
+	c.advance(2 * time.Second)
+	s.sweep()
+	if _, ok := s.buckets["fast"]; ok {
+		t.Error("full bucket is not swept")
+	}
+	if _, ok := s.buckets["slow"]; !ok {
+		t.Error("slow bucket is swept before it is full")
+	}
+
+	c.advance(time.Minute)
+	s.sweep()
+	if _, ok := s.entries["counter"]; ok {
+		t.Error("expired counter is not swept")
+	}
+	if d, _ := s.TTL(context.Background(), "lock"); d != time.Hour-time.Minute-2*time.Second {
+		t.Errorf("lock TTL = %s", d)
+	}
+
+	c.advance(100 * time.Second)
+	s.sweep()
+	if len(s.buckets) != 0 {
+		t.Errorf("buckets after refill = %v", s.buckets)
+	}
+}
+
+func TestAuthFailed(t *testing.T) {
+	c := newClock()
+	prevStore, prevSetting := store, *setting.RateLimitSetting
+	defer func() { store, *setting.RateLimitSetting = prevStore, prevSetting }()
+	store = newMemoryStore(c.now)
+	setting.RateLimitSetting.LockoutThreshold = 3
+	setting.RateLimitSetting.LockoutDuration = time.Minute
+	setting.RateLimitSetting.LockoutMaxDuration = 5 * time.Minute
+
+	// 达到阈值后锁定，此后每次失败翻倍，不超过上限
+	for i, want := range []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if got := AuthFailed(context.Background(), "alice"); got != want {
+			t.Fatalf("failure %d: locked for %s, want %s", i+1, got, want)
+		}
+	}
+	if d := Locked(context.Background(), "alice"); d != 5*time.Minute {
+		t.Fatalf("Locked = %s, want 5m", d)
+	}
+	if d := Locked(context.Background(), "bob"); d != 0 {
+		t.Fatalf("bob is locked for %s", d)
+	}
+
+	c.advance(5 * time.Minute)
+	if d := Locked(context.Background(), "alice"); d != 0 {
+		t.Fatalf("still locked for %s after the lockout", d)
+	}
+
+	// 登录成功后重新计数
+	AuthSucceeded(context.Background(), "alice")
+	if d := AuthFailed(context.Background(), "alice"); d != 0 {
+		t.Fatalf("first failure after success: locked for %s", d)
+	}
+
+	// 关闭锁定时不计数
+	setting.RateLimitSetting.LockoutThreshold = 0
+	for i := 0; i < 5; i++ {
+		if d := AuthFailed(context.Background(), "carol"); d != 0 {
+			t.Fatalf("lockout disabled: locked for %s", d)
+		}
+	}
+}
+
+// TestClientIP 只有来自信任代理的请求才按 X-Forwarded-For 区分客户端，伪造的请求头不能换到新的令牌桶
+func TestClientIP(t *testing.T) {
+	prevStore, prevPolicy := store, policies["test"]
+	defer func() { store, policies["test"] = prevStore, prevPolicy }()
+
+	tests := []struct {
+		name    string
+		proxies []string
+		want    []int
+	}{
+		{"no trusted proxies", nil, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+		{"trusted proxy", []string{"10.0.0.1"}, []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store = newMemoryStore(newClock().now)
+			policies["test"] = &Policy{Name: "test", Limit: 2, Period: time.Minute, Key: KEY_IP}
+
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatal(err)
+			}
+			r.GET("/", Limit("test"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for i, want := range tt.want {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != want {
+					t.Fatalf("request %d: status = %d, want %d", i+1, w.Code, want)
+				}
+			}
+		})
+	}
+}
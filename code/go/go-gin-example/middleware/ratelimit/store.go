@@ -0,0 +1,261 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+)
+
+// Result 一次取令牌的结果
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter 未放行时距离下一个令牌可用的时间
+	RetryAfter time.Duration
+	// Reset 令牌桶重新装满所需的时间
+	Reset time.Duration
+}
+
+// Store 令牌桶与失败计数的存储，单实例部署使用内存，多实例部署使用 Redis 共享状态
+type Store interface {
+	// Take 从 key 对应的令牌桶中取一个令牌，rate 为每秒补充的令牌数，burst 为桶容量
+	Take(ctx context.Context, key string, rate float64, burst int) (Result, error)
+	// Incr 计数器加一并返回新值，计数器在首次创建 expire 后过期
+	Incr(ctx context.Context, key string, expire time.Duration) (int, error)
+	// Lock 设置一个 d 后过期的锁
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// TTL 返回锁或计数器的剩余时间，不存在时返回 0
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Delete 删除 key
+	Delete(ctx context.Context, key string) error
+}
+
+func bucketResult(tokens, rate float64, burst int, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		r.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	return r
+}
+
+// bucket 令牌桶，记录创建时的 rate 与 burst，清理时按各自的策略判断是否已装满
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  int
+}
+
+// full 到 now 时令牌桶是否已装满，装满的桶与新建的没有区别，可以删除
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= float64(b.burst)
+}
+
+type entry struct {
+	count  int
+	expire time.Time
+}
+
+// SWEEP_INTERVAL 内存存储清理已装满的令牌桶与已过期的计数器、锁的间隔
+const SWEEP_INTERVAL = time.Minute
+
+// memoryStore 进程内存储，只适用于单实例部署
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+// NewMemoryStore 创建内存存储，并每隔 SWEEP_INTERVAL 清理一次，以免 map 无限增长
+func NewMemoryStore() Store {
+	s := newMemoryStore(time.Now)
+	go func() {
+		ticker := time.NewTicker(SWEEP_INTERVAL)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.sweep()
+		}
+	}()
+
+	return s
+}
+
+func newMemoryStore(now func() time.Time) *memoryStore {
+	return &memoryStore{
+		buckets: make(map[string]*bucket),
+		entries: make(map[string]*entry),
+		now:     now,
+	}
+}
+
+func (s *memoryStore) Take(_ context.Context, key string, rate float64, burst int) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.rate, b.burst = rate, burst
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return bucketResult(b.tokens, rate, burst, allowed), nil
+}
+
+// sweep 删除已装满的令牌桶与已过期的计数器、锁
+func (s *memoryStore) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, b := range s.buckets {
+		if b.full(now) {
+			delete(s.buckets, k)
+		}
+	}
+	for k, e := range s.entries {
+		if !now.Before(e.expire) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+func (s *memoryStore) get(key string) *entry {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if !s.now().Before(e.expire) {
+		delete(s.entries, key)
+		return nil
+	}
+
+	return e
+}
+
+func (s *memoryStore) Incr(_ context.Context, key string, expire time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.get(key)
+	if e == nil {
+		e = &entry{expire: s.now().Add(expire)}
+		s.entries[key] = e
+	}
+	e.count++
+
+	return e.count, nil
+}
+
+func (s *memoryStore) Lock(_ context.Context, key string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &entry{count: 1, expire: s.now().Add(d)}
+	return nil
+}
+
+func (s *memoryStore) TTL(_ context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.get(key)
+	if e == nil {
+		return 0, nil
+	}
+
+	return e.expire.Sub(s.now()), nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.buckets, key)
+	delete(s.entries, key)
+	return nil
+}
+
+// takeScript 在 Redis 中原子地补充并扣减令牌，返回 {是否放行, 剩余令牌}
+const takeScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, tostring(tokens)}
+`
+
+// redisStore 基于 gredis 的存储，多个实例共享限流状态
+type redisStore struct{}
+
+func NewRedisStore() Store {
+	return redisStore{}
+}
+
+func (redisStore) Take(ctx context.Context, key string, rate float64, burst int) (Result, error) {
+	reply, err := gredis.Eval(ctx, takeScript, []string{key}, rate, burst, time.Now().UnixMilli())
+	if err != nil {
+		return Result{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	s, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return bucketResult(tokens, rate, burst, allowed == 1), nil
+}
+
+func (redisStore) Incr(ctx context.Context, key string, expire time.Duration) (int, error) {
+	n, err := gredis.Incr(ctx, key, expire)
+	return int(n), err
+}
+
+func (redisStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	return gredis.Set(ctx, key, 1, int(math.Ceil(d.Seconds())))
+}
+
+func (redisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return gredis.TTL(ctx, key)
+}
+
+func (redisStore) Delete(ctx context.Context, key string) error {
+	_, err := gredis.Delete(ctx, key)
+	return err
+}
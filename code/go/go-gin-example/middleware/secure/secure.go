@@ -0,0 +1,51 @@
+package secure
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// SWAGGER_CSP swagger 页面使用内联脚本与样式，默认的 Content-Security-Policy 会使其无法显示
+const SWAGGER_CSP = "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'"
+
+// Headers 为所有响应设置安全相关的响应头，配置在 [security] 中，HSTS 只对 HTTPS 请求设置
+func Headers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if csp := setting.SecuritySetting.ContentSecurityPolicy; csp != "" {
+			header.Set("Content-Security-Policy", csp)
+		}
+		if frame := setting.SecuritySetting.FrameOptions; frame != "" {
+			header.Set("X-Frame-Options", frame)
+		}
+		if maxAge := setting.SecuritySetting.HSTSMaxAge; maxAge > 0 && IsHTTPS(c) {
+			hsts := "max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+			if setting.SecuritySetting.HSTSIncludeSubdomains {
+				hsts += "; includeSubDomains"
+			}
+			header.Set("Strict-Transport-Security", hsts)
+		}
+
+		c.Next()
+	}
+}
+
+// CSP 覆盖 Headers 设置的 Content-Security-Policy，用于 swagger 等需要执行脚本的页面，未配置 CSP 时不设置
+func CSP(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if setting.SecuritySetting.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", policy)
+		}
+		c.Next()
+	}
+}
+
+// IsHTTPS 请求是否通过 HTTPS 访问，部署在反向代理之后时按 X-Forwarded-Proto 判断
+func IsHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
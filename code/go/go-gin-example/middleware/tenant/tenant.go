@@ -0,0 +1,82 @@
+package tenant
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/service/tenant_service"
+)
+
+// TENANT_KEY 当前请求所属的站点在 gin.Context 中的键
+const TENANT_KEY = "tenant"
+
+// HEADER 指定站点名称的请求头，优先于请求的域名
+const HEADER = "X-Tenant"
+
+// Resolve 确定请求所属的站点，找不到启用的站点时返回 404
+func Resolve() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appG := app.Gin{C: c}
+
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		tenant, err := tenant_service.Resolve(c.Request.Context(), c.GetHeader(HEADER), host)
+		if err != nil {
+			logging.Error(err)
+			appG.Response(http.StatusInternalServerError, e.ERROR_GET_TENANT_FAIL, nil)
+			c.Abort()
+			return
+		}
+		if tenant.ID == 0 {
+			appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TENANT, nil)
+			c.Abort()
+			return
+		}
+
+		c.Set(TENANT_KEY, tenant)
+		c.Next()
+	}
+}
+
+// RequireDefault 只允许默认站点访问，用于站点管理、定时任务等全局的接口
+func RequireDefault() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenant := Get(c); tenant != nil && tenant_service.IsDefault(tenant) {
+			c.Next()
+			return
+		}
+
+		appG := app.Gin{C: c}
+		appG.Response(http.StatusForbidden, e.ERROR_TENANT_FORBIDDEN, nil)
+		c.Abort()
+	}
+}
+
+// Get 获取当前请求所属的站点，未经过 Resolve 中间件时返回 nil
+func Get(c *gin.Context) *models.Tenant {
+	if v, ok := c.Get(TENANT_KEY); ok {
+		if tenant, ok := v.(*models.Tenant); ok {
+			return tenant
+		}
+	}
+
+	return nil
+}
+
+// GetID 获取当前请求所属站点的 ID，未经过 Resolve 中间件时返回 0
+func GetID(c *gin.Context) int {
+	if tenant := Get(c); tenant != nil {
+		return tenant.ID
+	}
+
+	return 0
+}
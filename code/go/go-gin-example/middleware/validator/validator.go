@@ -0,0 +1,102 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+)
+
+var (
+	once   sync.Once
+	router routers.Router
+	err    error
+
+	// invalidMsg 无法由请求结构体得到错误信息时，各参数统一的错误信息
+	invalidMsg = map[string]string{e.LANG_ZH: "格式不正确", e.LANG_EN: "is invalid"}
+)
+
+// OpenAPI 按照 OpenAPI 文档校验请求，文档中未声明的接口或参数不符合定义的请求会被拒绝
+func OpenAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		once.Do(func() {
+			// 错误信息中不附带完整的 schema，日志更简洁
+			openapi3.SchemaErrorDetailsDisabled = true
+			router, err = legacy.NewRouter(openapi.Document())
+		})
+		if err != nil {
+			logging.Error(err)
+			abort(c, http.StatusInternalServerError, e.ERROR, nil)
+			return
+		}
+
+		// 请求已由 gin 匹配到路由，文档中找不到说明接口没有注册到文档中
+		route, pathParams, findErr := router.FindRoute(c.Request)
+		if findErr != nil {
+			logging.Error("route is not in the openapi document", c.Request.Method, c.FullPath(), findErr)
+			abort(c, http.StatusInternalServerError, e.ERROR, nil)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				// token 由 jwt 中间件校验
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if validateErr := openapi3filter.ValidateRequest(c.Request.Context(), input); validateErr != nil {
+			logging.Info(validateErr)
+			abort(c, http.StatusBadRequest, e.INVALID_PARAMS, fieldErrors(c, validateErr))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// fieldErrors 将校验错误转换为与 app.BindAndValid 相同的 参数名 → 错误信息，不向客户端返回校验库的原始错误
+func fieldErrors(c *gin.Context, validateErr error) map[string]string {
+	// 文档由请求结构体的 binding 标签生成，用同一结构体再校验一次即可得到翻译后的错误信息
+	if op, ok := openapi.Lookup(c.Request.Method, c.FullPath()); ok && op.Request != nil {
+		form := reflect.New(reflect.TypeOf(op.Request)).Interface()
+		if _, _, errs := app.BindAndValid(c, form); len(errs) > 0 {
+			return errs
+		}
+	}
+
+	// 类型不符、多余的字段等 binding 标签无法表达的错误，只指出参数名
+	name := "body"
+	var reqErr *openapi3filter.RequestError
+	if errors.As(validateErr, &reqErr) && reqErr.Parameter != nil {
+		name = reqErr.Parameter.Name
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(validateErr, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			name = strings.Join(pointer, ".")
+		}
+	}
+
+	return map[string]string{name: invalidMsg[app.Lang(c)]}
+}
+
+func abort(c *gin.Context, httpCode, code int, detail interface{}) {
+	appG := app.Gin{C: c}
+	appG.Response(httpCode, code, detail)
+	c.Abort()
+}
@@ -0,0 +1,86 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+)
+
+type itemForm struct {
+	Name  string `form:"name" binding:"required,max=10"`
+	State int    `form:"state" binding:"oneof=0 1"`
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	openapi.Register(openapi.Operation{Method: http.MethodPost, Path: "/items", Summary: "新建", Tag: "test", Request: itemForm{}})
+}
+
+func newRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(OpenAPI())
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.POST("/items", ok)
+	r.GET("/undocumented", ok)
+
+	return r
+}
+
+func TestOpenAPI(t *testing.T) {
+	r := newRouter()
+
+	tests := []struct {
+		method, path string
+		form         url.Values
+		status, code int
+	}{
+		{http.MethodPost, "/items", url.Values{"name": {"go"}, "state": {"1"}}, http.StatusNoContent, 0},
+		{http.MethodPost, "/items", url.Values{"state": {"1"}}, http.StatusBadRequest, e.INVALID_PARAMS},
+		{http.MethodPost, "/items", url.Values{"name": {"go"}, "state": {"2"}}, http.StatusBadRequest, e.INVALID_PARAMS},
+		{http.MethodPost, "/items", url.Values{"name": {"a long name"}}, http.StatusBadRequest, e.INVALID_PARAMS},
+		// 文档中没有的接口是注册遗漏，返回 500 而不是参数错误
+		{http.MethodGet, "/undocumented", nil, http.StatusInternalServerError, e.ERROR},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s %s %v: status = %d, want %d", tt.method, tt.path, tt.form, w.Code, tt.status)
+		}
+		if tt.code != 0 && !strings.Contains(w.Body.String(), fmt.Sprintf(`"code":%d`, tt.code)) {
+			t.Errorf("%s %s %v: body = %s, want code %d", tt.method, tt.path, tt.form, w.Body.String(), tt.code)
+		}
+	}
+}
+
+// TestFieldErrors 校验失败时返回按请求语言翻译的参数错误，而不是校验库的原始错误
+func TestFieldErrors(t *testing.T) {
+	r := newRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/items?lang=en", strings.NewReader("state=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body struct {
+		Code int               `json:"code"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || body.Code != e.INVALID_PARAMS || body.Data["name"] != "name is a required field" {
+		t.Fatalf("%d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	SCOPE_READ           = "read"
+	SCOPE_ARTICLES_WRITE = "articles:write"
+	SCOPE_ADMIN          = "admin"
+)
+
+// ApiKey 账号的长期凭据，只保存 key 的 SHA-256，吊销即软删除
+type ApiKey struct {
+	Model
+
+	TenantID   int    `json:"tenant_id"`
+	Username   string `json:"username"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`
+	KeyHash    string `json:"-"`
+	Scope      string `json:"scope"`
+	ExpiresOn  int    `json:"expires_on"`
+	LastUsedOn int    `json:"last_used_on"`
+}
+
+// GetApiKeys 获取站点下的 API Key，username 为空时获取所有账号的
+func GetApiKeys(ctx context.Context, tenantID int, username string) ([]ApiKey, error) {
+	var keys []ApiKey
+	query := withContext(ctx).Where("tenant_id = ? AND deleted_on = ?", tenantID, 0)
+	if username != "" {
+		query = query.Where("username = ?", username)
+	}
+	err := query.Order("id desc").Find(&keys).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetApiKey 获取站点下未吊销的 API Key，不存在时返回 ID 为 0 的 API Key
+func GetApiKey(ctx context.Context, tenantID, id int) (*ApiKey, error) {
+	var key ApiKey
+	err := withContext(ctx).Where("id = ? AND tenant_id = ? AND deleted_on = ?", id, tenantID, 0).First(&key).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetApiKeyByHash 按 key 的 SHA-256 获取未吊销的 API Key，不存在时返回 ID 为 0 的 API Key
+func GetApiKeyByHash(ctx context.Context, keyHash string) (*ApiKey, error) {
+	var key ApiKey
+	err := withContext(ctx).Where("key_hash = ? AND deleted_on = ?", keyHash, 0).First(&key).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func AddApiKey(ctx context.Context, key *ApiKey) error {
+	return withContext(ctx).Create(key).Error
+}
+
+// DeleteApiKey 吊销 API Key
+func DeleteApiKey(ctx context.Context, tenantID, id int) error {
+	return withContext(ctx).Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&ApiKey{}).Error
+}
+
+// TouchApiKey 记录最近使用时间，不更新修改时间
+func TouchApiKey(ctx context.Context, id, usedOn int) error {
+	return withContext(ctx).Model(&ApiKey{}).Where("id = ?", id).UpdateColumn("last_used_on", usedOn).Error
+}
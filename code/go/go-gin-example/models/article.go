@@ -1,26 +1,43 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"context"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/fzzv/go-gin-example/pkg/util"
+)
 
 type Article struct {
 	Model
 
 	// gorm:index，用于声明这个字段为索引，如果使用了自动迁移功能则会有所影响，不使用则无影响
-	TagID int `json:"tag_id" gorm:"index"`
-	Tag   Tag `json:"tag"`
+	TenantID int `json:"tenant_id" gorm:"index"`
+	TagID    int `json:"tag_id" gorm:"index"`
+	Tag      Tag `json:"tag"`
 
 	Title         string `json:"title"`
+	Slug          string `json:"slug"`
 	Desc          string `json:"desc"`
 	Content       string `json:"content"`
 	CreatedBy     string `json:"created_by"`
 	ModifiedBy    string `json:"modified_by"`
 	CoverImageUrl string `json:"cover_image_url"`
 	State         int    `json:"state"`
+	Version       int    `json:"version"` // 每次修改加一，用于乐观锁
+
+	// 浏览数先在 Redis 中累计，由定时任务定期写入，点赞数随点赞实时更新，两者都不改变 Version
+	ViewCount int `json:"view_count"`
+	LikeCount int `json:"like_count"`
+
+	// 文章所在系列及前后篇，只在获取单篇文章时填充
+	Series *SeriesNav `json:"series,omitempty" gorm:"-"`
 }
 
-func ExistArticleByID(id int) (bool, error) {
+func ExistArticleByID(ctx context.Context, tenantID, id int) (bool, error) {
 	var article Article
-	err := db.Select("id").Where("id = ? AND deleted_on = ? ", id, 0).First(&article).Error
+	err := withContext(ctx).Select("id").Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).First(&article).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -32,9 +49,9 @@ func ExistArticleByID(id int) (bool, error) {
 	return false, nil
 }
 
-func GetArticleTotal(maps interface{}) (int, error) {
+func GetArticleTotal(ctx context.Context, maps interface{}) (int, error) {
 	var count int
-	if err := db.Model(&Article{}).Where(maps).Count(&count).Error; err != nil {
+	if err := withContext(ctx).Model(&Article{}).Where(maps).Count(&count).Error; err != nil {
 		return 0, err
 	}
 
@@ -47,9 +64,9 @@ Preload就是一个预加载器，它会执行两条SQL，
 分别是SELECT * FROM blog_articles;和SELECT * FROM blog_tag WHERE id IN (1,2,3,4);
 那么在查询出结构后，gorm内部处理对应的映射逻辑，将其填充到Article的Tag中，会特别方便，并且避免了循环查询
 */
-func GetArticles(pageNum int, pageSize int, maps interface{}) ([]*Article, error) {
+func GetArticles(ctx context.Context, pageNum int, pageSize int, maps interface{}) ([]*Article, error) {
 	var articles []*Article
-	err := db.Preload("Tag").Where(maps).Offset(pageNum).Limit(pageSize).Find(&articles).Error
+	err := withContext(ctx).Preload("Tag").Where(maps).Offset(pageNum).Limit(pageSize).Find(&articles).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -63,9 +80,9 @@ Article是如何关联到Tag？
 Article有一个结构体成员是TagID，就是外键。gorm会通过类名+ID的方式去找到这两个类之间的关联关系
 Article有一个结构体成员是Tag，就是我们嵌套在Article里的Tag结构体，我们可以通过Related进行关联查询
 */
-func GetArticle(id int) (*Article, error) {
+func GetArticle(ctx context.Context, tenantID, id int) (*Article, error) {
 	var article Article
-	err := db.Where("id = ? AND deleted_on = ? ", id, 0).First(&article).Related(&article.Tag).Error
+	err := withContext(ctx).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).First(&article).Related(&article.Tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -73,39 +90,174 @@ func GetArticle(id int) (*Article, error) {
 	return &article, nil
 }
 
-func EditArticle(id int, data interface{}) error {
-	if err := db.Model(&Article{}).Where("id = ? AND deleted_on = ? ", id, 0).Updates(data).Error; err != nil {
-		return err
-	}
-
-	return nil
+// EditArticle 修改文章，version 大于 0 时仅在当前版本等于 version 时修改，否则返回 ErrVersionConflict
+func EditArticle(ctx context.Context, tenantID, id int, version int, data interface{}) error {
+	return editVersioned(withContext(ctx).Model(&Article{}).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0), version, data)
 }
 
-func AddArticle(data map[string]interface{}) error {
+func AddArticle(ctx context.Context, data map[string]interface{}) (*Article, error) {
 	article := Article{
+		TenantID:      data["tenant_id"].(int),
 		TagID:         data["tag_id"].(int),
 		Title:         data["title"].(string),
+		Slug:          data["slug"].(string),
 		Desc:          data["desc"].(string),
 		Content:       data["content"].(string),
 		CreatedBy:     data["created_by"].(string),
 		State:         data["state"].(int),
 		CoverImageUrl: data["cover_image_url"].(string),
 	}
-	if err := db.Create(&article).Error; err != nil {
-		return err
+	if err := withContext(ctx).Create(&article).Error; err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &article, nil
 }
 
-func DeleteArticle(id int) error {
-	if err := db.Where("id = ?", id).Delete(Article{}).Error; err != nil {
+func DeleteArticle(ctx context.Context, tenantID, id int) error {
+	if err := withContext(ctx).Where("tenant_id = ? AND id = ?", tenantID, id).Delete(Article{}).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// ExistArticleBySlug 判断 slug 是否已被站点内的其他文章使用，回收站中的文章也占用 slug
+func ExistArticleBySlug(ctx context.Context, tenantID int, slug string, excludeID int) (bool, error) {
+	return existArticleBySlug(withContext(ctx), tenantID, slug, excludeID)
+}
+
+func existArticleBySlug(tx *gorm.DB, tenantID int, slug string, excludeID int) (bool, error) {
+	var article Article
+	err := tx.Select("id").Where("tenant_id = ? AND slug = ? AND id != ?", tenantID, slug, excludeID).First(&article).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+
+	return article.ID > 0, nil
+}
+
+// UniqueArticleSlug 由标题生成站点内未被使用的 slug，重复时追加 -2、-3 等序号
+func UniqueArticleSlug(ctx context.Context, tenantID int, title string) (string, error) {
+	return uniqueArticleSlug(withContext(ctx), tenantID, title)
+}
+
+func uniqueArticleSlug(tx *gorm.DB, tenantID int, title string) (string, error) {
+	base := util.Slugify(title)
+	if base == "" {
+		base = "article"
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		exists, err := existArticleBySlug(tx, tenantID, slug, 0)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+		slug = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// published 站点内已发布且所属标签已启用的文章，tagID 为 0 时不限标签
+func published(ctx context.Context, tenantID, tagID int) *gorm.DB {
+	tags := withContext(ctx).Model(&Tag{}).Select("id").Where("tenant_id = ? AND state = ? AND deleted_on = ?", tenantID, 1, 0).QueryExpr()
+	query := withContext(ctx).Model(&Article{}).Where("tenant_id = ? AND state = ? AND deleted_on = ? AND tag_id IN (?)", tenantID, 1, 0, tags)
+	if tagID > 0 {
+		query = query.Where("tag_id = ?", tagID)
+	}
+
+	return query
+}
+
+// GetPublishedArticles 获取已发布的文章，最新发布的在前
+func GetPublishedArticles(ctx context.Context, tenantID, pageNum, pageSize, tagID int) ([]*Article, error) {
+	var articles []*Article
+	err := published(ctx, tenantID, tagID).Preload("Tag").Order("created_on DESC, id DESC").Offset(pageNum).Limit(pageSize).Find(&articles).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+func GetPublishedArticleTotal(ctx context.Context, tenantID, tagID int) (int, error) {
+	var count int
+	if err := published(ctx, tenantID, tagID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetPublishedArticleBySlug 按 slug 获取已发布的文章，不存在时返回 ID 为 0 的文章
+func GetPublishedArticleBySlug(ctx context.Context, tenantID int, slug string) (*Article, error) {
+	var article Article
+	err := published(ctx, tenantID, 0).Preload("Tag").Where("slug = ?", slug).First(&article).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// GetPublishedArticleIndex 获取所有已发布文章的 slug 与时间，用于生成站点地图
+func GetPublishedArticleIndex(ctx context.Context, tenantID, limit int) ([]*Article, error) {
+	var articles []*Article
+	err := published(ctx, tenantID, 0).Select("id, slug, created_on, modified_on").Order("created_on DESC, id DESC").Limit(limit).Find(&articles).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// GetArticlesByIDs 批量获取未删除的文章及其标签，按 ID 排序
+func GetArticlesByIDs(ctx context.Context, tenantID int, ids []int) ([]*Article, error) {
+	var articles []*Article
+	err := withContext(ctx).Preload("Tag").Where("tenant_id = ? AND id IN (?) AND deleted_on = ? ", tenantID, ids, 0).Order("id").Find(&articles).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// GetArticlesByTags 批量获取多个标签下未删除的文章，不带出标签
+func GetArticlesByTags(ctx context.Context, tenantID int, tagIDs []int) ([]*Article, error) {
+	var articles []*Article
+	err := withContext(ctx).Where("tenant_id = ? AND tag_id IN (?) AND deleted_on = ? ", tenantID, tagIDs, 0).Order("id").Find(&articles).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// GetArticlesByAuthors 批量获取多个作者未删除的文章，不带出标签
+func GetArticlesByAuthors(ctx context.Context, tenantID int, usernames []string) ([]*Article, error) {
+	var articles []*Article
+	err := withContext(ctx).Where("tenant_id = ? AND created_by IN (?) AND deleted_on = ? ", tenantID, usernames, 0).Order("id").Find(&articles).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// GetArticleAuthors 获取站点内未删除的文章的作者，按用户名排序
+func GetArticleAuthors(ctx context.Context, tenantID int) ([]string, error) {
+	var usernames []string
+	err := withContext(ctx).Model(&Article{}).Where("tenant_id = ? AND deleted_on = ? AND created_by != ?", tenantID, 0, "").Order("created_by").Pluck("DISTINCT created_by", &usernames).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return usernames, nil
+}
+
 // func (article *Article) BeforeCreate(scope *gorm.Scope) error {
 // 	// time.Now().Unix() 返回当前的时间戳
 // 	scope.SetColumn("CreatedOn", time.Now().Unix())
@@ -117,7 +269,60 @@ func DeleteArticle(id int) error {
 // 	return nil
 // }
 
-func CleanAllArticle() bool {
-	db.Unscoped().Where("deleted_on != ?", 0).Delete(&Article{})
-	return true
+// GetDeletedArticle 获取回收站中的文章，不存在时返回 ID 为 0 的文章
+func GetDeletedArticle(ctx context.Context, tenantID, id int) (*Article, error) {
+	var article Article
+	err := withContext(ctx).Where("tenant_id = ? AND id = ? AND deleted_on != ? ", tenantID, id, 0).First(&article).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// GetDeletedArticles 获取回收站中的文章，最近删除的在前
+func GetDeletedArticles(ctx context.Context, tenantID int, pageNum int, pageSize int) ([]*Article, error) {
+	var articles []*Article
+	err := withContext(ctx).Where("tenant_id = ? AND deleted_on != ? ", tenantID, 0).Order("deleted_on DESC").Offset(pageNum).Limit(pageSize).Find(&articles).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+func GetDeletedArticleTotal(ctx context.Context, tenantID int) (int, error) {
+	var count int
+	if err := withContext(ctx).Model(&Article{}).Where("tenant_id = ? AND deleted_on != ? ", tenantID, 0).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// RestoreArticle 从回收站恢复文章
+func RestoreArticle(ctx context.Context, tenantID, id int) error {
+	return withContext(ctx).Model(&Article{}).Where("tenant_id = ? AND id = ? AND deleted_on != ? ", tenantID, id, 0).Update("deleted_on", 0).Error
+}
+
+// CleanAllArticle 物理删除在 deletedBefore 之前被软删除的文章，及其浏览、点赞记录与所在系列中的位置
+func CleanAllArticle(ctx context.Context, deletedBefore int) (bool, error) {
+	err := withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&Article{}).Select("id").Where("deleted_on != ? AND deleted_on < ? ", 0, deletedBefore).QueryExpr()
+		if err := tx.Where("article_id IN (?)", ids).Delete(&ArticleView{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id IN (?)", ids).Delete(&ArticleLike{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id IN (?)", ids).Delete(&SeriesArticle{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("deleted_on != ? AND deleted_on < ? ", 0, deletedBefore).Delete(&Article{}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
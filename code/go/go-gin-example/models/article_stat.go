@@ -0,0 +1,122 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ArticleView 文章某天的浏览数，Day 为当天 0 点（UTC）的时间戳
+type ArticleView struct {
+	ID        int `gorm:"primary_key" json:"id"`
+	TenantID  int `json:"tenant_id" gorm:"index"`
+	ArticleID int `json:"article_id" gorm:"unique_index:idx_article_day"`
+	Day       int `json:"day" gorm:"unique_index:idx_article_day"`
+	Views     int `json:"views"`
+}
+
+// ArticleLike 用户对文章的点赞，同一用户对同一篇文章只记录一次
+type ArticleLike struct {
+	ID        int    `gorm:"primary_key" json:"id"`
+	TenantID  int    `json:"tenant_id" gorm:"index"`
+	ArticleID int    `json:"article_id" gorm:"unique_index:idx_article_username"`
+	Username  string `json:"username" gorm:"unique_index:idx_article_username"`
+	CreatedOn int    `json:"created_on"`
+}
+
+// AddArticleViews 累加文章的总浏览数与当天的浏览数，使用 UpdateColumn 以免修改 modified_on 与 version
+func AddArticleViews(ctx context.Context, views []ArticleView) error {
+	return withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, view := range views {
+			err := tx.Model(&Article{}).Where("tenant_id = ? AND id = ?", view.TenantID, view.ArticleID).UpdateColumn("view_count", gorm.Expr("view_count + ?", view.Views)).Error
+			if err != nil {
+				return err
+			}
+
+			result := tx.Model(&ArticleView{}).Where("article_id = ? AND day = ?", view.ArticleID, view.Day).UpdateColumn("views", gorm.Expr("views + ?", view.Views))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				continue
+			}
+			view.ID = 0
+			if err := tx.Create(&view).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LikeArticle 记录用户对未删除文章的点赞并更新点赞数，文章不存在或已点赞时返回 false
+func LikeArticle(ctx context.Context, tenantID, id int, username string) (bool, error) {
+	liked := false
+	err := withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var like ArticleLike
+		err := tx.Where("article_id = ? AND username = ?", id, username).First(&like).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if like.ID > 0 {
+			return nil
+		}
+
+		result := tx.Model(&Article{}).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).UpdateColumn("like_count", gorm.Expr("like_count + ?", 1))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		liked = true
+		return tx.Create(&ArticleLike{TenantID: tenantID, ArticleID: id, Username: username}).Error
+	})
+
+	return liked, err
+}
+
+// UnlikeArticle 取消用户对文章的点赞并更新点赞数，未点赞时返回 false
+func UnlikeArticle(ctx context.Context, tenantID, id int, username string) (bool, error) {
+	unliked := false
+	err := withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("tenant_id = ? AND article_id = ? AND username = ?", tenantID, id, username).Delete(&ArticleLike{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		unliked = true
+		return tx.Model(&Article{}).Where("tenant_id = ? AND id = ?", tenantID, id).UpdateColumn("like_count", gorm.Expr("like_count - ?", 1)).Error
+	})
+
+	return unliked, err
+}
+
+// ExistArticleLike 判断用户是否已点赞文章
+func ExistArticleLike(ctx context.Context, tenantID, id int, username string) (bool, error) {
+	var like ArticleLike
+	err := withContext(ctx).Select("id").Where("tenant_id = ? AND article_id = ? AND username = ?", tenantID, id, username).First(&like).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+
+	return like.ID > 0, nil
+}
+
+// GetArticleViewsSince 获取站点内从 day 这天起每篇文章每天的浏览数
+func GetArticleViewsSince(ctx context.Context, tenantID, day int) ([]ArticleView, error) {
+	var views []ArticleView
+	err := withContext(ctx).Where("tenant_id = ? AND day >= ?", tenantID, day).Find(&views).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// GetArticleLikesSince 获取站点内 since 之后的点赞
+func GetArticleLikesSince(ctx context.Context, tenantID, since int) ([]ArticleLike, error) {
+	var likes []ArticleLike
+	err := withContext(ctx).Where("tenant_id = ? AND created_on >= ?", tenantID, since).Find(&likes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return likes, nil
+}
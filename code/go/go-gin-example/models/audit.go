@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	AUDIT_CREATE  = "create"
+	AUDIT_EDIT    = "edit"
+	AUDIT_DELETE  = "delete"
+	AUDIT_RESTORE = "restore"
+	AUDIT_IMPORT  = "import"
+	AUDIT_EXPORT  = "export"
+	AUDIT_RUN     = "run"
+	AUDIT_PAUSE   = "pause"
+	AUDIT_RESUME  = "resume"
+	AUDIT_REPLAY  = "replay"
+)
+
+// Audit 站点内一次写操作的审计记录，Before、After 为操作前后数据的 JSON
+type Audit struct {
+	ID         int    `gorm:"primary_key" json:"id"`
+	TenantID   int    `json:"tenant_id"`
+	Username   string `json:"username"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	IP         string `gorm:"column:ip" json:"ip"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	CreatedOn  int    `json:"created_on"`
+}
+
+func AddAudit(ctx context.Context, audit *Audit) error {
+	if audit.CreatedOn == 0 {
+		audit.CreatedOn = int(time.Now().Unix())
+	}
+
+	return withContext(ctx).Create(audit).Error
+}
+
+// GetAudits 按条件获取审计记录，最近的在前，start、end 限定 created_on 的范围，0 表示不限
+func GetAudits(ctx context.Context, pageNum int, pageSize int, maps interface{}, start, end int) ([]Audit, error) {
+	var audits []Audit
+	err := createdBetween(withContext(ctx).Where(maps), start, end).Order("id DESC").Offset(pageNum).Limit(pageSize).Find(&audits).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return audits, nil
+}
+
+func GetAuditTotal(ctx context.Context, maps interface{}, start, end int) (int, error) {
+	var count int
+	if err := createdBetween(withContext(ctx).Model(&Audit{}).Where(maps), start, end).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func createdBetween(db *gorm.DB, start, end int) *gorm.DB {
+	if start > 0 {
+		db = db.Where("created_on >= ?", start)
+	}
+	if end > 0 {
+		db = db.Where("created_on <= ?", end)
+	}
+
+	return db
+}
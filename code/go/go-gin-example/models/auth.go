@@ -1,13 +1,59 @@
 package models
 
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ROLE_ADMIN  = "admin"
+	ROLE_EDITOR = "editor"
+)
+
 type Auth struct {
 	ID       int    `gorm:"primary_key" json:"id"`
+	TenantID int    `json:"tenant_id"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Role     string `json:"role"`
+	// 通过 OIDC 登录时绑定的身份提供方用户 sub，为空表示尚未绑定
+	Subject string `json:"subject"`
+}
+
+// CheckAuth 校验站点下的账号密码，成功时返回账号信息
+func CheckAuth(ctx context.Context, tenantID int, username, password string) (*Auth, bool) {
+	var auth Auth
+	withContext(ctx).Select("id, tenant_id, username, role").Where(Auth{TenantID: tenantID, Username: username, Password: password}).First(&auth)
+	return &auth, auth.ID > 0
 }
 
-func CheckAuth(username, password string) bool {
+// GetAuth 获取站点下的账号，不存在时返回 ID 为 0 的账号
+func GetAuth(ctx context.Context, tenantID int, username string) (*Auth, error) {
 	var auth Auth
-	db.Select("id").Where(Auth{Username: username, Password: password}).First(&auth)
-	return auth.ID > 0
+	err := withContext(ctx).Where("tenant_id = ? AND username = ?", tenantID, username).First(&auth).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+// GetAuthBySubject 获取站点下绑定了 OIDC 用户 sub 的账号，不存在时返回 ID 为 0 的账号
+func GetAuthBySubject(ctx context.Context, tenantID int, subject string) (*Auth, error) {
+	var auth Auth
+	err := withContext(ctx).Where("tenant_id = ? AND subject = ?", tenantID, subject).First(&auth).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+func AddAuth(ctx context.Context, auth *Auth) error {
+	return withContext(ctx).Create(auth).Error
+}
+
+func EditAuth(ctx context.Context, tenantID int, username string, data interface{}) error {
+	return withContext(ctx).Model(&Auth{}).Where("tenant_id = ? AND username = ?", tenantID, username).Updates(data).Error
 }
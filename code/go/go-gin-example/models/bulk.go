@@ -0,0 +1,277 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 批量操作的类型
+const (
+	BULK_CREATE = "create"
+	BULK_UPDATE = "update"
+	BULK_DELETE = "delete"
+	BULK_STATE  = "state"
+)
+
+// BulkOp 批量操作中的一项，Data 为新建或修改的字段
+type BulkOp struct {
+	Op      string
+	ID      int
+	Data    map[string]interface{}
+	Cascade bool // 删除标签时是否同时删除其下的文章
+	Version int  // 修改或删除时期望的当前版本，为 0 时不检查
+}
+
+// BulkResult 单项操作的结果，Before、After 为操作前后的记录，用于审计
+type BulkResult struct {
+	ID     int
+	Err    error
+	Before interface{}
+	After  interface{}
+}
+
+// runBulk 执行批量操作，atomic 为 true 时所有操作在同一事务中执行，任一项失败则全部回滚，
+// 否则每一项在各自的事务中执行，互不影响。返回的 error 仅表示事务本身失败
+func runBulk(ctx context.Context, ops []BulkOp, atomic bool, exec func(tx *gorm.DB, op BulkOp) BulkResult) ([]BulkResult, error) {
+	results := make([]BulkResult, len(ops))
+
+	if atomic {
+		tx := withContext(ctx).Begin()
+		if err := tx.Error; err != nil {
+			return nil, err
+		}
+
+		failed := false
+		for i, op := range ops {
+			results[i] = exec(tx, op)
+			if results[i].Err != nil {
+				failed = true
+			}
+		}
+		if failed {
+			return results, tx.Rollback().Error
+		}
+
+		return results, tx.Commit().Error
+	}
+
+	for i, op := range ops {
+		tx := withContext(ctx).Begin()
+		if err := tx.Error; err != nil {
+			return nil, err
+		}
+
+		results[i] = exec(tx, op)
+		if results[i].Err != nil {
+			tx.Rollback()
+			continue
+		}
+		if err := tx.Commit().Error; err != nil {
+			results[i] = BulkResult{ID: op.ID, Err: err}
+		}
+	}
+
+	return results, nil
+}
+
+// BulkArticles 批量新建、修改、删除站点内的文章或修改文章状态
+func BulkArticles(ctx context.Context, tenantID int, ops []BulkOp, atomic bool) ([]BulkResult, error) {
+	return runBulk(ctx, ops, atomic, func(tx *gorm.DB, op BulkOp) BulkResult {
+		return execArticle(tx, tenantID, op)
+	})
+}
+
+func execArticle(tx *gorm.DB, tenantID int, op BulkOp) BulkResult {
+	result := BulkResult{ID: op.ID}
+
+	if op.Op == BULK_CREATE {
+		if result.Err = checkTag(tx, tenantID, op.Data["tag_id"]); result.Err != nil {
+			return result
+		}
+
+		slug, _ := op.Data["slug"].(string)
+		if slug == "" {
+			title, _ := op.Data["title"].(string)
+			if slug, result.Err = uniqueArticleSlug(tx, tenantID, title); result.Err != nil {
+				return result
+			}
+		} else if result.Err = checkSlug(tx, tenantID, slug, 0); result.Err != nil {
+			return result
+		}
+
+		article := Article{
+			TenantID:      tenantID,
+			TagID:         op.Data["tag_id"].(int),
+			Title:         op.Data["title"].(string),
+			Slug:          slug,
+			Desc:          op.Data["desc"].(string),
+			Content:       op.Data["content"].(string),
+			CreatedBy:     op.Data["created_by"].(string),
+			State:         op.Data["state"].(int),
+			CoverImageUrl: op.Data["cover_image_url"].(string),
+		}
+		if result.Err = tx.Create(&article).Error; result.Err != nil {
+			return result
+		}
+		result.ID = article.ID
+		result.After = &article
+		return result
+	}
+
+	before, err := getArticle(tx, tenantID, op.ID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Before = before
+	if op.Version > 0 && before.Version != op.Version {
+		result.Err = ErrVersionConflict
+		return result
+	}
+
+	switch op.Op {
+	case BULK_DELETE:
+		result.Err = tx.Where("id = ?", op.ID).Delete(Article{}).Error
+		return result
+	case BULK_UPDATE:
+		if tagID, ok := op.Data["tag_id"]; ok && tagID != before.TagID {
+			if result.Err = checkTag(tx, tenantID, tagID); result.Err != nil {
+				return result
+			}
+		}
+		if slug, ok := op.Data["slug"].(string); ok {
+			if result.Err = checkSlug(tx, tenantID, slug, op.ID); result.Err != nil {
+				return result
+			}
+		}
+	}
+
+	if result.Err = editVersioned(tx.Model(&Article{}).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, op.ID, 0), op.Version, op.Data); result.Err != nil {
+		return result
+	}
+	result.After, result.Err = getArticle(tx, tenantID, op.ID)
+
+	return result
+}
+
+func getArticle(tx *gorm.DB, tenantID, id int) (*Article, error) {
+	var article Article
+	err := tx.Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).First(&article).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrNotExist
+	}
+
+	return &article, err
+}
+
+func checkTag(tx *gorm.DB, tenantID int, id interface{}) error {
+	var tag Tag
+	err := tx.Select("id").Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).First(&tag).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrTagNotExist
+	}
+
+	return err
+}
+
+func checkSlug(tx *gorm.DB, tenantID int, slug string, excludeID int) error {
+	exists, err := existArticleBySlug(tx, tenantID, slug, excludeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrSlugExist
+	}
+
+	return nil
+}
+
+// BulkTags 批量新建、修改、删除站点内的标签或修改标签状态
+func BulkTags(ctx context.Context, tenantID int, ops []BulkOp, atomic bool) ([]BulkResult, error) {
+	return runBulk(ctx, ops, atomic, func(tx *gorm.DB, op BulkOp) BulkResult {
+		return execTag(tx, tenantID, op)
+	})
+}
+
+func execTag(tx *gorm.DB, tenantID int, op BulkOp) BulkResult {
+	result := BulkResult{ID: op.ID}
+
+	if op.Op == BULK_CREATE {
+		if result.Err = checkTagName(tx, tenantID, op.Data["name"].(string), 0); result.Err != nil {
+			return result
+		}
+
+		tag := Tag{
+			TenantID:  tenantID,
+			Name:      op.Data["name"].(string),
+			State:     op.Data["state"].(int),
+			CreatedBy: op.Data["created_by"].(string),
+		}
+		if result.Err = tx.Create(&tag).Error; result.Err != nil {
+			return result
+		}
+		result.ID = tag.ID
+		result.After = &tag
+		return result
+	}
+
+	var before Tag
+	if err := tx.Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, op.ID, 0).First(&before).Error; err != nil {
+		result.Err = err
+		if err == gorm.ErrRecordNotFound {
+			result.Err = ErrNotExist
+		}
+		return result
+	}
+	result.Before = &before
+	if op.Version > 0 && before.Version != op.Version {
+		result.Err = ErrVersionConflict
+		return result
+	}
+
+	switch op.Op {
+	case BULK_DELETE:
+		if !op.Cascade {
+			var count int
+			if result.Err = tx.Model(&Article{}).Where("tenant_id = ? AND tag_id = ? AND deleted_on = ? ", tenantID, op.ID, 0).Count(&count).Error; result.Err != nil {
+				return result
+			}
+			if count > 0 {
+				result.Err = ErrTagHasArticles
+				return result
+			}
+		}
+		result.Err = deleteTag(tx, tenantID, op.ID, op.Cascade)
+		return result
+	case BULK_UPDATE:
+		if name, ok := op.Data["name"].(string); ok && name != before.Name {
+			if result.Err = checkTagName(tx, tenantID, name, op.ID); result.Err != nil {
+				return result
+			}
+		}
+	}
+
+	if result.Err = editVersioned(tx.Model(&Tag{}).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, op.ID, 0), op.Version, op.Data); result.Err != nil {
+		return result
+	}
+
+	var after Tag
+	result.Err = tx.Where("id = ?", op.ID).First(&after).Error
+	result.After = &after
+
+	return result
+}
+
+func checkTagName(tx *gorm.DB, tenantID int, name string, excludeID int) error {
+	var tag Tag
+	err := tx.Select("id").Where("tenant_id = ? AND name = ? AND deleted_on = ? AND id != ?", tenantID, name, 0, excludeID).First(&tag).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return ErrNameExist
+}
@@ -0,0 +1,20 @@
+package models
+
+import "errors"
+
+var (
+	// ErrInvalidOperation 批量操作中的某一项缺少必要的字段，由 service 包装后返回
+	ErrInvalidOperation = errors.New("invalid operation")
+
+	ErrNotExist       = errors.New("record does not exist")
+	ErrTagNotExist    = errors.New("tag does not exist")
+	ErrSlugExist      = errors.New("slug is already in use")
+	ErrNameExist      = errors.New("name is already in use")
+	ErrTagHasArticles = errors.New("tag still has articles")
+
+	// ErrArticleInSeries 文章已属于其他系列
+	ErrArticleInSeries = errors.New("article already belongs to another series")
+
+	// ErrVersionConflict 带版本条件的修改时，记录已被其他请求修改
+	ErrVersionConflict = errors.New("version conflict")
+)
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	JOB_RUN_RUNNING = "running"
+	JOB_RUN_SUCCESS = "success"
+	JOB_RUN_FAILED  = "failed"
+
+	JOB_TRIGGER_SCHEDULE = "schedule"
+	JOB_TRIGGER_MANUAL   = "manual"
+)
+
+// Job 定时任务的状态，暂停状态保存在数据库中以便所有实例共享
+type Job struct {
+	Model
+
+	Name       string `json:"name"`
+	Paused     int    `json:"paused"`
+	ModifiedBy string `json:"modified_by"`
+}
+
+// JobRun 定时任务的一次执行记录
+type JobRun struct {
+	ID        int    `gorm:"primary_key" json:"id"`
+	Job       string `json:"job"`
+	Trigger   string `json:"trigger"`
+	TriggerBy string `json:"trigger_by"`
+	Instance  string `json:"instance"`
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	StartedOn int    `json:"started_on"`
+	Duration  int    `json:"duration"` // 毫秒
+}
+
+func GetJob(ctx context.Context, name string) (*Job, error) {
+	var job Job
+	err := withContext(ctx).Where("name = ? AND deleted_on = ? ", name, 0).First(&job).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// SetJobPaused 暂停或恢复定时任务，任务记录不存在时创建
+func SetJobPaused(ctx context.Context, name string, paused int, modifiedBy string) error {
+	job, err := GetJob(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if job.ID == 0 {
+		return withContext(ctx).Create(&Job{Name: name, Paused: paused, ModifiedBy: modifiedBy}).Error
+	}
+
+	return withContext(ctx).Model(job).Updates(map[string]interface{}{"paused": paused, "modified_by": modifiedBy}).Error
+}
+
+func AddJobRun(ctx context.Context, run *JobRun) error {
+	return withContext(ctx).Create(run).Error
+}
+
+// FinishJobRun 记录任务的执行结果
+func FinishJobRun(ctx context.Context, id int, runErr error, duration time.Duration) error {
+	data := map[string]interface{}{
+		"status":   JOB_RUN_SUCCESS,
+		"duration": int(duration / time.Millisecond),
+	}
+	if runErr != nil {
+		data["status"] = JOB_RUN_FAILED
+		data["error"] = runErr.Error()
+	}
+
+	return withContext(ctx).Model(&JobRun{ID: id}).Updates(data).Error
+}
+
+func GetJobRuns(ctx context.Context, job string, pageNum int, pageSize int) ([]JobRun, error) {
+	var runs []JobRun
+	err := withContext(ctx).Where("job = ?", job).Order("id DESC").Offset(pageNum).Limit(pageSize).Find(&runs).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func GetJobRunTotal(ctx context.Context, job string) (int, error) {
+	var count int
+	if err := withContext(ctx).Model(&JobRun{}).Where("job = ?", job).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetLastJobRun 获取任务最近一次执行记录，从未执行过时返回 nil
+func GetLastJobRun(ctx context.Context, job string) (*JobRun, error) {
+	var run JobRun
+	err := withContext(ctx).Where("job = ?", job).Order("id DESC").First(&run).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// DBLock 基于 MySQL GET_LOCK 的命名锁，锁与数据库连接绑定，持有锁的实例退出或连接断开时自动释放
+type DBLock struct {
+	name string
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewDBLock(name string) *DBLock {
+	return &DBLock{name: name}
+}
+
+// TryLock 尝试获取锁，已持有时检查锁是否仍然有效，不会阻塞
+func (l *DBLock) TryLock() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ctx := context.Background()
+	if l.conn != nil {
+		var holder sql.NullInt64
+		var self int64
+		err := l.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?), CONNECTION_ID()", l.name).Scan(&holder, &self)
+		if err == nil && holder.Valid && holder.Int64 == self {
+			return true, nil
+		}
+		// 连接已断开或锁已丢失，换一个连接重新获取
+		l.conn.Close()
+		l.conn = nil
+		if err != nil {
+			return false, err
+		}
+	}
+
+	conn, err := db.DB().Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.name).Scan(&got); err != nil {
+		conn.Close()
+		return false, err
+	}
+	if got.Int64 != 1 {
+		conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Unlock 释放锁
+func (l *DBLock) Unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return
+	}
+
+	l.conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", l.name)
+	l.conn.Close()
+	l.conn = nil
+}
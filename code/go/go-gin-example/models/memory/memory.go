@@ -0,0 +1,1030 @@
+// Package memory 提供 models 中各 Repository 的内存实现，行为与数据库实现一致，用于测试
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/util"
+)
+
+// Store 保存标签、文章与系列，标签与文章的级联删除、恢复需要同时访问两者，因此共用一个 Store
+type Store struct {
+	mu       sync.Mutex
+	tags     map[int]models.Tag
+	articles map[int]models.Article
+	views    map[viewKey]models.ArticleView
+	likes    map[likeKey]models.ArticleLike
+	series   map[int]models.Series
+	members  map[int]models.SeriesArticle // 文章 ID → 所在系列中的位置
+	nextID   int
+}
+
+type viewKey struct {
+	articleID, day int
+}
+
+type likeKey struct {
+	articleID int
+	username  string
+}
+
+func New() *Store {
+	return &Store{
+		tags:     make(map[int]models.Tag),
+		articles: make(map[int]models.Article),
+		views:    make(map[viewKey]models.ArticleView),
+		likes:    make(map[likeKey]models.ArticleLike),
+		series:   make(map[int]models.Series),
+		members:  make(map[int]models.SeriesArticle),
+	}
+}
+
+// Tags 返回基于该 Store 的 TagRepository
+func (s *Store) Tags() models.TagRepository {
+	return tagRepository{s}
+}
+
+// Articles 返回基于该 Store 的 ArticleRepository
+func (s *Store) Articles() models.ArticleRepository {
+	return articleRepository{s}
+}
+
+// Series 返回基于该 Store 的 SeriesRepository
+func (s *Store) Series() models.SeriesRepository {
+	return seriesRepository{s}
+}
+
+func (s *Store) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+func now() int {
+	return int(time.Now().Unix())
+}
+
+// snapshot 复制当前数据，用于批量操作失败时回滚
+func (s *Store) snapshot() func() {
+	tags := make(map[int]models.Tag, len(s.tags))
+	for id, tag := range s.tags {
+		tags[id] = tag
+	}
+	articles := make(map[int]models.Article, len(s.articles))
+	for id, article := range s.articles {
+		articles[id] = article
+	}
+	nextID := s.nextID
+
+	return func() {
+		s.tags, s.articles, s.nextID = tags, articles, nextID
+	}
+}
+
+// runBulk 与数据库实现相同，atomic 时任一项失败则恢复到执行前的数据
+func (s *Store) runBulk(ops []models.BulkOp, atomic bool, exec func(op models.BulkOp) models.BulkResult) []models.BulkResult {
+	results := make([]models.BulkResult, len(ops))
+
+	var rollback func()
+	if atomic {
+		rollback = s.snapshot()
+	}
+	failed := false
+	for i, op := range ops {
+		if !atomic {
+			rollback = s.snapshot()
+		}
+		results[i] = exec(op)
+		if results[i].Err == nil {
+			continue
+		}
+		failed = true
+		if !atomic {
+			rollback()
+		}
+	}
+	if atomic && failed {
+		rollback()
+	}
+
+	return results
+}
+
+// page 按 gorm 的 Offset、Limit 语义截取，pageSize 小于 0 表示不限制
+func page(n, pageNum, pageSize int) (int, int) {
+	start := pageNum
+	if start < 0 {
+		start = 0
+	}
+	if start > n {
+		start = n
+	}
+	end := n
+	if pageSize >= 0 && start+pageSize < n {
+		end = start + pageSize
+	}
+
+	return start, end
+}
+
+// match 判断记录是否满足所有等值条件，column 返回列名对应的值，未知的列视为不满足
+func match(maps map[string]interface{}, column func(name string) (interface{}, bool)) bool {
+	for name, want := range maps {
+		got, ok := column(name)
+		if !ok || toString(got) != toString(want) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	return ""
+}
+
+func toInt(v interface{}) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+
+	return 0
+}
+
+type tagRepository struct {
+	s *Store
+}
+
+func tagColumn(t *models.Tag) func(string) (interface{}, bool) {
+	return func(name string) (interface{}, bool) {
+		switch name {
+		case "id":
+			return t.ID, true
+		case "tenant_id":
+			return t.TenantID, true
+		case "name":
+			return t.Name, true
+		case "state":
+			return t.State, true
+		case "created_by":
+			return t.CreatedBy, true
+		case "modified_by":
+			return t.ModifiedBy, true
+		case "deleted_on":
+			return t.DeletedOn, true
+		}
+		return nil, false
+	}
+}
+
+func (r tagRepository) ExistByName(_ context.Context, tenantID int, name string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.existByName(tenantID, name, 0), nil
+}
+
+func (r tagRepository) existByName(tenantID int, name string, excludeID int) bool {
+	for _, tag := range r.s.tags {
+		if tag.TenantID == tenantID && tag.Name == name && tag.DeletedOn == 0 && tag.ID != excludeID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tag 获取站点内的标签，不论是否已删除
+func (r tagRepository) tag(tenantID, id int) (models.Tag, bool) {
+	tag, ok := r.s.tags[id]
+	return tag, ok && tag.TenantID == tenantID
+}
+
+func (r tagRepository) ExistByID(_ context.Context, tenantID, id int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tag, ok := r.tag(tenantID, id)
+	return ok && tag.DeletedOn == 0, nil
+}
+
+func (r tagRepository) Add(_ context.Context, tenantID int, name string, state int, createdBy string) (*models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.add(tenantID, name, state, createdBy), nil
+}
+
+func (r tagRepository) add(tenantID int, name string, state int, createdBy string) *models.Tag {
+	tag := models.Tag{TenantID: tenantID, Name: name, State: state, CreatedBy: createdBy, Version: 1}
+	tag.ID = r.s.id()
+	tag.CreatedOn, tag.ModifiedOn = now(), now()
+	r.s.tags[tag.ID] = tag
+
+	return &tag
+}
+
+func (r tagRepository) Get(_ context.Context, tenantID, id int) (*models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tag, ok := r.tag(tenantID, id)
+	if !ok || tag.DeletedOn != 0 {
+		return &models.Tag{}, nil
+	}
+
+	return &tag, nil
+}
+
+func (r tagRepository) GetByIDs(_ context.Context, tenantID int, ids []int) ([]models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var tags []models.Tag
+	for _, id := range ids {
+		if tag, ok := r.tag(tenantID, id); ok {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+func (r tagRepository) GetAll(_ context.Context, pageNum, pageSize int, maps map[string]interface{}) ([]models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tags := r.find(func(t *models.Tag) bool { return match(maps, tagColumn(t)) })
+	start, end := page(len(tags), pageNum, pageSize)
+
+	return tags[start:end], nil
+}
+
+func (r tagRepository) find(filter func(t *models.Tag) bool) []models.Tag {
+	var tags []models.Tag
+	for _, tag := range r.s.tags {
+		if filter(&tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].ID < tags[j].ID })
+
+	return tags
+}
+
+func (r tagRepository) Count(_ context.Context, maps map[string]interface{}) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.find(func(t *models.Tag) bool { return match(maps, tagColumn(t)) })), nil
+}
+
+func (r tagRepository) Edit(_ context.Context, tenantID, id, version int, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.edit(tenantID, id, version, data)
+}
+
+func (r tagRepository) edit(tenantID, id, version int, data map[string]interface{}) error {
+	tag, ok := r.tag(tenantID, id)
+	if !ok || tag.DeletedOn != 0 || (version > 0 && tag.Version != version) {
+		if version > 0 {
+			return models.ErrVersionConflict
+		}
+		return nil
+	}
+
+	for name, value := range data {
+		switch name {
+		case "name":
+			tag.Name = value.(string)
+		case "state":
+			tag.State = toInt(value)
+		case "modified_by":
+			tag.ModifiedBy = value.(string)
+		}
+	}
+	tag.ModifiedOn = now()
+	tag.Version++
+	r.s.tags[id] = tag
+
+	return nil
+}
+
+func (r tagRepository) Delete(_ context.Context, tenantID, id int, cascade bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.delete(tenantID, id, cascade)
+	return nil
+}
+
+func (r tagRepository) delete(tenantID, id int, cascade bool) {
+	tag, ok := r.tag(tenantID, id)
+	if !ok || tag.DeletedOn != 0 {
+		return
+	}
+
+	deletedOn := now()
+	if cascade {
+		for articleID, article := range r.s.articles {
+			if article.TagID == id && article.DeletedOn == 0 {
+				article.DeletedOn = deletedOn
+				r.s.articles[articleID] = article
+			}
+		}
+	}
+
+	tag.DeletedOn = deletedOn
+	r.s.tags[id] = tag
+}
+
+func (r tagRepository) CountArticles(_ context.Context, tenantID, id int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.countArticles(tenantID, id), nil
+}
+
+func (r tagRepository) countArticles(tenantID, id int) int {
+	count := 0
+	for _, article := range r.s.articles {
+		if article.TenantID == tenantID && article.TagID == id && article.DeletedOn == 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r tagRepository) GetDeleted(_ context.Context, tenantID, id int) (*models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tag, ok := r.tag(tenantID, id)
+	if !ok || tag.DeletedOn == 0 {
+		return &models.Tag{}, nil
+	}
+
+	return &tag, nil
+}
+
+func (r tagRepository) GetDeletedAll(_ context.Context, tenantID, pageNum, pageSize int) ([]models.Tag, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	tags := r.find(func(t *models.Tag) bool { return t.TenantID == tenantID && t.DeletedOn != 0 })
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].DeletedOn > tags[j].DeletedOn })
+	start, end := page(len(tags), pageNum, pageSize)
+
+	return tags[start:end], nil
+}
+
+func (r tagRepository) CountDeleted(_ context.Context, tenantID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.find(func(t *models.Tag) bool { return t.TenantID == tenantID && t.DeletedOn != 0 })), nil
+}
+
+func (r tagRepository) Restore(_ context.Context, tag *models.Tag, cascade bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if cascade {
+		for id, article := range r.s.articles {
+			if article.TenantID == tag.TenantID && article.TagID == tag.ID && article.DeletedOn == tag.DeletedOn {
+				article.DeletedOn = 0
+				r.s.articles[id] = article
+			}
+		}
+	}
+	if current, ok := r.tag(tag.TenantID, tag.ID); ok && current.DeletedOn != 0 {
+		current.DeletedOn = 0
+		r.s.tags[tag.ID] = current
+	}
+
+	return nil
+}
+
+func (r tagRepository) Bulk(_ context.Context, tenantID int, ops []models.BulkOp, atomic bool) ([]models.BulkResult, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.runBulk(ops, atomic, func(op models.BulkOp) models.BulkResult {
+		return r.exec(tenantID, op)
+	}), nil
+}
+
+func (r tagRepository) exec(tenantID int, op models.BulkOp) models.BulkResult {
+	result := models.BulkResult{ID: op.ID}
+
+	if op.Op == models.BULK_CREATE {
+		name := op.Data["name"].(string)
+		if r.existByName(tenantID, name, 0) {
+			result.Err = models.ErrNameExist
+			return result
+		}
+		tag := r.add(tenantID, name, op.Data["state"].(int), op.Data["created_by"].(string))
+		result.ID, result.After = tag.ID, tag
+		return result
+	}
+
+	before, ok := r.tag(tenantID, op.ID)
+	if !ok || before.DeletedOn != 0 {
+		result.Err = models.ErrNotExist
+		return result
+	}
+	result.Before = &before
+	if op.Version > 0 && before.Version != op.Version {
+		result.Err = models.ErrVersionConflict
+		return result
+	}
+
+	switch op.Op {
+	case models.BULK_DELETE:
+		if !op.Cascade && r.countArticles(tenantID, op.ID) > 0 {
+			result.Err = models.ErrTagHasArticles
+			return result
+		}
+		r.delete(tenantID, op.ID, op.Cascade)
+		return result
+	case models.BULK_UPDATE:
+		if name, ok := op.Data["name"].(string); ok && name != before.Name && r.existByName(tenantID, name, op.ID) {
+			result.Err = models.ErrNameExist
+			return result
+		}
+	}
+
+	if result.Err = r.edit(tenantID, op.ID, op.Version, op.Data); result.Err != nil {
+		return result
+	}
+	after := r.s.tags[op.ID]
+	result.After = &after
+
+	return result
+}
+
+type articleRepository struct {
+	s *Store
+}
+
+func articleColumn(a *models.Article) func(string) (interface{}, bool) {
+	return func(name string) (interface{}, bool) {
+		switch name {
+		case "id":
+			return a.ID, true
+		case "tenant_id":
+			return a.TenantID, true
+		case "tag_id":
+			return a.TagID, true
+		case "title":
+			return a.Title, true
+		case "slug":
+			return a.Slug, true
+		case "state":
+			return a.State, true
+		case "created_by":
+			return a.CreatedBy, true
+		case "modified_by":
+			return a.ModifiedBy, true
+		case "deleted_on":
+			return a.DeletedOn, true
+		}
+		return nil, false
+	}
+}
+
+// withTag 与数据库实现的 Preload 一致，关联的标签即使已删除也会带出
+func (r articleRepository) withTag(a models.Article) *models.Article {
+	a.Tag = r.s.tags[a.TagID]
+	return &a
+}
+
+func (r articleRepository) find(filter func(a *models.Article) bool) []*models.Article {
+	var articles []*models.Article
+	for _, article := range r.s.articles {
+		if filter(&article) {
+			articles = append(articles, r.withTag(article))
+		}
+	}
+	sort.Slice(articles, func(i, j int) bool { return articles[i].ID < articles[j].ID })
+
+	return articles
+}
+
+// article 获取站点内的文章，不论是否已删除
+func (r articleRepository) article(tenantID, id int) (models.Article, bool) {
+	article, ok := r.s.articles[id]
+	return article, ok && article.TenantID == tenantID
+}
+
+func (r articleRepository) ExistByID(_ context.Context, tenantID, id int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	article, ok := r.article(tenantID, id)
+	return ok && article.DeletedOn == 0, nil
+}
+
+func (r articleRepository) ExistBySlug(_ context.Context, tenantID int, slug string, excludeID int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.existBySlug(tenantID, slug, excludeID), nil
+}
+
+func (r articleRepository) existBySlug(tenantID int, slug string, excludeID int) bool {
+	for _, article := range r.s.articles {
+		if article.TenantID == tenantID && article.Slug == slug && article.ID != excludeID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r articleRepository) UniqueSlug(_ context.Context, tenantID int, title string) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.uniqueSlug(tenantID, title), nil
+}
+
+func (r articleRepository) uniqueSlug(tenantID int, title string) string {
+	base := util.Slugify(title)
+	if base == "" {
+		base = "article"
+	}
+
+	slug := base
+	for i := 2; r.existBySlug(tenantID, slug, 0); i++ {
+		slug = base + "-" + strconv.Itoa(i)
+	}
+
+	return slug
+}
+
+func (r articleRepository) Add(_ context.Context, data map[string]interface{}) (*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.add(data), nil
+}
+
+func (r articleRepository) add(data map[string]interface{}) *models.Article {
+	article := models.Article{
+		TenantID:      data["tenant_id"].(int),
+		TagID:         data["tag_id"].(int),
+		Title:         data["title"].(string),
+		Slug:          data["slug"].(string),
+		Desc:          data["desc"].(string),
+		Content:       data["content"].(string),
+		CreatedBy:     data["created_by"].(string),
+		State:         data["state"].(int),
+		CoverImageUrl: data["cover_image_url"].(string),
+		Version:       1,
+	}
+	article.ID = r.s.id()
+	article.CreatedOn, article.ModifiedOn = now(), now()
+	r.s.articles[article.ID] = article
+
+	return &article
+}
+
+func (r articleRepository) Get(_ context.Context, tenantID, id int) (*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	article, ok := r.article(tenantID, id)
+	if !ok || article.DeletedOn != 0 {
+		return &models.Article{}, nil
+	}
+
+	return r.withTag(article), nil
+}
+
+func (r articleRepository) GetAll(_ context.Context, pageNum, pageSize int, maps map[string]interface{}) ([]*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	articles := r.find(func(a *models.Article) bool { return match(maps, articleColumn(a)) })
+	start, end := page(len(articles), pageNum, pageSize)
+
+	return articles[start:end], nil
+}
+
+func (r articleRepository) GetByIDs(_ context.Context, tenantID int, ids []int) ([]*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	want := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+
+	return r.find(func(a *models.Article) bool {
+		return a.TenantID == tenantID && want[a.ID] && a.DeletedOn == 0
+	}), nil
+}
+
+func (r articleRepository) GetByTags(_ context.Context, tenantID int, tagIDs []int) ([]*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ids := make(map[int]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		ids[id] = true
+	}
+
+	return r.find(func(a *models.Article) bool {
+		return a.TenantID == tenantID && ids[a.TagID] && a.DeletedOn == 0
+	}), nil
+}
+
+func (r articleRepository) GetByAuthors(_ context.Context, tenantID int, usernames []string) ([]*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	names := make(map[string]bool, len(usernames))
+	for _, name := range usernames {
+		names[name] = true
+	}
+
+	return r.find(func(a *models.Article) bool {
+		return a.TenantID == tenantID && names[a.CreatedBy] && a.DeletedOn == 0
+	}), nil
+}
+
+func (r articleRepository) GetAuthors(_ context.Context, tenantID int) ([]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, article := range r.s.articles {
+		if article.TenantID == tenantID && article.DeletedOn == 0 && article.CreatedBy != "" && !seen[article.CreatedBy] {
+			seen[article.CreatedBy] = true
+			usernames = append(usernames, article.CreatedBy)
+		}
+	}
+	sort.Strings(usernames)
+
+	return usernames, nil
+}
+
+func (r articleRepository) Count(_ context.Context, maps map[string]interface{}) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.find(func(a *models.Article) bool { return match(maps, articleColumn(a)) })), nil
+}
+
+func (r articleRepository) Edit(_ context.Context, tenantID, id, version int, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.edit(tenantID, id, version, data)
+}
+
+func (r articleRepository) edit(tenantID, id, version int, data map[string]interface{}) error {
+	article, ok := r.article(tenantID, id)
+	if !ok || article.DeletedOn != 0 || (version > 0 && article.Version != version) {
+		if version > 0 {
+			return models.ErrVersionConflict
+		}
+		return nil
+	}
+
+	for name, value := range data {
+		switch name {
+		case "tag_id":
+			article.TagID = toInt(value)
+		case "title":
+			article.Title = value.(string)
+		case "slug":
+			article.Slug = value.(string)
+		case "desc":
+			article.Desc = value.(string)
+		case "content":
+			article.Content = value.(string)
+		case "cover_image_url":
+			article.CoverImageUrl = value.(string)
+		case "state":
+			article.State = toInt(value)
+		case "modified_by":
+			article.ModifiedBy = value.(string)
+		}
+	}
+	article.ModifiedOn = now()
+	article.Version++
+	r.s.articles[id] = article
+
+	return nil
+}
+
+func (r articleRepository) Delete(_ context.Context, tenantID, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.delete(tenantID, id)
+	return nil
+}
+
+func (r articleRepository) delete(tenantID, id int) {
+	if article, ok := r.article(tenantID, id); ok {
+		article.DeletedOn = now()
+		r.s.articles[id] = article
+	}
+}
+
+func (r articleRepository) GetDeleted(_ context.Context, tenantID, id int) (*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	article, ok := r.article(tenantID, id)
+	if !ok || article.DeletedOn == 0 {
+		return &models.Article{}, nil
+	}
+
+	return &article, nil
+}
+
+func (r articleRepository) GetDeletedAll(_ context.Context, tenantID, pageNum, pageSize int) ([]*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	articles := r.find(func(a *models.Article) bool { return a.TenantID == tenantID && a.DeletedOn != 0 })
+	sort.SliceStable(articles, func(i, j int) bool { return articles[i].DeletedOn > articles[j].DeletedOn })
+	start, end := page(len(articles), pageNum, pageSize)
+
+	return articles[start:end], nil
+}
+
+func (r articleRepository) CountDeleted(_ context.Context, tenantID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.find(func(a *models.Article) bool { return a.TenantID == tenantID && a.DeletedOn != 0 })), nil
+}
+
+func (r articleRepository) Restore(_ context.Context, tenantID, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if article, ok := r.article(tenantID, id); ok && article.DeletedOn != 0 {
+		article.DeletedOn = 0
+		r.s.articles[id] = article
+	}
+
+	return nil
+}
+
+// published 已发布且所属标签已启用的文章，最新发布的在前
+func (r articleRepository) published(tenantID, tagID int) []*models.Article {
+	articles := r.find(func(a *models.Article) bool {
+		tag, ok := r.s.tags[a.TagID]
+		return a.TenantID == tenantID && a.State == 1 && a.DeletedOn == 0 && ok && tag.State == 1 && tag.DeletedOn == 0 &&
+			(tagID == 0 || a.TagID == tagID)
+	})
+	sort.SliceStable(articles, func(i, j int) bool {
+		if articles[i].CreatedOn != articles[j].CreatedOn {
+			return articles[i].CreatedOn > articles[j].CreatedOn
+		}
+		return articles[i].ID > articles[j].ID
+	})
+
+	return articles
+}
+
+func (r articleRepository) GetPublished(_ context.Context, tenantID, pageNum, pageSize, tagID int) ([]*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	articles := r.published(tenantID, tagID)
+	start, end := page(len(articles), pageNum, pageSize)
+
+	return articles[start:end], nil
+}
+
+func (r articleRepository) CountPublished(_ context.Context, tenantID, tagID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.published(tenantID, tagID)), nil
+}
+
+func (r articleRepository) GetPublishedBySlug(_ context.Context, tenantID int, slug string) (*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, article := range r.published(tenantID, 0) {
+		if article.Slug == slug {
+			return article, nil
+		}
+	}
+
+	return &models.Article{}, nil
+}
+
+func (r articleRepository) GetPublishedIndex(_ context.Context, tenantID, limit int) ([]*models.Article, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	articles := r.published(tenantID, 0)
+	_, end := page(len(articles), 0, limit)
+
+	return articles[:end], nil
+}
+
+func (r articleRepository) Bulk(_ context.Context, tenantID int, ops []models.BulkOp, atomic bool) ([]models.BulkResult, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return r.s.runBulk(ops, atomic, func(op models.BulkOp) models.BulkResult {
+		return r.exec(tenantID, op)
+	}), nil
+}
+
+func (r articleRepository) exec(tenantID int, op models.BulkOp) models.BulkResult {
+	result := models.BulkResult{ID: op.ID}
+
+	if op.Op == models.BULK_CREATE {
+		if result.Err = r.checkTag(tenantID, op.Data["tag_id"]); result.Err != nil {
+			return result
+		}
+
+		data := make(map[string]interface{}, len(op.Data)+1)
+		for k, v := range op.Data {
+			data[k] = v
+		}
+		data["tenant_id"] = tenantID
+		slug, _ := data["slug"].(string)
+		if slug == "" {
+			title, _ := data["title"].(string)
+			data["slug"] = r.uniqueSlug(tenantID, title)
+		} else if r.existBySlug(tenantID, slug, 0) {
+			result.Err = models.ErrSlugExist
+			return result
+		}
+
+		article := r.add(data)
+		result.ID, result.After = article.ID, article
+		return result
+	}
+
+	before, ok := r.article(tenantID, op.ID)
+	if !ok || before.DeletedOn != 0 {
+		result.Err = models.ErrNotExist
+		return result
+	}
+	result.Before = &before
+	if op.Version > 0 && before.Version != op.Version {
+		result.Err = models.ErrVersionConflict
+		return result
+	}
+
+	switch op.Op {
+	case models.BULK_DELETE:
+		r.delete(tenantID, op.ID)
+		return result
+	case models.BULK_UPDATE:
+		if tagID, ok := op.Data["tag_id"]; ok && tagID != before.TagID {
+			if result.Err = r.checkTag(tenantID, tagID); result.Err != nil {
+				return result
+			}
+		}
+		if slug, ok := op.Data["slug"].(string); ok && r.existBySlug(tenantID, slug, op.ID) {
+			result.Err = models.ErrSlugExist
+			return result
+		}
+	}
+
+	if result.Err = r.edit(tenantID, op.ID, op.Version, op.Data); result.Err != nil {
+		return result
+	}
+	after := r.s.articles[op.ID]
+	result.After = &after
+
+	return result
+}
+
+func (r articleRepository) checkTag(tenantID int, id interface{}) error {
+	if tag, ok := r.s.tags[toInt(id)]; !ok || tag.TenantID != tenantID || tag.DeletedOn != 0 {
+		return models.ErrTagNotExist
+	}
+
+	return nil
+}
+
+func (r articleRepository) AddViews(_ context.Context, views []models.ArticleView) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, view := range views {
+		if article, ok := r.article(view.TenantID, view.ArticleID); ok {
+			article.ViewCount += view.Views
+			r.s.articles[article.ID] = article
+		}
+
+		key := viewKey{view.ArticleID, view.Day}
+		if existing, ok := r.s.views[key]; ok {
+			existing.Views += view.Views
+			r.s.views[key] = existing
+			continue
+		}
+		view.ID = r.s.id()
+		r.s.views[key] = view
+	}
+
+	return nil
+}
+
+func (r articleRepository) Like(_ context.Context, tenantID, id int, username string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := likeKey{id, username}
+	article, ok := r.article(tenantID, id)
+	if _, liked := r.s.likes[key]; liked || !ok || article.DeletedOn != 0 {
+		return false, nil
+	}
+
+	article.LikeCount++
+	r.s.articles[id] = article
+	r.s.likes[key] = models.ArticleLike{ID: r.s.id(), TenantID: tenantID, ArticleID: id, Username: username, CreatedOn: now()}
+
+	return true, nil
+}
+
+func (r articleRepository) Unlike(_ context.Context, tenantID, id int, username string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := likeKey{id, username}
+	if like, ok := r.s.likes[key]; !ok || like.TenantID != tenantID {
+		return false, nil
+	}
+
+	delete(r.s.likes, key)
+	if article, ok := r.article(tenantID, id); ok {
+		article.LikeCount--
+		r.s.articles[id] = article
+	}
+
+	return true, nil
+}
+
+func (r articleRepository) ExistLike(_ context.Context, tenantID, id int, username string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	like, ok := r.s.likes[likeKey{id, username}]
+	return ok && like.TenantID == tenantID, nil
+}
+
+func (r articleRepository) GetViewsSince(_ context.Context, tenantID, day int) ([]models.ArticleView, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var views []models.ArticleView
+	for _, view := range r.s.views {
+		if view.TenantID == tenantID && view.Day >= day {
+			views = append(views, view)
+		}
+	}
+
+	return views, nil
+}
+
+func (r articleRepository) GetLikesSince(_ context.Context, tenantID, since int) ([]models.ArticleLike, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var likes []models.ArticleLike
+	for _, like := range r.s.likes {
+		if like.TenantID == tenantID && like.CreatedOn >= since {
+			likes = append(likes, like)
+		}
+	}
+
+	return likes, nil
+}
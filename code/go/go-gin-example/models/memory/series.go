@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fzzv/go-gin-example/models"
+)
+
+type seriesRepository struct {
+	s *Store
+}
+
+// get 获取站点内未删除的系列
+func (r seriesRepository) get(tenantID, id int) (models.Series, bool) {
+	series, ok := r.s.series[id]
+	return series, ok && series.TenantID == tenantID && series.DeletedOn == 0
+}
+
+func (r seriesRepository) Get(_ context.Context, tenantID, id int) (*models.Series, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	series, ok := r.get(tenantID, id)
+	if !ok {
+		return &models.Series{}, nil
+	}
+
+	return &series, nil
+}
+
+func (r seriesRepository) list(tenantID int) []models.Series {
+	var list []models.Series
+	for _, series := range r.s.series {
+		if series.TenantID == tenantID && series.DeletedOn == 0 {
+			list = append(list, series)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+
+	return list
+}
+
+func (r seriesRepository) GetAll(_ context.Context, tenantID, pageNum, pageSize int) ([]models.Series, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	list := r.list(tenantID)
+	start, end := page(len(list), pageNum, pageSize)
+
+	return list[start:end], nil
+}
+
+func (r seriesRepository) Count(_ context.Context, tenantID int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return len(r.list(tenantID)), nil
+}
+
+func (r seriesRepository) Add(_ context.Context, series *models.Series) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	series.ID = r.s.id()
+	series.CreatedOn, series.ModifiedOn = now(), now()
+	r.s.series[series.ID] = *series
+
+	return nil
+}
+
+func (r seriesRepository) Edit(_ context.Context, tenantID, id int, data map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	series, ok := r.get(tenantID, id)
+	if !ok {
+		return nil
+	}
+	for name, value := range data {
+		switch name {
+		case "title":
+			series.Title = value.(string)
+		case "desc":
+			series.Desc = value.(string)
+		case "modified_by":
+			series.ModifiedBy = value.(string)
+		}
+	}
+	series.ModifiedOn = now()
+	r.s.series[id] = series
+
+	return nil
+}
+
+func (r seriesRepository) Delete(_ context.Context, tenantID, id int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	series, ok := r.get(tenantID, id)
+	if !ok {
+		return nil
+	}
+	r.removeArticles(id)
+	series.DeletedOn = now()
+	r.s.series[id] = series
+
+	return nil
+}
+
+func (r seriesRepository) removeArticles(id int) {
+	for articleID, member := range r.s.members {
+		if member.SeriesID == id {
+			delete(r.s.members, articleID)
+		}
+	}
+}
+
+func (r seriesRepository) GetArticleIDs(_ context.Context, tenantID, id int) ([]int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var members []models.SeriesArticle
+	for _, member := range r.s.members {
+		if member.TenantID == tenantID && member.SeriesID == id {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Position < members[j].Position })
+
+	ids := make([]int, len(members))
+	for i, member := range members {
+		ids[i] = member.ArticleID
+	}
+
+	return ids, nil
+}
+
+func (r seriesRepository) SetArticles(_ context.Context, tenantID, id int, articleIDs []int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, articleID := range articleIDs {
+		if member, ok := r.s.members[articleID]; ok && member.SeriesID != id {
+			return models.ErrArticleInSeries
+		}
+	}
+
+	r.removeArticles(id)
+	for i, articleID := range articleIDs {
+		r.s.members[articleID] = models.SeriesArticle{ID: r.s.id(), TenantID: tenantID, SeriesID: id, ArticleID: articleID, Position: i + 1}
+	}
+
+	return nil
+}
+
+func (r seriesRepository) GetByArticle(_ context.Context, tenantID, articleID int) (*models.Series, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	member, ok := r.s.members[articleID]
+	if !ok || member.TenantID != tenantID {
+		return &models.Series{}, nil
+	}
+	series, ok := r.get(tenantID, member.SeriesID)
+	if !ok {
+		return &models.Series{}, nil
+	}
+
+	return &series, nil
+}
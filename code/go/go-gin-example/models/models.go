@@ -2,7 +2,6 @@ package models
 
 import (
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/fzzv/go-gin-example/pkg/setting"
@@ -19,21 +18,24 @@ type Model struct {
 	DeletedOn  int `json:"deleted_on"`
 }
 
-func Setup() {
-	var (
-		err error
-	)
-
-	db, err = gorm.Open(setting.DatabaseSetting.Type, fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=Local",
+// Setup 按配置连接数据库，连接检查失败时返回 error，但仍保留连接对象
+func Setup() error {
+	return Open(setting.DatabaseSetting.Type, fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=Local",
 		setting.DatabaseSetting.User,
 		setting.DatabaseSetting.Password,
 		setting.DatabaseSetting.Host,
 		setting.DatabaseSetting.Name,
 	))
+}
 
-	if err != nil {
-		log.Println(err)
+// Open 建立数据库连接并注册回调，dialect 为 gorm 的方言名，除 mysql 外需由调用方导入对应的方言包，
+// 如集成测试中使用 sqlite3。连接检查失败时仍保留连接对象，之后的查询会返回错误
+func Open(dialect string, args ...interface{}) error {
+	conn, err := gorm.Open(dialect, args...)
+	if conn == nil {
+		return err
 	}
+	db = conn
 
 	gorm.DefaultTableNameHandler = func(db *gorm.DB, defaultTableName string) string {
 		return setting.DatabaseSetting.TablePrefix + defaultTableName
@@ -42,10 +44,20 @@ func Setup() {
 	db.SingularTable(true)
 	db.Callback().Create().Replace("gorm:update_time_stamp", updateTimeStampForCreateCallback)
 	db.Callback().Update().Replace("gorm:update_time_stamp", updateTimeStampForUpdateCallback)
+	db.Callback().Create().After("gorm:update_time_stamp").Register("create_version", updateVersionForCreateCallback)
+	db.Callback().Update().After("gorm:update_time_stamp").Register("update_version", updateVersionForUpdateCallback)
 	db.Callback().Delete().Replace("gorm:delete", deleteCallback)
-	db.LogMode(true)
+	registerTracing(db, dialect)
+	db.LogMode(setting.ServerSetting.RunMode != "test")
 	db.DB().SetMaxIdleConns(10)
 	db.DB().SetMaxOpenConns(100)
+
+	return err
+}
+
+// Migrate 按模型创建缺少的表与列，生产环境的表结构以 db/create.sql 为准，这里用于测试等临时数据库
+func Migrate() error {
+	return db.AutoMigrate(&Tenant{}, &Tag{}, &Article{}, &ArticleView{}, &ArticleLike{}, &Series{}, &SeriesArticle{}, &Auth{}, &ApiKey{}, &Audit{}, &Job{}, &JobRun{}, &Webhook{}, &WebhookDelivery{}).Error
 }
 
 func CloseDB() {
@@ -82,6 +94,43 @@ func updateTimeStampForUpdateCallback(scope *gorm.Scope) {
 	}
 }
 
+// updateVersionForCreateCallback 含有 Version 字段的模型新建时版本号为 1，0 保留表示不检查版本
+func updateVersionForCreateCallback(scope *gorm.Scope) {
+	if !scope.HasError() {
+		if versionField, ok := scope.FieldByName("Version"); ok && versionField.IsBlank {
+			versionField.Set(1)
+		}
+	}
+}
+
+// updateVersionForUpdateCallback 含有 Version 字段的模型每次更新时版本号加一，与 ModifiedOn 一样跳过 UpdateColumn
+func updateVersionForUpdateCallback(scope *gorm.Scope) {
+	if _, ok := scope.Get("gorm:update_column"); ok {
+		return
+	}
+	if _, ok := scope.FieldByName("Version"); ok {
+		// 表达式无法赋值给结构体字段，SetColumn 会返回错误，但仍会写入更新的列
+		scope.SetColumn("Version", gorm.Expr("version + 1"))
+	}
+}
+
+// editVersioned 执行带版本条件的更新，version 为 0 时不检查版本
+func editVersioned(query *gorm.DB, version int, data interface{}) error {
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Updates(data)
+	if result.Error != nil {
+		return result.Error
+	}
+	if version > 0 && result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
 func deleteCallback(scope *gorm.Scope) {
 	if !scope.HasError() {
 		var extraOption string
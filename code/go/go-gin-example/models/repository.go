@@ -0,0 +1,301 @@
+package models
+
+import "context"
+
+// TagRepository 站点内标签的存取，maps 为 字段名 → 值 的等值条件，如 tenant_id、deleted_on、name、state
+type TagRepository interface {
+	ExistByName(ctx context.Context, tenantID int, name string) (bool, error)
+	ExistByID(ctx context.Context, tenantID, id int) (bool, error)
+	Add(ctx context.Context, tenantID int, name string, state int, createdBy string) (*Tag, error)
+	// Get 获取未删除的标签，不存在时返回 ID 为 0 的标签
+	Get(ctx context.Context, tenantID, id int) (*Tag, error)
+	// GetByIDs 批量获取标签，已删除的标签也会返回
+	GetByIDs(ctx context.Context, tenantID int, ids []int) ([]Tag, error)
+	GetAll(ctx context.Context, pageNum, pageSize int, maps map[string]interface{}) ([]Tag, error)
+	Count(ctx context.Context, maps map[string]interface{}) (int, error)
+	Edit(ctx context.Context, tenantID, id, version int, data map[string]interface{}) error
+	Delete(ctx context.Context, tenantID, id int, cascade bool) error
+	CountArticles(ctx context.Context, tenantID, id int) (int, error)
+	GetDeleted(ctx context.Context, tenantID, id int) (*Tag, error)
+	GetDeletedAll(ctx context.Context, tenantID, pageNum, pageSize int) ([]Tag, error)
+	CountDeleted(ctx context.Context, tenantID int) (int, error)
+	Restore(ctx context.Context, tag *Tag, cascade bool) error
+	Bulk(ctx context.Context, tenantID int, ops []BulkOp, atomic bool) ([]BulkResult, error)
+}
+
+// ArticleRepository 站点内文章的存取，maps 为 字段名 → 值 的等值条件，如 tenant_id、deleted_on、state、tag_id
+type ArticleRepository interface {
+	ExistByID(ctx context.Context, tenantID, id int) (bool, error)
+	// ExistBySlug 判断 slug 是否已被站点内 excludeID 以外的文章使用，回收站中的文章也占用 slug
+	ExistBySlug(ctx context.Context, tenantID int, slug string, excludeID int) (bool, error)
+	UniqueSlug(ctx context.Context, tenantID int, title string) (string, error)
+	// Add 新建文章，data 中的 tenant_id 为文章所属的站点
+	Add(ctx context.Context, data map[string]interface{}) (*Article, error)
+	// Get 获取未删除的文章及其标签，不存在时返回 ID 为 0 的文章
+	Get(ctx context.Context, tenantID, id int) (*Article, error)
+	GetAll(ctx context.Context, pageNum, pageSize int, maps map[string]interface{}) ([]*Article, error)
+	// GetByIDs 批量获取未删除的文章及其标签，按 ID 排序
+	GetByIDs(ctx context.Context, tenantID int, ids []int) ([]*Article, error)
+	// GetByTags、GetByAuthors 批量获取多个标签下、多个作者未删除的文章，按 ID 排序
+	GetByTags(ctx context.Context, tenantID int, tagIDs []int) ([]*Article, error)
+	GetByAuthors(ctx context.Context, tenantID int, usernames []string) ([]*Article, error)
+	// GetAuthors 获取站点内未删除的文章的作者，按用户名排序
+	GetAuthors(ctx context.Context, tenantID int) ([]string, error)
+	Count(ctx context.Context, maps map[string]interface{}) (int, error)
+	Edit(ctx context.Context, tenantID, id, version int, data map[string]interface{}) error
+	Delete(ctx context.Context, tenantID, id int) error
+	GetDeleted(ctx context.Context, tenantID, id int) (*Article, error)
+	GetDeletedAll(ctx context.Context, tenantID, pageNum, pageSize int) ([]*Article, error)
+	CountDeleted(ctx context.Context, tenantID int) (int, error)
+	Restore(ctx context.Context, tenantID, id int) error
+	GetPublished(ctx context.Context, tenantID, pageNum, pageSize, tagID int) ([]*Article, error)
+	CountPublished(ctx context.Context, tenantID, tagID int) (int, error)
+	GetPublishedBySlug(ctx context.Context, tenantID int, slug string) (*Article, error)
+	GetPublishedIndex(ctx context.Context, tenantID, limit int) ([]*Article, error)
+	Bulk(ctx context.Context, tenantID int, ops []BulkOp, atomic bool) ([]BulkResult, error)
+	// AddViews 累加浏览数，不改变文章的 modified_on 与 version
+	AddViews(ctx context.Context, views []ArticleView) error
+	// Like、Unlike 点赞与取消点赞，返回是否有变化，文章不存在时 Like 返回 false
+	Like(ctx context.Context, tenantID, id int, username string) (bool, error)
+	Unlike(ctx context.Context, tenantID, id int, username string) (bool, error)
+	ExistLike(ctx context.Context, tenantID, id int, username string) (bool, error)
+	// GetViewsSince 获取从 day 这天起每篇文章每天的浏览数，GetLikesSince 获取 since 之后的点赞
+	GetViewsSince(ctx context.Context, tenantID, day int) ([]ArticleView, error)
+	GetLikesSince(ctx context.Context, tenantID, since int) ([]ArticleLike, error)
+}
+
+// SeriesRepository 站点内系列及其文章顺序的存取
+type SeriesRepository interface {
+	// Get 获取未删除的系列，不存在时返回 ID 为 0 的系列
+	Get(ctx context.Context, tenantID, id int) (*Series, error)
+	GetAll(ctx context.Context, tenantID, pageNum, pageSize int) ([]Series, error)
+	Count(ctx context.Context, tenantID int) (int, error)
+	Add(ctx context.Context, series *Series) error
+	Edit(ctx context.Context, tenantID, id int, data map[string]interface{}) error
+	// Delete 软删除系列，其中的文章移出系列
+	Delete(ctx context.Context, tenantID, id int) error
+	// GetArticleIDs 按顺序获取系列中的文章 ID，包括回收站中的文章
+	GetArticleIDs(ctx context.Context, tenantID, id int) ([]int, error)
+	// SetArticles 按 articleIDs 的顺序设置系列中的文章，任一文章已属于其他系列时返回 ErrArticleInSeries
+	SetArticles(ctx context.Context, tenantID, id int, articleIDs []int) error
+	// GetByArticle 获取文章所在的系列，不在任何系列中时返回 ID 为 0 的系列
+	GetByArticle(ctx context.Context, tenantID, articleID int) (*Series, error)
+}
+
+// NewTagRepository 返回基于数据库的 TagRepository
+func NewTagRepository() TagRepository {
+	return dbTagRepository{}
+}
+
+// NewArticleRepository 返回基于数据库的 ArticleRepository
+func NewArticleRepository() ArticleRepository {
+	return dbArticleRepository{}
+}
+
+// NewSeriesRepository 返回基于数据库的 SeriesRepository
+func NewSeriesRepository() SeriesRepository {
+	return dbSeriesRepository{}
+}
+
+type dbTagRepository struct{}
+
+func (dbTagRepository) ExistByID(ctx context.Context, tenantID, id int) (bool, error) {
+	return ExistTagByID(ctx, tenantID, id)
+}
+func (dbTagRepository) Get(ctx context.Context, tenantID, id int) (*Tag, error) {
+	return GetTag(ctx, tenantID, id)
+}
+func (dbTagRepository) CountArticles(ctx context.Context, tenantID, id int) (int, error) {
+	return CountArticlesByTag(ctx, tenantID, id)
+}
+func (dbTagRepository) GetDeleted(ctx context.Context, tenantID, id int) (*Tag, error) {
+	return GetDeletedTag(ctx, tenantID, id)
+}
+func (dbTagRepository) CountDeleted(ctx context.Context, tenantID int) (int, error) {
+	return GetDeletedTagTotal(ctx, tenantID)
+}
+func (dbTagRepository) Restore(ctx context.Context, tag *Tag, cascade bool) error {
+	return RestoreTag(ctx, tag, cascade)
+}
+
+func (dbTagRepository) ExistByName(ctx context.Context, tenantID int, name string) (bool, error) {
+	return ExistTagByName(ctx, tenantID, name)
+}
+
+func (dbTagRepository) Add(ctx context.Context, tenantID int, name string, state int, createdBy string) (*Tag, error) {
+	return AddTag(ctx, tenantID, name, state, createdBy)
+}
+
+func (dbTagRepository) GetByIDs(ctx context.Context, tenantID int, ids []int) ([]Tag, error) {
+	return GetTagsByIDs(ctx, tenantID, ids)
+}
+
+func (dbTagRepository) GetAll(ctx context.Context, pageNum, pageSize int, maps map[string]interface{}) ([]Tag, error) {
+	return GetTags(ctx, pageNum, pageSize, maps)
+}
+
+func (dbTagRepository) Count(ctx context.Context, maps map[string]interface{}) (int, error) {
+	return GetTagTotal(ctx, maps)
+}
+
+func (dbTagRepository) Edit(ctx context.Context, tenantID, id, version int, data map[string]interface{}) error {
+	return EditTag(ctx, tenantID, id, version, data)
+}
+
+func (dbTagRepository) Delete(ctx context.Context, tenantID, id int, cascade bool) error {
+	return DeleteTag(ctx, tenantID, id, cascade)
+}
+
+func (dbTagRepository) GetDeletedAll(ctx context.Context, tenantID, pageNum, pageSize int) ([]Tag, error) {
+	return GetDeletedTags(ctx, tenantID, pageNum, pageSize)
+}
+
+func (dbTagRepository) Bulk(ctx context.Context, tenantID int, ops []BulkOp, atomic bool) ([]BulkResult, error) {
+	return BulkTags(ctx, tenantID, ops, atomic)
+}
+
+type dbArticleRepository struct{}
+
+func (dbArticleRepository) ExistByID(ctx context.Context, tenantID, id int) (bool, error) {
+	return ExistArticleByID(ctx, tenantID, id)
+}
+func (dbArticleRepository) Get(ctx context.Context, tenantID, id int) (*Article, error) {
+	return GetArticle(ctx, tenantID, id)
+}
+func (dbArticleRepository) Delete(ctx context.Context, tenantID, id int) error {
+	return DeleteArticle(ctx, tenantID, id)
+}
+func (dbArticleRepository) Restore(ctx context.Context, tenantID, id int) error {
+	return RestoreArticle(ctx, tenantID, id)
+}
+
+func (dbArticleRepository) UniqueSlug(ctx context.Context, tenantID int, title string) (string, error) {
+	return UniqueArticleSlug(ctx, tenantID, title)
+}
+
+func (dbArticleRepository) GetDeleted(ctx context.Context, tenantID, id int) (*Article, error) {
+	return GetDeletedArticle(ctx, tenantID, id)
+}
+
+func (dbArticleRepository) CountDeleted(ctx context.Context, tenantID int) (int, error) {
+	return GetDeletedArticleTotal(ctx, tenantID)
+}
+
+func (dbArticleRepository) CountPublished(ctx context.Context, tenantID, tagID int) (int, error) {
+	return GetPublishedArticleTotal(ctx, tenantID, tagID)
+}
+
+func (dbArticleRepository) ExistBySlug(ctx context.Context, tenantID int, slug string, excludeID int) (bool, error) {
+	return ExistArticleBySlug(ctx, tenantID, slug, excludeID)
+}
+
+func (dbArticleRepository) Add(ctx context.Context, data map[string]interface{}) (*Article, error) {
+	return AddArticle(ctx, data)
+}
+
+func (dbArticleRepository) GetAll(ctx context.Context, pageNum, pageSize int, maps map[string]interface{}) ([]*Article, error) {
+	return GetArticles(ctx, pageNum, pageSize, maps)
+}
+
+func (dbArticleRepository) GetByIDs(ctx context.Context, tenantID int, ids []int) ([]*Article, error) {
+	return GetArticlesByIDs(ctx, tenantID, ids)
+}
+
+func (dbArticleRepository) GetByTags(ctx context.Context, tenantID int, tagIDs []int) ([]*Article, error) {
+	return GetArticlesByTags(ctx, tenantID, tagIDs)
+}
+
+func (dbArticleRepository) GetByAuthors(ctx context.Context, tenantID int, usernames []string) ([]*Article, error) {
+	return GetArticlesByAuthors(ctx, tenantID, usernames)
+}
+
+func (dbArticleRepository) GetAuthors(ctx context.Context, tenantID int) ([]string, error) {
+	return GetArticleAuthors(ctx, tenantID)
+}
+
+func (dbArticleRepository) Count(ctx context.Context, maps map[string]interface{}) (int, error) {
+	return GetArticleTotal(ctx, maps)
+}
+
+func (dbArticleRepository) Edit(ctx context.Context, tenantID, id, version int, data map[string]interface{}) error {
+	return EditArticle(ctx, tenantID, id, version, data)
+}
+
+func (dbArticleRepository) GetDeletedAll(ctx context.Context, tenantID, pageNum, pageSize int) ([]*Article, error) {
+	return GetDeletedArticles(ctx, tenantID, pageNum, pageSize)
+}
+
+func (dbArticleRepository) GetPublished(ctx context.Context, tenantID, pageNum, pageSize, tagID int) ([]*Article, error) {
+	return GetPublishedArticles(ctx, tenantID, pageNum, pageSize, tagID)
+}
+
+func (dbArticleRepository) GetPublishedBySlug(ctx context.Context, tenantID int, slug string) (*Article, error) {
+	return GetPublishedArticleBySlug(ctx, tenantID, slug)
+}
+
+func (dbArticleRepository) GetPublishedIndex(ctx context.Context, tenantID, limit int) ([]*Article, error) {
+	return GetPublishedArticleIndex(ctx, tenantID, limit)
+}
+
+func (dbArticleRepository) Bulk(ctx context.Context, tenantID int, ops []BulkOp, atomic bool) ([]BulkResult, error) {
+	return BulkArticles(ctx, tenantID, ops, atomic)
+}
+
+func (dbArticleRepository) AddViews(ctx context.Context, views []ArticleView) error {
+	return AddArticleViews(ctx, views)
+}
+
+func (dbArticleRepository) Like(ctx context.Context, tenantID, id int, username string) (bool, error) {
+	return LikeArticle(ctx, tenantID, id, username)
+}
+
+func (dbArticleRepository) Unlike(ctx context.Context, tenantID, id int, username string) (bool, error) {
+	return UnlikeArticle(ctx, tenantID, id, username)
+}
+
+func (dbArticleRepository) ExistLike(ctx context.Context, tenantID, id int, username string) (bool, error) {
+	return ExistArticleLike(ctx, tenantID, id, username)
+}
+
+func (dbArticleRepository) GetViewsSince(ctx context.Context, tenantID, day int) ([]ArticleView, error) {
+	return GetArticleViewsSince(ctx, tenantID, day)
+}
+
+func (dbArticleRepository) GetLikesSince(ctx context.Context, tenantID, since int) ([]ArticleLike, error) {
+	return GetArticleLikesSince(ctx, tenantID, since)
+}
+
+type dbSeriesRepository struct{}
+
+func (dbSeriesRepository) Get(ctx context.Context, tenantID, id int) (*Series, error) {
+	return GetSeries(ctx, tenantID, id)
+}
+func (dbSeriesRepository) Count(ctx context.Context, tenantID int) (int, error) {
+	return GetSeriesTotal(ctx, tenantID)
+}
+func (dbSeriesRepository) Add(ctx context.Context, series *Series) error {
+	return AddSeries(ctx, series)
+}
+func (dbSeriesRepository) Delete(ctx context.Context, tenantID, id int) error {
+	return DeleteSeries(ctx, tenantID, id)
+}
+
+func (dbSeriesRepository) GetAll(ctx context.Context, tenantID, pageNum, pageSize int) ([]Series, error) {
+	return GetSeriesList(ctx, tenantID, pageNum, pageSize)
+}
+
+func (dbSeriesRepository) Edit(ctx context.Context, tenantID, id int, data map[string]interface{}) error {
+	return EditSeries(ctx, tenantID, id, data)
+}
+
+func (dbSeriesRepository) GetArticleIDs(ctx context.Context, tenantID, id int) ([]int, error) {
+	return GetSeriesArticleIDs(ctx, tenantID, id)
+}
+
+func (dbSeriesRepository) SetArticles(ctx context.Context, tenantID, id int, articleIDs []int) error {
+	return SetSeriesArticles(ctx, tenantID, id, articleIDs)
+}
+
+func (dbSeriesRepository) GetByArticle(ctx context.Context, tenantID, articleID int) (*Series, error) {
+	return GetSeriesByArticle(ctx, tenantID, articleID)
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Series 系列，将多篇文章按顺序组织在一起，一篇文章最多属于一个系列
+type Series struct {
+	Model
+
+	TenantID   int    `json:"tenant_id" gorm:"index"`
+	Title      string `json:"title"`
+	Desc       string `json:"desc"`
+	CreatedBy  string `json:"created_by"`
+	ModifiedBy string `json:"modified_by"`
+}
+
+// SeriesArticle 系列中的文章，Position 从 1 开始
+type SeriesArticle struct {
+	ID        int `gorm:"primary_key" json:"id"`
+	TenantID  int `json:"tenant_id"`
+	SeriesID  int `json:"series_id" gorm:"index"`
+	ArticleID int `json:"article_id" gorm:"unique_index"`
+	Position  int `json:"position"`
+}
+
+// SeriesNav 文章在系列中的位置及前后篇，回收站中的文章不计入
+type SeriesNav struct {
+	ID       int         `json:"id"`
+	Title    string      `json:"title"`
+	Position int         `json:"position"`
+	Total    int         `json:"total"`
+	Prev     *SeriesItem `json:"prev"`
+	Next     *SeriesItem `json:"next"`
+}
+
+// SeriesItem 系列导航中的一篇文章
+type SeriesItem struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// GetSeries 获取未删除的系列，不存在时返回 ID 为 0 的系列
+func GetSeries(ctx context.Context, tenantID, id int) (*Series, error) {
+	var series Series
+	err := withContext(ctx).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).First(&series).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &series, nil
+}
+
+// GetSeriesList 获取站点内的系列，最新创建的在前
+func GetSeriesList(ctx context.Context, tenantID, pageNum, pageSize int) ([]Series, error) {
+	var series []Series
+	err := withContext(ctx).Where("tenant_id = ? AND deleted_on = ? ", tenantID, 0).Order("id DESC").Offset(pageNum).Limit(pageSize).Find(&series).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func GetSeriesTotal(ctx context.Context, tenantID int) (int, error) {
+	var count int
+	if err := withContext(ctx).Model(&Series{}).Where("tenant_id = ? AND deleted_on = ? ", tenantID, 0).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func AddSeries(ctx context.Context, series *Series) error {
+	return withContext(ctx).Create(series).Error
+}
+
+func EditSeries(ctx context.Context, tenantID, id int, data interface{}) error {
+	return withContext(ctx).Model(&Series{}).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).Updates(data).Error
+}
+
+// DeleteSeries 软删除系列，其中的文章移出系列
+func DeleteSeries(ctx context.Context, tenantID, id int) error {
+	return withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND series_id = ?", tenantID, id).Delete(&SeriesArticle{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&Series{}).Error
+	})
+}
+
+// GetSeriesArticleIDs 按顺序获取系列中的文章 ID，包括回收站中的文章
+func GetSeriesArticleIDs(ctx context.Context, tenantID, id int) ([]int, error) {
+	var ids []int
+	err := withContext(ctx).Model(&SeriesArticle{}).Where("tenant_id = ? AND series_id = ?", tenantID, id).Order("position").Pluck("article_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// SetSeriesArticles 按 articleIDs 的顺序设置系列中的文章，不在其中的文章移出系列，
+// 任一文章已属于其他系列时不做修改并返回 ErrArticleInSeries
+func SetSeriesArticles(ctx context.Context, tenantID, id int, articleIDs []int) error {
+	return withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(articleIDs) > 0 {
+			var count int
+			err := tx.Model(&SeriesArticle{}).Where("article_id IN (?) AND series_id != ?", articleIDs, id).Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrArticleInSeries
+			}
+		}
+
+		if err := tx.Where("tenant_id = ? AND series_id = ?", tenantID, id).Delete(&SeriesArticle{}).Error; err != nil {
+			return err
+		}
+		for i, articleID := range articleIDs {
+			item := SeriesArticle{TenantID: tenantID, SeriesID: id, ArticleID: articleID, Position: i + 1}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetSeriesByArticle 获取文章所在的未删除的系列，不在任何系列中时返回 ID 为 0 的系列
+func GetSeriesByArticle(ctx context.Context, tenantID, articleID int) (*Series, error) {
+	var item SeriesArticle
+	err := withContext(ctx).Where("tenant_id = ? AND article_id = ?", tenantID, articleID).First(&item).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if item.ID == 0 {
+		return &Series{}, nil
+	}
+
+	return GetSeries(ctx, tenantID, item.SeriesID)
+}
@@ -1,20 +1,27 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
 
 // 用于Gorm的使用。并给予了附属属性json，这样子在c.JSON的时候就会自动转换格式，非常的便利
 type Tag struct {
 	Model
 
+	TenantID   int    `json:"tenant_id" gorm:"index"`
 	Name       string `json:"name"`
 	CreatedBy  string `json:"created_by"`
 	ModifiedBy string `json:"modified_by"`
 	State      int    `json:"state"`
+	Version    int    `json:"version"` // 每次修改加一，用于乐观锁
 }
 
-func GetTags(pageNum int, pageSize int, maps interface{}) ([]Tag, error) {
+func GetTags(ctx context.Context, pageNum int, pageSize int, maps interface{}) ([]Tag, error) {
 	var tags []Tag
-	err := db.Where(maps).Offset(pageNum).Limit(pageSize).Find(&tags).Error
+	err := withContext(ctx).Where(maps).Offset(pageNum).Limit(pageSize).Find(&tags).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -22,18 +29,18 @@ func GetTags(pageNum int, pageSize int, maps interface{}) ([]Tag, error) {
 	return tags, nil
 }
 
-func GetTagTotal(maps interface{}) (int, error) {
+func GetTagTotal(ctx context.Context, maps interface{}) (int, error) {
 	var count int
-	if err := db.Model(&Tag{}).Where(maps).Count(&count).Error; err != nil {
+	if err := withContext(ctx).Model(&Tag{}).Where(maps).Count(&count).Error; err != nil {
 		return 0, err
 	}
 
 	return count, nil
 }
 
-func ExistTagByName(name string) (bool, error) {
+func ExistTagByName(ctx context.Context, tenantID int, name string) (bool, error) {
 	var tag Tag
-	err := db.Select("id").Where("name = ? AND deleted_on = ? ", name, 0).First(&tag).Error
+	err := withContext(ctx).Select("id").Where("tenant_id = ? AND name = ? AND deleted_on = ? ", tenantID, name, 0).First(&tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -45,17 +52,39 @@ func ExistTagByName(name string) (bool, error) {
 	return false, nil
 }
 
-func AddTag(name string, state int, createdBy string) error {
+func AddTag(ctx context.Context, tenantID int, name string, state int, createdBy string) (*Tag, error) {
 	tag := Tag{
+		TenantID:  tenantID,
 		Name:      name,
 		State:     state,
 		CreatedBy: createdBy,
 	}
-	if err := db.Create(&tag).Error; err != nil {
-		return err
+	if err := withContext(ctx).Create(&tag).Error; err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func GetTag(ctx context.Context, tenantID, id int) (*Tag, error) {
+	var tag Tag
+	err := withContext(ctx).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).First(&tag).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// GetTagsByIDs 批量获取站点内的标签，已删除的标签也会返回，与文章 Preload 标签的行为一致
+func GetTagsByIDs(ctx context.Context, tenantID int, ids []int) ([]Tag, error) {
+	var tags []Tag
+	err := withContext(ctx).Where("tenant_id = ? AND id IN (?)", tenantID, ids).Find(&tags).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
 	}
 
-	return nil
+	return tags, nil
 }
 
 /*
@@ -74,9 +103,9 @@ gorm所支持的回调方法：
 //		scope.SetColumn("ModifiedOn", time.Now().Unix())
 //		return nil
 //	}
-func ExistTagByID(id int) (bool, error) {
+func ExistTagByID(ctx context.Context, tenantID, id int) (bool, error) {
 	var tag Tag
-	err := db.Select("id").Where("id = ? AND deleted_on = ? ", id, 0).First(&tag).Error
+	err := withContext(ctx).Select("id").Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).First(&tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -87,24 +116,85 @@ func ExistTagByID(id int) (bool, error) {
 	return false, nil
 }
 
-func DeleteTag(id int) error {
-	if err := db.Where("id = ?", id).Delete(&Tag{}).Error; err != nil {
-		return err
+// DeleteTag 软删除标签，cascade 为 true 时同时软删除该标签下的文章，二者的删除时间相同以便一起恢复
+func DeleteTag(ctx context.Context, tenantID, id int, cascade bool) error {
+	return withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteTag(tx, tenantID, id, cascade)
+	})
+}
+
+// deleteTag 在事务 tx 中软删除标签，cascade 时其下的文章使用相同的删除时间，以便恢复时一并恢复
+func deleteTag(tx *gorm.DB, tenantID, id int, cascade bool) error {
+	now := time.Now().Unix()
+	if cascade {
+		err := tx.Model(&Article{}).Where("tenant_id = ? AND tag_id = ? AND deleted_on = ? ", tenantID, id, 0).Update("deleted_on", now).Error
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return tx.Model(&Tag{}).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0).Update("deleted_on", now).Error
 }
 
-func EditTag(id int, data interface{}) error {
-	if err := db.Model(&Tag{}).Where("id = ? AND deleted_on = ? ", id, 0).Updates(data).Error; err != nil {
-		return err
+// CountArticlesByTag 统计站点内标签下未删除的文章数
+func CountArticlesByTag(ctx context.Context, tenantID, id int) (int, error) {
+	return GetArticleTotal(ctx, map[string]interface{}{"tenant_id": tenantID, "tag_id": id, "deleted_on": 0})
+}
+
+// GetDeletedTag 获取回收站中的标签，不存在时返回 ID 为 0 的标签
+func GetDeletedTag(ctx context.Context, tenantID, id int) (*Tag, error) {
+	var tag Tag
+	err := withContext(ctx).Where("tenant_id = ? AND id = ? AND deleted_on != ? ", tenantID, id, 0).First(&tag).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
 	}
 
-	return nil
+	return &tag, nil
+}
+
+// GetDeletedTags 获取回收站中的标签，最近删除的在前
+func GetDeletedTags(ctx context.Context, tenantID int, pageNum int, pageSize int) ([]Tag, error) {
+	var tags []Tag
+	err := withContext(ctx).Where("tenant_id = ? AND deleted_on != ? ", tenantID, 0).Order("deleted_on DESC").Offset(pageNum).Limit(pageSize).Find(&tags).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func GetDeletedTagTotal(ctx context.Context, tenantID int) (int, error) {
+	var count int
+	if err := withContext(ctx).Model(&Tag{}).Where("tenant_id = ? AND deleted_on != ? ", tenantID, 0).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// RestoreTag 从回收站恢复标签，cascade 为 true 时同时恢复随该标签一起删除的文章
+func RestoreTag(ctx context.Context, tag *Tag, cascade bool) error {
+	return withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if cascade {
+			err := tx.Model(&Article{}).Where("tenant_id = ? AND tag_id = ? AND deleted_on = ? ", tag.TenantID, tag.ID, tag.DeletedOn).Update("deleted_on", 0).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&Tag{}).Where("tenant_id = ? AND id = ? AND deleted_on != ? ", tag.TenantID, tag.ID, 0).Update("deleted_on", 0).Error
+	})
+}
+
+// EditTag 修改标签，version 大于 0 时仅在当前版本等于 version 时修改，否则返回 ErrVersionConflict
+func EditTag(ctx context.Context, tenantID, id int, version int, data interface{}) error {
+	return editVersioned(withContext(ctx).Model(&Tag{}).Where("tenant_id = ? AND id = ? AND deleted_on = ? ", tenantID, id, 0), version, data)
 }
 
-func CleanAllTag() (bool, error) {
-	if err := db.Unscoped().Where("deleted_on != ? ", 0).Delete(&Tag{}).Error; err != nil {
+// CleanAllTag 物理删除在 deletedBefore 之前被软删除、且已没有任何文章（包括回收站中的文章）引用的标签
+func CleanAllTag(ctx context.Context, deletedBefore int) (bool, error) {
+	articles := withContext(ctx).Model(&Article{}).Select("tag_id").Where("tag_id IS NOT NULL").QueryExpr()
+	if err := withContext(ctx).Unscoped().Where("deleted_on != ? AND deleted_on < ? AND id NOT IN (?)", 0, deletedBefore, articles).Delete(&Tag{}).Error; err != nil {
 		return false, err
 	}
 
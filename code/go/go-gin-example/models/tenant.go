@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Tenant 站点，标签、文章、账号、审计记录与 webhook 都属于某个站点。
+// Name 用于 X-Tenant 请求头与上传、导出文件的目录，Host 为站点的域名，
+// Title、Description、Link 为空时使用 [feed] 中的配置
+type Tenant struct {
+	Model
+
+	Name        string `json:"name"`
+	Host        string `json:"host"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Link        string `json:"link"`
+	State       int    `json:"state"`
+	CreatedBy   string `json:"created_by"`
+	ModifiedBy  string `json:"modified_by"`
+}
+
+// GetTenantByName 获取启用的站点，不存在时返回 ID 为 0 的站点
+func GetTenantByName(ctx context.Context, name string) (*Tenant, error) {
+	return getTenant(ctx, "name = ? AND state = ? AND deleted_on = ?", name, 1, 0)
+}
+
+// GetTenantByHost 按域名获取启用的站点，不存在时返回 ID 为 0 的站点
+func GetTenantByHost(ctx context.Context, host string) (*Tenant, error) {
+	return getTenant(ctx, "host = ? AND host != '' AND state = ? AND deleted_on = ?", host, 1, 0)
+}
+
+// GetTenant 获取站点，不论是否启用，不存在时返回 ID 为 0 的站点
+func GetTenant(ctx context.Context, id int) (*Tenant, error) {
+	return getTenant(ctx, "id = ? AND deleted_on = ?", id, 0)
+}
+
+func getTenant(ctx context.Context, query string, args ...interface{}) (*Tenant, error) {
+	var tenant Tenant
+	err := withContext(ctx).Where(query, args...).First(&tenant).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+func GetTenants(ctx context.Context, pageNum int, pageSize int) ([]Tenant, error) {
+	var tenants []Tenant
+	err := withContext(ctx).Where("deleted_on = ?", 0).Offset(pageNum).Limit(pageSize).Find(&tenants).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return tenants, nil
+}
+
+func GetTenantTotal(ctx context.Context) (int, error) {
+	var count int
+	if err := withContext(ctx).Model(&Tenant{}).Where("deleted_on = ?", 0).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ExistTenant 判断名称或域名是否已被 excludeID 以外的站点使用
+func ExistTenant(ctx context.Context, name, host string, excludeID int) (bool, error) {
+	var tenant Tenant
+	query := withContext(ctx).Select("id").Where("id != ? AND deleted_on = ?", excludeID, 0)
+	if host != "" {
+		query = query.Where("name = ? OR host = ?", name, host)
+	} else {
+		query = query.Where("name = ?", name)
+	}
+	err := query.First(&tenant).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+
+	return tenant.ID > 0, nil
+}
+
+// AddTenant 新建站点，admin 不为 nil 时在同一事务中创建站点的第一个账号
+func AddTenant(ctx context.Context, tenant *Tenant, admin *Auth) error {
+	return withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tenant).Error; err != nil {
+			return err
+		}
+		if admin == nil {
+			return nil
+		}
+
+		admin.TenantID = tenant.ID
+		return tx.Create(admin).Error
+	})
+}
+
+func EditTenant(ctx context.Context, id int, data interface{}) error {
+	return withContext(ctx).Model(&Tenant{}).Where("id = ? AND deleted_on = ?", id, 0).Updates(data).Error
+}
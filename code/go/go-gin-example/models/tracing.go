@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fzzv/go-gin-example/pkg/telemetry"
+)
+
+const (
+	// TRACING_CONTEXT 在 gorm 中保存调用方 context 的键，语句的 span 成为其中 span 的子 span
+	TRACING_CONTEXT = "otel:ctx"
+	// TRACING_SPAN 在 scope 中保存当前语句 span 的键
+	TRACING_SPAN = "tracing:span"
+)
+
+// withContext 返回带有 ctx 的连接，所有查询都应通过它执行，事务会沿用其中的 ctx
+func withContext(ctx context.Context) *gorm.DB {
+	return db.Set(TRACING_CONTEXT, ctx)
+}
+
+// registerTracing 为每条经过 gorm 回调的语句创建请求 span 的子 span，Exec 执行的原始 SQL 不经过回调
+func registerTracing(db *gorm.DB, dialect string) {
+	system := semconv.DBSystemNameKey.String(dialect)
+	if dialect == "sqlite3" {
+		system = semconv.DBSystemNameSQLite
+	}
+
+	callbacks := []struct {
+		operation string
+		processor func(*gorm.Callback) *gorm.CallbackProcessor
+		name      string
+	}{
+		{"select", (*gorm.Callback).Query, "gorm:query"},
+		{"select", (*gorm.Callback).RowQuery, "gorm:row_query"},
+		{"insert", (*gorm.Callback).Create, "gorm:create"},
+		{"update", (*gorm.Callback).Update, "gorm:update"},
+		{"delete", (*gorm.Callback).Delete, "gorm:delete"},
+	}
+	for _, cb := range callbacks {
+		operation := cb.operation
+		// db.Callback() 每次都会复制回调，Before、After 也会修改 processor 本身，每次注册都需重新获取
+		cb.processor(db.Callback()).Before(cb.name).Register("tracing:before_"+cb.name, func(scope *gorm.Scope) {
+			table := scope.TableName()
+			ctx, _ := scope.Get(TRACING_CONTEXT)
+			parent, ok := ctx.(context.Context)
+			if !ok {
+				parent = context.Background()
+			}
+			span := telemetry.StartChild(parent, "gorm "+operation+" "+table, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+				system,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(table),
+			))
+			// 嵌套的语句会继承外层 scope 的设置，没有创建 span 时也要覆盖，以免结束外层的 span
+			scope.Set(TRACING_SPAN, span)
+		})
+		cb.processor(db.Callback()).After(cb.name).Register("tracing:after_"+cb.name, endTracing)
+	}
+}
+
+func endTracing(scope *gorm.Scope) {
+	v, _ := scope.Get(TRACING_SPAN)
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(semconv.DBQueryText(scope.SQL), attribute.Int64("db.response.returned_rows", scope.DB().RowsAffected))
+	if err := scope.DB().Error; err != nil && err != gorm.ErrRecordNotFound {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
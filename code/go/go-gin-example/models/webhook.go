@@ -0,0 +1,161 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	DELIVERY_PENDING = "pending"
+	DELIVERY_SENDING = "sending"
+	DELIVERY_SUCCESS = "success"
+	DELIVERY_FAILED  = "failed"
+)
+
+// Webhook 站点的事件订阅，Events 为逗号分隔的事件名，* 表示订阅所有事件
+type Webhook struct {
+	Model
+
+	TenantID   int    `json:"tenant_id"`
+	URL        string `gorm:"column:url" json:"url"`
+	Secret     string `json:"-"`
+	Events     string `json:"events"`
+	State      int    `json:"state"`
+	CreatedBy  string `json:"created_by"`
+	ModifiedBy string `json:"modified_by"`
+}
+
+// WebhookDelivery 一次事件投递，失败后按退避时间重试，NextRetryOn 为下次投递时间
+type WebhookDelivery struct {
+	ID           int    `gorm:"primary_key" json:"id"`
+	WebhookID    int    `json:"webhook_id"`
+	Event        string `json:"event"`
+	Payload      string `json:"payload"`
+	Status       string `json:"status"`
+	Attempts     int    `json:"attempts"`
+	ResponseCode int    `json:"response_code"`
+	ResponseBody string `json:"response_body"`
+	Error        string `json:"error"`
+	NextRetryOn  int    `json:"next_retry_on"`
+	ReplayOf     int    `json:"replay_of"`   // 重放时为原投递记录的 ID
+	Replayed     int    `json:"replayed"`    // 是否已被重放
+	Traceparent  string `json:"traceparent"` // 创建投递记录的请求的 trace，投递时写入请求头
+	CreatedOn    int    `json:"created_on"`
+	ModifiedOn   int    `json:"modified_on"`
+}
+
+func GetWebhooks(ctx context.Context, tenantID int, pageNum int, pageSize int) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := withContext(ctx).Where("tenant_id = ? AND deleted_on = ?", tenantID, 0).Offset(pageNum).Limit(pageSize).Find(&webhooks).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func GetWebhookTotal(ctx context.Context, tenantID int) (int, error) {
+	var count int
+	if err := withContext(ctx).Model(&Webhook{}).Where("tenant_id = ? AND deleted_on = ?", tenantID, 0).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetEnabledWebhooks 获取站点所有启用的订阅
+func GetEnabledWebhooks(ctx context.Context, tenantID int) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := withContext(ctx).Where("tenant_id = ? AND state = ? AND deleted_on = ?", tenantID, 1, 0).Find(&webhooks).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook 获取订阅，不存在时返回 ID 为 0 的订阅
+func GetWebhook(ctx context.Context, id int) (*Webhook, error) {
+	var webhook Webhook
+	err := withContext(ctx).Where("id = ? AND deleted_on = ?", id, 0).First(&webhook).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func AddWebhook(ctx context.Context, webhook *Webhook) error {
+	return withContext(ctx).Create(webhook).Error
+}
+
+func EditWebhook(ctx context.Context, id int, data interface{}) error {
+	return withContext(ctx).Model(&Webhook{}).Where("id = ? AND deleted_on = ?", id, 0).Updates(data).Error
+}
+
+func DeleteWebhook(ctx context.Context, id int) error {
+	return withContext(ctx).Where("id = ?", id).Delete(&Webhook{}).Error
+}
+
+func AddWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	return withContext(ctx).Create(delivery).Error
+}
+
+// GetDueWebhookDeliveries 获取到期待投递的记录，包括租约已过期的投递中记录
+func GetDueWebhookDeliveries(ctx context.Context, now int, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := withContext(ctx).Where("status IN (?) AND next_retry_on <= ?", []string{DELIVERY_PENDING, DELIVERY_SENDING}, now).
+		Order("next_retry_on").Limit(limit).Find(&deliveries).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ClaimWebhookDelivery 将到期的记录标记为投递中，租约到 leaseUntil 为止，返回是否抢到，避免多个实例重复投递
+func ClaimWebhookDelivery(ctx context.Context, id int, now int, leaseUntil int) (bool, error) {
+	res := withContext(ctx).Model(&WebhookDelivery{}).
+		Where("id = ? AND status IN (?) AND next_retry_on <= ?", id, []string{DELIVERY_PENDING, DELIVERY_SENDING}, now).
+		Updates(map[string]interface{}{"status": DELIVERY_SENDING, "next_retry_on": leaseUntil})
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+func EditWebhookDelivery(ctx context.Context, id int, data interface{}) error {
+	return withContext(ctx).Model(&WebhookDelivery{}).Where("id = ?", id).Updates(data).Error
+}
+
+// GetWebhookDelivery 获取投递记录，不存在时返回 ID 为 0 的记录
+func GetWebhookDelivery(ctx context.Context, webhookID, id int) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := withContext(ctx).Where("id = ? AND webhook_id = ?", id, webhookID).First(&delivery).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+func GetWebhookDeliveries(ctx context.Context, pageNum int, pageSize int, maps interface{}) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := withContext(ctx).Where(maps).Order("id DESC").Offset(pageNum).Limit(pageSize).Find(&deliveries).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func GetWebhookDeliveryTotal(ctx context.Context, maps interface{}) (int, error) {
+	var count int
+	if err := withContext(ctx).Model(&WebhookDelivery{}).Where(maps).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
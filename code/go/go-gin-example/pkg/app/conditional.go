@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotModified 按 RFC 7232 判断客户端缓存是否仍然有效，存在 If-None-Match 时忽略 If-Modified-Since，
+// 比较 ETag 时忽略弱校验前缀 W/，lastModified 为零值时不检查 If-Modified-Since
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag = strings.TrimPrefix(etag, "W/")
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// VersionETag 由记录的版本号生成强 ETag
+func VersionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// IfMatch 判断 If-Match 请求头是否与 etag 匹配，未携带该请求头时返回 true。
+// If-Match 只允许强比较，带 W/ 前缀的 ETag 不匹配
+func IfMatch(r *http.Request, etag string) bool {
+	im := r.Header.Get("If-Match")
+	if im == "" {
+		return true
+	}
+
+	for _, tag := range strings.Split(im, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	etag := VersionETag(3)
+	tests := []struct {
+		ifMatch string
+		want    bool
+	}{
+		{"", true},
+		{`"3"`, true},
+		{"*", true},
+		{`"2", "3"`, true},
+		{`"2"`, false},
+		// If-Match 只允许强比较
+		{`W/"3"`, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/", nil)
+		if tt.ifMatch != "" {
+			r.Header.Set("If-Match", tt.ifMatch)
+		}
+		if got := IfMatch(r, etag); got != tt.want {
+			t.Errorf("IfMatch(%q, %s) = %v, want %v", tt.ifMatch, etag, got, tt.want)
+		}
+	}
+}
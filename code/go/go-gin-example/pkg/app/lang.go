@@ -0,0 +1,95 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	enLocale "github.com/go-playground/locales/en"
+	zhLocale "github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	zhTranslations "github.com/go-playground/validator/v10/translations/zh"
+	"golang.org/x/text/language"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+)
+
+var (
+	matcher = language.NewMatcher([]language.Tag{language.Chinese, language.English})
+	uni     = ut.New(zhLocale.New(), zhLocale.New(), enLocale.New())
+)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// 校验信息中的字段名使用请求参数名，而不是结构体字段名
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"form", "uri", "json"} {
+			name := strings.Split(f.Tag.Get(tag), ",")[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return f.Name
+	})
+
+	zh, _ := uni.GetTranslator(e.LANG_ZH)
+	if err := zhTranslations.RegisterDefaultTranslations(v, zh); err != nil {
+		log.Printf("register zh translations err: %v", err)
+	}
+	en, _ := uni.GetTranslator(e.LANG_EN)
+	if err := enTranslations.RegisterDefaultTranslations(v, en); err != nil {
+		log.Printf("register en translations err: %v", err)
+	}
+}
+
+// Lang 获取请求的语言，优先取 lang 查询参数，其次匹配 Accept-Language 请求头
+func Lang(c *gin.Context) string {
+	if lang := c.Query("lang"); lang != "" {
+		return matchLang(lang)
+	}
+	if accept := c.GetHeader("Accept-Language"); accept != "" {
+		return matchLang(accept)
+	}
+
+	return e.DefaultLang
+}
+
+func matchLang(s string) string {
+	tags, _, err := language.ParseAcceptLanguage(s)
+	if err != nil || len(tags) == 0 {
+		return e.DefaultLang
+	}
+
+	tag, _, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return e.DefaultLang
+	}
+	base, _ := tag.Base()
+
+	return base.String()
+}
+
+// TranslateErrors 将参数校验错误翻译为指定语言，返回参数名到错误信息的映射
+func TranslateErrors(err error, lang string) map[string]string {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	trans, _ := uni.GetTranslator(lang)
+	result := make(map[string]string, len(errs))
+	for _, fe := range errs {
+		result[fe.Field()] = fe.Translate(trans)
+	}
+
+	return result
+}
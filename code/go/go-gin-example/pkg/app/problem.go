@@ -0,0 +1,26 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// problemJSON 以 application/problem+json 输出的 render
+type problemJSON struct {
+	Data interface{}
+}
+
+func (r problemJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func (r problemJSON) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+}
@@ -1,13 +1,47 @@
 package app
 
 import (
-	"github.com/astaxie/beego/validation"
+	"errors"
+	"net/http"
 
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
 	"github.com/fzzv/go-gin-example/pkg/logging"
 )
 
-func MarkErrors(errors []*validation.Error) {
-	for _, err := range errors {
-		logging.Info(err.Key, err.Message)
+// BindAndValid 绑定并校验请求参数，路径参数取 uri 标签，查询参数与表单取 form 标签，JSON 请求体取 json 标签，
+// 校验规则统一写在 binding 标签中，OpenAPI 文档也由这些标签生成。
+// 校验失败时返回按请求语言翻译后的错误信息
+func BindAndValid(c *gin.Context, form interface{}) (int, int, map[string]string) {
+	params := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		params[p.Key] = []string{p.Value}
+	}
+	if err := binding.MapFormWithTag(form, params, "uri"); err != nil {
+		logging.Info(err)
+		return http.StatusBadRequest, e.INVALID_PARAMS, nil
+	}
+
+	if err := c.ShouldBind(form); err != nil {
+		MarkErrors(err)
+		return http.StatusBadRequest, e.INVALID_PARAMS, TranslateErrors(err, Lang(c))
+	}
+
+	return http.StatusOK, e.SUCCESS, nil
+}
+
+// MarkErrors 记录参数校验错误
+func MarkErrors(err error) {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		logging.Info(err)
+		return
+	}
+
+	for _, fe := range errs {
+		logging.Info(fe.Field(), fe.Error())
 	}
 }
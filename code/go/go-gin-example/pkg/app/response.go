@@ -1,19 +1,100 @@
 package app
 
 import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/telemetry"
 )
 
+// VERSION_PROBLEM 起始的 API 版本，错误响应使用 RFC 7807 problem+json 格式
+const VERSION_PROBLEM = 2
+
 type Gin struct {
 	C *gin.Context
 }
 
 func (g *Gin) Response(httpCode, errCode int, data interface{}) {
+	if httpCode >= http.StatusBadRequest && Version(g.C) >= VERSION_PROBLEM {
+		g.Problem(httpCode, errCode, data)
+		return
+	}
+
+	defer g.traceRender()()
 	g.C.JSON(httpCode, gin.H{
 		"code": errCode,
-		"msg":  e.GetMsg(errCode),
+		"msg":  e.GetMsgByLang(errCode, Lang(g.C)),
 		"data": data,
 	})
 }
+
+// Problem 以 application/problem+json 格式返回错误，code 作为扩展字段保留。
+// detail 为 data 中的说明或各参数的错误信息，都没有时与 title 相同
+func (g *Gin) Problem(httpCode, errCode int, data interface{}) {
+	defer g.traceRender()()
+	title := e.GetMsgByLang(errCode, Lang(g.C))
+	problem := gin.H{
+		"type":     "urn:go-gin-example:error:" + strconv.Itoa(errCode),
+		"title":    title,
+		"status":   httpCode,
+		"detail":   title,
+		"instance": g.C.Request.URL.Path,
+		"code":     errCode,
+	}
+	switch v := data.(type) {
+	case nil:
+	case string:
+		problem["detail"] = v
+	case map[string]string:
+		// 参数校验错误
+		if len(v) > 0 {
+			problem["errors"] = v
+			problem["detail"] = joinErrors(v)
+		}
+	default:
+		problem["data"] = v
+	}
+
+	g.C.Render(httpCode, problemJSON{Data: problem})
+}
+
+// joinErrors 按参数名排序后拼接各参数的错误信息
+func joinErrors(errs map[string]string) string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + errs[name]
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// traceRender 为编码并写出响应体创建 span，与数据库、Redis 的 span 一起区分请求的耗时，返回的函数结束该 span
+func (g *Gin) traceRender() (end func()) {
+	span := telemetry.StartChild(g.C.Request.Context(), "render json")
+	if span == nil {
+		return func() {}
+	}
+
+	return func() { span.End() }
+}
+
+// Version 获取请求使用的 API 版本，优先取 X-Api-Version 请求头，否则使用配置的默认版本
+func Version(c *gin.Context) int {
+	if v, err := strconv.Atoi(c.GetHeader("X-Api-Version")); err == nil && v > 0 {
+		return v
+	}
+
+	return setting.AppSetting.ApiVersion
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func respond(version string, data interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/articles/1", nil)
+	c.Request.Header.Set("X-Api-Version", version)
+
+	appG := Gin{C: c}
+	appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, data)
+	return w
+}
+
+// TestProblemJSON X-Api-Version 为 2 时错误响应使用 problem+json，为 1 时保持 code / msg / data 格式
+func TestProblemJSON(t *testing.T) {
+	title := e.GetMsg(e.ERROR_NOT_EXIST_ARTICLE)
+	for _, data := range []interface{}{nil, "article 1 is deleted"} {
+		w := respond("2", data)
+		if ct := w.Header().Get("Content-Type"); w.Code != http.StatusNotFound || !strings.HasPrefix(ct, "application/problem+json") {
+			t.Fatalf("v2: %d %s: %s", w.Code, ct, w.Body.String())
+		}
+		var problem struct {
+			Type     string `json:"type"`
+			Title    string `json:"title"`
+			Status   int    `json:"status"`
+			Detail   string `json:"detail"`
+			Instance string `json:"instance"`
+			Code     int    `json:"code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatal(err)
+		}
+		detail := title
+		if s, ok := data.(string); ok {
+			detail = s
+		}
+		if problem.Type != fmt.Sprintf("urn:go-gin-example:error:%d", e.ERROR_NOT_EXIST_ARTICLE) || problem.Title != title ||
+			problem.Status != http.StatusNotFound || problem.Detail != detail ||
+			problem.Instance != "/api/v1/articles/1" || problem.Code != e.ERROR_NOT_EXIST_ARTICLE {
+			t.Fatalf("v2 problem = %+v", problem)
+		}
+	}
+
+	w := respond("1", nil)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("v1 Content-Type = %s", ct)
+	}
+	var body struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != e.ERROR_NOT_EXIST_ARTICLE || body.Msg != title {
+		t.Fatalf("v1 body = %s", w.Body.String())
+	}
+}
+
+// TestProblemErrors 参数校验错误放在 errors 中，detail 为按参数名排序后拼接的错误信息
+func TestProblemErrors(t *testing.T) {
+	w := respond("2", map[string]string{"state": "state 格式不正确", "name": "name 为必填字段"})
+
+	var problem struct {
+		Detail string            `json:"detail"`
+		Errors map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Detail != "name: name 为必填字段; state: state 格式不正确" || len(problem.Errors) != 2 {
+		t.Fatalf("problem = %+v", problem)
+	}
+}
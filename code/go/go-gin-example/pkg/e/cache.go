@@ -1,6 +1,17 @@
 package e
 
 const (
+	CACHE_TENANT = "TENANT"
+
 	CACHE_ARTICLE = "ARTICLE"
 	CACHE_TAG     = "TAG"
+	CACHE_VIEWS   = "VIEWS"
+	CACHE_RELATED = "RELATED"
+
+	CACHE_RATELIMIT = "RATELIMIT"
+	CACHE_LOCKOUT   = "LOCKOUT"
+
+	CACHE_SCHEDULER = "SCHEDULER"
+
+	CACHE_FEED = "FEED"
 )
@@ -5,6 +5,8 @@ const (
 	ERROR          = 500
 	INVALID_PARAMS = 400
 
+	TOO_MANY_REQUESTS = 429
+
 	ERROR_EXIST_TAG       = 10001
 	ERROR_EXIST_TAG_FAIL  = 10002
 	ERROR_NOT_EXIST_TAG   = 10003
@@ -23,10 +25,58 @@ const (
 	ERROR_GET_ARTICLES_FAIL        = 10015
 	ERROR_GET_ARTICLE_FAIL         = 10016
 
+	ERROR_TAG_HAS_ARTICLES          = 10017
+	ERROR_RESTORE_TAG_FAIL          = 10018
+	ERROR_RESTORE_ARTICLE_FAIL      = 10019
+	ERROR_ARTICLE_TAG_DELETED       = 10020
+	ERROR_GET_TRASH_FAIL            = 10021
+	ERROR_GET_FEED_FAIL             = 10022
+	ERROR_EXIST_ARTICLE_SLUG        = 10023
+	ERROR_GET_SITEMAP_FAIL          = 10024
+	ERROR_BULK_PARTIAL              = 10025
+	ERROR_BULK_ROLLED_BACK          = 10026
+	ERROR_BULK_NOT_APPLIED          = 10027
+	ERROR_BULK_FAIL                 = 10028
+	ERROR_PRECONDITION_FAILED       = 10029
+	ERROR_VERSION_CONFLICT          = 10030
+	ERROR_LIKE_ARTICLE_FAIL         = 10031
+	ERROR_UNLIKE_ARTICLE_FAIL       = 10032
+	ERROR_GET_POPULAR_ARTICLES_FAIL = 10033
+	ERROR_GET_RELATED_ARTICLES_FAIL = 10034
+
+	ERROR_NOT_EXIST_SERIES          = 10101
+	ERROR_GET_SERIES_FAIL           = 10102
+	ERROR_COUNT_SERIES_FAIL         = 10103
+	ERROR_ADD_SERIES_FAIL           = 10104
+	ERROR_EDIT_SERIES_FAIL          = 10105
+	ERROR_DELETE_SERIES_FAIL        = 10106
+	ERROR_ARTICLE_IN_OTHER_SERIES   = 10107
+	ERROR_EDIT_SERIES_ARTICLES_FAIL = 10108
+
 	ERROR_AUTH_CHECK_TOKEN_FAIL    = 20001
 	ERROR_AUTH_CHECK_TOKEN_TIMEOUT = 20002
 	ERROR_AUTH_TOKEN               = 20003
 	ERROR_AUTH                     = 20004
+	ERROR_AUTH_LOCKED              = 20005
+	ERROR_AUTH_FORBIDDEN           = 20006
+	ERROR_CSRF_TOKEN               = 20007
+	ERROR_AUTH_CHECK_API_KEY_FAIL  = 20008
+	ERROR_API_KEY_SCOPE            = 20009
+
+	ERROR_NOT_EXIST_API_KEY   = 20101
+	ERROR_GET_API_KEYS_FAIL   = 20102
+	ERROR_ADD_API_KEY_FAIL    = 20103
+	ERROR_DELETE_API_KEY_FAIL = 20104
+
+	ERROR_OIDC_DISABLED      = 20201
+	ERROR_OIDC_STATE         = 20202
+	ERROR_OIDC_PROVIDER      = 20203
+	ERROR_OIDC_ID_TOKEN      = 20204
+	ERROR_OIDC_DENIED        = 20205
+	ERROR_OIDC_NO_ROLE       = 20206
+	ERROR_OIDC_NO_USER       = 20207
+	ERROR_OIDC_USER_MISMATCH = 20208
+	ERROR_OIDC_NOT_BOUND     = 20209
 
 	ERROR_UPLOAD_SAVE_IMAGE_FAIL    = 30001
 	ERROR_UPLOAD_CHECK_IMAGE_FAIL   = 30002
@@ -36,4 +86,36 @@ const (
 	ERROR_IMPORT_TAG_FAIL     = 40002
 	ERROR_EXPORT_ARTICLE_FAIL = 40003
 	ERROR_IMPORT_ARTICLE_FAIL = 40004
+
+	ERROR_NOT_EXIST_JOB     = 50001
+	ERROR_GET_JOBS_FAIL     = 50002
+	ERROR_RUN_JOB_FAIL      = 50003
+	ERROR_JOB_RUNNING       = 50004
+	ERROR_PAUSE_JOB_FAIL    = 50005
+	ERROR_RESUME_JOB_FAIL   = 50006
+	ERROR_GET_JOB_RUNS_FAIL = 50007
+
+	ERROR_GET_AUDITS_FAIL    = 50101
+	ERROR_EXPORT_AUDITS_FAIL = 50102
+
+	ERROR_NOT_EXIST_WEBHOOK           = 60001
+	ERROR_GET_WEBHOOKS_FAIL           = 60002
+	ERROR_ADD_WEBHOOK_FAIL            = 60003
+	ERROR_EDIT_WEBHOOK_FAIL           = 60004
+	ERROR_DELETE_WEBHOOK_FAIL         = 60005
+	ERROR_GET_WEBHOOK_DELIVERIES_FAIL = 60006
+	ERROR_NOT_EXIST_WEBHOOK_DELIVERY  = 60007
+	ERROR_WEBHOOK_DELIVERY_PENDING    = 60008
+	ERROR_REPLAY_WEBHOOK_FAIL         = 60009
+
+	ERROR_NOT_EXIST_TENANT = 70001
+	ERROR_GET_TENANT_FAIL  = 70002
+	ERROR_GET_TENANTS_FAIL = 70003
+	ERROR_EXIST_TENANT     = 70004
+	ERROR_ADD_TENANT_FAIL  = 70005
+	ERROR_EDIT_TENANT_FAIL = 70006
+	ERROR_TENANT_FORBIDDEN = 70007
+
+	ERROR_GRAPHQL_TOO_DEEP    = 80001
+	ERROR_GRAPHQL_TOO_COMPLEX = 80002
 )
@@ -1,43 +1,38 @@
 package e
 
-var MsgFlags = map[int]string{
-	SUCCESS:                         "ok",
-	ERROR:                           "fail",
-	INVALID_PARAMS:                  "请求参数错误",
-	ERROR_EXIST_TAG:                 "已存在该标签名称",
-	ERROR_EXIST_TAG_FAIL:            "获取已存在标签失败",
-	ERROR_NOT_EXIST_TAG:             "该标签不存在",
-	ERROR_GET_TAGS_FAIL:             "获取所有标签失败",
-	ERROR_COUNT_TAG_FAIL:            "统计标签失败",
-	ERROR_ADD_TAG_FAIL:              "新增标签失败",
-	ERROR_EDIT_TAG_FAIL:             "修改标签失败",
-	ERROR_DELETE_TAG_FAIL:           "删除标签失败",
-	ERROR_NOT_EXIST_ARTICLE:         "该文章不存在",
-	ERROR_ADD_ARTICLE_FAIL:          "新增文章失败",
-	ERROR_DELETE_ARTICLE_FAIL:       "删除文章失败",
-	ERROR_CHECK_EXIST_ARTICLE_FAIL:  "检查文章是否存在失败",
-	ERROR_EDIT_ARTICLE_FAIL:         "修改文章失败",
-	ERROR_COUNT_ARTICLE_FAIL:        "统计文章失败",
-	ERROR_GET_ARTICLES_FAIL:         "获取多个文章失败",
-	ERROR_GET_ARTICLE_FAIL:          "获取单个文章失败",
-	ERROR_AUTH_CHECK_TOKEN_FAIL:     "Token鉴权失败",
-	ERROR_AUTH_CHECK_TOKEN_TIMEOUT:  "Token已超时",
-	ERROR_AUTH_TOKEN:                "Token生成失败",
-	ERROR_AUTH:                      "Token错误",
-	ERROR_UPLOAD_SAVE_IMAGE_FAIL:    "保存图片失败",
-	ERROR_UPLOAD_CHECK_IMAGE_FAIL:   "检查图片失败",
-	ERROR_UPLOAD_CHECK_IMAGE_FORMAT: "校验图片错误，图片格式或大小有问题",
-	ERROR_EXPORT_TAG_FAIL:           "导出标签失败",
-	ERROR_IMPORT_TAG_FAIL:           "导入标签失败",
-	ERROR_EXPORT_ARTICLE_FAIL:       "导出文章失败",
-	ERROR_IMPORT_ARTICLE_FAIL:       "导入文章失败",
+const (
+	LANG_ZH = "zh"
+	LANG_EN = "en"
+)
+
+// DefaultLang 请求未指定语言时使用的语言
+var DefaultLang = LANG_ZH
+
+// Langs 支持的语言，顺序即匹配 Accept-Language 时的优先级
+var Langs = []string{LANG_ZH, LANG_EN}
+
+// Catalogs 各语言的错误信息，每个 code 在所有语言中都必须有对应的翻译
+var Catalogs = map[string]map[int]string{
+	LANG_ZH: msgZh,
+	LANG_EN: msgEn,
 }
 
+// GetMsg 获取默认语言的错误信息
 func GetMsg(code int) string {
-	msg, ok := MsgFlags[code]
+	return GetMsgByLang(code, DefaultLang)
+}
+
+// GetMsgByLang 获取指定语言的错误信息，语言或 code 不存在时回退到默认语言及 ERROR 的信息
+func GetMsgByLang(code int, lang string) string {
+	catalog, ok := Catalogs[lang]
+	if !ok {
+		catalog = Catalogs[DefaultLang]
+	}
+
+	msg, ok := catalog[code]
 	if ok {
 		return msg
 	}
 
-	return MsgFlags[ERROR]
+	return catalog[ERROR]
 }
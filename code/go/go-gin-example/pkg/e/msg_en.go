@@ -0,0 +1,106 @@
+package e
+
+var msgEn = map[int]string{
+	SUCCESS:                           "ok",
+	ERROR:                             "fail",
+	INVALID_PARAMS:                    "Invalid request parameters",
+	TOO_MANY_REQUESTS:                 "Too many requests, please try again later",
+	ERROR_EXIST_TAG:                   "A tag with this name already exists",
+	ERROR_EXIST_TAG_FAIL:              "Failed to check whether the tag exists",
+	ERROR_NOT_EXIST_TAG:               "The tag does not exist",
+	ERROR_GET_TAGS_FAIL:               "Failed to get tags",
+	ERROR_COUNT_TAG_FAIL:              "Failed to count tags",
+	ERROR_ADD_TAG_FAIL:                "Failed to add tag",
+	ERROR_EDIT_TAG_FAIL:               "Failed to edit tag",
+	ERROR_DELETE_TAG_FAIL:             "Failed to delete tag",
+	ERROR_NOT_EXIST_ARTICLE:           "The article does not exist",
+	ERROR_ADD_ARTICLE_FAIL:            "Failed to add article",
+	ERROR_DELETE_ARTICLE_FAIL:         "Failed to delete article",
+	ERROR_CHECK_EXIST_ARTICLE_FAIL:    "Failed to check whether the article exists",
+	ERROR_EDIT_ARTICLE_FAIL:           "Failed to edit article",
+	ERROR_COUNT_ARTICLE_FAIL:          "Failed to count articles",
+	ERROR_GET_ARTICLES_FAIL:           "Failed to get articles",
+	ERROR_GET_ARTICLE_FAIL:            "Failed to get article",
+	ERROR_TAG_HAS_ARTICLES:            "The tag still has articles, delete them first or use cascade",
+	ERROR_RESTORE_TAG_FAIL:            "Failed to restore tag",
+	ERROR_RESTORE_ARTICLE_FAIL:        "Failed to restore article",
+	ERROR_ARTICLE_TAG_DELETED:         "The tag of this article has been deleted, restore the tag first",
+	ERROR_GET_TRASH_FAIL:              "Failed to get trash",
+	ERROR_GET_FEED_FAIL:               "Failed to get feed",
+	ERROR_EXIST_ARTICLE_SLUG:          "The slug is already used by another article",
+	ERROR_GET_SITEMAP_FAIL:            "Failed to generate sitemap",
+	ERROR_BULK_PARTIAL:                "Some operations failed",
+	ERROR_BULK_ROLLED_BACK:            "Some operations failed, all operations were rolled back",
+	ERROR_BULK_NOT_APPLIED:            "The operation was rolled back because another operation failed",
+	ERROR_BULK_FAIL:                   "Bulk operation failed",
+	ERROR_PRECONDITION_FAILED:         "The record has been modified, fetch the latest version and retry",
+	ERROR_VERSION_CONFLICT:            "The record was modified by another request, fetch the latest version and retry",
+	ERROR_LIKE_ARTICLE_FAIL:           "Failed to like the article",
+	ERROR_UNLIKE_ARTICLE_FAIL:         "Failed to unlike the article",
+	ERROR_GET_POPULAR_ARTICLES_FAIL:   "Failed to get popular articles",
+	ERROR_GET_RELATED_ARTICLES_FAIL:   "Failed to get related articles",
+	ERROR_NOT_EXIST_SERIES:            "The series does not exist",
+	ERROR_GET_SERIES_FAIL:             "Failed to get series",
+	ERROR_COUNT_SERIES_FAIL:           "Failed to count series",
+	ERROR_ADD_SERIES_FAIL:             "Failed to create the series",
+	ERROR_EDIT_SERIES_FAIL:            "Failed to update the series",
+	ERROR_DELETE_SERIES_FAIL:          "Failed to delete the series",
+	ERROR_ARTICLE_IN_OTHER_SERIES:     "The article already belongs to another series",
+	ERROR_EDIT_SERIES_ARTICLES_FAIL:   "Failed to update the articles of the series",
+	ERROR_AUTH_CHECK_TOKEN_FAIL:       "Token authentication failed",
+	ERROR_AUTH_CHECK_TOKEN_TIMEOUT:    "Token has expired",
+	ERROR_AUTH_TOKEN:                  "Failed to generate token",
+	ERROR_AUTH:                        "Invalid username or password",
+	ERROR_AUTH_LOCKED:                 "Too many failed logins, the account is temporarily locked",
+	ERROR_AUTH_FORBIDDEN:              "Permission denied",
+	ERROR_CSRF_TOKEN:                  "Invalid CSRF token",
+	ERROR_AUTH_CHECK_API_KEY_FAIL:     "API key is invalid, revoked or expired",
+	ERROR_API_KEY_SCOPE:               "API key scope does not allow this request",
+	ERROR_NOT_EXIST_API_KEY:           "API key does not exist",
+	ERROR_GET_API_KEYS_FAIL:           "Failed to get API keys",
+	ERROR_ADD_API_KEY_FAIL:            "Failed to create API key",
+	ERROR_DELETE_API_KEY_FAIL:         "Failed to revoke API key",
+	ERROR_OIDC_DISABLED:               "OIDC login is not enabled",
+	ERROR_OIDC_STATE:                  "OIDC login state is invalid or expired, please sign in again",
+	ERROR_OIDC_PROVIDER:               "Failed to reach the identity provider",
+	ERROR_OIDC_ID_TOKEN:               "Invalid ID token from the identity provider",
+	ERROR_OIDC_DENIED:                 "The identity provider denied the login",
+	ERROR_OIDC_NO_ROLE:                "This account has no role for the blog",
+	ERROR_OIDC_NO_USER:                "This account does not exist on this site",
+	ERROR_OIDC_USER_MISMATCH:          "This username is bound to another identity provider account",
+	ERROR_OIDC_NOT_BOUND:              "This username is not bound to an identity provider account yet, ask an administrator to bind it",
+	ERROR_UPLOAD_SAVE_IMAGE_FAIL:      "Failed to save image",
+	ERROR_UPLOAD_CHECK_IMAGE_FAIL:     "Failed to check image",
+	ERROR_UPLOAD_CHECK_IMAGE_FORMAT:   "Invalid image, check its format and size",
+	ERROR_EXPORT_TAG_FAIL:             "Failed to export tags",
+	ERROR_IMPORT_TAG_FAIL:             "Failed to import tags",
+	ERROR_EXPORT_ARTICLE_FAIL:         "Failed to export articles",
+	ERROR_IMPORT_ARTICLE_FAIL:         "Failed to import articles",
+	ERROR_NOT_EXIST_JOB:               "The job does not exist",
+	ERROR_GET_JOBS_FAIL:               "Failed to get jobs",
+	ERROR_RUN_JOB_FAIL:                "Failed to run job",
+	ERROR_JOB_RUNNING:                 "The job is already running",
+	ERROR_PAUSE_JOB_FAIL:              "Failed to pause job",
+	ERROR_RESUME_JOB_FAIL:             "Failed to resume job",
+	ERROR_GET_JOB_RUNS_FAIL:           "Failed to get job runs",
+	ERROR_GET_AUDITS_FAIL:             "Failed to get audit logs",
+	ERROR_EXPORT_AUDITS_FAIL:          "Failed to export audit logs",
+	ERROR_NOT_EXIST_WEBHOOK:           "The webhook does not exist",
+	ERROR_GET_WEBHOOKS_FAIL:           "Failed to get webhooks",
+	ERROR_ADD_WEBHOOK_FAIL:            "Failed to add webhook",
+	ERROR_EDIT_WEBHOOK_FAIL:           "Failed to edit webhook",
+	ERROR_DELETE_WEBHOOK_FAIL:         "Failed to delete webhook",
+	ERROR_GET_WEBHOOK_DELIVERIES_FAIL: "Failed to get webhook deliveries",
+	ERROR_NOT_EXIST_WEBHOOK_DELIVERY:  "The delivery does not exist",
+	ERROR_WEBHOOK_DELIVERY_PENDING:    "The delivery has not finished yet and cannot be replayed",
+	ERROR_REPLAY_WEBHOOK_FAIL:         "Failed to replay delivery",
+	ERROR_NOT_EXIST_TENANT:            "The site does not exist",
+	ERROR_GET_TENANT_FAIL:             "Failed to get the site",
+	ERROR_GET_TENANTS_FAIL:            "Failed to get sites",
+	ERROR_EXIST_TENANT:                "A site with this name or host already exists",
+	ERROR_ADD_TENANT_FAIL:             "Failed to create the site",
+	ERROR_EDIT_TENANT_FAIL:            "Failed to update the site",
+	ERROR_TENANT_FORBIDDEN:            "This operation is only allowed on the default site",
+	ERROR_GRAPHQL_TOO_DEEP:            "The query is nested too deeply",
+	ERROR_GRAPHQL_TOO_COMPLEX:         "The query is too complex",
+}
@@ -0,0 +1,64 @@
+package e
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+)
+
+// codes 解析 code.go，返回其中声明的所有错误码
+func codes(t *testing.T) map[string]int {
+	f, err := parser.ParseFile(token.NewFileSet(), "code.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[string]int)
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok {
+					t.Fatalf("%s: 错误码必须是整数字面量", name.Name)
+				}
+				code, err := strconv.Atoi(lit.Value)
+				if err != nil {
+					t.Fatalf("%s: %v", name.Name, err)
+				}
+				result[name.Name] = code
+			}
+		}
+	}
+
+	return result
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	all := codes(t)
+	if len(all) == 0 {
+		t.Fatal("code.go 中没有错误码")
+	}
+
+	for _, lang := range Langs {
+		catalog, ok := Catalogs[lang]
+		if !ok {
+			t.Errorf("缺少语言 %s 的错误信息", lang)
+			continue
+		}
+		for name, code := range all {
+			if msg := catalog[code]; msg == "" {
+				t.Errorf("%s(%d) 缺少 %s 翻译", name, code, lang)
+			}
+		}
+		if len(catalog) != len(all) {
+			t.Errorf("语言 %s 的错误信息数量为 %d，错误码数量为 %d", lang, len(catalog), len(all))
+		}
+	}
+}
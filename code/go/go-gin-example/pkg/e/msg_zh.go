@@ -0,0 +1,106 @@
+package e
+
+var msgZh = map[int]string{
+	SUCCESS:                           "ok",
+	ERROR:                             "fail",
+	INVALID_PARAMS:                    "请求参数错误",
+	TOO_MANY_REQUESTS:                 "请求过于频繁，请稍后再试",
+	ERROR_EXIST_TAG:                   "已存在该标签名称",
+	ERROR_EXIST_TAG_FAIL:              "获取已存在标签失败",
+	ERROR_NOT_EXIST_TAG:               "该标签不存在",
+	ERROR_GET_TAGS_FAIL:               "获取所有标签失败",
+	ERROR_COUNT_TAG_FAIL:              "统计标签失败",
+	ERROR_ADD_TAG_FAIL:                "新增标签失败",
+	ERROR_EDIT_TAG_FAIL:               "修改标签失败",
+	ERROR_DELETE_TAG_FAIL:             "删除标签失败",
+	ERROR_NOT_EXIST_ARTICLE:           "该文章不存在",
+	ERROR_ADD_ARTICLE_FAIL:            "新增文章失败",
+	ERROR_DELETE_ARTICLE_FAIL:         "删除文章失败",
+	ERROR_CHECK_EXIST_ARTICLE_FAIL:    "检查文章是否存在失败",
+	ERROR_EDIT_ARTICLE_FAIL:           "修改文章失败",
+	ERROR_COUNT_ARTICLE_FAIL:          "统计文章失败",
+	ERROR_GET_ARTICLES_FAIL:           "获取多个文章失败",
+	ERROR_GET_ARTICLE_FAIL:            "获取单个文章失败",
+	ERROR_TAG_HAS_ARTICLES:            "该标签下还有文章，请先删除文章或使用 cascade 一并删除",
+	ERROR_RESTORE_TAG_FAIL:            "恢复标签失败",
+	ERROR_RESTORE_ARTICLE_FAIL:        "恢复文章失败",
+	ERROR_ARTICLE_TAG_DELETED:         "文章所属的标签已被删除，请先恢复标签",
+	ERROR_GET_TRASH_FAIL:              "获取回收站失败",
+	ERROR_GET_FEED_FAIL:               "获取订阅源失败",
+	ERROR_EXIST_ARTICLE_SLUG:          "该 slug 已被其他文章使用",
+	ERROR_GET_SITEMAP_FAIL:            "生成站点地图失败",
+	ERROR_BULK_PARTIAL:                "部分操作失败",
+	ERROR_BULK_ROLLED_BACK:            "存在失败的操作，所有操作均已回滚",
+	ERROR_BULK_NOT_APPLIED:            "因其他操作失败，该操作已回滚",
+	ERROR_BULK_FAIL:                   "批量操作失败",
+	ERROR_PRECONDITION_FAILED:         "记录已被修改，请获取最新版本后重试",
+	ERROR_VERSION_CONFLICT:            "记录在修改过程中被其他请求修改，请获取最新版本后重试",
+	ERROR_LIKE_ARTICLE_FAIL:           "点赞文章失败",
+	ERROR_UNLIKE_ARTICLE_FAIL:         "取消点赞失败",
+	ERROR_GET_POPULAR_ARTICLES_FAIL:   "获取热门文章失败",
+	ERROR_GET_RELATED_ARTICLES_FAIL:   "获取相关文章失败",
+	ERROR_NOT_EXIST_SERIES:            "系列不存在",
+	ERROR_GET_SERIES_FAIL:             "获取系列失败",
+	ERROR_COUNT_SERIES_FAIL:           "统计系列失败",
+	ERROR_ADD_SERIES_FAIL:             "新建系列失败",
+	ERROR_EDIT_SERIES_FAIL:            "修改系列失败",
+	ERROR_DELETE_SERIES_FAIL:          "删除系列失败",
+	ERROR_ARTICLE_IN_OTHER_SERIES:     "文章已属于其他系列",
+	ERROR_EDIT_SERIES_ARTICLES_FAIL:   "修改系列中的文章失败",
+	ERROR_AUTH_CHECK_TOKEN_FAIL:       "Token鉴权失败",
+	ERROR_AUTH_CHECK_TOKEN_TIMEOUT:    "Token已超时",
+	ERROR_AUTH_TOKEN:                  "Token生成失败",
+	ERROR_AUTH:                        "Token错误",
+	ERROR_AUTH_LOCKED:                 "登录失败次数过多，账号已被临时锁定",
+	ERROR_AUTH_FORBIDDEN:              "没有权限访问",
+	ERROR_CSRF_TOKEN:                  "CSRF token 无效",
+	ERROR_AUTH_CHECK_API_KEY_FAIL:     "API Key 无效、已吊销或已过期",
+	ERROR_API_KEY_SCOPE:               "API Key 的权限范围不足",
+	ERROR_NOT_EXIST_API_KEY:           "该 API Key 不存在",
+	ERROR_GET_API_KEYS_FAIL:           "获取 API Key 失败",
+	ERROR_ADD_API_KEY_FAIL:            "新建 API Key 失败",
+	ERROR_DELETE_API_KEY_FAIL:         "吊销 API Key 失败",
+	ERROR_OIDC_DISABLED:               "未启用 OIDC 登录",
+	ERROR_OIDC_STATE:                  "OIDC 登录状态无效或已过期，请重新登录",
+	ERROR_OIDC_PROVIDER:               "无法连接身份提供方",
+	ERROR_OIDC_ID_TOKEN:               "身份提供方返回的 ID Token 无效",
+	ERROR_OIDC_DENIED:                 "身份提供方拒绝了登录",
+	ERROR_OIDC_NO_ROLE:                "该账号没有访问博客的角色",
+	ERROR_OIDC_NO_USER:                "该账号在本站不存在",
+	ERROR_OIDC_USER_MISMATCH:          "该用户名已绑定其他身份提供方账号",
+	ERROR_OIDC_NOT_BOUND:              "该用户名尚未绑定身份提供方账号，请联系管理员绑定",
+	ERROR_UPLOAD_SAVE_IMAGE_FAIL:      "保存图片失败",
+	ERROR_UPLOAD_CHECK_IMAGE_FAIL:     "检查图片失败",
+	ERROR_UPLOAD_CHECK_IMAGE_FORMAT:   "校验图片错误，图片格式或大小有问题",
+	ERROR_EXPORT_TAG_FAIL:             "导出标签失败",
+	ERROR_IMPORT_TAG_FAIL:             "导入标签失败",
+	ERROR_EXPORT_ARTICLE_FAIL:         "导出文章失败",
+	ERROR_IMPORT_ARTICLE_FAIL:         "导入文章失败",
+	ERROR_NOT_EXIST_JOB:               "该定时任务不存在",
+	ERROR_GET_JOBS_FAIL:               "获取定时任务失败",
+	ERROR_RUN_JOB_FAIL:                "执行定时任务失败",
+	ERROR_JOB_RUNNING:                 "该定时任务正在执行",
+	ERROR_PAUSE_JOB_FAIL:              "暂停定时任务失败",
+	ERROR_RESUME_JOB_FAIL:             "恢复定时任务失败",
+	ERROR_GET_JOB_RUNS_FAIL:           "获取定时任务执行记录失败",
+	ERROR_GET_AUDITS_FAIL:             "获取审计日志失败",
+	ERROR_EXPORT_AUDITS_FAIL:          "导出审计日志失败",
+	ERROR_NOT_EXIST_WEBHOOK:           "该 webhook 不存在",
+	ERROR_GET_WEBHOOKS_FAIL:           "获取 webhook 失败",
+	ERROR_ADD_WEBHOOK_FAIL:            "新建 webhook 失败",
+	ERROR_EDIT_WEBHOOK_FAIL:           "修改 webhook 失败",
+	ERROR_DELETE_WEBHOOK_FAIL:         "删除 webhook 失败",
+	ERROR_GET_WEBHOOK_DELIVERIES_FAIL: "获取投递记录失败",
+	ERROR_NOT_EXIST_WEBHOOK_DELIVERY:  "该投递记录不存在",
+	ERROR_WEBHOOK_DELIVERY_PENDING:    "该投递尚未完成，不能重放",
+	ERROR_REPLAY_WEBHOOK_FAIL:         "重放投递失败",
+	ERROR_NOT_EXIST_TENANT:            "该站点不存在",
+	ERROR_GET_TENANT_FAIL:             "获取站点失败",
+	ERROR_GET_TENANTS_FAIL:            "获取站点列表失败",
+	ERROR_EXIST_TENANT:                "已存在相同名称或域名的站点",
+	ERROR_ADD_TENANT_FAIL:             "新建站点失败",
+	ERROR_EDIT_TENANT_FAIL:            "修改站点失败",
+	ERROR_TENANT_FORBIDDEN:            "只能在默认站点下执行该操作",
+	ERROR_GRAPHQL_TOO_DEEP:            "查询的嵌套层数超过限制",
+	ERROR_GRAPHQL_TOO_COMPLEX:         "查询的复杂度超过限制",
+}
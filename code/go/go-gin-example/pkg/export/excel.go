@@ -1,6 +1,11 @@
 package export
 
-import "github.com/fzzv/go-gin-example/pkg/setting"
+import (
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// CONTENT_TYPE 导出的 Excel 文件的类型
+const CONTENT_TYPE = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 
 func GetExcelFullUrl(name string) string {
 	return setting.AppSetting.PrefixUrl + "/" + GetExcelPath() + name
@@ -0,0 +1,138 @@
+package gredis
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache 查询结果的缓存，值以 JSON 保存，Get 在 key 不存在时返回 nil
+type Cache interface {
+	Set(ctx context.Context, key string, data interface{}, expireSeconds int) error
+	Exists(ctx context.Context, key string) bool
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) (bool, error)
+	// LikeDeletes 删除包含 pattern 的所有 key
+	LikeDeletes(ctx context.Context, pattern string) error
+}
+
+// NewCache 返回基于 Setup 所建立的 Redis 连接的 Cache
+func NewCache() Cache {
+	return redisCache{}
+}
+
+type redisCache struct{}
+
+func (redisCache) Set(ctx context.Context, key string, data interface{}, expireSeconds int) error {
+	return Set(ctx, key, data, expireSeconds)
+}
+
+func (redisCache) Exists(ctx context.Context, key string) bool {
+	return Exists(ctx, key)
+}
+
+func (redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return Get(ctx, key)
+}
+
+func (redisCache) Delete(ctx context.Context, key string) (bool, error) {
+	return Delete(ctx, key)
+}
+
+func (redisCache) LikeDeletes(ctx context.Context, pattern string) error {
+	return LikeDeletes(ctx, pattern)
+}
+
+// MemoryCache 进程内的 Cache，用于测试或无需 Redis 的单实例场景
+type MemoryCache struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	value   []byte
+	expires time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryItem)}
+}
+
+func (m *MemoryCache) Set(_ context.Context, key string, data interface{}, expireSeconds int) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item := memoryItem{value: value}
+	if expireSeconds > 0 {
+		item.expires = time.Now().Add(time.Duration(expireSeconds) * time.Second)
+	}
+	m.items[key] = item
+	return nil
+}
+
+func (m *MemoryCache) Exists(_ context.Context, key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.get(key)
+	return ok
+}
+
+func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, _ := m.get(key)
+	return item.value, nil
+}
+
+func (m *MemoryCache) Delete(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.get(key)
+	delete(m.items, key)
+	return ok, nil
+}
+
+func (m *MemoryCache) LikeDeletes(_ context.Context, pattern string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.items {
+		if strings.Contains(key, pattern) {
+			delete(m.items, key)
+		}
+	}
+	return nil
+}
+
+// Keys 返回所有未过期的 key，便于测试检查缓存的内容
+func (m *MemoryCache) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []string
+	for key := range m.items {
+		if _, ok := m.get(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (m *MemoryCache) get(key string) (memoryItem, bool) {
+	item, ok := m.items[key]
+	if ok && !item.expires.IsZero() && time.Now().After(item.expires) {
+		delete(m.items, key)
+		return memoryItem{}, false
+	}
+	return item, ok
+}
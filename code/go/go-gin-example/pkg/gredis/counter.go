@@ -0,0 +1,76 @@
+package gredis
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// Counter 按 field 累加的计数器，用于缓冲浏览数等写入频繁的计数，由定时任务定期取出写入数据库
+type Counter interface {
+	Incr(ctx context.Context, key, field string, n int64) error
+	// Take 取出 key 下所有 field 的计数并清零
+	Take(ctx context.Context, key string) (map[string]int64, error)
+}
+
+// NewCounter 返回基于 Setup 所建立的 Redis 连接的 Counter，多个实例共享计数
+func NewCounter() Counter {
+	return redisCounter{}
+}
+
+type redisCounter struct{}
+
+func (redisCounter) Incr(ctx context.Context, key, field string, n int64) error {
+	_, err := HIncrBy(ctx, key, field, n)
+	return err
+}
+
+func (redisCounter) Take(ctx context.Context, key string) (map[string]int64, error) {
+	values, err := HTakeAll(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(values))
+	for field, value := range values {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		counts[field] = n
+	}
+	return counts, nil
+}
+
+// MemoryCounter 进程内的 Counter，用于测试或无需 Redis 的单实例场景
+type MemoryCounter struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int64
+}
+
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{counts: make(map[string]map[string]int64)}
+}
+
+func (m *MemoryCounter) Incr(_ context.Context, key, field string, n int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.counts[key] == nil {
+		m.counts[key] = make(map[string]int64)
+	}
+	m.counts[key][field] += n
+	return nil
+}
+
+func (m *MemoryCounter) Take(_ context.Context, key string) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := m.counts[key]
+	delete(m.counts, key)
+	if counts == nil {
+		counts = make(map[string]int64)
+	}
+	return counts, nil
+}
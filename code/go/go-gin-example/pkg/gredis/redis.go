@@ -11,10 +11,7 @@ import (
 	"github.com/fzzv/go-gin-example/pkg/setting"
 )
 
-var (
-	rdb *redis.Client
-	ctx = context.Background()
-)
+var rdb *redis.Client
 
 // Setup 初始化 Redis 客户端
 func Setup() error {
@@ -25,9 +22,10 @@ func Setup() error {
 		PoolSize:     setting.RedisSetting.MaxActive,
 		MinIdleConns: setting.RedisSetting.MaxIdle,
 	})
+	rdb.AddHook(tracingHook{})
 
 	// 测试连接
-	result, err := rdb.Ping(ctx).Result()
+	result, err := rdb.Ping(context.Background()).Result()
 	if err != nil {
 		return err
 	}
@@ -36,7 +34,7 @@ func Setup() error {
 }
 
 // Set 设置 key 并指定过期时间（秒）
-func Set(key string, data interface{}, expireSeconds int) error {
+func Set(ctx context.Context, key string, data interface{}, expireSeconds int) error {
 	value, err := json.Marshal(data)
 	if err != nil {
 		return err
@@ -46,7 +44,7 @@ func Set(key string, data interface{}, expireSeconds int) error {
 }
 
 // Exists 判断 key 是否存在
-func Exists(key string) bool {
+func Exists(ctx context.Context, key string) bool {
 	ok, err := rdb.Exists(ctx, key).Result()
 	if err != nil {
 		return false
@@ -55,7 +53,7 @@ func Exists(key string) bool {
 }
 
 // Get 获取 key
-func Get(key string) ([]byte, error) {
+func Get(ctx context.Context, key string) ([]byte, error) {
 	val, err := rdb.Get(ctx, key).Bytes()
 	if err == redis.Nil {
 		return nil, nil // key 不存在
@@ -64,13 +62,13 @@ func Get(key string) ([]byte, error) {
 }
 
 // Delete 删除 key
-func Delete(key string) (bool, error) {
+func Delete(ctx context.Context, key string) (bool, error) {
 	deleted, err := rdb.Del(ctx, key).Result()
 	return deleted > 0, err
 }
 
 // LikeDeletes 按模式删除（模糊匹配）
-func LikeDeletes(pattern string) error {
+func LikeDeletes(ctx context.Context, pattern string) error {
 	iter := rdb.Scan(ctx, 0, "*"+pattern+"*", 0).Iterator()
 	for iter.Next(ctx) {
 		key := iter.Val()
@@ -84,3 +82,56 @@ func LikeDeletes(pattern string) error {
 	}
 	return nil
 }
+
+// Incr 计数器加一，首次创建时设置过期时间
+func Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	n, err := rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		err = rdb.Expire(ctx, key, expire).Err()
+	}
+	return n, err
+}
+
+// TTL 获取 key 的剩余过期时间，key 不存在或未设置过期时间时返回 0
+func TTL(ctx context.Context, key string) (time.Duration, error) {
+	d, err := rdb.PTTL(ctx, key).Result()
+	if err != nil || d < 0 {
+		return 0, err
+	}
+	return d, nil
+}
+
+// Eval 执行 Lua 脚本
+func Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return rdb.Eval(ctx, script, keys, args...).Result()
+}
+
+// HIncrBy 哈希 key 中 field 的值加 n
+func HIncrBy(ctx context.Context, key, field string, n int64) (int64, error) {
+	return rdb.HIncrBy(ctx, key, field, n).Result()
+}
+
+// takeScript 读取并删除整个哈希，两步在一个脚本中执行，期间的 HIncrBy 不会丢失
+const takeScript = `
+local values = redis.call('HGETALL', KEYS[1])
+redis.call('DEL', KEYS[1])
+return values
+`
+
+// HTakeAll 取出哈希 key 中的所有 field 并删除 key，key 不存在时返回空的 map
+func HTakeAll(ctx context.Context, key string) (map[string]string, error) {
+	res, err := Eval(ctx, takeScript, []string{key})
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := res.([]interface{})
+	values := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		values[fmt.Sprint(items[i])] = fmt.Sprint(items[i+1])
+	}
+	return values, nil
+}
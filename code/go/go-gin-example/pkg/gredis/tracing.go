@@ -0,0 +1,63 @@
+package gredis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fzzv/go-gin-example/pkg/telemetry"
+)
+
+// tracingHook 为每条命令创建请求 span 的子 span，命令的参数可能包含缓存的内容，不写入 span
+type tracingHook struct{}
+
+func (tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		span := startSpan(ctx, cmd.FullName(), attribute.Int("db.operation.batch.size", 1))
+		err := next(ctx, cmd)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func (tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			names[i] = cmd.FullName()
+		}
+		span := startSpan(ctx, "pipeline "+strings.Join(names, " "), attribute.Int("db.operation.batch.size", len(cmds)))
+		err := next(ctx, cmds)
+		endSpan(span, err)
+		return err
+	}
+}
+
+// startSpan ctx 中没有请求 span 时不创建 span
+func startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) trace.Span {
+	return telemetry.StartChild(ctx, "redis "+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(append(attrs,
+		semconv.DBSystemNameRedis,
+		semconv.DBOperationName(operation),
+	)...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	// 键不存在不是错误
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -16,7 +16,8 @@ var (
 	DefaultPrefix      = ""
 	DefaultCallerDepth = 2
 
-	logger     *log.Logger
+	// Setup 之前输出到标准错误，单独测试各个包时无需创建日志文件
+	logger     = log.New(os.Stderr, DefaultPrefix, log.LstdFlags)
 	logPrefix  = ""
 	levelFlags = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
 )
@@ -0,0 +1,204 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Operation 描述一个接口，请求参数的定义与校验规则都来自 Request 结构体的标签
+type Operation struct {
+	Method  string
+	Path    string // gin 风格的路径，如 /api/v1/tags/:id
+	Summary string
+	Tag     string
+	Auth    bool        // 是否需要 token 鉴权
+	Request interface{} // 请求结构体，uri 标签为路径参数，form 标签为查询参数或请求体
+}
+
+var (
+	mu         sync.Mutex
+	operations []Operation
+	doc        *openapi3.T
+
+	ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+	// 所有接口统一的 code / msg / data 响应结构
+	responseSchema = openapi3.NewObjectSchema().
+			WithProperty("code", openapi3.NewIntegerSchema()).
+			WithProperty("msg", openapi3.NewStringSchema()).
+			WithPropertyRef("data", openapi3.NewSchemaRef("", &openapi3.Schema{Nullable: true}))
+
+	// RFC 7807 错误响应结构，code 为扩展字段
+	problemSchema = openapi3.NewObjectSchema().
+			WithProperty("type", openapi3.NewStringSchema()).
+			WithProperty("title", openapi3.NewStringSchema()).
+			WithProperty("status", openapi3.NewIntegerSchema()).
+			WithProperty("detail", openapi3.NewStringSchema()).
+			WithProperty("instance", openapi3.NewStringSchema()).
+			WithProperty("code", openapi3.NewIntegerSchema()).
+			WithProperty("errors", openapi3.NewObjectSchema().WithAdditionalProperties(openapi3.NewStringSchema()))
+
+	// 通过 X-Api-Version 请求头选择 API 版本
+	versionParameter = openapi3.NewHeaderParameter("X-Api-Version").
+				WithDescription("API 版本，2 及以上的错误响应使用 application/problem+json").
+				WithSchema(openapi3.NewIntegerSchema().WithMin(1))
+
+	// 错误信息的语言，优先于 Accept-Language 请求头
+	langParameter = openapi3.NewQueryParameter("lang").
+			WithDescription("错误信息的语言，未指定时按 Accept-Language 请求头选择").
+			WithSchema(openapi3.NewStringSchema())
+)
+
+// Register 注册接口，通常在 handler 所在文件的 init 中调用
+func Register(ops ...Operation) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	operations = append(operations, ops...)
+	doc = nil
+}
+
+// Lookup 按请求方法与 gin 风格的路径查找已注册的接口
+func Lookup(method, path string) (Operation, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, op := range operations {
+		if op.Method == method && op.Path == path {
+			return op, true
+		}
+	}
+
+	return Operation{}, false
+}
+
+// Document 返回由所有已注册接口生成的 OpenAPI 3 文档
+func Document() *openapi3.T {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if doc == nil {
+		doc = build(operations)
+	}
+
+	return doc
+}
+
+func build(ops []Operation) *openapi3.T {
+	t := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:   "go-gin-example",
+			Version: "1.0",
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{
+				"Response": openapi3.NewSchemaRef("", responseSchema),
+				"Problem":  openapi3.NewSchemaRef("", problemSchema),
+			},
+			Parameters: openapi3.ParametersMap{
+				"ApiVersion": &openapi3.ParameterRef{Value: versionParameter},
+				"Lang":       &openapi3.ParameterRef{Value: langParameter},
+			},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"token": &openapi3.SecuritySchemeRef{
+					Value: openapi3.NewSecurityScheme().WithType("apiKey").WithIn("query").WithName("token"),
+				},
+				// /api/v1/api-keys 创建的长期凭据，权限受 scope 限制
+				"apiKey": &openapi3.SecuritySchemeRef{
+					Value: openapi3.NewSecurityScheme().WithType("apiKey").WithIn("header").WithName("X-Api-Key"),
+				},
+				// /auth?cookie=true 登录后由浏览器携带，写请求还需带上 X-CSRF-Token 请求头
+				"cookie": &openapi3.SecuritySchemeRef{
+					Value: openapi3.NewSecurityScheme().WithType("apiKey").WithIn("cookie").WithName("token"),
+				},
+			},
+		},
+	}
+
+	for _, op := range ops {
+		path := ginParam.ReplaceAllString(op.Path, "{$1}")
+		item := t.Paths.Value(path)
+		if item == nil {
+			item = &openapi3.PathItem{}
+			t.Paths.Set(path, item)
+		}
+		item.SetOperation(op.Method, newOperation(op))
+	}
+
+	return t
+}
+
+func newOperation(op Operation) *openapi3.Operation {
+	o := openapi3.NewOperation()
+	o.Summary = op.Summary
+	o.OperationID = strings.ToLower(op.Method) + ginParam.ReplaceAllString(strings.ReplaceAll(op.Path, "/", "_"), "$1")
+	if op.Tag != "" {
+		o.Tags = []string{op.Tag}
+	}
+	if op.Auth {
+		o.Security = openapi3.NewSecurityRequirements().
+			With(openapi3.NewSecurityRequirement().Authenticate("token")).
+			With(openapi3.NewSecurityRequirement().Authenticate("apiKey")).
+			With(openapi3.NewSecurityRequirement().Authenticate("cookie"))
+	}
+
+	hasBody := op.Method == http.MethodPost || op.Method == http.MethodPut || op.Method == http.MethodPatch
+	body := openapi3.NewObjectSchema()
+	var required []string
+	multipart := false
+
+	for _, f := range parseFields(op.Request) {
+		switch {
+		case f.In == "path":
+			o.AddParameter(openapi3.NewPathParameter(f.Name).WithSchema(f.Schema))
+		case hasBody:
+			body.WithProperty(f.Name, f.Schema)
+			if f.Required {
+				required = append(required, f.Name)
+			}
+			if f.Schema.Format == "binary" {
+				multipart = true
+			}
+		default:
+			o.AddParameter(openapi3.NewQueryParameter(f.Name).WithRequired(f.Required).WithSchema(f.Schema))
+		}
+	}
+
+	if len(body.Properties) > 0 {
+		body.WithRequired(required)
+		consumes := []string{"application/json", "application/x-www-form-urlencoded", "multipart/form-data"}
+		if multipart {
+			consumes = []string{"multipart/form-data"}
+		}
+		o.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().
+				WithRequired(len(required) > 0).
+				WithContent(openapi3.NewContentWithSchema(body, consumes)),
+		}
+	}
+
+	o.Parameters = append(o.Parameters,
+		&openapi3.ParameterRef{Ref: "#/components/parameters/ApiVersion", Value: versionParameter},
+		&openapi3.ParameterRef{Ref: "#/components/parameters/Lang", Value: langParameter},
+	)
+
+	o.AddResponse(http.StatusOK, openapi3.NewResponse().
+		WithDescription("ok").
+		WithJSONSchemaRef(openapi3.NewSchemaRef("#/components/schemas/Response", responseSchema)))
+	o.Responses.Set("default", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("错误，API 版本 2 及以上返回 application/problem+json").
+			WithContent(openapi3.Content{
+				"application/json":         openapi3.NewMediaType().WithSchemaRef(openapi3.NewSchemaRef("#/components/schemas/Response", responseSchema)),
+				"application/problem+json": openapi3.NewMediaType().WithSchemaRef(openapi3.NewSchemaRef("#/components/schemas/Problem", problemSchema)),
+			}),
+	})
+
+	return o
+}
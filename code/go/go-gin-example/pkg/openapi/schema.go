@@ -0,0 +1,156 @@
+package openapi
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var fileHeaderType = reflect.TypeOf(&multipart.FileHeader{})
+
+// field 请求结构体中的一个字段，由 uri / form 与 binding 标签描述
+type field struct {
+	Name     string
+	In       string // path 或 form
+	Required bool
+	Schema   *openapi3.Schema
+}
+
+// parseFields 解析请求结构体，路径参数取 uri 标签，其余参数取 form 标签
+func parseFields(v interface{}) []field {
+	if v == nil {
+		return nil
+	}
+
+	return typeFields(reflect.TypeOf(v))
+}
+
+func typeFields(t reflect.Type) []field {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		in, name := "", ""
+		if tag := tagName(f.Tag.Get("uri")); tag != "" {
+			in, name = "path", tag
+		} else if tag := tagName(f.Tag.Get("form")); tag != "" {
+			in, name = "form", tag
+		} else {
+			continue
+		}
+
+		schema := typeSchema(f.Type)
+		required := applyBinding(schema, f.Tag.Get("binding"))
+		fields = append(fields, field{Name: name, In: in, Required: required, Schema: schema})
+	}
+
+	return fields
+}
+
+func tagName(tag string) string {
+	name := strings.Split(tag, ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// typeSchema 根据字段类型生成基础 schema
+func typeSchema(t reflect.Type) *openapi3.Schema {
+	if t == fileHeaderType {
+		return openapi3.NewStringSchema().WithFormat("binary")
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return openapi3.NewBoolSchema()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return openapi3.NewIntegerSchema()
+	case reflect.Float32, reflect.Float64:
+		return openapi3.NewFloat64Schema()
+	case reflect.Slice, reflect.Array:
+		return openapi3.NewArraySchema().WithItems(typeSchema(t.Elem()))
+	case reflect.Map:
+		return openapi3.NewObjectSchema()
+	case reflect.Struct:
+		// 嵌套的结构体，如批量接口中的每一项，属性同样取 form 标签
+		schema := openapi3.NewObjectSchema()
+		var required []string
+		for _, f := range typeFields(t) {
+			schema.WithProperty(f.Name, f.Schema)
+			if f.Required {
+				required = append(required, f.Name)
+			}
+		}
+		return schema.WithRequired(required)
+	default:
+		return openapi3.NewStringSchema()
+	}
+}
+
+// applyBinding 将 binding 标签中的校验规则写入 schema，返回该字段是否必填
+func applyBinding(schema *openapi3.Schema, tag string) bool {
+	required := false
+	isString := schema.Type.Is(openapi3.TypeString) && schema.Format != "binary"
+	isArray := schema.Type.Is(openapi3.TypeArray)
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "gte":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			if isString {
+				schema.WithMinLength(int64(n))
+			} else if isArray {
+				schema.WithMinItems(int64(n))
+			} else {
+				schema.WithMin(n)
+			}
+		case "max", "lte":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			if isString {
+				schema.WithMaxLength(int64(n))
+			} else if isArray {
+				schema.WithMaxItems(int64(n))
+			} else {
+				schema.WithMax(n)
+			}
+		case "oneof":
+			var values []interface{}
+			for _, s := range strings.Fields(arg) {
+				if isString {
+					values = append(values, s)
+				} else if n, err := strconv.ParseFloat(s, 64); err == nil {
+					// 请求中的数值统一解码为 float64，枚举值需保持同一类型
+					values = append(values, n)
+				}
+			}
+			schema.WithEnum(values...)
+		}
+	}
+
+	return required
+}
@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// Elector 多实例部署时选出唯一执行定时任务的 leader
+type Elector interface {
+	// Campaign 尝试成为 leader 或续约，返回当前实例是否为 leader
+	Campaign() (bool, error)
+	// Resign 放弃 leader 身份
+	Resign()
+}
+
+func newElector() Elector {
+	switch setting.SchedulerSetting.Lock {
+	case "redis":
+		return &redisElector{key: e.CACHE_SCHEDULER + "_LEADER", ttl: setting.SchedulerSetting.LockTTL}
+	case "none":
+		return standalone{}
+	default:
+		return &dbElector{lock: models.NewDBLock(setting.DatabaseSetting.Name + ".scheduler")}
+	}
+}
+
+// standalone 单实例部署，始终为 leader
+type standalone struct{}
+
+func (standalone) Campaign() (bool, error) { return true, nil }
+func (standalone) Resign()                 {}
+
+// dbElector 持有 MySQL 命名锁的实例为 leader
+type dbElector struct {
+	lock *models.DBLock
+}
+
+func (d *dbElector) Campaign() (bool, error) { return d.lock.TryLock() }
+func (d *dbElector) Resign()                 { d.lock.Unlock() }
+
+// redisElector 以实例 ID 为值写入带过期时间的 key，写入成功的实例为 leader，leader 定期续约
+type redisElector struct {
+	key string
+	ttl time.Duration
+}
+
+// campaignScript 已是 leader 时续约，key 不存在时抢占
+const campaignScript = `
+local holder = redis.call('GET', KEYS[1])
+if holder == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if not holder then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`
+
+// resignScript 只删除自己持有的 key
+const resignScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+func (r *redisElector) Campaign() (bool, error) {
+	res, err := gredis.Eval(context.Background(), campaignScript, []string{r.key}, Instance, r.ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+
+	n, _ := res.(int64)
+	return n == 1, nil
+}
+
+func (r *redisElector) Resign() {
+	gredis.Eval(context.Background(), resignScript, []string{r.key}, Instance)
+}
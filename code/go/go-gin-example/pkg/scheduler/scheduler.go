@@ -0,0 +1,341 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+var (
+	ErrNotExist = errors.New("job does not exist")
+	ErrRunning  = errors.New("job is already running")
+)
+
+// Instance 当前实例的标识，写入执行记录，也用于 redis 选主
+var Instance = instanceID()
+
+// job 按名称注册的定时任务，执行时间来自配置 [jobs]
+type job struct {
+	name string
+	spec string
+	fn   func(ctx context.Context) error
+}
+
+var (
+	mu      sync.Mutex
+	jobs    = make(map[string]*job)
+	running = make(map[string]bool)
+
+	c       *cron.Cron
+	elector Elector
+	leader  atomic.Bool
+	stop    chan struct{}
+	// stopped 在选举的 goroutine 退出后关闭
+	stopped chan struct{}
+)
+
+// Register 注册定时任务，需在 Setup 之前调用，name 对应配置 [jobs] 中的同名配置项，如 clean_tags 对应 CleanTags
+func Register(name string, fn func(ctx context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	jobs[name] = &job{name: name, fn: fn}
+}
+
+// Setup 按配置调度已注册的任务，并开始参与 leader 选举，只有 leader 会按计划执行任务
+func Setup() error {
+	c = cron.New()
+	for _, j := range jobs {
+		spec, ok := setting.JobSpec(j.name)
+		if !ok {
+			return fmt.Errorf("job %s has no config in [jobs]", j.name)
+		}
+		j.spec = spec
+		if spec == "" {
+			continue
+		}
+		if err := c.AddJob(spec, j); err != nil {
+			return fmt.Errorf("job %s: %w", j.name, err)
+		}
+	}
+
+	if !setting.SchedulerSetting.Enabled {
+		return nil
+	}
+
+	elector = newElector()
+	stop, stopped = make(chan struct{}), make(chan struct{})
+	go campaign(stop, stopped)
+	c.Start()
+
+	return nil
+}
+
+// Stop 停止调度并放弃 leader 身份，已开始的任务不会被中断
+func Stop() {
+	if stop == nil {
+		return
+	}
+
+	c.Stop()
+	close(stop)
+	// 等待进行中的选举结束，以免放弃 leader 身份后又被它设置为 leader
+	<-stopped
+	stop = nil
+	elector.Resign()
+	leader.Store(false)
+}
+
+// IsLeader 当前实例是否负责执行定时任务
+func IsLeader() bool {
+	return leader.Load()
+}
+
+// campaign 每隔租约的三分之一参与一次选举，leader 借此续约
+func campaign(stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(setting.SchedulerSetting.LockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		ok, err := elector.Campaign()
+		if err != nil {
+			logging.Warn("scheduler campaign", err)
+		}
+		if leader.Swap(ok) != ok {
+			logging.Info("scheduler leader changed", Instance, ok)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run 由 cron 按计划调用，非 leader 或任务已暂停时跳过
+func (j *job) Run() {
+	if !leader.Load() {
+		return
+	}
+
+	ctx := context.Background()
+	paused, err := isPaused(ctx, j.name)
+	if err != nil {
+		logging.Error("job", j.name, err)
+		return
+	}
+	if paused {
+		return
+	}
+
+	run, err := begin(ctx, j, models.JOB_TRIGGER_SCHEDULE, "")
+	if err != nil {
+		logging.Warn("job", j.name, err)
+		return
+	}
+	execute(j, run)
+}
+
+// Trigger 立即在当前实例上执行任务，不受暂停状态影响，返回执行记录，任务在后台执行
+func Trigger(ctx context.Context, name, by string) (*models.JobRun, error) {
+	j, ok := jobs[name]
+	if !ok {
+		return nil, ErrNotExist
+	}
+
+	run, err := begin(ctx, j, models.JOB_TRIGGER_MANUAL, by)
+	if err != nil {
+		return nil, err
+	}
+	go execute(j, run)
+
+	return run, nil
+}
+
+// begin 标记任务开始执行并写入执行记录，同一实例上同一任务不会并发执行
+func begin(ctx context.Context, j *job, trigger, by string) (*models.JobRun, error) {
+	mu.Lock()
+	if running[j.name] {
+		mu.Unlock()
+		return nil, ErrRunning
+	}
+	running[j.name] = true
+	mu.Unlock()
+
+	run := &models.JobRun{
+		Job:       j.name,
+		Trigger:   trigger,
+		TriggerBy: by,
+		Instance:  Instance,
+		Status:    models.JOB_RUN_RUNNING,
+		StartedOn: int(time.Now().Unix()),
+	}
+	if err := models.AddJobRun(ctx, run); err != nil {
+		done(j.name)
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// execute 执行任务并记录耗时与错误
+func execute(j *job, run *models.JobRun) {
+	defer done(j.name)
+
+	// 任务在后台执行，不随触发任务的请求取消
+	ctx := context.Background()
+	start := time.Now()
+	err := call(ctx, j.fn)
+	if err != nil {
+		logging.Error("job", j.name, "failed", err)
+	} else {
+		logging.Info("job", j.name, "finished in", time.Since(start))
+	}
+
+	if err := models.FinishJobRun(ctx, run.ID, err, time.Since(start)); err != nil {
+		logging.Error("job", j.name, err)
+	}
+}
+
+// call 执行任务函数，panic 视为执行失败
+func call(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func done(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(running, name)
+}
+
+// Status 任务的当前状态
+type Status struct {
+	Name    string         `json:"name"`
+	Spec    string         `json:"spec"`
+	Paused  bool           `json:"paused"`
+	Running bool           `json:"running"`  // 是否正在当前实例上执行
+	NextRun int64          `json:"next_run"` // 下次计划执行时间，未调度时为 0
+	LastRun *models.JobRun `json:"last_run"`
+}
+
+// List 获取所有已注册任务的状态，按名称排序
+func List(ctx context.Context) ([]Status, error) {
+	next := make(map[string]time.Time)
+	if c != nil {
+		for _, entry := range c.Entries() {
+			if j, ok := entry.Job.(*job); ok {
+				next[j.name] = entry.Next
+			}
+		}
+	}
+
+	list := make([]Status, 0, len(jobs))
+	for _, j := range jobs {
+		s, err := status(ctx, j, next[j.name])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].Name < list[k].Name })
+
+	return list, nil
+}
+
+func status(ctx context.Context, j *job, next time.Time) (Status, error) {
+	s := Status{Name: j.name, Spec: j.spec}
+
+	paused, err := isPaused(ctx, j.name)
+	if err != nil {
+		return s, err
+	}
+	s.Paused = paused
+
+	if s.LastRun, err = models.GetLastJobRun(ctx, j.name); err != nil {
+		return s, err
+	}
+
+	mu.Lock()
+	s.Running = running[j.name]
+	mu.Unlock()
+
+	if !next.IsZero() {
+		s.NextRun = next.Unix()
+	}
+
+	return s, nil
+}
+
+// Pause 暂停任务的计划执行，对所有实例生效
+func Pause(ctx context.Context, name, by string) error {
+	return setPaused(ctx, name, 1, by)
+}
+
+// Resume 恢复任务的计划执行
+func Resume(ctx context.Context, name, by string) error {
+	return setPaused(ctx, name, 0, by)
+}
+
+func setPaused(ctx context.Context, name string, paused int, by string) error {
+	if _, ok := jobs[name]; !ok {
+		return ErrNotExist
+	}
+
+	return models.SetJobPaused(ctx, name, paused, by)
+}
+
+func isPaused(ctx context.Context, name string) (bool, error) {
+	j, err := models.GetJob(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	return j.Paused == 1, nil
+}
+
+// Runs 分页获取任务的执行记录，最近的在前
+func Runs(ctx context.Context, name string, pageNum, pageSize int) ([]models.JobRun, int, error) {
+	if _, ok := jobs[name]; !ok {
+		return nil, 0, ErrNotExist
+	}
+
+	runs, err := models.GetJobRuns(ctx, name, pageNum, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := models.GetJobRunTotal(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
+
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,342 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+var redis *miniredis.Miniredis
+
+// TestMain 执行记录与暂停状态写入临时目录中的 SQLite，选主使用进程内的 Redis
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "scheduler")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := func() int {
+		defer os.RemoveAll(dir)
+
+		redis = miniredis.NewMiniRedis()
+		if err := redis.Start(); err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer redis.Close()
+		setting.RedisSetting.Host = redis.Addr()
+		setting.RedisSetting.MaxActive = 10
+		if err := gredis.Setup(); err != nil {
+			log.Println(err)
+			return 1
+		}
+
+		if err := models.Open("sqlite3", filepath.Join(dir, "blog.db")); err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer models.CloseDB()
+		if err := models.Migrate(); err != nil {
+			log.Println(err)
+			return 1
+		}
+
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// register 以真实的任务名注册 fn，测试结束后恢复原有的任务与调度状态
+func register(t *testing.T, name string, fn func(context.Context) error) {
+	t.Helper()
+
+	mu.Lock()
+	prev, ok := jobs[name]
+	mu.Unlock()
+	Register(name, fn)
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if ok {
+			jobs[name] = prev
+		} else {
+			delete(jobs, name)
+		}
+	})
+}
+
+// waitRun 等待执行记录结束
+func waitRun(t *testing.T, name string, id int) models.JobRun {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		runs, _, err := Runs(context.Background(), name, 0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, run := range runs {
+			if run.ID == id && run.Status != models.JOB_RUN_RUNNING {
+				return run
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %d of %s did not finish", id, name)
+
+	return models.JobRun{}
+}
+
+func TestStandaloneElector(t *testing.T) {
+	e := standalone{}
+	for i := 0; i < 3; i++ {
+		if ok, err := e.Campaign(); !ok || err != nil {
+			t.Fatalf("standalone campaign = %v, %v", ok, err)
+		}
+	}
+	e.Resign()
+}
+
+func TestRedisElector(t *testing.T) {
+	prev := Instance
+	defer func() { Instance = prev }()
+
+	ttl := 3 * time.Second
+	a := &redisElector{key: "TEST_LEADER", ttl: ttl}
+	b := &redisElector{key: "TEST_LEADER", ttl: ttl}
+	campaign := func(instance string, e Elector) bool {
+		t.Helper()
+		Instance = instance
+		ok, err := e.Campaign()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if !campaign("a", a) {
+		t.Fatal("a is not elected")
+	}
+	if campaign("b", b) {
+		t.Fatal("b is elected while a holds the lease")
+	}
+
+	// leader 在租约过期前续约
+	redis.FastForward(2 * time.Second)
+	if !campaign("a", a) {
+		t.Fatal("a cannot renew its lease")
+	}
+	redis.FastForward(2 * time.Second)
+	if campaign("b", b) {
+		t.Fatal("b is elected before the renewed lease expires")
+	}
+
+	// 只能放弃自己持有的 leader 身份
+	Instance = "b"
+	b.Resign()
+	if campaign("b", b) {
+		t.Fatal("b resigned a's lease")
+	}
+	Instance = "a"
+	a.Resign()
+	if !campaign("b", b) {
+		t.Fatal("b is not elected after a resigned")
+	}
+
+	// leader 停止续约后租约过期，其他实例接替
+	redis.FastForward(ttl)
+	if !campaign("a", a) {
+		t.Fatal("a is not elected after b's lease expired")
+	}
+}
+
+func TestNewElector(t *testing.T) {
+	prev := *setting.SchedulerSetting
+	defer func() { *setting.SchedulerSetting = prev }()
+
+	for lock, want := range map[string]string{"none": "scheduler.standalone", "redis": "*scheduler.redisElector", "mysql": "*scheduler.dbElector"} {
+		setting.SchedulerSetting.Lock = lock
+		if got := fmt.Sprintf("%T", newElector()); got != want {
+			t.Errorf("lock %s: elector = %s, want %s", lock, got, want)
+		}
+	}
+}
+
+// TestLeaderOnly 只有 leader 按计划执行任务，暂停的任务不执行，Trigger 不受两者影响
+func TestLeaderOnly(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	register(t, "clean_tags", func(context.Context) error {
+		calls <- struct{}{}
+		return nil
+	})
+
+	prevScheduler, prevJobs := *setting.SchedulerSetting, *setting.JobsSetting
+	defer func() { *setting.SchedulerSetting, *setting.JobsSetting = prevScheduler, prevJobs }()
+	*setting.SchedulerSetting = setting.Scheduler{Enabled: true, Lock: "none", LockTTL: 3 * time.Second}
+	// 按年执行，测试期间不会被 cron 触发，由测试直接调用 Run
+	*setting.JobsSetting = setting.Jobs{CleanTags: "0 0 0 1 1 *"}
+
+	j := jobs["clean_tags"]
+	j.Run()
+	if len(calls) != 0 {
+		t.Fatal("job ran before the instance became leader")
+	}
+
+	if err := Setup(); err != nil {
+		t.Fatal(err)
+	}
+	defer Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for !IsLeader() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !IsLeader() {
+		t.Fatal("standalone instance did not become leader")
+	}
+
+	j.Run()
+	if len(calls) != 1 {
+		t.Fatalf("leader ran the job %d times, want 1", len(calls))
+	}
+	<-calls
+	run, err := models.GetLastJobRun(context.Background(), "clean_tags")
+	if err != nil || run.Trigger != models.JOB_TRIGGER_SCHEDULE || run.Status != models.JOB_RUN_SUCCESS || run.Instance != Instance {
+		t.Fatalf("last run = %+v, %v", run, err)
+	}
+
+	list, err := List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status *Status
+	for i := range list {
+		if list[i].Name == "clean_tags" {
+			status = &list[i]
+		}
+	}
+	if status == nil || status.Spec != "0 0 0 1 1 *" || status.NextRun == 0 || status.LastRun == nil || status.LastRun.ID != run.ID {
+		t.Fatalf("status = %+v", status)
+	}
+
+	if err := Pause(context.Background(), "clean_tags", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	j.Run()
+	if len(calls) != 0 {
+		t.Fatal("paused job ran on schedule")
+	}
+
+	// 手动执行不受暂停影响
+	manual, err := Trigger(context.Background(), "clean_tags", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-calls
+	if got := waitRun(t, "clean_tags", manual.ID); got.Trigger != models.JOB_TRIGGER_MANUAL || got.TriggerBy != "admin" {
+		t.Fatalf("manual run = %+v", got)
+	}
+
+	if err := Resume(context.Background(), "clean_tags", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	j.Run()
+	if len(calls) != 1 {
+		t.Fatal("resumed job did not run")
+	}
+	<-calls
+
+	Stop()
+	if IsLeader() {
+		t.Fatal("still leader after Stop")
+	}
+	j.Run()
+	if len(calls) != 0 {
+		t.Fatal("job ran after Stop")
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	release := make(chan struct{})
+	register(t, "clean_articles", func(context.Context) error {
+		<-release
+		return errors.New("database is gone")
+	})
+	register(t, "flush_views", func(context.Context) error {
+		panic("boom")
+	})
+
+	run, err := Trigger(context.Background(), "clean_articles", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != models.JOB_RUN_RUNNING || run.Trigger != models.JOB_TRIGGER_MANUAL || run.TriggerBy != "alice" {
+		t.Fatalf("run = %+v", run)
+	}
+
+	// 同一任务在当前实例上不并发执行
+	if _, err := Trigger(context.Background(), "clean_articles", "bob"); err != ErrRunning {
+		t.Fatalf("trigger a running job: %v, want ErrRunning", err)
+	}
+	list, err := List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range list {
+		if s.Name == "clean_articles" && !s.Running {
+			t.Fatalf("status = %+v, want running", s)
+		}
+	}
+
+	close(release)
+	if got := waitRun(t, "clean_articles", run.ID); got.Status != models.JOB_RUN_FAILED || got.Error != "database is gone" {
+		t.Fatalf("finished run = %+v", got)
+	}
+
+	// panic 视为执行失败
+	run, err = Trigger(context.Background(), "flush_views", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := waitRun(t, "flush_views", run.ID); got.Status != models.JOB_RUN_FAILED || got.Error != "panic: boom" {
+		t.Fatalf("panicked run = %+v", got)
+	}
+
+	// 结束后可以再次执行
+	run, err = Trigger(context.Background(), "flush_views", "alice")
+	if err != nil {
+		t.Fatalf("trigger after the previous run finished: %v", err)
+	}
+	waitRun(t, "flush_views", run.ID)
+
+	runs, total, err := Runs(context.Background(), "flush_views", 0, 1)
+	if err != nil || total != 2 || len(runs) != 1 || runs[0].ID != run.ID {
+		t.Fatalf("runs = %+v, %d, %v", runs, total, err)
+	}
+}
+
+func TestNotExist(t *testing.T) {
+	if _, err := Trigger(context.Background(), "missing", "alice"); err != ErrNotExist {
+		t.Errorf("Trigger: %v", err)
+	}
+	if err := Pause(context.Background(), "missing", "alice"); err != ErrNotExist {
+		t.Errorf("Pause: %v", err)
+	}
+	if err := Resume(context.Background(), "missing", "alice"); err != ErrNotExist {
+		t.Errorf("Resume: %v", err)
+	}
+	if _, _, err := Runs(context.Background(), "missing", 0, 10); err != ErrNotExist {
+		t.Errorf("Runs: %v", err)
+	}
+}
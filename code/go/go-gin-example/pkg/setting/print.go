@@ -0,0 +1,56 @@
+package setting
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// REDACTED 打印配置时用于替换敏感配置项的值
+const REDACTED = "******"
+
+// Print 以 ini 格式输出当前生效的配置，redact 为 true 时隐藏标记为 secret 的配置项
+func Print(w io.Writer, redact bool) {
+	for i, s := range sections {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "[%s]\n", s.Name)
+
+		rv := reflect.ValueOf(s.Value).Elem()
+		rt := rv.Type()
+		for j := 0; j < rt.NumField(); j++ {
+			field := rt.Field(j)
+			value := format(field.Name, rv.Field(j).Interface())
+			if redact && field.Tag.Get("secret") == "true" && value != "" {
+				value = REDACTED
+			}
+			fmt.Fprintf(w, "%s = %s\n", field.Name, value)
+		}
+	}
+}
+
+func format(name string, v interface{}) string {
+	switch v := v.(type) {
+	case time.Duration:
+		return v.String()
+	case string:
+		// 与配置文件中的写法一致，含 ; 或 # 的值用反引号括起来，避免被当作注释
+		if strings.ContainsAny(v, ";#") {
+			return "`" + v + "`"
+		}
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	case int:
+		// ImageMaxSize 在加载后换算成了字节，打印时还原为配置中的 MB
+		if name == "ImageMaxSize" {
+			return fmt.Sprint(v / 1024 / 1024)
+		}
+		return fmt.Sprint(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -1,16 +1,18 @@
 package setting
 
 import (
-	"log"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
 	"time"
-
-	"github.com/go-ini/ini"
 )
 
 type App struct {
-	JwtSecret       string
+	JwtSecret       string `secret:"true"`
 	PageSize        int
 	RuntimeRootPath string
+	ApiVersion      int
 
 	PrefixUrl      string
 	ImageSavePath  string
@@ -28,10 +30,11 @@ type App struct {
 var AppSetting = &App{}
 
 type Server struct {
-	RunMode      string
-	HttpPort     int
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	RunMode        string
+	HttpPort       int
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	TrustedProxies []string // 信任其 X-Forwarded-For 的代理 IP 或 CIDR，为空时直接使用连接的对端地址
 }
 
 var ServerSetting = &Server{}
@@ -39,7 +42,7 @@ var ServerSetting = &Server{}
 type Database struct {
 	Type        string
 	User        string
-	Password    string
+	Password    string `secret:"true"`
 	Host        string
 	Name        string
 	TablePrefix string
@@ -49,7 +52,7 @@ var DatabaseSetting = &Database{}
 
 type Redis struct {
 	Host        string
-	Password    string
+	Password    string `secret:"true"`
 	MaxIdle     int
 	MaxActive   int
 	IdleTimeout time.Duration
@@ -57,38 +60,355 @@ type Redis struct {
 
 var RedisSetting = &Redis{}
 
-func Setup() {
-	Cfg, err := ini.Load("conf/app.ini")
+type RateLimit struct {
+	Store              string // memory 或 redis，多实例部署时应使用 redis
+	Auth               string // 登录接口的限流策略，如 5/m
+	Write              string // 写接口的限流策略，如 60/m,user
+	LockoutThreshold   int    // 连续登录失败多少次后锁定，0 表示不锁定
+	LockoutDuration    time.Duration
+	LockoutMaxDuration time.Duration
+}
+
+var RateLimitSetting = &RateLimit{}
+
+type Scheduler struct {
+	Enabled bool          // 为 false 时本实例不执行定时任务，仍可通过接口手动触发
+	Lock    string        // 选主方式：mysql、redis 或 none，none 仅适用于单实例部署
+	LockTTL time.Duration // leader 租约时长，leader 宕机后最长经过该时长由其他实例接替
+}
+
+var SchedulerSetting = &Scheduler{}
+
+type Trash struct {
+	Retention time.Duration // 软删除的数据在回收站中保留的时长，超过后由清理任务物理删除
+}
+
+var TrashSetting = &Trash{}
+
+type Webhook struct {
+	Timeout      time.Duration // 单次投递的超时时间
+	MaxAttempts  int           // 最多投递次数，超过后标记为失败，可手动重放
+	RetryBase    time.Duration // 第一次重试的等待时间，之后每次翻倍
+	RetryMax     time.Duration // 重试等待时间的上限
+	PollInterval time.Duration // 检查待重试投递的间隔
+	// 是否允许投递到回环、内网与链路本地地址，默认拒绝，避免订阅被用来访问内部服务
+	AllowPrivateNetwork bool
+}
+
+var WebhookSetting = &Webhook{}
+
+type Feed struct {
+	Title       string
+	Description string
+	Link        string        // 站点地址，文章链接为 Link/articles/:slug，留空时使用 app.PrefixUrl
+	Limit       int           // 订阅源中最多包含的文章数
+	CacheTTL    time.Duration // 订阅源在 Redis 中的缓存时长，文章或标签变更时会提前失效
+}
+
+var FeedSetting = &Feed{}
+
+// Jobs 各定时任务的 cron 表达式，秒 分 时 日 月 [周]，也支持 @every 1h、@daily 等写法，留空表示不定时执行
+type Jobs struct {
+	CleanTags     string
+	CleanArticles string
+	FlushViews    string
+}
+
+var JobsSetting = &Jobs{}
+
+type Tenant struct {
+	Default string // 请求既没有 X-Tenant 请求头、域名也不属于任何站点时使用的站点名称，留空时返回 404
+}
+
+var TenantSetting = &Tenant{}
+
+type GraphQL struct {
+	MaxDepth      int // 查询中字段的最大嵌套层数
+	MaxComplexity int // 查询的最大复杂度，每个字段计 1，列表字段的子字段按 limit 参数或 app.PageSize 倍计算
+}
+
+var GraphQLSetting = &GraphQL{}
+
+type Popularity struct {
+	HalfLife   time.Duration // 浏览与点赞对热度的贡献每经过 HalfLife 减半
+	LikeWeight int           // 一次点赞相当于多少次浏览
+	MaxWindow  time.Duration // 热门文章统计时间范围的上限
+}
+
+var PopularitySetting = &Popularity{}
+
+type Related struct {
+	Size      int           // 每篇文章最多推荐的相关文章数
+	TagWeight float64       // 同一标签的文章额外增加的相似度，标题、描述与内容的 TF-IDF 余弦相似度在 0 到 1 之间
+	Debounce  time.Duration // 文章变更后等待该时长再在后台重新计算，期间的多次变更只计算一次
+}
+
+var RelatedSetting = &Related{}
+
+type Site struct {
+	OutputPath   string // export-site 命令的输出目录
+	TemplatePath string // 自定义模板所在目录，其中的模板覆盖同名的内置模板，为空时只使用内置模板
+	PageSize     int    // 首页与标签页每页的文章数
+}
+
+var SiteSetting = &Site{}
+
+// CORS 各路由组允许跨域访问的来源，如 https://admin.example.com，* 表示任意来源，为空时不允许跨域
+type CORS struct {
+	Api    []string      // /auth、/api/v1、/graphql、/upload 等管理接口，只有明确列出的来源可以携带 Cookie
+	Public []string      // /api/v1/public、订阅源、sitemap 与图片等公开内容
+	MaxAge time.Duration // 浏览器缓存预检请求结果的时长
+}
+
+var CORSSetting = &CORS{}
+
+type Security struct {
+	ContentSecurityPolicy string        // 响应头 Content-Security-Policy，为空时不设置
+	FrameOptions          string        // 响应头 X-Frame-Options，DENY 或 SAMEORIGIN，为空时不设置
+	HSTSMaxAge            time.Duration // HTTPS 响应的 Strict-Transport-Security max-age，为 0 时不设置
+	HSTSIncludeSubdomains bool
+	CookieSameSite        string // 登录 Cookie 的 SameSite 属性：lax、strict 或 none，none 需通过 HTTPS 访问
+}
+
+var SecuritySetting = &Security{}
+
+// OIDC 通过 OpenID Connect 身份提供方登录，Issuer 为空时不启用
+type OIDC struct {
+	Issuer        string // 身份提供方地址，如 https://sso.example.com/realms/blog，需与发现文档中的 issuer 一致
+	ClientID      string
+	ClientSecret  string   `secret:"true"`
+	RedirectURL   string   // 在身份提供方登记的回调地址，指向 /auth/oidc/callback
+	Scopes        []string // 必须包含 openid
+	UsernameClaim string   // 自动创建本地用户时作为用户名的声明，不用于匹配已有账号
+	RoleClaim     string   // 包含用户组或角色的声明，可以是字符串或字符串数组
+	AdminValues   []string // RoleClaim 中包含任意一个时为 admin
+	EditorValues  []string // RoleClaim 中包含任意一个时为 editor，为空时其余用户都是 editor
+	AutoCreate    bool     // 首次登录时是否自动创建本地用户，否则只允许已绑定 sub 的用户登录
+}
+
+var OIDCSetting = &OIDC{}
+
+// Tracing OpenTelemetry 链路追踪，Exporter 为空时不导出，但仍会透传请求中的 traceparent
+type Tracing struct {
+	Exporter    string  // otlp 发送到 Endpoint，stdout 输出到标准输出用于本地调试，为空表示不导出
+	Endpoint    string  // OTLP/HTTP 接收地址，如 http://127.0.0.1:4318，span 发送到 Endpoint/v1/traces
+	ServiceName string  // span 中的 service.name
+	SampleRatio float64 // 没有上游 traceparent 的请求的采样比例，0 到 1，有上游时沿用上游的采样决定
+}
+
+var TracingSetting = &Tracing{}
+
+// DefaultPath 未通过 --config 或 BLOG_CONFIG 指定时使用的配置文件
+const DefaultPath = "conf/app.ini"
+
+// EnvPrefix 环境变量前缀，如 BLOG_DATABASE_PASSWORD 对应 [database] Password
+const EnvPrefix = "BLOG_"
+
+// sections 配置节与对应的结构体，加载、校验与打印都按此顺序进行
+var sections = []struct {
+	Name  string
+	Value interface{}
+}{
+	{"app", AppSetting},
+	{"server", ServerSetting},
+	{"database", DatabaseSetting},
+	{"redis", RedisSetting},
+	{"ratelimit", RateLimitSetting},
+	{"scheduler", SchedulerSetting},
+	{"trash", TrashSetting},
+	{"webhook", WebhookSetting},
+	{"feed", FeedSetting},
+	{"jobs", JobsSetting},
+	{"tenant", TenantSetting},
+	{"graphql", GraphQLSetting},
+	{"popularity", PopularitySetting},
+	{"related", RelatedSetting},
+	{"site", SiteSetting},
+	{"cors", CORSSetting},
+	{"security", SecuritySetting},
+	{"oidc", OIDCSetting},
+	{"tracing", TracingSetting},
+}
+
+// Setup 按 默认值 → 配置文件 → 环境变量 → 命令行参数 的顺序加载配置，后者覆盖前者，最后校验配置
+func Setup(opts Options) error {
+	path := opts.Path
+	if path == "" {
+		path = os.Getenv(EnvPrefix + "CONFIG")
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+
+	layers := []values{defaults()}
+
+	file, err := loadFile(path)
 	if err != nil {
-		log.Fatalf("Fail to parse 'conf/app.ini': %v", err)
+		return err
 	}
+	if unknown := unknownKeys(file); len(unknown) > 0 {
+		return fmt.Errorf("unknown keys in %s: %s", path, strings.Join(unknown, ", "))
+	}
+	layers = append(layers, file, loadEnv(os.Environ()))
 
-	// MapTo 将配置文件中的数据映射到结构体中
-	err = Cfg.Section("app").MapTo(AppSetting)
+	flags, err := parseSets(opts.Sets)
 	if err != nil {
-		log.Fatalf("Cfg.MapTo AppSetting err: %v", err)
+		return err
+	}
+	if unknown := unknownKeys(flags); len(unknown) > 0 {
+		return fmt.Errorf("unknown keys in --set: %s", strings.Join(unknown, ", "))
+	}
+	layers = append(layers, flags)
+
+	for _, s := range sections {
+		for _, layer := range layers {
+			if err := apply(s.Name, s.Value, layer[s.Name]); err != nil {
+				return err
+			}
+		}
 	}
 
 	AppSetting.ImageMaxSize = AppSetting.ImageMaxSize * 1024 * 1024
 
-	err = Cfg.Section("server").MapTo(ServerSetting)
-	if err != nil {
-		log.Fatalf("Cfg.MapTo ServerSetting err: %v", err)
+	if err := Validate(); err != nil {
+		return fmt.Errorf("invalid config %s:\n%w", path, err)
 	}
 
-	// ini 配置文件中读到的数值是「纯数字」(int)，
-	// 而 Go 中的 time.Duration 是以「纳秒」为单位的整数。
-	// 因此需要将数值转换为 time.Duration 类型。
-	ServerSetting.ReadTimeout = ServerSetting.ReadTimeout * time.Second
-	ServerSetting.WriteTimeout = ServerSetting.ReadTimeout * time.Second
+	return nil
+}
 
-	err = Cfg.Section("database").MapTo(DatabaseSetting)
-	if err != nil {
-		log.Fatalf("Cfg.MapTo DatabaseSetting err: %v", err)
+// JobSpec 返回定时任务的 cron 表达式，name 为 clean_tags 形式的任务名，对应 [jobs] 中的 CleanTags
+func JobSpec(name string) (string, bool) {
+	kv := make(values)
+	rv := reflect.ValueOf(JobsSetting).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		kv.set("jobs", rv.Type().Field(i).Name, rv.Field(i).String())
 	}
 
-	err = Cfg.Section("redis").MapTo(RedisSetting)
-	if err != nil {
-		log.Fatalf("Cfg.MapTo RedisSetting err: %v", err)
+	spec, ok := kv["jobs"][normalize(name)]
+	return spec, ok
+}
+
+// SiteLink 前端站点地址，未配置 feed.Link 时使用 app.PrefixUrl，不以 / 结尾
+func SiteLink() string {
+	if FeedSetting.Link != "" {
+		return strings.TrimRight(FeedSetting.Link, "/")
+	}
+
+	return strings.TrimRight(AppSetting.PrefixUrl, "/")
+}
+
+// defaults 各配置项的默认值
+func defaults() values {
+	return values{
+		"app": {
+			"pagesize":        "10",
+			"runtimerootpath": "runtime/",
+			"apiversion":      "1",
+			"prefixurl":       "http://127.0.0.1:8000",
+			"imagesavepath":   "upload/images/",
+			"imagemaxsize":    "5",
+			"imageallowexts":  ".jpg,.jpeg,.png",
+			"exportsavepath":  "export/",
+			"logsavepath":     "logs/",
+			"logsavename":     "log",
+			"logfileext":      "log",
+			"timeformat":      "20060102",
+		},
+		"server": {
+			"runmode":      "debug",
+			"httpport":     "8000",
+			"readtimeout":  "60",
+			"writetimeout": "60",
+		},
+		"database": {
+			"type":        "mysql",
+			"user":        "root",
+			"host":        "127.0.0.1:3306",
+			"name":        "blog",
+			"tableprefix": "blog_",
+		},
+		"redis": {
+			"host":        "127.0.0.1:6379",
+			"maxidle":     "30",
+			"maxactive":   "30",
+			"idletimeout": "200",
+		},
+		"ratelimit": {
+			"store":              "memory",
+			"auth":               "10/m",
+			"write":              "60/m,user",
+			"lockoutthreshold":   "5",
+			"lockoutduration":    "60",
+			"lockoutmaxduration": "3600",
+		},
+		"scheduler": {
+			"enabled": "true",
+			"lock":    "mysql",
+			"lockttl": "30",
+		},
+		"trash": {
+			"retention": "720h",
+		},
+		"webhook": {
+			"timeout":             "10",
+			"maxattempts":         "6",
+			"retrybase":           "30",
+			"retrymax":            "3600",
+			"pollinterval":        "5",
+			"allowprivatenetwork": "false",
+		},
+		"feed": {
+			"title":    "go-gin-example",
+			"limit":    "20",
+			"cachettl": "300",
+		},
+		"jobs": {
+			"cleantags":     "0 0 3 * * *",
+			"cleanarticles": "0 0 3 * * *",
+			"flushviews":    "@every 1m",
+		},
+		"tenant": {
+			"default": "default",
+		},
+		"graphql": {
+			"maxdepth":      "8",
+			"maxcomplexity": "1000",
+		},
+		"popularity": {
+			"halflife":   "72h",
+			"likeweight": "5",
+			"maxwindow":  "2160h",
+		},
+		"related": {
+			"size":      "5",
+			"tagweight": "0.2",
+			"debounce":  "5s",
+		},
+		"site": {
+			"outputpath": "runtime/site/",
+			"pagesize":   "10",
+		},
+		"cors": {
+			"public": "*",
+			"maxage": "10m",
+		},
+		"security": {
+			"contentsecuritypolicy": "default-src 'none'; frame-ancestors 'none'",
+			"frameoptions":          "DENY",
+			"hstsmaxage":            "8760h",
+			"cookiesamesite":        "lax",
+		},
+		"oidc": {
+			"scopes":        "openid,profile,email",
+			"usernameclaim": "preferred_username",
+			"roleclaim":     "groups",
+			"autocreate":    "true",
+		},
+		"tracing": {
+			"endpoint":    "http://127.0.0.1:4318",
+			"servicename": "go-gin-example",
+			"sampleratio": "1",
+		},
 	}
 }
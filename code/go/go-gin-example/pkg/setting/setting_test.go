@@ -0,0 +1,253 @@
+package setting
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reset 将所有配置节恢复为零值，避免上一次 Setup 的结果影响下一个用例
+func reset(t *testing.T) {
+	t.Helper()
+
+	zero := func() {
+		for _, s := range sections {
+			rv := reflect.ValueOf(s.Value).Elem()
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+	}
+	zero()
+	t.Cleanup(zero)
+}
+
+// writeConfig 在临时目录中写入配置文件，返回其路径
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+const iniConfig = `
+[app]
+JwtSecret = file-secret
+PageSize = 20
+ImageAllowExts = .jpg,.png
+
+[server]
+HttpPort = 9000
+
+[database]
+Name = from file
+`
+
+func TestSetupPrecedence(t *testing.T) {
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"app.ini", iniConfig},
+		{"app.yaml", `
+app:
+  JwtSecret: file-secret
+  PageSize: 20
+  ImageAllowExts: [.jpg, .png]
+server:
+  HttpPort: 9000
+database:
+  Name: from file
+`},
+		{"app.toml", `
+[app]
+JwtSecret = "file-secret"
+PageSize = 20
+ImageAllowExts = [".jpg", ".png"]
+
+[server]
+HttpPort = 9000
+
+[database]
+Name = "from file"
+`},
+	}
+
+	for _, f := range files {
+		t.Run(f.name, func(t *testing.T) {
+			reset(t)
+			t.Setenv("BLOG_SERVER_HTTP_PORT", "9001")
+			t.Setenv("BLOG_DATABASE_NAME", "from env")
+
+			err := Setup(Options{Path: writeConfig(t, f.name, f.content), Sets: []string{"database.name=from flag"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				key  string
+				got  interface{}
+				want interface{}
+			}{
+				// 配置文件未指定，使用默认值
+				{"server.RunMode", ServerSetting.RunMode, "debug"},
+				{"server.ReadTimeout", ServerSetting.ReadTimeout, 60 * time.Second},
+				{"app.ImageMaxSize", AppSetting.ImageMaxSize, 5 * 1024 * 1024},
+				// 配置文件覆盖默认值
+				{"app.JwtSecret", AppSetting.JwtSecret, "file-secret"},
+				{"app.PageSize", AppSetting.PageSize, 20},
+				{"app.ImageAllowExts", AppSetting.ImageAllowExts, []string{".jpg", ".png"}},
+				// 环境变量覆盖配置文件
+				{"server.HttpPort", ServerSetting.HttpPort, 9001},
+				// --set 覆盖环境变量
+				{"database.Name", DatabaseSetting.Name, "from flag"},
+			}
+			for _, tt := range tests {
+				if !reflect.DeepEqual(tt.got, tt.want) {
+					t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	tests := []struct {
+		env     string
+		section string
+		key     string
+		want    string
+	}{
+		{"BLOG_DATABASE_PASSWORD=secret", "database", "password", "secret"},
+		// 配置项名称中的下划线与大小写都被忽略
+		{"BLOG_APP_JWT_SECRET=jwt", "app", "jwtsecret", "jwt"},
+		{"BLOG_RATELIMIT_LOCKOUT_MAX_DURATION=1h", "ratelimit", "lockoutmaxduration", "1h"},
+		{"BLOG_DATABASE_NAME=a=b", "database", "name", "a=b"},
+		{"BLOG_SERVER_RUNMODE=", "server", "runmode", ""},
+	}
+	for _, tt := range tests {
+		v := loadEnv([]string{tt.env})
+		got, ok := v[tt.section][tt.key]
+		if !ok || got != tt.want {
+			t.Errorf("%s: %s.%s = %q, %v, want %q", tt.env, tt.section, tt.key, got, ok, tt.want)
+		}
+	}
+
+	// 没有前缀或缺少配置项名称的环境变量不是配置
+	if v := loadEnv([]string{"HOME=/root", "BLOG_CONFIG=app.ini", "XBLOG_APP_PAGE_SIZE=1"}); len(v) != 0 {
+		t.Errorf("unrelated env loaded: %v", v)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		sets    []string
+		wantErr string
+	}{
+		{"misspelled key", iniConfig + "[redis]\nHots = 127.0.0.1:6379\n", nil, "app.ini: redis.hots"},
+		{"unknown section", iniConfig + "[mail]\nHost = smtp\n", nil, "[mail]"},
+		{"set misspelled key", iniConfig, []string{"app.page_sise=10"}, "unknown keys in --set: app.pagesise"},
+		{"set without section", iniConfig, []string{"pagesize=10"}, `invalid --set "pagesize=10"`},
+		{"set without value", iniConfig, []string{"app.pagesize"}, `invalid --set "app.pagesize"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset(t)
+			err := Setup(Options{Path: writeConfig(t, "app.ini", tt.file), Sets: tt.sets})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	got := unknownKeys(values{"redis": {"hots": "x", "host": "y"}, "mail": {"host": "z"}})
+	if want := []string{"[mail]", "redis.hots"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unknownKeys = %v, want %v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		sets    []string
+		wantErr string
+	}{
+		{"jwt secret", "[app]\nPageSize = 10\n", nil, "app.JwtSecret: is required, set it with BLOG_APP_JWT_SECRET"},
+		{"page size", iniConfig, []string{"app.pagesize=0"}, "app.PageSize: must be greater than 0, got 0"},
+		{"run mode", iniConfig, []string{"server.runmode=prod"}, `server.RunMode: must be one of debug, release, test, got "prod"`},
+		{"port", iniConfig, []string{"server.httpport=70000"}, "server.HttpPort: must be between 1 and 65535, got 70000"},
+		{"not an integer", iniConfig, []string{"server.httpport=abc"}, `server.HttpPort: "abc" is not an integer`},
+		{"not a duration", iniConfig, []string{"server.readtimeout=soon"}, `server.ReadTimeout: "soon" is not a duration`},
+		{"trusted proxy", iniConfig, []string{"server.trustedproxies=10.0.0.0/8,proxy"}, `server.TrustedProxies: "proxy" is not an IP or CIDR`},
+		{"lockout", iniConfig, []string{"ratelimit.lockoutmaxduration=10"}, "ratelimit.LockoutMaxDuration: must not be less than LockoutDuration"},
+		{"cron", iniConfig, []string{"jobs.cleantags=every day"}, `jobs.CleanTags: invalid cron expression "every day"`},
+		{"cors origin", iniConfig, []string{"cors.api=example.com"}, `cors.Api: "example.com" must be * or look like https://example.com`},
+		{"oidc", iniConfig, []string{"oidc.issuer=https://sso.example.com"}, "oidc.ClientID: is required when oidc.Issuer is set"},
+		{"sample ratio", iniConfig, []string{"tracing.sampleratio=2"}, "tracing.SampleRatio: must be between 0 and 1, got 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset(t)
+			err := Setup(Options{Path: writeConfig(t, "app.ini", tt.file), Sets: tt.sets})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 所有问题一起报告
+	reset(t)
+	err := Setup(Options{Path: writeConfig(t, "app.ini", iniConfig), Sets: []string{"app.pagesize=0", "server.httpport=0"}})
+	var errs ValidationError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("err = %v, want 2 problems", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	reset(t)
+	err := Setup(Options{Path: writeConfig(t, "app.ini", iniConfig), Sets: []string{
+		"database.password=db-secret",
+		"oidc.clientsecret=oidc-secret",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var redacted, plain bytes.Buffer
+	Print(&redacted, true)
+	Print(&plain, false)
+
+	for _, line := range []string{
+		"JwtSecret = " + REDACTED,
+		"ClientSecret = " + REDACTED,
+		// 未设置的敏感配置项保持为空，便于发现遗漏
+		"[redis]\nHost = 127.0.0.1:6379\nPassword = \n",
+		// 与配置文件写法一致，便于直接复制使用
+		"ImageMaxSize = 5\n",
+		"ReadTimeout = 1m0s\n",
+		"ContentSecurityPolicy = `default-src 'none'; frame-ancestors 'none'`\n",
+	} {
+		if !strings.Contains(redacted.String(), line) {
+			t.Errorf("redacted output does not contain %q:\n%s", line, redacted.String())
+		}
+	}
+	for _, secret := range []string{"file-secret", "db-secret", "oidc-secret"} {
+		if strings.Contains(redacted.String(), secret) {
+			t.Errorf("redacted output contains %q", secret)
+		}
+		if !strings.Contains(plain.String(), secret) {
+			t.Errorf("plain output does not contain %q", secret)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package setting
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ini/ini"
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// values 配置节 → 配置项 → 值，配置项名称统一经过 normalize 处理
+type values map[string]map[string]string
+
+func (v values) set(section, key, value string) {
+	section = normalize(section)
+	if v[section] == nil {
+		v[section] = make(map[string]string)
+	}
+	v[section][normalize(key)] = value
+}
+
+// normalize 忽略大小写与下划线，JwtSecret、jwt_secret、JWT_SECRET 视为同一配置项
+func normalize(s string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+}
+
+// Options 命令行中与配置相关的参数
+type Options struct {
+	Path string   // 配置文件路径
+	Sets []string // section.key=value 形式的配置项
+}
+
+// Bind 将 --config 与 --set 参数绑定到 FlagSet
+func (o *Options) Bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.Path, "config", "", "配置文件路径，支持 .ini/.yaml/.yml/.toml，默认读取 BLOG_CONFIG 或 "+DefaultPath)
+	fs.Func("set", "覆盖配置项，格式为 section.key=value，可重复指定", func(s string) error {
+		o.Sets = append(o.Sets, s)
+		return nil
+	})
+}
+
+// loadFile 按扩展名解析配置文件
+func loadFile(path string) (values, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read config %s: %w", path, err)
+	}
+
+	v := make(values)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".ini":
+		cfg, err := ini.Load(data)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse %s: %w", path, err)
+		}
+		for _, section := range cfg.Sections() {
+			for _, key := range section.Keys() {
+				v.set(section.Name(), key.Name(), key.Value())
+			}
+		}
+	case ".yaml", ".yml", ".toml":
+		raw := make(map[string]map[string]interface{})
+		if ext == ".toml" {
+			err = toml.Unmarshal(data, &raw)
+		} else {
+			err = yaml.Unmarshal(data, &raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse %s: %w", path, err)
+		}
+		for section, keys := range raw {
+			for key, value := range keys {
+				v.set(section, key, stringify(value))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q, use .ini, .yaml, .yml or .toml", ext)
+	}
+
+	return v, nil
+}
+
+// stringify 将 YAML/TOML 中的值转换为与 INI 一致的字符串形式，列表以逗号分隔
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, stringify(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// loadEnv 读取 BLOG_<SECTION>_<KEY> 形式的环境变量
+func loadEnv(environ []string) values {
+	v := make(values)
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		section, name, ok := strings.Cut(strings.TrimPrefix(key, EnvPrefix), "_")
+		if !ok {
+			continue
+		}
+		v.set(section, name, value)
+	}
+
+	return v
+}
+
+// parseSets 解析 --set section.key=value 参数
+func parseSets(sets []string) (values, error) {
+	v := make(values)
+	for _, s := range sets {
+		key, value, ok := strings.Cut(s, "=")
+		section, name, ok2 := strings.Cut(key, ".")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid --set %q, expected section.key=value", s)
+		}
+		v.set(section, name, value)
+	}
+
+	return v, nil
+}
+
+// apply 将一个配置节的值写入对应结构体
+func apply(section string, target interface{}, kv map[string]string) error {
+	rv := reflect.ValueOf(target).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		raw, ok := kv[normalize(rt.Field(i).Name)]
+		if !ok {
+			continue
+		}
+		if err := setField(rv.Field(i), strings.TrimSpace(raw)); err != nil {
+			return fmt.Errorf("%s.%s: %w", section, rt.Field(i).Name, err)
+		}
+	}
+
+	return nil
+}
+
+func setField(f reflect.Value, raw string) error {
+	switch f.Interface().(type) {
+	case string:
+		f.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		f.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		f.SetInt(int64(n))
+	case float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		f.SetFloat(n)
+	case time.Duration:
+		// 纯数字按秒处理，与原有 ini 配置保持一致，也支持 30s、1m 等写法
+		if n, err := strconv.Atoi(raw); err == nil {
+			f.SetInt(int64(time.Duration(n) * time.Second))
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", raw)
+		}
+		f.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package setting
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// ValidationError 配置校验失败的所有问题
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "  - " + strings.Join(e, "\n  - ")
+}
+
+// Validate 校验当前配置
+func Validate() error {
+	var errs ValidationError
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, key+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(AppSetting.JwtSecret != "", "app.JwtSecret", "is required, set it with %sAPP_JWT_SECRET", EnvPrefix)
+	check(AppSetting.PageSize > 0, "app.PageSize", "must be greater than 0, got %d", AppSetting.PageSize)
+	check(AppSetting.ApiVersion > 0, "app.ApiVersion", "must be greater than 0, got %d", AppSetting.ApiVersion)
+	check(AppSetting.RuntimeRootPath != "", "app.RuntimeRootPath", "is required")
+	check(AppSetting.ImageMaxSize > 0, "app.ImageMaxSize", "must be greater than 0")
+	for _, ext := range AppSetting.ImageAllowExts {
+		check(strings.HasPrefix(ext, "."), "app.ImageAllowExts", "%q must start with a dot", ext)
+	}
+	check(AppSetting.TimeFormat != "", "app.TimeFormat", "is required")
+
+	check(oneOf(ServerSetting.RunMode, "debug", "release", "test"), "server.RunMode", "must be one of debug, release, test, got %q", ServerSetting.RunMode)
+	check(ServerSetting.HttpPort > 0 && ServerSetting.HttpPort < 65536, "server.HttpPort", "must be between 1 and 65535, got %d", ServerSetting.HttpPort)
+	check(ServerSetting.ReadTimeout > 0, "server.ReadTimeout", "must be greater than 0")
+	check(ServerSetting.WriteTimeout > 0, "server.WriteTimeout", "must be greater than 0")
+	for _, proxy := range ServerSetting.TrustedProxies {
+		check(isIPOrCIDR(proxy), "server.TrustedProxies", "%q is not an IP or CIDR", proxy)
+	}
+
+	check(DatabaseSetting.Type != "", "database.Type", "is required")
+	check(DatabaseSetting.Host != "", "database.Host", "is required")
+	check(DatabaseSetting.Name != "", "database.Name", "is required")
+
+	check(RedisSetting.Host != "", "redis.Host", "is required")
+	check(RedisSetting.MaxIdle >= 0, "redis.MaxIdle", "must not be negative")
+	check(RedisSetting.MaxActive > 0, "redis.MaxActive", "must be greater than 0")
+
+	check(oneOf(RateLimitSetting.Store, "memory", "redis"), "ratelimit.Store", "must be one of memory, redis, got %q", RateLimitSetting.Store)
+	check(RateLimitSetting.LockoutThreshold >= 0, "ratelimit.LockoutThreshold", "must not be negative")
+	if RateLimitSetting.LockoutThreshold > 0 {
+		check(RateLimitSetting.LockoutDuration > 0, "ratelimit.LockoutDuration", "must be greater than 0")
+		check(RateLimitSetting.LockoutMaxDuration >= RateLimitSetting.LockoutDuration, "ratelimit.LockoutMaxDuration", "must not be less than LockoutDuration")
+	}
+
+	check(oneOf(SchedulerSetting.Lock, "mysql", "redis", "none"), "scheduler.Lock", "must be one of mysql, redis, none, got %q", SchedulerSetting.Lock)
+	check(SchedulerSetting.LockTTL >= 3*time.Second, "scheduler.LockTTL", "must be at least 3s, got %s", SchedulerSetting.LockTTL)
+	check(TrashSetting.Retention >= 0, "trash.Retention", "must not be negative")
+	check(WebhookSetting.Timeout > 0, "webhook.Timeout", "must be greater than 0")
+	check(WebhookSetting.MaxAttempts > 0, "webhook.MaxAttempts", "must be greater than 0, got %d", WebhookSetting.MaxAttempts)
+	check(WebhookSetting.RetryBase > 0, "webhook.RetryBase", "must be greater than 0")
+	check(WebhookSetting.RetryMax >= WebhookSetting.RetryBase, "webhook.RetryMax", "must not be less than RetryBase")
+	check(WebhookSetting.PollInterval > 0, "webhook.PollInterval", "must be greater than 0")
+	check(FeedSetting.Title != "", "feed.Title", "is required")
+	check(FeedSetting.Limit > 0 && FeedSetting.Limit <= 100, "feed.Limit", "must be between 1 and 100, got %d", FeedSetting.Limit)
+	check(FeedSetting.CacheTTL >= 0, "feed.CacheTTL", "must not be negative")
+	check(GraphQLSetting.MaxDepth > 0, "graphql.MaxDepth", "must be greater than 0, got %d", GraphQLSetting.MaxDepth)
+	check(GraphQLSetting.MaxComplexity > 0, "graphql.MaxComplexity", "must be greater than 0, got %d", GraphQLSetting.MaxComplexity)
+	check(PopularitySetting.HalfLife > 0, "popularity.HalfLife", "must be greater than 0")
+	check(PopularitySetting.LikeWeight >= 0, "popularity.LikeWeight", "must not be negative, got %d", PopularitySetting.LikeWeight)
+	check(PopularitySetting.MaxWindow >= 24*time.Hour, "popularity.MaxWindow", "must be at least 24h, got %s", PopularitySetting.MaxWindow)
+	check(RelatedSetting.Size > 0 && RelatedSetting.Size <= 50, "related.Size", "must be between 1 and 50, got %d", RelatedSetting.Size)
+	check(RelatedSetting.TagWeight >= 0, "related.TagWeight", "must not be negative, got %g", RelatedSetting.TagWeight)
+	check(RelatedSetting.Debounce >= 0, "related.Debounce", "must not be negative")
+	check(SiteSetting.OutputPath != "", "site.OutputPath", "is required")
+	check(SiteSetting.PageSize > 0, "site.PageSize", "must be greater than 0, got %d", SiteSetting.PageSize)
+	for key, origins := range map[string][]string{"cors.Api": CORSSetting.Api, "cors.Public": CORSSetting.Public} {
+		for _, origin := range origins {
+			check(validOrigin(origin), key, "%q must be * or look like https://example.com", origin)
+		}
+	}
+	check(CORSSetting.MaxAge >= 0, "cors.MaxAge", "must not be negative")
+	check(oneOf(SecuritySetting.FrameOptions, "", "DENY", "SAMEORIGIN"), "security.FrameOptions", "must be one of DENY, SAMEORIGIN or empty, got %q", SecuritySetting.FrameOptions)
+	check(SecuritySetting.HSTSMaxAge >= 0, "security.HSTSMaxAge", "must not be negative")
+	check(oneOf(SecuritySetting.CookieSameSite, "lax", "strict", "none"), "security.CookieSameSite", "must be one of lax, strict, none, got %q", SecuritySetting.CookieSameSite)
+	if OIDCSetting.Issuer != "" {
+		check(validURL(OIDCSetting.Issuer), "oidc.Issuer", "must look like https://sso.example.com, got %q", OIDCSetting.Issuer)
+		check(OIDCSetting.ClientID != "", "oidc.ClientID", "is required when oidc.Issuer is set")
+		check(validURL(OIDCSetting.RedirectURL), "oidc.RedirectURL", "must look like https://blog.example.com/auth/oidc/callback, got %q", OIDCSetting.RedirectURL)
+		check(contains(OIDCSetting.Scopes, "openid"), "oidc.Scopes", "must include openid")
+		check(OIDCSetting.UsernameClaim != "", "oidc.UsernameClaim", "is required when oidc.Issuer is set")
+	}
+	check(oneOf(TracingSetting.Exporter, "", "otlp", "stdout"), "tracing.Exporter", "must be one of otlp, stdout or empty, got %q", TracingSetting.Exporter)
+	if TracingSetting.Exporter == "otlp" {
+		check(validURL(TracingSetting.Endpoint), "tracing.Endpoint", "must look like http://127.0.0.1:4318, got %q", TracingSetting.Endpoint)
+	}
+	check(TracingSetting.ServiceName != "", "tracing.ServiceName", "is required")
+	check(TracingSetting.SampleRatio >= 0 && TracingSetting.SampleRatio <= 1, "tracing.SampleRatio", "must be between 0 and 1, got %g", TracingSetting.SampleRatio)
+
+	rv := reflect.ValueOf(JobsSetting).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if spec := rv.Field(i).String(); spec != "" {
+			_, err := cron.Parse(spec)
+			check(err == nil, "jobs."+rv.Type().Field(i).Name, "invalid cron expression %q: %v", spec, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// unknownKeys 返回不对应任何配置项的键，用于发现拼写错误
+func unknownKeys(v values) []string {
+	var unknown []string
+	for section, keys := range v {
+		target := sectionValue(section)
+		if target == nil {
+			unknown = append(unknown, "["+section+"]")
+			continue
+		}
+		fields := make(map[string]bool)
+		rt := reflect.TypeOf(target).Elem()
+		for i := 0; i < rt.NumField(); i++ {
+			fields[normalize(rt.Field(i).Name)] = true
+		}
+		for key := range keys {
+			if !fields[key] {
+				unknown = append(unknown, section+"."+key)
+			}
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+func sectionValue(name string) interface{} {
+	for _, s := range sections {
+		if s.Name == name {
+			return s.Value
+		}
+	}
+
+	return nil
+}
+
+func isIPOrCIDR(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(s)
+
+	return err == nil
+}
+
+// validOrigin 来源必须是 * 或不带路径的 scheme://host[:port]
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+// validURL 地址必须是 http 或 https 的绝对地址
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func oneOf(s string, options ...string) bool {
+	for _, o := range options {
+		if s == o {
+			return true
+		}
+	}
+
+	return false
+}
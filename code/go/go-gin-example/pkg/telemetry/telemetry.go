@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// NAME 本服务创建 span 时使用的 tracer 名称
+const NAME = "github.com/fzzv/go-gin-example"
+
+var (
+	tracer  trace.Tracer = noop.NewTracerProvider().Tracer(NAME)
+	enabled bool
+)
+
+func init() {
+	// 不导出时也透传请求中的 traceparent，下游仍能与上游串联
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Setup 按 [tracing] 创建导出 span 的 TracerProvider，返回的函数在退出前调用，导出尚未发送的 span
+func Setup() (shutdown func(context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+	switch setting.TracingSetting.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(setting.TracingSetting.Endpoint+"/v1/traces"))
+	default:
+		err = fmt.Errorf("unknown exporter %q", setting.TracingSetting.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(setting.TracingSetting.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(setting.TracingSetting.SampleRatio))),
+	)
+	Use(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Use 使用 provider 创建 span，测试中可传入记录 span 的 provider，返回的函数用于恢复之前的设置
+func Use(provider trace.TracerProvider) (restore func()) {
+	prevTracer, prevEnabled, prevProvider := tracer, enabled, otel.GetTracerProvider()
+	tracer, enabled = provider.Tracer(NAME), true
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		tracer, enabled = prevTracer, prevEnabled
+		otel.SetTracerProvider(prevProvider)
+	}
+}
+
+// Enabled 是否导出 span，未启用时数据库与 Redis 不创建 span
+func Enabled() bool {
+	return enabled
+}
+
+// Tracer 本服务的 tracer，未启用时创建的 span 不记录也不导出，但会沿用上游的 trace context
+func Tracer() trace.Tracer {
+	return tracer
+}
+
+// StartChild 在 ctx 所属的 trace 中创建子 span，ctx 不属于任何 trace 时不创建，返回 nil，
+// 避免后台任务的每次查询都成为单独的 trace
+func StartChild(ctx context.Context, name string, opts ...trace.SpanStartOption) trace.Span {
+	if !enabled || !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	_, span := tracer.Start(ctx, name, opts...)
+
+	return span
+}
@@ -6,6 +6,7 @@ import (
 	"mime/multipart"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/fzzv/go-gin-example/pkg/file"
@@ -40,6 +41,11 @@ func GetImageFullPath() string {
 	return setting.AppSetting.RuntimeRootPath + GetImagePath()
 }
 
+// 获取站点图片所在的子目录，站点上传的图片名以它开头
+func GetTenantDir(tenantID int) string {
+	return strconv.Itoa(tenantID) + "/"
+}
+
 // 检查图片扩展名
 func CheckImageExt(fileName string) bool {
 	ext := file.GetExt(fileName)
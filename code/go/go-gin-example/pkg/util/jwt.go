@@ -8,22 +8,36 @@ import (
 	"github.com/fzzv/go-gin-example/pkg/setting"
 )
 
-var jwtSecret = []byte(setting.AppSetting.JwtSecret)
+// jwtSecret 在使用时读取，配置加载完成前读取会得到空密钥
+func jwtSecret() []byte {
+	return []byte(setting.AppSetting.JwtSecret)
+}
+
+// TOKEN_EXPIRE token 的有效期，登录 Cookie 同时过期
+const TOKEN_EXPIRE = 3 * time.Hour
 
 type Claims struct {
+	TenantID int    `json:"tenant_id"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Role     string `json:"role"`
+	// 通过 API Key 鉴权时由 jwt 中间件设置，不会出现在 token 中
+	ApiKeyID int    `json:"-"`
+	Scope    string `json:"-"`
 	jwt.StandardClaims
 }
 
-func GenerateToken(username, password string) (string, error) {
+// GenerateToken 生成站点下账号的 token，token 只能用于签发它的站点
+func GenerateToken(tenantID int, username, password, role string) (string, error) {
 	nowTime := time.Now()
-	expireTime := nowTime.Add(3 * time.Hour)
+	expireTime := nowTime.Add(TOKEN_EXPIRE)
 
 	claims := Claims{
-		username,
-		password,
-		jwt.StandardClaims{
+		TenantID: tenantID,
+		Username: username,
+		Password: password,
+		Role:     role,
+		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expireTime.Unix(),
 			Issuer:    "gin-blog",
 		},
@@ -36,7 +50,7 @@ func GenerateToken(username, password string) (string, error) {
 	*/
 	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	// SignedString方法内部生成签名字符串，再用于获取完整、已签名的token
-	token, err := tokenClaims.SignedString(jwtSecret)
+	token, err := tokenClaims.SignedString(jwtSecret())
 
 	return token, err
 }
@@ -46,7 +60,7 @@ func GenerateToken(username, password string) (string, error) {
 */
 func ParseToken(token string) (*Claims, error) {
 	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		return jwtSecret(), nil
 	})
 
 	if tokenClaims != nil {
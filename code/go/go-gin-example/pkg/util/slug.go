@@ -0,0 +1,34 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SLUG_MAX_LEN slug 最多包含的字符数，留出追加 -2 等序号的空间
+const SLUG_MAX_LEN = 80
+
+// Slugify 将标题转换为 URL 中使用的 slug，保留各语言的字母与数字，其余字符替换为连字符
+func Slugify(s string) string {
+	var b strings.Builder
+	n := 0
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if n >= SLUG_MAX_LEN {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			n++
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			n++
+			dash = true
+		}
+	}
+
+	return strings.TrimRight(b.String(), "-")
+}
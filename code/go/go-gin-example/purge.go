@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+const purgeDeletedUsage = "usage: go-gin-example purge-deleted [--older-than duration]"
+
+// runPurgeDeleted 处理 purge-deleted 子命令，立即物理删除所有站点回收站中超过保留时长的标签与文章，与定时任务 clean_tags、clean_articles 相同
+func runPurgeDeleted(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("purge-deleted", flag.ExitOnError)
+	flags.bind(fs)
+	olderThan := fs.Duration("older-than", -1, "删除超过该时长的数据，默认为 [trash] Retention，0 为清空回收站")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, purgeDeletedUsage)
+		return 2
+	}
+
+	if _, err := flags.setup(false); err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	if *olderThan < 0 {
+		*olderThan = setting.TrashSetting.Retention
+	}
+	before := int(time.Now().Add(-*olderThan).Unix())
+
+	// 先删除文章，以便同时删除只被这些文章引用的标签
+	if _, err := models.CleanAllArticle(context.Background(), before); err != nil {
+		return fail(err)
+	}
+	if _, err := models.CleanAllTag(context.Background(), before); err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("purged tags and articles deleted before %s\n", time.Unix(int64(before), 0).Format(time.RFC3339))
+	return 0
+}
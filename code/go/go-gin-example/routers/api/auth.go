@@ -2,55 +2,107 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
-	"github.com/astaxie/beego/validation"
 	"github.com/gin-gonic/gin"
 
+	"github.com/fzzv/go-gin-example/middleware/csrf"
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/ratelimit"
+	"github.com/fzzv/go-gin-example/middleware/secure"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
 	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
 	"github.com/fzzv/go-gin-example/pkg/e"
-	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
 	"github.com/fzzv/go-gin-example/pkg/util"
 )
 
-type auth struct {
-	Username string `valid:"Required; MaxSize(50)"`
-	Password string `valid:"Required; MaxSize(50)"`
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/auth", Summary: "获取 token", Tag: "auth", Request: AuthForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/auth", Summary: "退出 Cookie 登录", Tag: "auth"},
+	)
+}
+
+type AuthForm struct {
+	Username string `form:"username" binding:"required,max=50"`
+	Password string `form:"password" binding:"required,max=50"`
+	// 为 true 时将 token 写入 HttpOnly Cookie，响应只返回 csrf_token，之后的写请求需在 X-CSRF-Token 请求头中携带它
+	Cookie bool `form:"cookie"`
 }
 
 func GetAuth(c *gin.Context) {
-	username := c.Query("username")
-	password := c.Query("password")
-
-	valid := validation.Validation{}
-	a := auth{Username: username, Password: password}
-	ok, _ := valid.Valid(&a)
-
-	data := make(map[string]interface{})
-	code := e.INVALID_PARAMS
-	if ok {
-		isExist := models.CheckAuth(username, password)
-		if isExist {
-			token, err := util.GenerateToken(username, password)
-			if err != nil {
-				code = e.ERROR_AUTH_TOKEN
-			} else {
-				data["token"] = token
-
-				code = e.SUCCESS
-			}
-
-		} else {
-			code = e.ERROR_AUTH
-		}
-	} else {
-		for _, err := range valid.Errors {
-			logging.Info(err.Key, err.Message)
+	var (
+		appG = app.Gin{C: c}
+		form AuthForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	// 不同站点可以有同名的账号，锁定按站点区分
+	tenantID := tenant.GetID(c)
+	lockKey := strconv.Itoa(tenantID) + ":" + form.Username
+	if d := ratelimit.Locked(c.Request.Context(), lockKey); d > 0 {
+		ratelimit.SetRetryAfter(c, d)
+		appG.Response(http.StatusTooManyRequests, e.ERROR_AUTH_LOCKED, nil)
+		return
+	}
+
+	auth, ok := models.CheckAuth(c.Request.Context(), tenantID, form.Username, form.Password)
+	if !ok {
+		if d := ratelimit.AuthFailed(c.Request.Context(), lockKey); d > 0 {
+			ratelimit.SetRetryAfter(c, d)
 		}
+		appG.Response(http.StatusUnauthorized, e.ERROR_AUTH, nil)
+		return
+	}
+	ratelimit.AuthSucceeded(c.Request.Context(), lockKey)
+
+	token, err := util.GenerateToken(tenantID, form.Username, form.Password, auth.Role)
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_TOKEN, nil)
+		return
+	}
+
+	if form.Cookie {
+		csrfToken := csrf.Token(token)
+		setCookies(c, token, csrfToken, int(util.TOKEN_EXPIRE.Seconds()))
+		appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
+			"csrf_token": csrfToken,
+		})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code": code,
-		"msg":  e.GetMsg(code),
-		"data": data,
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
+		"token": token,
 	})
 }
+
+// Logout 清除登录 Cookie，token 在过期前仍然有效
+func Logout(c *gin.Context) {
+	appG := app.Gin{C: c}
+	setCookies(c, "", "", -1)
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// setCookies 设置登录 Cookie 与 CSRF Cookie，maxAge 小于 0 时删除
+func setCookies(c *gin.Context, token, csrfToken string, maxAge int) {
+	switch setting.SecuritySetting.CookieSameSite {
+	case "strict":
+		c.SetSameSite(http.SameSiteStrictMode)
+	case "none":
+		c.SetSameSite(http.SameSiteNoneMode)
+	default:
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+
+	https := secure.IsHTTPS(c)
+	c.SetCookie(jwt.COOKIE_NAME, token, maxAge, "/", "", https, true)
+	c.SetCookie(csrf.COOKIE_NAME, csrfToken, maxAge, "/", "", https, false)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service/feed_service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+func init() {
+	for _, format := range []string{feed_service.FORMAT_RSS, feed_service.FORMAT_ATOM, feed_service.FORMAT_JSON} {
+		openapi.Register(
+			openapi.Operation{Method: http.MethodGet, Path: "/feed." + format, Summary: "获取最新文章的订阅源", Tag: "feed"},
+			openapi.Operation{Method: http.MethodGet, Path: "/tags/:id/feed." + format, Summary: "获取标签下最新文章的订阅源", Tag: "feed", Request: FeedForm{}},
+		)
+	}
+}
+
+type FeedForm struct {
+	TagID int `uri:"id" form:"-" json:"-" binding:"omitempty,min=1"`
+}
+
+// GetFeed 输出已发布文章的订阅源，格式由路由的扩展名决定，支持 If-None-Match 与 If-Modified-Since
+func GetFeed(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form FeedForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	if form.TagID > 0 {
+		tagService := tag_service.Tag{TenantID: tenant.GetID(c), ID: form.TagID}
+		exists, err := tagService.ExistByID(c.Request.Context())
+		if err != nil {
+			appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
+			return
+		}
+		if !exists {
+			appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TAG, nil)
+			return
+		}
+	}
+
+	feedService := feed_service.Feed{
+		Tenant: tenant.Get(c),
+		TagID:  form.TagID,
+		Format: strings.TrimPrefix(path.Ext(c.FullPath()), "."),
+	}
+	output, err := feedService.Get(c.Request.Context())
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_FEED_FAIL, nil)
+		return
+	}
+
+	lastModified := time.Unix(output.LastModified, 0).UTC()
+	c.Header("ETag", output.ETag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(setting.FeedSetting.CacheTTL/time.Second)))
+
+	if app.NotModified(c.Request, output.ETag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, output.ContentType, output.Body)
+}
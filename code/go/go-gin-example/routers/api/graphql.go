@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/service/graphql_service"
+)
+
+func init() {
+	openapi.Register(openapi.Operation{Method: http.MethodPost, Path: "/graphql", Summary: "执行 GraphQL 查询或修改", Tag: "graphql", Auth: true, Request: GraphQLForm{}})
+}
+
+type GraphQLForm struct {
+	Query         string                 `form:"query" json:"query" binding:"required,max=10000"`
+	OperationName string                 `form:"operationName" json:"operationName" binding:"max=100"`
+	Variables     map[string]interface{} `form:"variables" json:"variables"`
+}
+
+// GraphQL 在一次请求中查询标签、文章与作者，或执行修改。
+// 请求参数有误时返回统一的 code / msg / data，其余情况按 GraphQL 规范返回 data 与 errors，业务码在 errors[].extensions.code 中
+func GraphQL(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GraphQLForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	viewer := &graphql_service.Viewer{
+		TenantID: tenant.GetID(c),
+		Username: jwt.GetUsername(c),
+		ReadOnly: !jwt.HasScope(c, models.SCOPE_ARTICLES_WRITE),
+		IP:       c.ClientIP(),
+		Lang:     app.Lang(c),
+	}
+	result := graphql_service.Do(c.Request.Context(), viewer, graphql_service.Request{
+		Query:         form.Query,
+		OperationName: form.OperationName,
+		Variables:     form.Variables,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
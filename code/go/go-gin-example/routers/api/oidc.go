@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/csrf"
+	"github.com/fzzv/go-gin-example/middleware/secure"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/audit_service"
+	"github.com/fzzv/go-gin-example/service/oidc_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/auth/oidc/login", Summary: "跳转到身份提供方登录", Tag: "auth", Request: OIDCLoginForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/auth/oidc/callback", Summary: "身份提供方登录回调", Tag: "auth", Request: OIDCCallbackForm{}},
+	)
+}
+
+// oidcCookiePath STATE_COOKIE 只在 OIDC 登录的接口中发送
+const oidcCookiePath = "/auth/oidc"
+
+type OIDCLoginForm struct {
+	// 登录成功后跳回的站内路径，如 /admin，不是站内路径时跳回 /
+	Redirect string `form:"redirect" binding:"max=2000"`
+}
+
+// OIDCLogin 开始 OIDC 登录，跳转到身份提供方，登录状态保存在 oidc_state Cookie 中
+func OIDCLogin(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form OIDCLoginForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	login, authURL, err := oidc_service.Begin(c.Request.Context(), tenant.GetID(c), form.Redirect)
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	value, err := login.Encode()
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR, nil)
+		return
+	}
+
+	// 从身份提供方跳回是跨站的顶层导航，SameSite 必须是 Lax 才会带上该 Cookie
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidc_service.STATE_COOKIE, value, int(oidc_service.STATE_TTL.Seconds()), oidcCookiePath, "", secure.IsHTTPS(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+type OIDCCallbackForm struct {
+	Code             string `form:"code" binding:"max=2000"`
+	State            string `form:"state" binding:"max=200"`
+	Error            string `form:"error" binding:"max=200"`
+	ErrorDescription string `form:"error_description" binding:"max=2000"`
+}
+
+// OIDCCallback 身份提供方登录后的回调，签发 token 写入登录 Cookie 后跳回登录时指定的路径
+func OIDCCallback(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form OIDCCallbackForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	// state 只能使用一次
+	value, _ := c.Cookie(oidc_service.STATE_COOKIE)
+	c.SetCookie(oidc_service.STATE_COOKIE, "", -1, oidcCookiePath, "", secure.IsHTTPS(c), true)
+	login, ok := oidc_service.Decode(value)
+	if !ok || form.State == "" || form.State != login.State || login.TenantID != tenant.GetID(c) {
+		appG.Response(http.StatusBadRequest, e.ERROR_OIDC_STATE, nil)
+		return
+	}
+	if form.Error != "" {
+		logging.Warn("oidc", form.Error, form.ErrorDescription)
+		appG.Response(http.StatusForbidden, e.ERROR_OIDC_DENIED, nil)
+		return
+	}
+	if form.Code == "" {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+
+	auth, created, err := login.Finish(c.Request.Context(), form.Code)
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	if created {
+		if err := audit_service.Record(c.Request.Context(), auth.TenantID, auth.Username, c.ClientIP(), models.AUDIT_CREATE, "user", auth.Username, nil, map[string]string{"role": auth.Role, "subject": auth.Subject}); err != nil {
+			logging.Error("audit", models.AUDIT_CREATE, "user", auth.Username, err)
+		}
+	}
+
+	token, err := util.GenerateToken(auth.TenantID, auth.Username, "", auth.Role)
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_TOKEN, nil)
+		return
+	}
+
+	setCookies(c, token, csrf.Token(token), int(util.TOKEN_EXPIRE.Seconds()))
+	c.Redirect(http.StatusFound, login.Redirect)
+}
+
+// oidcError 按 oidc_service 返回的错误响应
+func oidcError(c *gin.Context, err error) {
+	appG := app.Gin{C: c}
+	switch {
+	case errors.Is(err, oidc_service.ErrDisabled):
+		appG.Response(http.StatusNotFound, e.ERROR_OIDC_DISABLED, nil)
+	case errors.Is(err, oidc_service.ErrProvider):
+		logging.Error("oidc", err)
+		appG.Response(http.StatusBadGateway, e.ERROR_OIDC_PROVIDER, nil)
+	case errors.Is(err, oidc_service.ErrIDToken):
+		logging.Warn("oidc", err)
+		appG.Response(http.StatusUnauthorized, e.ERROR_OIDC_ID_TOKEN, nil)
+	case errors.Is(err, oidc_service.ErrNoRole):
+		appG.Response(http.StatusForbidden, e.ERROR_OIDC_NO_ROLE, nil)
+	case errors.Is(err, oidc_service.ErrNoUser):
+		appG.Response(http.StatusForbidden, e.ERROR_OIDC_NO_USER, nil)
+	case errors.Is(err, oidc_service.ErrNotBound):
+		appG.Response(http.StatusForbidden, e.ERROR_OIDC_NOT_BOUND, nil)
+	case errors.Is(err, oidc_service.ErrUserMismatch):
+		appG.Response(http.StatusConflict, e.ERROR_OIDC_USER_MISMATCH, nil)
+	default:
+		logging.Error("oidc", err)
+		appG.Response(http.StatusInternalServerError, e.ERROR, nil)
+	}
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+)
+
+// GetOpenAPI 返回由已注册接口生成的 OpenAPI 3 文档
+func GetOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, openapi.Document())
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/service/sitemap_service"
+)
+
+func init() {
+	openapi.Register(openapi.Operation{Method: http.MethodGet, Path: "/sitemap.xml", Summary: "获取站点地图", Tag: "public"})
+}
+
+// GetSitemap 输出包含站点所有已发布文章的站点地图
+func GetSitemap(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	data, err := sitemap_service.Get(c.Request.Context(), tenant.Get(c))
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_SITEMAP_FAIL, nil)
+		return
+	}
+
+	c.Data(http.StatusOK, sitemap_service.CONTENT_TYPE, data)
+}
@@ -1,64 +1,80 @@
 package api
 
 import (
+	"mime/multipart"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/pkg/app"
 	"github.com/fzzv/go-gin-example/pkg/e"
 	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
 	"github.com/fzzv/go-gin-example/pkg/upload"
 )
 
+func init() {
+	openapi.Register(openapi.Operation{Method: http.MethodPost, Path: "/upload", Summary: "上传图片", Tag: "upload", Request: UploadImageForm{}})
+}
+
+type UploadImageForm struct {
+	Image *multipart.FileHeader `form:"image" binding:"required"`
+}
+
 func UploadImage(c *gin.Context) {
-	code := e.SUCCESS
-	data := make(map[string]string)
+	var (
+		appG = app.Gin{C: c}
+		form UploadImageForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
 
 	// 获取上传的文件
-	file, image, err := c.Request.FormFile("image")
+	image := form.Image
+	file, err := image.Open()
 	if err != nil {
 		logging.Warn(err)
-		code = e.ERROR
-		c.JSON(http.StatusOK, gin.H{
-			"code": code,
-			"msg":  e.GetMsg(code),
-			"data": data,
-		})
+		appG.Response(http.StatusInternalServerError, e.ERROR, nil)
+		return
 	}
+	defer file.Close()
 
-	if image == nil {
-		code = e.INVALID_PARAMS
-	} else {
-		imageName := upload.GetImageName(image.Filename)
-		fullPath := upload.GetImageFullPath()
-		savePath := upload.GetImagePath()
+	// 图片按站点保存在不同的子目录中
+	tenantDir := upload.GetTenantDir(tenant.GetID(c))
+	imageName := tenantDir + upload.GetImageName(image.Filename)
+	fullPath := upload.GetImageFullPath()
+	savePath := upload.GetImagePath()
 
-		// 获取图片完整保存路径
-		src := fullPath + imageName
+	// 获取图片完整保存路径
+	src := fullPath + imageName
 
-		// 检查图片扩展名和大小
-		if !upload.CheckImageExt(imageName) || !upload.CheckImageSize(file) {
-			code = e.ERROR_UPLOAD_CHECK_IMAGE_FORMAT
-		} else {
-			// 检查图片是否存在
-			err := upload.CheckImage(fullPath)
-			if err != nil {
-				logging.Warn(err)
-				code = e.ERROR_UPLOAD_CHECK_IMAGE_FAIL
-				// SaveUploadedFile 保存图片到指定路径
-			} else if err := c.SaveUploadedFile(image, src); err != nil {
-				logging.Warn(err)
-				code = e.ERROR_UPLOAD_SAVE_IMAGE_FAIL
-			} else {
-				data["image_url"] = upload.GetImageFullUrl(imageName)
-				data["image_save_url"] = savePath + imageName
-			}
-		}
+	// 检查图片扩展名和大小
+	if !upload.CheckImageExt(imageName) || !upload.CheckImageSize(file) {
+		appG.Response(http.StatusBadRequest, e.ERROR_UPLOAD_CHECK_IMAGE_FORMAT, nil)
+		return
+	}
+
+	// 检查图片是否存在
+	if err := upload.CheckImage(fullPath + tenantDir); err != nil {
+		logging.Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_UPLOAD_CHECK_IMAGE_FAIL, nil)
+		return
+	}
+
+	// SaveUploadedFile 保存图片到指定路径
+	if err := c.SaveUploadedFile(image, src); err != nil {
+		logging.Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_UPLOAD_SAVE_IMAGE_FAIL, nil)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code": code,
-		"msg":  e.GetMsg(code),
-		"data": data,
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
+		"image_url":      upload.GetImageFullUrl(imageName),
+		"image_save_url": savePath + imageName,
 	})
 }
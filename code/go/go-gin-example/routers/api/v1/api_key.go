@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/service/api_key_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/api-keys", Summary: "获取 API Key 列表", Tag: "api-key", Auth: true, Request: GetApiKeysForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/api-keys", Summary: "新建 API Key", Tag: "api-key", Auth: true, Request: AddApiKeyForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/api-keys/:id", Summary: "吊销 API Key", Tag: "api-key", Auth: true, Request: ApiKeyIDForm{}},
+	)
+}
+
+type GetApiKeysForm struct {
+	Username string `form:"username" binding:"max=50"`
+}
+
+// 获取当前账号的 API Key，admin 可以通过 username 查看其他账号的，不指定时查看站点下所有的；不返回 key 本身
+func GetApiKeys(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetApiKeysForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	claims := jwt.GetClaims(c)
+	username := claims.Username
+	if claims.Role == models.ROLE_ADMIN {
+		username = form.Username
+	} else if form.Username != "" && form.Username != claims.Username {
+		appG.Response(http.StatusForbidden, e.ERROR_AUTH_FORBIDDEN, nil)
+		return
+	}
+
+	apiKeyService := api_key_service.ApiKey{TenantID: tenant.GetID(c), Username: username}
+	keys, err := apiKeyService.GetAll(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_API_KEYS_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists":  keys,
+		"total":  len(keys),
+		"scopes": api_key_service.Scopes,
+	})
+}
+
+type AddApiKeyForm struct {
+	Name      string `form:"name" json:"name" binding:"required,max=100"`
+	Scope     string `form:"scope" json:"scope" binding:"required,oneof=read articles:write admin"`
+	ExpiresIn int    `form:"expires_in" json:"expires_in" binding:"min=0,max=3650"`
+}
+
+// 为当前账号新建 API Key，expires_in 为有效天数，0 表示不过期；admin 权限范围只能由 admin 创建，key 只在此时返回
+func AddApiKey(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form AddApiKeyForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	claims := jwt.GetClaims(c)
+	if form.Scope == models.SCOPE_ADMIN && claims.Role != models.ROLE_ADMIN {
+		appG.Response(http.StatusForbidden, e.ERROR_AUTH_FORBIDDEN, nil)
+		return
+	}
+
+	apiKeyService := api_key_service.ApiKey{
+		TenantID: tenant.GetID(c),
+		Username: claims.Username,
+		Name:     form.Name,
+		Scope:    form.Scope,
+	}
+	if form.ExpiresIn > 0 {
+		apiKeyService.ExpiresOn = int(time.Now().AddDate(0, 0, form.ExpiresIn).Unix())
+	}
+	apiKey, key, err := apiKeyService.Add(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_API_KEY_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_CREATE, "api_key", apiKey.ID, nil, apiKey)
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"api_key": apiKey,
+		"key":     key,
+	})
+}
+
+type ApiKeyIDForm struct {
+	ID int `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+}
+
+// 吊销 API Key，只能吊销自己的，admin 可以吊销站点下所有账号的
+func DeleteApiKey(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form ApiKeyIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	apiKeyService := api_key_service.ApiKey{TenantID: tenant.GetID(c), ID: form.ID}
+	before, err := apiKeyService.Get(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_API_KEYS_FAIL, nil)
+		return
+	}
+	claims := jwt.GetClaims(c)
+	// 不暴露其他账号的 key 是否存在
+	if before.ID == 0 || (before.Username != claims.Username && claims.Role != models.ROLE_ADMIN) {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_API_KEY, nil)
+		return
+	}
+
+	if err := apiKeyService.Delete(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_DELETE_API_KEY_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_DELETE, "api_key", form.ID, before, nil)
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
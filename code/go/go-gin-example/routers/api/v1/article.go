@@ -1,93 +1,139 @@
 package v1
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
+	"time"
 
-	"github.com/astaxie/beego/validation"
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
 	"github.com/fzzv/go-gin-example/pkg/app"
 	"github.com/fzzv/go-gin-example/pkg/e"
-	"github.com/fzzv/go-gin-example/pkg/export"
 	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
 	"github.com/fzzv/go-gin-example/pkg/setting"
 	"github.com/fzzv/go-gin-example/pkg/util"
 	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/series_service"
 	"github.com/fzzv/go-gin-example/service/tag_service"
 	"github.com/gin-gonic/gin"
-	"github.com/unknwon/com"
 )
 
-// 获取单个文章
-func GetArticle(c *gin.Context) {
-	appG := app.Gin{C: c}
-	id := com.StrTo(c.Param("id")).MustInt()
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/articles", Summary: "获取文章列表", Tag: "article", Auth: true, Request: GetArticlesForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/articles/:id", Summary: "获取指定文章", Tag: "article", Auth: true, Request: ArticleIDForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/articles", Summary: "新建文章", Tag: "article", Auth: true, Request: AddArticleForm{}},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/articles/:id", Summary: "更新指定文章", Tag: "article", Auth: true, Request: EditArticleForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/articles/:id", Summary: "删除指定文章", Tag: "article", Auth: true, Request: ArticleIDForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/articles/:id/restore", Summary: "从回收站恢复文章", Tag: "article", Auth: true, Request: ArticleIDForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/articles/export", Summary: "导出文章", Tag: "article", Auth: true},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/articles/import", Summary: "导入文章", Tag: "article", Auth: true, Request: ImportForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/articles/:id/related", Summary: "获取相关文章", Tag: "article", Auth: true, Request: ArticleIDForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/articles/popular", Summary: "获取热门文章", Tag: "article", Auth: true, Request: GetPopularArticlesForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/articles/:id/like", Summary: "点赞文章", Tag: "article", Auth: true, Request: ArticleIDForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/articles/:id/like", Summary: "取消点赞", Tag: "article", Auth: true, Request: ArticleIDForm{}},
+	)
+}
 
-	valid := validation.Validation{}
-	valid.Min(id, 1, "id").Message("ID必须大于0")
+type ArticleIDForm struct {
+	ID int `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+}
 
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
+// 获取单个文章
+func GetArticle(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form ArticleIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
 		return
 	}
-	articleService := article_service.Article{ID: id}
-	exists, err := articleService.ExistByID()
+
+	articleService := article_service.Article{TenantID: tenant.GetID(c), ID: form.ID}
+	exists, err := articleService.ExistByID(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
 		return
 	}
 	if !exists {
-		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE, nil)
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
 		return
 	}
+	articleService.View(c.Request.Context())
 
-	article, err := articleService.Get()
+	article, err := articleService.Get(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_GET_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLE_FAIL, nil)
+		return
+	}
+	// 系列导航随系列中其他文章变化，不随文章缓存，获取失败时只写日志
+	if article.Series, err = series_service.Nav(c.Request.Context(), articleService.TenantID, articleService.ID); err != nil {
+		logging.Warn(err)
+	}
+
+	// ETag 为文章的版本号，修改时通过 If-Match 带回以避免覆盖他人的修改
+	etag := app.VersionETag(article.Version)
+	c.Header("ETag", etag)
+	if app.NotModified(c.Request, etag, time.Time{}) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
 	appG.Response(http.StatusOK, e.SUCCESS, article)
 }
 
+type GetArticlesForm struct {
+	TagID *int `form:"tag_id" binding:"omitempty,min=1"`
+	State *int `form:"state" binding:"omitempty,oneof=0 1"`
+	Page  int  `form:"page" binding:"omitempty,min=1"`
+}
+
 // 获取多个文章
 func GetArticles(c *gin.Context) {
-	appG := app.Gin{C: c}
-	valid := validation.Validation{}
+	var (
+		appG = app.Gin{C: c}
+		form GetArticlesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
 
 	state := -1
-	if arg := c.PostForm("state"); arg != "" {
-		state = com.StrTo(arg).MustInt()
-		valid.Range(state, 0, 1, "state").Message("状态只允许0或1")
+	if form.State != nil {
+		state = *form.State
 	}
 
 	tagId := -1
-	if arg := c.PostForm("tag_id"); arg != "" {
-		tagId = com.StrTo(arg).MustInt()
-		valid.Min(tagId, 1, "tag_id").Message("标签ID必须大于0")
-	}
-
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
-		return
+	if form.TagID != nil {
+		tagId = *form.TagID
 	}
 
 	articleService := article_service.Article{
+		TenantID: tenant.GetID(c),
 		TagID:    tagId,
 		State:    state,
 		PageNum:  util.GetPage(c),
 		PageSize: setting.AppSetting.PageSize,
 	}
 
-	total, err := articleService.Count()
+	total, err := articleService.Count(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_COUNT_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_COUNT_ARTICLE_FAIL, nil)
 		return
 	}
 
-	articles, err := articleService.GetAll()
+	articles, err := articleService.GetAll(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_GET_ARTICLES_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLES_FAIL, nil)
 		return
 	}
 
@@ -98,202 +144,460 @@ func GetArticles(c *gin.Context) {
 	appG.Response(http.StatusOK, e.SUCCESS, data)
 }
 
-// @Summary 新增文章标签
-// @Produce  json
-// @Param name query string true "Name"
-// @Param state query int false "State"
-// @Param created_by query int false "CreatedBy"
-// @Success 200 {string} json "{"code":200,"data":{},"msg":"ok"}"
-// @Router /api/v1/tags [post]
+type AddArticleForm struct {
+	TagID         int    `form:"tag_id" json:"tag_id" binding:"required,min=1"`
+	Title         string `form:"title" json:"title" binding:"required,max=100"`
+	Slug          string `form:"slug" json:"slug" binding:"omitempty,max=100"`
+	Desc          string `form:"desc" json:"desc" binding:"required,max=255"`
+	Content       string `form:"content" json:"content" binding:"required,max=65535"`
+	CoverImageUrl string `form:"cover_image_url" json:"cover_image_url" binding:"required,max=255"`
+	State         int    `form:"state" json:"state" binding:"oneof=0 1"`
+}
+
+// 新增文章
 func AddArticle(c *gin.Context) {
-	appG := app.Gin{C: c}
-	tagId := com.StrTo(c.PostForm("tag_id")).MustInt()
-	title := c.PostForm("title")
-	desc := c.PostForm("desc")
-	content := c.PostForm("content")
-	createdBy := c.PostForm("created_by")
-	coverImageUrl := c.PostForm("cover_image_url")
-	state := com.StrTo(c.DefaultPostForm("state", "0")).MustInt()
-
-	valid := validation.Validation{}
-	valid.Min(tagId, 1, "tag_id").Message("标签ID必须大于0")
-	valid.Required(title, "title").Message("标题不能为空")
-	valid.Required(desc, "desc").Message("简述不能为空")
-	valid.Required(content, "content").Message("内容不能为空")
-	valid.Required(createdBy, "created_by").Message("创建人不能为空")
-	valid.Required(coverImageUrl, "cover_image_url").Message("封面地址不能为空")
-	valid.Range(state, 0, 1, "state").Message("状态只允许0或1")
-
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
-		return
-	}
-
-	tagService := tag_service.Tag{ID: tagId}
-	exists, err := tagService.ExistByID()
+	var (
+		appG = app.Gin{C: c}
+		form AddArticleForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	tagService := tag_service.Tag{TenantID: tenant.GetID(c), ID: form.TagID}
+	exists, err := tagService.ExistByID(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EXIST_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
 		return
 	}
 
 	if !exists {
-		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_TAG, nil)
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TAG, nil)
+		return
+	}
+
+	slug, ok := checkSlug(appG, form.Slug, 0)
+	if !ok {
 		return
 	}
 
 	articleService := article_service.Article{
-		TagID:         tagId,
-		Title:         title,
-		Desc:          desc,
-		Content:       content,
-		CoverImageUrl: coverImageUrl,
-		State:         state,
+		TenantID:      tenant.GetID(c),
+		TagID:         form.TagID,
+		Title:         form.Title,
+		Slug:          slug,
+		Desc:          form.Desc,
+		Content:       form.Content,
+		CoverImageUrl: form.CoverImageUrl,
+		State:         form.State,
+		CreatedBy:     jwt.GetUsername(c),
 	}
-	if err := articleService.Add(); err != nil {
-		appG.Response(http.StatusOK, e.ERROR_ADD_ARTICLE_FAIL, nil)
+	article, err := articleService.Add(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_ARTICLE_FAIL, nil)
 		return
 	}
+	audit(c, models.AUDIT_CREATE, "article", article.ID, nil, article)
 
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
 
+type EditArticleForm struct {
+	ID            int    `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	TagID         int    `form:"tag_id" json:"tag_id" binding:"required,min=1"`
+	Title         string `form:"title" json:"title" binding:"required,max=100"`
+	Slug          string `form:"slug" json:"slug" binding:"omitempty,max=100"`
+	Desc          string `form:"desc" json:"desc" binding:"required,max=255"`
+	Content       string `form:"content" json:"content" binding:"max=65535"`
+	CoverImageUrl string `form:"cover_image_url" json:"cover_image_url" binding:"required,max=255"`
+	State         *int   `form:"state" json:"state" binding:"omitempty,oneof=0 1"`
+}
+
 // 修改文章
 func EditArticle(c *gin.Context) {
-	appG := app.Gin{C: c}
-	valid := validation.Validation{}
-	id := com.StrTo(c.Param("id")).MustInt()
-	tagId := com.StrTo(c.PostForm("tag_id")).MustInt()
-	title := c.PostForm("title")
-	desc := c.PostForm("desc")
-	content := c.PostForm("content")
-	coverImageUrl := c.PostForm("cover_image_url")
-	modifiedBy := c.PostForm("modified_by")
+	var (
+		appG = app.Gin{C: c}
+		form EditArticleForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
 
 	state := -1
-	if arg := c.PostForm("state"); arg != "" {
-		state = com.StrTo(arg).MustInt()
-		valid.Range(state, 0, 1, "state").Message("状态只允许0或1")
+	if form.State != nil {
+		state = *form.State
 	}
 
-	valid.Min(id, 1, "id").Message("ID必须大于0")
-	valid.Min(tagId, 1, "tag_id").Message("标签ID必须大于0")
-	valid.MaxSize(title, 100, "title").Message("标题最长为100字符")
-	valid.Required(title, "title").Message("标题不能为空")
-	valid.MaxSize(desc, 255, "desc").Message("简述最长为255字符")
-	valid.Required(desc, "desc").Message("简述不能为空")
-	valid.MaxSize(content, 65535, "content").Message("内容最长为65535字符")
-	valid.Required(modifiedBy, "modified_by").Message("修改人不能为空")
-	valid.Required(coverImageUrl, "cover_image_url").Message("封面地址不能为空")
-	valid.MaxSize(coverImageUrl, 255, "cover_image_url").Message("封面地址最长为255字符")
-	valid.MaxSize(modifiedBy, 100, "modified_by").Message("修改人最长为100字符")
-
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
+	slug, ok := checkSlug(appG, form.Slug, form.ID)
+	if !ok {
 		return
 	}
 
 	articleService := article_service.Article{
-		ID:            id,
-		Title:         title,
-		Desc:          desc,
-		Content:       content,
-		CoverImageUrl: coverImageUrl,
-		ModifiedBy:    modifiedBy,
-	}
-	exists, err := articleService.ExistByID()
+		TenantID:      tenant.GetID(c),
+		ID:            form.ID,
+		TagID:         form.TagID,
+		Title:         form.Title,
+		Slug:          slug,
+		Desc:          form.Desc,
+		Content:       form.Content,
+		CoverImageUrl: form.CoverImageUrl,
+		State:         state,
+		ModifiedBy:    jwt.GetUsername(c),
+	}
+	before, err := articleService.Load(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
 		return
 	}
-	if !exists {
-		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE, nil)
+	if before.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
 		return
 	}
 
-	tagService := tag_service.Tag{ID: tagId}
-	exists, err = tagService.ExistByID()
+	version, ok := checkIfMatch(appG, before.Version)
+	if !ok {
+		return
+	}
+	articleService.Version = version
+
+	tagService := tag_service.Tag{TenantID: tenant.GetID(c), ID: form.TagID}
+	exists, err := tagService.ExistByID(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EXIST_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
 		return
 	}
 
 	if !exists {
-		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_TAG, nil)
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TAG, nil)
 		return
 	}
 
-	err = articleService.Edit()
+	err = articleService.Edit(c.Request.Context())
+	if errors.Is(err, models.ErrVersionConflict) {
+		current, err := articleService.Load(c.Request.Context())
+		if err != nil {
+			logging.Error("reload after version conflict", "article", form.ID, err)
+			appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_ARTICLE_FAIL, nil)
+			return
+		}
+		versionConflict(appG, current.Version)
+		return
+	}
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EDIT_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_ARTICLE_FAIL, nil)
 		return
 	}
+	after, err := articleService.Load(c.Request.Context())
+	if err != nil {
+		logging.Error("reload after edit article", form.ID, err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_ARTICLE_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "article", form.ID, before, after)
 
-	appG.Response(http.StatusOK, e.SUCCESS, nil)
+	versionOK(appG, after.Version)
+}
+
+// checkSlug 规范化客户端指定的 slug 并检查是否已被 id 以外的文章使用，未指定时返回空字符串，失败时直接写入响应
+func checkSlug(appG app.Gin, raw string, id int) (string, bool) {
+	if raw == "" {
+		return "", true
+	}
+
+	slug := util.Slugify(raw)
+	if slug == "" {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"slug": "must contain letters or digits"})
+		return "", false
+	}
+
+	articleService := article_service.Article{TenantID: tenant.GetID(appG.C), ID: id, Slug: slug}
+	exists, err := articleService.ExistBySlug(appG.C.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		return "", false
+	}
+	if exists {
+		appG.Response(http.StatusConflict, e.ERROR_EXIST_ARTICLE_SLUG, nil)
+		return "", false
+	}
+
+	return slug, true
 }
 
 // 删除文章
 func DeleteArticle(c *gin.Context) {
-	appG := app.Gin{C: c}
-	valid := validation.Validation{}
-	id := com.StrTo(c.Param("id")).MustInt()
-	valid.Min(id, 1, "id").Message("ID必须大于0")
+	var (
+		appG = app.Gin{C: c}
+		form ArticleIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	articleService := article_service.Article{TenantID: tenant.GetID(c), ID: form.ID}
+	before, err := articleService.Load(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		return
+	}
+	if before.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
+		return
+	}
+
+	err = articleService.Delete(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_DELETE_ARTICLE_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_DELETE, "article", form.ID, before, nil)
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// 从回收站恢复文章，所属标签也已删除时需先恢复标签
+func RestoreArticle(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form ArticleIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
 
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
+	articleService := article_service.Article{TenantID: tenant.GetID(c), ID: form.ID}
+	article, err := articleService.GetDeleted(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		return
+	}
+	if article.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
 		return
 	}
 
-	articleService := article_service.Article{ID: id}
-	exists, err := articleService.ExistByID()
+	tagService := tag_service.Tag{TenantID: tenant.GetID(c), ID: article.TagID}
+	exists, err := tagService.ExistByID(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
 		return
 	}
 	if !exists {
-		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE, nil)
+		appG.Response(http.StatusConflict, e.ERROR_ARTICLE_TAG_DELETED, nil)
 		return
 	}
 
-	err = articleService.Delete()
+	if err := articleService.Restore(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_RESTORE_ARTICLE_FAIL, nil)
+		return
+	}
+	after, err := articleService.Load(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_DELETE_ARTICLE_FAIL, nil)
+		logging.Error("reload after restore article", form.ID, err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_RESTORE_ARTICLE_FAIL, nil)
 		return
 	}
+	audit(c, models.AUDIT_RESTORE, "article", form.ID, article, after)
 
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
 
+// 导出文章
 func ExportArticle(c *gin.Context) {
 	appG := app.Gin{C: c}
-	articleService := article_service.Article{}
-	filename, err := articleService.Export()
-	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EXPORT_ARTICLE_FAIL, nil)
+	articleService := article_service.Article{TenantID: tenant.GetID(c)}
+	var buf bytes.Buffer
+	if err := articleService.Export(c.Request.Context(), &buf); err != nil {
+		logging.Error("export articles", err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXPORT_ARTICLE_FAIL, nil)
 		return
 	}
-	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
-		"export_url":      export.GetExcelFullUrl(filename),
-		"export_save_url": export.GetExcelPath() + filename,
-	})
+	audit(c, models.AUDIT_EXPORT, "article", nil, nil, nil)
+
+	attachment(c, "articles", &buf)
 }
 
+// 导入文章
 func ImportArticle(c *gin.Context) {
-	appG := app.Gin{C: c}
-	file, _, err := c.Request.FormFile("file")
+	var (
+		appG = app.Gin{C: c}
+		form ImportForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	file, err := form.File.Open()
 	if err != nil {
 		logging.Warn(err)
-		appG.Response(http.StatusOK, e.ERROR, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR, nil)
 		return
 	}
-	articleService := article_service.Article{}
-	err = articleService.Import(file)
+	defer file.Close()
+
+	articleService := article_service.Article{TenantID: tenant.GetID(c), CreatedBy: jwt.GetUsername(c)}
+	count, err := articleService.Import(c.Request.Context(), file)
 	if err != nil {
 		logging.Warn(err)
-		appG.Response(http.StatusOK, e.ERROR_IMPORT_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_IMPORT_ARTICLE_FAIL, nil)
 		return
 	}
+	audit(c, models.AUDIT_IMPORT, "article", nil, nil, map[string]interface{}{"filename": form.File.Filename, "count": count})
+
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
+
+// 获取与指定文章相关的已发布文章，按相关度从高到低排列
+func GetRelatedArticles(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form ArticleIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	articleService := article_service.Article{TenantID: tenant.GetID(c), ID: form.ID}
+	exists, err := articleService.ExistByID(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		return
+	}
+	if !exists {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
+		return
+	}
+
+	articles, err := articleService.GetRelated(c.Request.Context())
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_RELATED_ARTICLES_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": articles,
+	})
+}
+
+type GetPopularArticlesForm struct {
+	Window string `form:"window" binding:"omitempty,max=10"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// 获取热门文章，window 为统计的时间范围，默认 7d，limit 默认为 app.PageSize
+func GetPopularArticles(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetPopularArticlesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	if form.Window == "" {
+		form.Window = "7d"
+	}
+	window, err := article_service.ParseWindow(form.Window)
+	if err != nil {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"window": err.Error()})
+		return
+	}
+	if form.Limit == 0 {
+		form.Limit = setting.AppSetting.PageSize
+	}
+
+	articleService := article_service.Article{TenantID: tenant.GetID(c)}
+	articles, err := articleService.Popular(c.Request.Context(), window, form.Limit)
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_POPULAR_ARTICLES_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists":  articles,
+		"window": form.Window,
+	})
+}
+
+// 点赞文章，重复点赞不报错
+func LikeArticle(c *gin.Context) {
+	setLike(c, true)
+}
+
+// 取消点赞，未点赞时不报错
+func UnlikeArticle(c *gin.Context) {
+	setLike(c, false)
+}
+
+// setLike 设置当前用户对文章的点赞状态，返回设置后的状态与文章的点赞数
+func setLike(c *gin.Context, like bool) {
+	var (
+		appG = app.Gin{C: c}
+		form ArticleIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	articleService := article_service.Article{TenantID: tenant.GetID(c), ID: form.ID}
+	exists, err := articleService.ExistByID(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		return
+	}
+	if !exists {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
+		return
+	}
+
+	errCode = e.ERROR_LIKE_ARTICLE_FAIL
+	if like {
+		_, err = articleService.Like(c.Request.Context(), jwt.GetUsername(c))
+	} else {
+		errCode = e.ERROR_UNLIKE_ARTICLE_FAIL
+		_, err = articleService.Unlike(c.Request.Context(), jwt.GetUsername(c))
+	}
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, errCode, nil)
+		return
+	}
+
+	article, err := articleService.Load(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLE_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"liked":      like,
+		"like_count": article.LikeCount,
+	})
+}
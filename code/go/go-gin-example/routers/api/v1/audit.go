@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/export"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/audit_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/audits", Summary: "获取审计日志", Tag: "admin", Auth: true, Request: GetAuditsForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/audits/export", Summary: "导出审计日志", Tag: "admin", Auth: true, Request: ExportAuditsForm{}},
+	)
+}
+
+// audit 记录当前用户的一次写操作，记录失败只写日志，不影响请求结果
+func audit(c *gin.Context, action, entityType string, entityID interface{}, before, after interface{}) {
+	id := ""
+	if entityID != nil {
+		id = fmt.Sprint(entityID)
+	}
+
+	err := audit_service.Record(c.Request.Context(), tenant.GetID(c), jwt.GetUsername(c), c.ClientIP(), action, entityType, id, before, after)
+	if err != nil {
+		logging.Error("audit", action, entityType, id, err)
+	}
+}
+
+type GetAuditsForm struct {
+	Username   string `form:"username" binding:"max=50"`
+	Action     string `form:"action" binding:"omitempty,oneof=create edit delete restore import export run pause resume replay"`
+	EntityType string `form:"entity_type" binding:"max=20"`
+	EntityID   string `form:"entity_id" binding:"max=50"`
+	StartTime  int    `form:"start_time" binding:"omitempty,min=0"`
+	EndTime    int    `form:"end_time" binding:"omitempty,min=0"`
+	Page       int    `form:"page" binding:"omitempty,min=1"`
+}
+
+// 按用户、操作、对象与时间范围查询审计日志
+func GetAudits(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetAuditsForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	auditService := audit_service.Audit{
+		TenantID:   tenant.GetID(c),
+		Username:   form.Username,
+		Action:     form.Action,
+		EntityType: form.EntityType,
+		EntityID:   form.EntityID,
+		StartTime:  form.StartTime,
+		EndTime:    form.EndTime,
+		PageNum:    util.GetPage(c),
+		PageSize:   setting.AppSetting.PageSize,
+	}
+
+	audits, err := auditService.GetAll(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_AUDITS_FAIL, nil)
+		return
+	}
+
+	total, err := auditService.Count(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_AUDITS_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": audits,
+		"total": total,
+	})
+}
+
+type ExportAuditsForm struct {
+	Username   string `form:"username" json:"username" binding:"max=50"`
+	Action     string `form:"action" json:"action" binding:"omitempty,oneof=create edit delete restore import export run pause resume replay"`
+	EntityType string `form:"entity_type" json:"entity_type" binding:"max=20"`
+	EntityID   string `form:"entity_id" json:"entity_id" binding:"max=50"`
+	StartTime  int    `form:"start_time" json:"start_time" binding:"omitempty,min=0"`
+	EndTime    int    `form:"end_time" json:"end_time" binding:"omitempty,min=0"`
+}
+
+// 按条件导出审计日志
+func ExportAudits(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form ExportAuditsForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	auditService := audit_service.Audit{
+		TenantID:   tenant.GetID(c),
+		Username:   form.Username,
+		Action:     form.Action,
+		EntityType: form.EntityType,
+		EntityID:   form.EntityID,
+		StartTime:  form.StartTime,
+		EndTime:    form.EndTime,
+	}
+
+	// 先写入内存，导出失败时仍能返回错误信息
+	var buf bytes.Buffer
+	if err := auditService.Export(c.Request.Context(), &buf); err != nil {
+		logging.Error("export audits", err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXPORT_AUDITS_FAIL, nil)
+		return
+	}
+	attachment(c, "audits", &buf)
+}
+
+// attachment 以附件形式返回导出的 Excel 文件，导出文件不落盘，只有有权限的请求才能拿到
+func attachment(c *gin.Context, prefix string, buf *bytes.Buffer) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d.xlsx"`, prefix, time.Now().Unix()))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, export.CONTENT_TYPE, buf.Bytes())
+}
@@ -0,0 +1,241 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/articles/bulk", Summary: "批量操作文章", Tag: "article", Auth: true, Request: BulkArticlesForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/tags/bulk", Summary: "批量操作标签", Tag: "tag", Auth: true, Request: BulkTagsForm{}},
+	)
+}
+
+// BulkItemResult 批量操作中单项的结果，index 为该项在请求中的位置
+type BulkItemResult struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"`
+	ID    int    `json:"id,omitempty"`
+	Code  int    `json:"code"`
+	Msg   string `json:"msg"`
+	Error string `json:"error,omitempty"`
+}
+
+type BulkArticleItem struct {
+	Op            string `form:"op" json:"op" binding:"required,oneof=create update delete state"`
+	ID            int    `form:"id" json:"id" binding:"omitempty,min=1"`
+	Version       int    `form:"version" json:"version" binding:"omitempty,min=1"`
+	TagID         int    `form:"tag_id" json:"tag_id" binding:"omitempty,min=1"`
+	Title         string `form:"title" json:"title" binding:"max=100"`
+	Slug          string `form:"slug" json:"slug" binding:"max=100"`
+	Desc          string `form:"desc" json:"desc" binding:"max=255"`
+	Content       string `form:"content" json:"content" binding:"max=65535"`
+	CoverImageUrl string `form:"cover_image_url" json:"cover_image_url" binding:"max=255"`
+	State         *int   `form:"state" json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+type BulkArticlesForm struct {
+	Atomic     bool              `form:"atomic" json:"atomic"`
+	Operations []BulkArticleItem `form:"operations" json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+// 批量新建、修改、删除文章或修改文章状态，atomic 为 true 时任一项失败则全部回滚，
+// 全部成功返回 200，部分失败返回 207，回滚返回 409，每一项的结果在 results 中按请求顺序返回
+func BulkArticles(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form BulkArticlesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	ops := make([]article_service.BulkOperation, len(form.Operations))
+	for i, item := range form.Operations {
+		state := -1
+		if item.State != nil {
+			state = *item.State
+		}
+		ops[i] = article_service.BulkOperation{
+			Op: item.Op,
+			Article: article_service.Article{
+				ID:            item.ID,
+				Version:       item.Version,
+				TagID:         item.TagID,
+				Title:         item.Title,
+				Slug:          item.Slug,
+				Desc:          item.Desc,
+				Content:       item.Content,
+				CoverImageUrl: item.CoverImageUrl,
+				State:         state,
+			},
+		}
+	}
+
+	results, err := article_service.Bulk(c.Request.Context(), tenant.GetID(c), ops, form.Atomic, jwt.GetUsername(c))
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_BULK_FAIL, nil)
+		return
+	}
+
+	items := make([]BulkItemResult, len(results))
+	for i, r := range results {
+		items[i] = bulkItemResult(c, i, r.Op, r.ID, r.Applied, r.Err, e.ERROR_NOT_EXIST_ARTICLE)
+		if r.Applied {
+			audit(c, bulkAuditAction(r.Op), "article", r.ID, r.Before, r.After)
+		}
+	}
+
+	bulkResponse(appG, form.Atomic, items)
+}
+
+type BulkTagItem struct {
+	Op      string `form:"op" json:"op" binding:"required,oneof=create update delete state"`
+	ID      int    `form:"id" json:"id" binding:"omitempty,min=1"`
+	Version int    `form:"version" json:"version" binding:"omitempty,min=1"`
+	Name    string `form:"name" json:"name" binding:"max=100"`
+	State   *int   `form:"state" json:"state" binding:"omitempty,oneof=0 1"`
+	Cascade bool   `form:"cascade" json:"cascade"`
+}
+
+type BulkTagsForm struct {
+	Atomic     bool          `form:"atomic" json:"atomic"`
+	Operations []BulkTagItem `form:"operations" json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+// 批量新建、修改、删除标签或修改标签状态，规则与批量操作文章相同，
+// 删除仍有文章的标签需指定 cascade
+func BulkTags(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form BulkTagsForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	ops := make([]tag_service.BulkOperation, len(form.Operations))
+	for i, item := range form.Operations {
+		state := -1
+		if item.State != nil {
+			state = *item.State
+		}
+		ops[i] = tag_service.BulkOperation{
+			Op: item.Op,
+			Tag: tag_service.Tag{
+				ID:      item.ID,
+				Version: item.Version,
+				Name:    item.Name,
+				State:   state,
+				Cascade: item.Cascade,
+			},
+		}
+	}
+
+	results, err := tag_service.Bulk(c.Request.Context(), tenant.GetID(c), ops, form.Atomic, jwt.GetUsername(c))
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_BULK_FAIL, nil)
+		return
+	}
+
+	items := make([]BulkItemResult, len(results))
+	for i, r := range results {
+		items[i] = bulkItemResult(c, i, r.Op, r.ID, r.Applied, r.Err, e.ERROR_NOT_EXIST_TAG)
+		if r.Applied {
+			audit(c, bulkAuditAction(r.Op), "tag", r.ID, r.Before, r.After)
+		}
+	}
+
+	bulkResponse(appG, form.Atomic, items)
+}
+
+// bulkItemResult 将单项的错误转换为错误码，notExistCode 为记录不存在时使用的错误码
+func bulkItemResult(c *gin.Context, index int, op string, id int, applied bool, err error, notExistCode int) BulkItemResult {
+	result := BulkItemResult{Index: index, Op: op, ID: id}
+
+	switch {
+	case err == nil && applied:
+		result.Code = e.SUCCESS
+	case err == nil:
+		result.Code = e.ERROR_BULK_NOT_APPLIED
+	case errors.Is(err, models.ErrInvalidOperation):
+		result.Code = e.INVALID_PARAMS
+		result.Error = err.Error()
+	case errors.Is(err, models.ErrNotExist):
+		result.Code = notExistCode
+	case errors.Is(err, models.ErrTagNotExist):
+		result.Code = e.ERROR_NOT_EXIST_TAG
+	case errors.Is(err, models.ErrSlugExist):
+		result.Code = e.ERROR_EXIST_ARTICLE_SLUG
+	case errors.Is(err, models.ErrNameExist):
+		result.Code = e.ERROR_EXIST_TAG
+	case errors.Is(err, models.ErrTagHasArticles):
+		result.Code = e.ERROR_TAG_HAS_ARTICLES
+	case errors.Is(err, models.ErrVersionConflict):
+		result.Code = e.ERROR_VERSION_CONFLICT
+	default:
+		logging.Error(err)
+		result.Code = e.ERROR
+	}
+	result.Msg = e.GetMsgByLang(result.Code, app.Lang(c))
+
+	return result
+}
+
+func bulkAuditAction(op string) string {
+	switch op {
+	case models.BULK_CREATE:
+		return models.AUDIT_CREATE
+	case models.BULK_DELETE:
+		return models.AUDIT_DELETE
+	default:
+		return models.AUDIT_EDIT
+	}
+}
+
+// bulkResponse 全部成功返回 200，非原子操作部分失败返回 207，原子操作回滚返回 409
+func bulkResponse(appG app.Gin, atomic bool, items []BulkItemResult) {
+	failed := 0
+	for _, item := range items {
+		if item.Code != e.SUCCESS {
+			failed++
+		}
+	}
+
+	data := map[string]interface{}{
+		"atomic":    atomic,
+		"succeeded": len(items) - failed,
+		"failed":    failed,
+		"results":   items,
+	}
+	switch {
+	case failed == 0:
+		appG.Response(http.StatusOK, e.SUCCESS, data)
+	case atomic:
+		data["succeeded"] = 0
+		appG.Response(http.StatusConflict, e.ERROR_BULK_ROLLED_BACK, data)
+	default:
+		appG.Response(http.StatusMultiStatus, e.ERROR_BULK_PARTIAL, data)
+	}
+}
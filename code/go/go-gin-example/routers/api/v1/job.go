@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/scheduler"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/util"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/jobs", Summary: "获取定时任务列表", Tag: "admin", Auth: true},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/jobs/:name/run", Summary: "立即执行定时任务", Tag: "admin", Auth: true, Request: JobForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/jobs/:name/pause", Summary: "暂停定时任务", Tag: "admin", Auth: true, Request: JobForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/jobs/:name/resume", Summary: "恢复定时任务", Tag: "admin", Auth: true, Request: JobForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/jobs/:name/runs", Summary: "获取定时任务执行记录", Tag: "admin", Auth: true, Request: GetJobRunsForm{}},
+	)
+}
+
+type JobForm struct {
+	Name string `uri:"name" form:"-" json:"-" binding:"required,max=50"`
+}
+
+type GetJobRunsForm struct {
+	Name string `uri:"name" form:"-" json:"-" binding:"required,max=50"`
+	Page int    `form:"page" binding:"omitempty,min=1"`
+}
+
+// 获取所有定时任务及其状态
+func GetJobs(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	jobs, err := scheduler.List(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_JOBS_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists":    jobs,
+		"instance": scheduler.Instance,
+		"leader":   scheduler.IsLeader(),
+	})
+}
+
+// 立即在当前实例上执行定时任务，任务在后台执行，返回本次执行记录
+func RunJob(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form JobForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	run, err := scheduler.Trigger(c.Request.Context(), form.Name, jwt.GetUsername(c))
+	switch err {
+	case nil:
+		audit(c, models.AUDIT_RUN, "job", form.Name, nil, run)
+		appG.Response(http.StatusAccepted, e.SUCCESS, run)
+	case scheduler.ErrNotExist:
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_JOB, nil)
+	case scheduler.ErrRunning:
+		appG.Response(http.StatusConflict, e.ERROR_JOB_RUNNING, nil)
+	default:
+		appG.Response(http.StatusInternalServerError, e.ERROR_RUN_JOB_FAIL, nil)
+	}
+}
+
+// 暂停定时任务，暂停后不再按计划执行，但仍可手动执行
+func PauseJob(c *gin.Context) {
+	setJobPaused(c, true)
+}
+
+// 恢复定时任务
+func ResumeJob(c *gin.Context) {
+	setJobPaused(c, false)
+}
+
+func setJobPaused(c *gin.Context, paused bool) {
+	var (
+		appG = app.Gin{C: c}
+		form JobForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	by := jwt.GetUsername(c)
+	var err error
+	action, failCode := models.AUDIT_RESUME, e.ERROR_RESUME_JOB_FAIL
+	if paused {
+		err = scheduler.Pause(c.Request.Context(), form.Name, by)
+		action, failCode = models.AUDIT_PAUSE, e.ERROR_PAUSE_JOB_FAIL
+	} else {
+		err = scheduler.Resume(c.Request.Context(), form.Name, by)
+	}
+
+	switch err {
+	case nil:
+		audit(c, action, "job", form.Name, nil, nil)
+		appG.Response(http.StatusOK, e.SUCCESS, nil)
+	case scheduler.ErrNotExist:
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_JOB, nil)
+	default:
+		appG.Response(http.StatusInternalServerError, failCode, nil)
+	}
+}
+
+// 分页获取定时任务的执行记录
+func GetJobRuns(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetJobRunsForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	runs, total, err := scheduler.Runs(c.Request.Context(), form.Name, util.GetPage(c), setting.AppSetting.PageSize)
+	switch err {
+	case nil:
+		appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+			"lists": runs,
+			"total": total,
+		})
+	case scheduler.ErrNotExist:
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_JOB, nil)
+	default:
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_JOB_RUNS_FAIL, nil)
+	}
+}
@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/public/articles", Summary: "获取已发布的文章列表", Tag: "public", Request: GetPublicArticlesForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/public/articles/:slug", Summary: "按 slug 获取已发布的文章", Tag: "public", Request: PublicArticleForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/public/tags", Summary: "获取已启用的标签列表", Tag: "public", Request: GetPublicTagsForm{}},
+	)
+}
+
+type GetPublicArticlesForm struct {
+	TagID int `form:"tag_id" binding:"omitempty,min=1"`
+	Page  int `form:"page" binding:"omitempty,min=1"`
+}
+
+// 获取已发布且所属标签已启用的文章，最新发布的在前，无需鉴权
+func GetPublicArticles(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetPublicArticlesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	articleService := article_service.Article{
+		TenantID: tenant.GetID(c),
+		TagID:    form.TagID,
+		PageNum:  util.GetPage(c),
+		PageSize: setting.AppSetting.PageSize,
+	}
+
+	total, err := articleService.CountPublished(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_COUNT_ARTICLE_FAIL, nil)
+		return
+	}
+
+	articles, err := articleService.GetPublished(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLES_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": articles,
+		"total": total,
+	})
+}
+
+type PublicArticleForm struct {
+	Slug string `uri:"slug" form:"-" json:"-" binding:"required,max=255"`
+}
+
+// 按 slug 获取已发布的文章，未发布、已删除或所属标签已禁用的文章返回 404
+func GetPublicArticle(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form PublicArticleForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	articleService := article_service.Article{TenantID: tenant.GetID(c), Slug: form.Slug}
+	article, err := articleService.GetPublishedBySlug(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLE_FAIL, nil)
+		return
+	}
+	if article.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
+		return
+	}
+	articleService.ID = article.ID
+	articleService.View(c.Request.Context())
+
+	appG.Response(http.StatusOK, e.SUCCESS, article)
+}
+
+type GetPublicTagsForm struct {
+	Page int `form:"page" binding:"omitempty,min=1"`
+}
+
+// 获取已启用的标签，无需鉴权
+func GetPublicTags(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetPublicTagsForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	tagService := tag_service.Tag{
+		TenantID: tenant.GetID(c),
+		PageNum:  util.GetPage(c),
+		PageSize: setting.AppSetting.PageSize,
+	}
+
+	total, err := tagService.CountEnabled(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_COUNT_TAG_FAIL, nil)
+		return
+	}
+
+	tags, err := tagService.GetEnabled(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_TAGS_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": tags,
+		"total": total,
+	})
+}
@@ -0,0 +1,358 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/series_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/series", Summary: "获取系列列表", Tag: "series", Auth: true, Request: GetSeriesListForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/series/:id", Summary: "获取指定系列及其文章", Tag: "series", Auth: true, Request: SeriesIDForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/series", Summary: "新建系列", Tag: "series", Auth: true, Request: AddSeriesForm{}},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/series/:id", Summary: "更新指定系列", Tag: "series", Auth: true, Request: EditSeriesForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/series/:id", Summary: "删除指定系列", Tag: "series", Auth: true, Request: SeriesIDForm{}},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/series/:id/articles", Summary: "设置系列中的文章及其顺序", Tag: "series", Auth: true, Request: SetSeriesArticlesForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/series/:id/articles", Summary: "将文章加到系列末尾", Tag: "series", Auth: true, Request: AddSeriesArticleForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/series/:id/articles/:article_id", Summary: "将文章移出系列", Tag: "series", Auth: true, Request: RemoveSeriesArticleForm{}},
+	)
+}
+
+type SeriesIDForm struct {
+	ID int `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+}
+
+type GetSeriesListForm struct {
+	Page int `form:"page" binding:"omitempty,min=1"`
+}
+
+// 获取系列列表
+func GetSeriesList(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetSeriesListForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{
+		TenantID: tenant.GetID(c),
+		PageNum:  util.GetPage(c),
+		PageSize: setting.AppSetting.PageSize,
+	}
+	series, err := seriesService.GetAll(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_SERIES_FAIL, nil)
+		return
+	}
+
+	count, err := seriesService.Count(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_COUNT_SERIES_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": series,
+		"total": count,
+	})
+}
+
+// 获取指定系列，articles 为按顺序排列的未删除文章
+func GetSeries(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form SeriesIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{TenantID: tenant.GetID(c), ID: form.ID}
+	series, ok := loadSeries(appG, &seriesService)
+	if !ok {
+		return
+	}
+
+	articles, err := seriesService.GetArticles(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLES_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"series":   series,
+		"articles": articles,
+	})
+}
+
+type AddSeriesForm struct {
+	Title string `form:"title" json:"title" binding:"required,max=100"`
+	Desc  string `form:"desc" json:"desc" binding:"max=255"`
+}
+
+// 新建系列
+func AddSeries(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form AddSeriesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{
+		TenantID:  tenant.GetID(c),
+		Title:     form.Title,
+		Desc:      form.Desc,
+		CreatedBy: jwt.GetUsername(c),
+	}
+	series, err := seriesService.Add(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_SERIES_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_CREATE, "series", series.ID, nil, series)
+
+	appG.Response(http.StatusOK, e.SUCCESS, series)
+}
+
+type EditSeriesForm struct {
+	ID    int    `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	Title string `form:"title" json:"title" binding:"required,max=100"`
+	Desc  string `form:"desc" json:"desc" binding:"max=255"`
+}
+
+// 修改系列
+func EditSeries(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form EditSeriesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{
+		TenantID:   tenant.GetID(c),
+		ID:         form.ID,
+		Title:      form.Title,
+		Desc:       form.Desc,
+		ModifiedBy: jwt.GetUsername(c),
+	}
+	before, ok := loadSeries(appG, &seriesService)
+	if !ok {
+		return
+	}
+
+	if err := seriesService.Edit(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_SERIES_FAIL, nil)
+		return
+	}
+	after, err := seriesService.Get(c.Request.Context())
+	if err != nil {
+		logging.Error("reload after edit series", form.ID, err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_SERIES_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "series", form.ID, before, after)
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// 删除系列，其中的文章移出系列，文章本身不受影响
+func DeleteSeries(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form SeriesIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{TenantID: tenant.GetID(c), ID: form.ID}
+	before, ok := loadSeries(appG, &seriesService)
+	if !ok {
+		return
+	}
+
+	if err := seriesService.Delete(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_DELETE_SERIES_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_DELETE, "series", form.ID, before, nil)
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+type SetSeriesArticlesForm struct {
+	ID         int   `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	ArticleIDs []int `form:"article_ids" json:"article_ids" binding:"required,max=100"`
+}
+
+// 按 article_ids 的顺序设置系列中的文章，用于调整顺序，不在其中的文章移出系列，传空列表时清空系列
+func SetSeriesArticles(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form SetSeriesArticlesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{TenantID: tenant.GetID(c), ID: form.ID}
+	if _, ok := loadSeries(appG, &seriesService); !ok {
+		return
+	}
+	if err := seriesService.CheckArticles(c.Request.Context(), form.ArticleIDs); err != nil {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"article_ids": err.Error()})
+		return
+	}
+
+	before, err := seriesService.GetArticleIDs(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLES_FAIL, nil)
+		return
+	}
+	if !setSeriesArticles(appG, seriesService.SetArticles(c.Request.Context(), form.ArticleIDs)) {
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "series", form.ID, map[string][]int{"article_ids": before}, map[string][]int{"article_ids": form.ArticleIDs})
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+type AddSeriesArticleForm struct {
+	ID        int `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	ArticleID int `form:"article_id" json:"article_id" binding:"required,min=1"`
+}
+
+// 将文章加到系列末尾，文章已在该系列中时不做修改
+func AddSeriesArticle(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form AddSeriesArticleForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{TenantID: tenant.GetID(c), ID: form.ID}
+	if _, ok := loadSeries(appG, &seriesService); !ok {
+		return
+	}
+	if err := seriesService.CheckArticles(c.Request.Context(), []int{form.ArticleID}); err != nil {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"article_id": err.Error()})
+		return
+	}
+
+	if !setSeriesArticles(appG, seriesService.AddArticle(c.Request.Context(), form.ArticleID)) {
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "series", form.ID, nil, map[string]int{"added_article_id": form.ArticleID})
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+type RemoveSeriesArticleForm struct {
+	ID        int `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	ArticleID int `uri:"article_id" form:"-" json:"-" binding:"required,min=1"`
+}
+
+// 将文章移出系列
+func RemoveSeriesArticle(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form RemoveSeriesArticleForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	seriesService := series_service.Series{TenantID: tenant.GetID(c), ID: form.ID}
+	if _, ok := loadSeries(appG, &seriesService); !ok {
+		return
+	}
+
+	removed, err := seriesService.RemoveArticle(c.Request.Context(), form.ArticleID)
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_SERIES_ARTICLES_FAIL, nil)
+		return
+	}
+	if !removed {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, nil)
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "series", form.ID, map[string]int{"removed_article_id": form.ArticleID}, nil)
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// loadSeries 获取要操作的系列，不存在或出错时写入响应并返回 false
+func loadSeries(appG app.Gin, seriesService *series_service.Series) (*models.Series, bool) {
+	series, err := seriesService.Get(appG.C.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_SERIES_FAIL, nil)
+		return nil, false
+	}
+	if series.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_SERIES, nil)
+		return nil, false
+	}
+
+	return series, true
+}
+
+// setSeriesArticles 根据修改系列文章的结果写入错误响应，成功时返回 true
+func setSeriesArticles(appG app.Gin, err error) bool {
+	if errors.Is(err, models.ErrArticleInSeries) {
+		appG.Response(http.StatusConflict, e.ERROR_ARTICLE_IN_OTHER_SERIES, nil)
+		return false
+	}
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_SERIES_ARTICLES_FAIL, nil)
+		return false
+	}
+
+	return true
+}
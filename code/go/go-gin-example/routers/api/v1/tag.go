@@ -1,44 +1,76 @@
 package v1
 
 import (
+	"bytes"
+	"errors"
+	"mime/multipart"
 	"net/http"
 
-	"github.com/astaxie/beego/validation"
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
 	"github.com/fzzv/go-gin-example/pkg/app"
 	"github.com/fzzv/go-gin-example/pkg/e"
-	"github.com/fzzv/go-gin-example/pkg/export"
 	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
 	"github.com/fzzv/go-gin-example/pkg/setting"
 	"github.com/fzzv/go-gin-example/pkg/util"
 	"github.com/fzzv/go-gin-example/service/tag_service"
 	"github.com/gin-gonic/gin"
-	"github.com/unknwon/com"
 )
 
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/tags", Summary: "获取标签列表", Tag: "tag", Auth: true, Request: GetTagsForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/tags", Summary: "新建标签", Tag: "tag", Auth: true, Request: AddTagForm{}},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/tags/:id", Summary: "更新指定标签", Tag: "tag", Auth: true, Request: EditTagForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/tags/:id", Summary: "删除指定标签", Tag: "tag", Auth: true, Request: DeleteTagForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/tags/:id/restore", Summary: "从回收站恢复标签", Tag: "tag", Auth: true, Request: RestoreTagForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/tags/export", Summary: "导出标签", Tag: "tag", Auth: true, Request: ExportTagForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/tags/import", Summary: "导入标签", Tag: "tag", Auth: true, Request: ImportForm{}},
+	)
+}
+
+type GetTagsForm struct {
+	Name  string `form:"name" binding:"max=100"`
+	State *int   `form:"state" binding:"omitempty,oneof=0 1"`
+	Page  int    `form:"page" binding:"omitempty,min=1"`
+}
+
 // 获取多个文章标签
 func GetTags(c *gin.Context) {
-	appG := app.Gin{C: c}
-	name := c.Query("name")
+	var (
+		appG = app.Gin{C: c}
+		form GetTagsForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
 	state := -1
-	if arg := c.Query("state"); arg != "" {
-		state = com.StrTo(arg).MustInt()
+	if form.State != nil {
+		state = *form.State
 	}
 
 	tagService := tag_service.Tag{
-		Name:     name,
+		TenantID: tenant.GetID(c),
+		Name:     form.Name,
 		State:    state,
 		PageNum:  util.GetPage(c),
 		PageSize: setting.AppSetting.PageSize,
 	}
-	tags, err := tagService.GetAll()
+	tags, err := tagService.GetAll(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_GET_TAGS_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_TAGS_FAIL, nil)
 		return
 	}
 
-	count, err := tagService.Count()
+	count, err := tagService.Count(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_COUNT_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_COUNT_TAG_FAIL, nil)
 		return
 	}
 
@@ -48,185 +80,307 @@ func GetTags(c *gin.Context) {
 	})
 }
 
+type AddTagForm struct {
+	Name  string `form:"name" json:"name" binding:"required,max=100"`
+	State int    `form:"state" json:"state" binding:"oneof=0 1"`
+}
+
 // 新增文章标签
 func AddTag(c *gin.Context) {
-	appG := app.Gin{C: c}
-	name := c.PostForm("name")
-	state := com.StrTo(c.DefaultPostForm("state", "0")).MustInt()
-	createdBy := c.PostForm("created_by")
-
-	valid := validation.Validation{}
-	valid.Required(name, "name").Message("名称不能为空")
-	valid.MaxSize(name, 100, "name").Message("名称最长为100字符")
-	valid.Required(createdBy, "created_by").Message("创建人不能为空")
-	valid.MaxSize(createdBy, 100, "created_by").Message("创建人最长为100字符")
-	valid.Range(state, 0, 1, "state").Message("状态只允许0或1")
-
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
+	var (
+		appG = app.Gin{C: c}
+		form AddTagForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
 		return
 	}
 
 	tagService := tag_service.Tag{
-		Name:      name,
-		CreatedBy: createdBy,
-		State:     state,
+		TenantID:  tenant.GetID(c),
+		Name:      form.Name,
+		CreatedBy: jwt.GetUsername(c),
+		State:     form.State,
 	}
 
-	exists, err := tagService.ExistByName()
+	exists, err := tagService.ExistByName(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EXIST_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
 		return
 	}
 	if exists {
-		appG.Response(http.StatusOK, e.ERROR_EXIST_TAG, nil)
+		appG.Response(http.StatusConflict, e.ERROR_EXIST_TAG, nil)
 		return
 	}
 
-	err = tagService.Add()
+	tag, err := tagService.Add(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_ADD_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_TAG_FAIL, nil)
 		return
 	}
+	audit(c, models.AUDIT_CREATE, "tag", tag.ID, nil, tag)
 
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
 
-// @Summary 修改文章标签
-// @Produce  json
-// @Param id path int true "ID"
-// @Param name query string true "ID"
-// @Param state query int false "State"
-// @Param modified_by query string true "ModifiedBy"
-// @Success 200 {string} json "{"code":200,"data":{},"msg":"ok"}"
-// @Router /api/v1/tags/{id} [put]
+type EditTagForm struct {
+	ID    int    `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	Name  string `form:"name" json:"name" binding:"required,max=100"`
+	State *int   `form:"state" json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+// 修改文章标签
 func EditTag(c *gin.Context) {
-	appG := app.Gin{C: c}
-	valid := validation.Validation{}
-	id := com.StrTo(c.Param("id")).MustInt()
-	name := c.PostForm("name")
-	modifiedBy := c.PostForm("modified_by")
+	var (
+		appG = app.Gin{C: c}
+		form EditTagForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
 
 	state := -1
-	if arg := c.PostForm("state"); arg != "" {
-		state = com.StrTo(arg).MustInt()
-		valid.Range(state, 0, 1, "state").Message("状态只允许0或1")
+	if form.State != nil {
+		state = *form.State
 	}
 
-	valid.Required(id, "id").Message("ID不能为空")
-	valid.Required(modifiedBy, "modified_by").Message("修改人不能为空")
-	valid.MaxSize(modifiedBy, 100, "modified_by").Message("修改人最长为100字符")
-	valid.Required(name, "name").Message("名称不能为空")
-	valid.MaxSize(name, 100, "name").Message("名称最长为100字符")
+	tagService := tag_service.Tag{
+		TenantID:   tenant.GetID(c),
+		ID:         form.ID,
+		Name:       form.Name,
+		ModifiedBy: jwt.GetUsername(c),
+		State:      state,
+	}
 
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
+	before, err := tagService.Load(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
 		return
 	}
 
-	tagService := tag_service.Tag{
-		ID:         id,
-		Name:       name,
-		ModifiedBy: modifiedBy,
-		State:      state,
+	if before.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TAG, nil)
+		return
 	}
 
-	exists, err := tagService.ExistByID()
+	version, ok := checkIfMatch(appG, before.Version)
+	if !ok {
+		return
+	}
+	tagService.Version = version
+
+	err = tagService.Edit(c.Request.Context())
+	if errors.Is(err, models.ErrVersionConflict) {
+		current, err := tagService.Load(c.Request.Context())
+		if err != nil {
+			logging.Error("reload after version conflict", "tag", form.ID, err)
+			appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_TAG_FAIL, nil)
+			return
+		}
+		versionConflict(appG, current.Version)
+		return
+	}
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EXIST_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_TAG_FAIL, nil)
 		return
 	}
+	after, err := tagService.Load(c.Request.Context())
+	if err != nil {
+		logging.Error("reload after edit tag", form.ID, err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_TAG_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "tag", form.ID, before, after)
+
+	versionOK(appG, after.Version)
+}
 
-	if !exists {
-		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_TAG, nil)
+type DeleteTagForm struct {
+	ID      int  `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	Cascade bool `form:"cascade"`
+}
+
+// 删除文章标签
+func DeleteTag(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form DeleteTagForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
 		return
 	}
 
-	err = tagService.Edit()
+	tagService := tag_service.Tag{TenantID: tenant.GetID(c), ID: form.ID, Cascade: form.Cascade}
+	before, err := tagService.Load(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EDIT_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
 		return
 	}
 
+	if before.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TAG, nil)
+		return
+	}
+
+	// 标签下还有文章时，除非指定 cascade 一并删除，否则拒绝删除，避免文章失去所属标签
+	if !form.Cascade {
+		count, err := tagService.CountArticles(c.Request.Context())
+		if err != nil {
+			appG.Response(http.StatusInternalServerError, e.ERROR_COUNT_ARTICLE_FAIL, nil)
+			return
+		}
+		if count > 0 {
+			appG.Response(http.StatusConflict, e.ERROR_TAG_HAS_ARTICLES, map[string]int{"articles": count})
+			return
+		}
+	}
+
+	if err := tagService.Delete(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_DELETE_TAG_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_DELETE, "tag", form.ID, before, map[string]bool{"cascade": form.Cascade})
+
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
 
-// 删除文章标签
-func DeleteTag(c *gin.Context) {
-	appG := app.Gin{C: c}
-	valid := validation.Validation{}
-	id := com.StrTo(c.Param("id")).MustInt()
-	valid.Min(id, 1, "id").Message("ID必须大于0")
+type RestoreTagForm struct {
+	ID      int  `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	Cascade bool `form:"cascade" json:"cascade"`
+}
 
-	if valid.HasErrors() {
-		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
+// 从回收站恢复标签，cascade 为 true 时同时恢复随该标签一起删除的文章
+func RestoreTag(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form RestoreTagForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
 	}
 
-	tagService := tag_service.Tag{ID: id}
-	exists, err := tagService.ExistByID()
+	tagService := tag_service.Tag{TenantID: tenant.GetID(c), ID: form.ID, Cascade: form.Cascade}
+	tag, err := tagService.GetDeleted(c.Request.Context())
 	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EXIST_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
+		return
+	}
+	if tag.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TAG, nil)
 		return
 	}
 
-	if !exists {
-		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_TAG, nil)
+	// 删除后又新建了同名标签时不能恢复
+	tagService.Name = tag.Name
+	exists, err := tagService.ExistByName(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
+		return
+	}
+	if exists {
+		appG.Response(http.StatusConflict, e.ERROR_EXIST_TAG, nil)
 		return
 	}
 
-	if err := tagService.Delete(); err != nil {
-		appG.Response(http.StatusOK, e.ERROR_DELETE_TAG_FAIL, nil)
+	if err := tagService.Restore(c.Request.Context(), tag); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_RESTORE_TAG_FAIL, nil)
+		return
+	}
+	after, err := tagService.Load(c.Request.Context())
+	if err != nil {
+		logging.Error("reload after restore tag", form.ID, err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_RESTORE_TAG_FAIL, nil)
 		return
 	}
+	audit(c, models.AUDIT_RESTORE, "tag", form.ID, tag, after)
 
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
 
+type ExportTagForm struct {
+	Name  string `form:"name" json:"name" binding:"max=100"`
+	State *int   `form:"state" json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+// 导出标签
 func ExportTag(c *gin.Context) {
-	appG := app.Gin{C: c}
-	name := c.PostForm("name")
+	var (
+		appG = app.Gin{C: c}
+		form ExportTagForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
 	state := -1
-	if arg := c.PostForm("state"); arg != "" {
-		state = com.StrTo(arg).MustInt()
+	if form.State != nil {
+		state = *form.State
 	}
 
 	tagService := tag_service.Tag{
-		Name:  name,
-		State: state,
+		TenantID: tenant.GetID(c),
+		Name:     form.Name,
+		State:    state,
 	}
 
-	filename, err := tagService.Export()
-	if err != nil {
-		appG.Response(http.StatusOK, e.ERROR_EXPORT_TAG_FAIL, nil)
+	var buf bytes.Buffer
+	if err := tagService.Export(c.Request.Context(), &buf); err != nil {
+		logging.Error("export tags", err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXPORT_TAG_FAIL, nil)
 		return
 	}
+	audit(c, models.AUDIT_EXPORT, "tag", nil, nil, map[string]interface{}{"name": form.Name, "state": state})
 
-	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
-		"export_url":      export.GetExcelFullUrl(filename),
-		"export_save_url": export.GetExcelPath() + filename,
-	})
+	attachment(c, "tags", &buf)
 }
 
+type ImportForm struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+// 导入标签
 func ImportTag(c *gin.Context) {
-	appG := app.Gin{C: c}
+	var (
+		appG = app.Gin{C: c}
+		form ImportForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
 
-	file, _, err := c.Request.FormFile("file")
+	file, err := form.File.Open()
 	if err != nil {
 		logging.Warn(err)
-		appG.Response(http.StatusOK, e.ERROR, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR, nil)
 		return
 	}
+	defer file.Close()
 
-	tagService := tag_service.Tag{}
-	err = tagService.Import(file)
+	tagService := tag_service.Tag{TenantID: tenant.GetID(c), CreatedBy: jwt.GetUsername(c)}
+	count, err := tagService.Import(c.Request.Context(), file)
 	if err != nil {
 		logging.Warn(err)
-		appG.Response(http.StatusOK, e.ERROR_IMPORT_TAG_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_IMPORT_TAG_FAIL, nil)
 		return
 	}
+	audit(c, models.AUDIT_IMPORT, "tag", nil, nil, map[string]interface{}{"filename": form.File.Filename, "count": count})
 
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
@@ -0,0 +1,212 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/tenant_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/tenants", Summary: "获取站点列表", Tag: "admin", Auth: true, Request: GetTenantsForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/tenants", Summary: "新建站点", Tag: "admin", Auth: true, Request: AddTenantForm{}},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/admin/tenants/:id", Summary: "更新站点", Tag: "admin", Auth: true, Request: EditTenantForm{}},
+	)
+}
+
+type GetTenantsForm struct {
+	Page int `form:"page" binding:"omitempty,min=1"`
+}
+
+// 获取站点列表
+func GetTenants(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetTenantsForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	tenantService := tenant_service.Tenant{PageNum: util.GetPage(c), PageSize: setting.AppSetting.PageSize}
+	tenants, err := tenantService.GetAll(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_TENANTS_FAIL, nil)
+		return
+	}
+
+	total, err := tenantService.Count(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_TENANTS_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": tenants,
+		"total": total,
+	})
+}
+
+type AddTenantForm struct {
+	Name          string `form:"name" json:"name" binding:"required,max=50"`
+	Host          string `form:"host" json:"host" binding:"omitempty,hostname,max=255"`
+	Title         string `form:"title" json:"title" binding:"max=100"`
+	Description   string `form:"description" json:"description" binding:"max=255"`
+	Link          string `form:"link" json:"link" binding:"omitempty,url,max=255"`
+	State         int    `form:"state" json:"state" binding:"oneof=0 1"`
+	AdminUsername string `form:"admin_username" json:"admin_username" binding:"required_with=AdminPassword,max=50"`
+	AdminPassword string `form:"admin_password" json:"admin_password" binding:"required_with=AdminUsername,max=50"`
+}
+
+// 新建站点，指定 admin_username 与 admin_password 时同时创建该站点的管理员
+func AddTenant(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form AddTenantForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+	if !checkTenantName(appG, form.Name) {
+		return
+	}
+
+	tenantService := tenant_service.Tenant{
+		Name:          form.Name,
+		Host:          form.Host,
+		Title:         form.Title,
+		Description:   form.Description,
+		Link:          form.Link,
+		State:         form.State,
+		CreatedBy:     jwt.GetUsername(c),
+		AdminUsername: form.AdminUsername,
+		AdminPassword: form.AdminPassword,
+	}
+	if !tenantAvailable(appG, &tenantService) {
+		return
+	}
+
+	tenant, err := tenantService.Add(c.Request.Context())
+	if err != nil {
+		logging.Error(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_TENANT_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_CREATE, "tenant", tenant.ID, nil, tenant)
+
+	appG.Response(http.StatusOK, e.SUCCESS, tenant)
+}
+
+type EditTenantForm struct {
+	ID          int    `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	Name        string `form:"name" json:"name" binding:"required,max=50"`
+	Host        string `form:"host" json:"host" binding:"omitempty,hostname,max=255"`
+	Title       string `form:"title" json:"title" binding:"max=100"`
+	Description string `form:"description" json:"description" binding:"max=255"`
+	Link        string `form:"link" json:"link" binding:"omitempty,url,max=255"`
+	State       *int   `form:"state" json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+// 更新站点，不能修改默认站点的名称，也不能停用默认站点
+func EditTenant(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form EditTenantForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+	if !checkTenantName(appG, form.Name) {
+		return
+	}
+
+	state := -1
+	if form.State != nil {
+		state = *form.State
+	}
+
+	tenantService := tenant_service.Tenant{
+		ID:          form.ID,
+		Name:        form.Name,
+		Host:        form.Host,
+		Title:       form.Title,
+		Description: form.Description,
+		Link:        form.Link,
+		State:       state,
+		ModifiedBy:  jwt.GetUsername(c),
+	}
+	before, err := tenantService.Get(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_TENANT_FAIL, nil)
+		return
+	}
+	if before.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_TENANT, nil)
+		return
+	}
+	if tenant_service.IsDefault(before) && (form.Name != before.Name || state == 0) {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"name": "the default site cannot be renamed or disabled"})
+		return
+	}
+	if !tenantAvailable(appG, &tenantService) {
+		return
+	}
+
+	if err := tenantService.Edit(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_TENANT_FAIL, nil)
+		return
+	}
+	after, err := tenantService.Get(c.Request.Context())
+	if err != nil {
+		logging.Error("reload after edit tenant", form.ID, err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_TENANT_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "tenant", form.ID, before, after)
+
+	appG.Response(http.StatusOK, e.SUCCESS, after)
+}
+
+// checkTenantName 站点名称用于请求头与文件目录，只允许小写字母、数字与 -，失败时直接写入响应
+func checkTenantName(appG app.Gin, name string) bool {
+	if util.Slugify(name) != name {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"name": "must contain only lowercase letters, digits and hyphens"})
+		return false
+	}
+
+	return true
+}
+
+// tenantAvailable 名称或域名已被其他站点使用、查询失败时直接写入响应并返回 false
+func tenantAvailable(appG app.Gin, tenantService *tenant_service.Tenant) bool {
+	exists, err := tenantService.Exist(appG.C.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_TENANT_FAIL, nil)
+		return false
+	}
+	if exists {
+		appG.Response(http.StatusConflict, e.ERROR_EXIST_TENANT, nil)
+		return false
+	}
+
+	return true
+}
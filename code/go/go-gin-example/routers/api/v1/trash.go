@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/trash", Summary: "获取回收站中的标签与文章", Tag: "trash", Auth: true, Request: GetTrashForm{}},
+	)
+}
+
+type GetTrashForm struct {
+	Type string `form:"type" binding:"omitempty,oneof=tag article"`
+	Page int    `form:"page" binding:"omitempty,min=1"`
+}
+
+// TrashItem 回收站中的一条数据
+type TrashItem struct {
+	Type      string `json:"type"`
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	TagID     int    `json:"tag_id,omitempty"`
+	DeletedOn int    `json:"deleted_on"`
+	PurgeOn   int    `json:"purge_on"` // 超过保留时长被物理删除的时间
+}
+
+// 获取回收站中的标签与文章，type 为空时两者都返回，分别分页
+func GetTrash(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetTrashForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	retention := int(setting.TrashSetting.Retention.Seconds())
+	data := make(map[string]interface{})
+
+	if form.Type == "" || form.Type == "tag" {
+		tagService := tag_service.Tag{TenantID: tenant.GetID(c), PageNum: util.GetPage(c), PageSize: setting.AppSetting.PageSize}
+		tags, err := tagService.GetTrash(c.Request.Context())
+		if err != nil {
+			appG.Response(http.StatusInternalServerError, e.ERROR_GET_TRASH_FAIL, nil)
+			return
+		}
+		total, err := tagService.CountTrash(c.Request.Context())
+		if err != nil {
+			appG.Response(http.StatusInternalServerError, e.ERROR_GET_TRASH_FAIL, nil)
+			return
+		}
+
+		items := make([]TrashItem, 0, len(tags))
+		for _, t := range tags {
+			items = append(items, TrashItem{Type: "tag", ID: t.ID, Name: t.Name, DeletedOn: t.DeletedOn, PurgeOn: t.DeletedOn + retention})
+		}
+		data["tags"] = map[string]interface{}{"lists": items, "total": total}
+	}
+
+	if form.Type == "" || form.Type == "article" {
+		articleService := article_service.Article{TenantID: tenant.GetID(c), PageNum: util.GetPage(c), PageSize: setting.AppSetting.PageSize}
+		articles, err := articleService.GetTrash(c.Request.Context())
+		if err != nil {
+			appG.Response(http.StatusInternalServerError, e.ERROR_GET_TRASH_FAIL, nil)
+			return
+		}
+		total, err := articleService.CountTrash(c.Request.Context())
+		if err != nil {
+			appG.Response(http.StatusInternalServerError, e.ERROR_GET_TRASH_FAIL, nil)
+			return
+		}
+
+		items := make([]TrashItem, 0, len(articles))
+		for _, a := range articles {
+			items = append(items, TrashItem{Type: "article", ID: a.ID, Name: a.Title, TagID: a.TagID, DeletedOn: a.DeletedOn, PurgeOn: a.DeletedOn + retention})
+		}
+		data["articles"] = map[string]interface{}{"lists": items, "total": total}
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, data)
+}
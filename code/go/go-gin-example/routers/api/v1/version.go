@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+)
+
+// checkIfMatch 校验 If-Match 请求头与记录的当前版本，不匹配时直接返回 412 与当前版本。
+// 携带 If-Match 时返回当前版本，用于带版本条件的修改；未携带时返回 0，修改不检查版本
+func checkIfMatch(appG app.Gin, version int) (int, bool) {
+	etag := app.VersionETag(version)
+	if !app.IfMatch(appG.C.Request, etag) {
+		appG.C.Header("ETag", etag)
+		appG.Response(http.StatusPreconditionFailed, e.ERROR_PRECONDITION_FAILED, map[string]int{"version": version})
+		return 0, false
+	}
+
+	if appG.C.GetHeader("If-Match") == "" {
+		return 0, true
+	}
+
+	return version, true
+}
+
+// versionConflict 校验通过后记录仍被其他请求抢先修改时返回 409 与最新版本
+func versionConflict(appG app.Gin, version int) {
+	appG.C.Header("ETag", app.VersionETag(version))
+	appG.Response(http.StatusConflict, e.ERROR_VERSION_CONFLICT, map[string]int{"version": version})
+}
+
+// versionOK 修改成功后通过 ETag 返回新版本
+func versionOK(appG app.Gin, version int) {
+	appG.C.Header("ETag", app.VersionETag(version))
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]int{"version": version})
+}
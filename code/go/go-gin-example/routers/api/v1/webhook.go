@@ -0,0 +1,337 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/webhook_service"
+)
+
+func init() {
+	openapi.Register(
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/webhooks", Summary: "获取 webhook 订阅列表", Tag: "webhook", Auth: true, Request: GetWebhooksForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/webhooks", Summary: "新建 webhook 订阅", Tag: "webhook", Auth: true, Request: AddWebhookForm{}},
+		openapi.Operation{Method: http.MethodPut, Path: "/api/v1/admin/webhooks/:id", Summary: "更新 webhook 订阅", Tag: "webhook", Auth: true, Request: EditWebhookForm{}},
+		openapi.Operation{Method: http.MethodDelete, Path: "/api/v1/admin/webhooks/:id", Summary: "删除 webhook 订阅", Tag: "webhook", Auth: true, Request: WebhookIDForm{}},
+		openapi.Operation{Method: http.MethodGet, Path: "/api/v1/admin/webhooks/:id/deliveries", Summary: "获取 webhook 投递记录", Tag: "webhook", Auth: true, Request: GetDeliveriesForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/webhooks/:id/deliveries/:delivery_id/replay", Summary: "重放一次投递", Tag: "webhook", Auth: true, Request: ReplayDeliveryForm{}},
+		openapi.Operation{Method: http.MethodPost, Path: "/api/v1/admin/webhooks/:id/replay", Summary: "重放所有失败的投递", Tag: "webhook", Auth: true, Request: WebhookIDForm{}},
+	)
+}
+
+type WebhookIDForm struct {
+	ID int `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+}
+
+type GetWebhooksForm struct {
+	Page int `form:"page" binding:"omitempty,min=1"`
+}
+
+// 获取 webhook 订阅列表，不返回密钥
+func GetWebhooks(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetWebhooksForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	webhookService := webhook_service.Webhook{TenantID: tenant.GetID(c), PageNum: util.GetPage(c), PageSize: setting.AppSetting.PageSize}
+	webhooks, err := webhookService.GetAll(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_WEBHOOKS_FAIL, nil)
+		return
+	}
+
+	total, err := webhookService.Count(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_WEBHOOKS_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists":  webhooks,
+		"total":  total,
+		"events": webhook_service.Events,
+	})
+}
+
+type AddWebhookForm struct {
+	URL    string `form:"url" json:"url" binding:"required,url,max=255"`
+	Events string `form:"events" json:"events" binding:"required,max=255"`
+	Secret string `form:"secret" json:"secret" binding:"omitempty,min=16,max=100"`
+	State  int    `form:"state" json:"state" binding:"oneof=0 1"`
+}
+
+// 新建 webhook 订阅，events 为逗号分隔的事件名，* 表示所有事件；未指定 secret 时自动生成，密钥只在此时返回
+func AddWebhook(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form AddWebhookForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	events, err := webhook_service.ParseEvents(form.Events)
+	if err != nil {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"events": err.Error()})
+		return
+	}
+
+	webhookService := webhook_service.Webhook{
+		TenantID:  tenant.GetID(c),
+		URL:       form.URL,
+		Events:    events,
+		Secret:    form.Secret,
+		State:     form.State,
+		CreatedBy: jwt.GetUsername(c),
+	}
+	webhook, err := webhookService.Add(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_WEBHOOK_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_CREATE, "webhook", webhook.ID, nil, webhook)
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"webhook": webhook,
+		"secret":  webhook.Secret,
+	})
+}
+
+type EditWebhookForm struct {
+	ID     int    `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	URL    string `form:"url" json:"url" binding:"required,url,max=255"`
+	Events string `form:"events" json:"events" binding:"required,max=255"`
+	Secret string `form:"secret" json:"secret" binding:"omitempty,min=16,max=100"`
+	State  *int   `form:"state" json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+// 更新 webhook 订阅，secret 为空时保留原密钥
+func EditWebhook(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form EditWebhookForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	events, err := webhook_service.ParseEvents(form.Events)
+	if err != nil {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, map[string]string{"events": err.Error()})
+		return
+	}
+
+	state := -1
+	if form.State != nil {
+		state = *form.State
+	}
+
+	webhookService := webhook_service.Webhook{
+		TenantID:   tenant.GetID(c),
+		ID:         form.ID,
+		URL:        form.URL,
+		Events:     events,
+		Secret:     form.Secret,
+		State:      state,
+		ModifiedBy: jwt.GetUsername(c),
+	}
+	before, err := webhookService.Get(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_WEBHOOKS_FAIL, nil)
+		return
+	}
+	if before.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_WEBHOOK, nil)
+		return
+	}
+
+	if err := webhookService.Edit(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_WEBHOOK_FAIL, nil)
+		return
+	}
+	after, err := webhookService.Get(c.Request.Context())
+	if err != nil {
+		logging.Error("reload after edit webhook", form.ID, err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_WEBHOOK_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_EDIT, "webhook", form.ID, before, after)
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// 删除 webhook 订阅，未完成的投递不再重试
+func DeleteWebhook(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form WebhookIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	webhookService := webhook_service.Webhook{TenantID: tenant.GetID(c), ID: form.ID}
+	before, err := webhookService.Get(c.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_WEBHOOKS_FAIL, nil)
+		return
+	}
+	if before.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_WEBHOOK, nil)
+		return
+	}
+
+	if err := webhookService.Delete(c.Request.Context()); err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_DELETE_WEBHOOK_FAIL, nil)
+		return
+	}
+	audit(c, models.AUDIT_DELETE, "webhook", form.ID, before, nil)
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+type GetDeliveriesForm struct {
+	ID     int    `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	Status string `form:"status" binding:"omitempty,oneof=pending sending success failed"`
+	Page   int    `form:"page" binding:"omitempty,min=1"`
+}
+
+// 分页获取 webhook 的投递记录，最近的在前
+func GetWebhookDeliveries(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form GetDeliveriesForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	if !webhookExists(appG, form.ID) {
+		return
+	}
+
+	deliveries, total, err := webhook_service.GetDeliveries(c.Request.Context(), form.ID, form.Status, util.GetPage(c), setting.AppSetting.PageSize)
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_WEBHOOK_DELIVERIES_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": deliveries,
+		"total": total,
+	})
+}
+
+type ReplayDeliveryForm struct {
+	ID         int `uri:"id" form:"-" json:"-" binding:"required,min=1"`
+	DeliveryID int `uri:"delivery_id" form:"-" json:"-" binding:"required,min=1"`
+}
+
+// 以原始内容重新投递一次，返回新的投递记录
+func ReplayWebhookDelivery(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form ReplayDeliveryForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	delivery, err := webhook_service.GetDelivery(c.Request.Context(), form.ID, form.DeliveryID)
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_WEBHOOK_DELIVERIES_FAIL, nil)
+		return
+	}
+	if delivery.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_WEBHOOK_DELIVERY, nil)
+		return
+	}
+
+	replay, err := webhook_service.Replay(c.Request.Context(), delivery)
+	switch err {
+	case nil:
+		audit(c, models.AUDIT_REPLAY, "webhook_delivery", delivery.ID, nil, replay)
+		appG.Response(http.StatusAccepted, e.SUCCESS, replay)
+	case webhook_service.ErrDeliveryPending:
+		appG.Response(http.StatusConflict, e.ERROR_WEBHOOK_DELIVERY_PENDING, nil)
+	default:
+		appG.Response(http.StatusInternalServerError, e.ERROR_REPLAY_WEBHOOK_FAIL, nil)
+	}
+}
+
+// 重放 webhook 下所有尚未重放过的失败投递
+func ReplayFailedWebhookDeliveries(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form WebhookIDForm
+	)
+
+	httpCode, errCode, errs := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, errs)
+		return
+	}
+
+	if !webhookExists(appG, form.ID) {
+		return
+	}
+
+	count, err := webhook_service.ReplayFailed(c.Request.Context(), form.ID)
+	if count > 0 {
+		audit(c, models.AUDIT_REPLAY, "webhook", form.ID, nil, map[string]int{"count": count})
+	}
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_REPLAY_WEBHOOK_FAIL, map[string]int{"count": count})
+		return
+	}
+
+	appG.Response(http.StatusAccepted, e.SUCCESS, map[string]int{"count": count})
+}
+
+// webhookExists 订阅不存在或查询失败时直接写入响应并返回 false
+func webhookExists(appG app.Gin, id int) bool {
+	webhookService := webhook_service.Webhook{TenantID: tenant.GetID(appG.C), ID: id}
+	webhook, err := webhookService.Get(appG.C.Request.Context())
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_WEBHOOKS_FAIL, nil)
+		return false
+	}
+	if webhook.ID == 0 {
+		appG.Response(http.StatusNotFound, e.ERROR_NOT_EXIST_WEBHOOK, nil)
+		return false
+	}
+
+	return true
+}
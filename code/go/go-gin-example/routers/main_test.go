@@ -0,0 +1,288 @@
+package routers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/fzzv/go-gin-example/middleware/ratelimit"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/oidc/oidctest"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/routers"
+)
+
+const (
+	testUser     = "test"
+	testPassword = "test123456"
+	testEditor   = "editor"
+	// testOrigin 允许跨域访问管理接口的前端
+	testOrigin = "https://admin.example.com"
+	// testOIDCCallback 在身份提供方登记的回调地址，测试中从中取出路径与参数交给 router
+	testOIDCCallback = "http://blog.example.com/auth/oidc/callback"
+)
+
+var (
+	router *gin.Engine
+	redis  *miniredis.Miniredis
+	issuer *oidctest.Server
+)
+
+// TestMain 在临时目录中使用 SQLite 与进程内的 Redis 启动完整的路由，运行时文件都写在该目录下
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "go-gin-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := func() int {
+		defer os.RemoveAll(dir)
+		if err := setup(dir); err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer redis.Close()
+		defer issuer.Close()
+		defer models.CloseDB()
+
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+func setup(dir string) error {
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+
+	redis = miniredis.NewMiniRedis()
+	if err := redis.Start(); err != nil {
+		return err
+	}
+
+	var err error
+	issuer, err = oidctest.NewServer("blog", "blog-secret")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile("app.ini", nil, 0644); err != nil {
+		return err
+	}
+	err = setting.Setup(setting.Options{Path: "app.ini", Sets: []string{
+		"app.jwtsecret=integration-test",
+		"server.runmode=test",
+		"database.type=sqlite3",
+		"redis.host=" + redis.Addr(),
+		"ratelimit.auth=",
+		"ratelimit.write=",
+		"cors.api=" + testOrigin,
+		"oidc.issuer=" + issuer.URL,
+		"oidc.clientid=" + issuer.ClientID,
+		"oidc.clientsecret=" + issuer.ClientSecret,
+		"oidc.redirecturl=" + testOIDCCallback,
+		"oidc.adminvalues=blog-admins",
+		"oidc.editorvalues=blog-editors",
+	}})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(setting.AppSetting.RuntimeRootPath, setting.AppSetting.LogSavePath), 0755); err != nil {
+		return err
+	}
+	logging.Setup()
+
+	dbPath := filepath.Join(dir, "blog.db")
+	if err := models.Open("sqlite3", dbPath); err != nil {
+		return err
+	}
+	if err := models.Migrate(); err != nil {
+		return err
+	}
+	if err := seed(dbPath); err != nil {
+		return err
+	}
+
+	if err := gredis.Setup(); err != nil {
+		return err
+	}
+	if err := ratelimit.Setup(); err != nil {
+		return err
+	}
+
+	gin.SetMode(gin.TestMode)
+	router = routers.InitRouter()
+
+	return nil
+}
+
+// seed 与 db/create.sql 一致，写入默认站点与其管理员、编辑账号，两个账号使用相同的密码
+func seed(dbPath string) error {
+	db, err := gorm.Open("sqlite3", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Exec("INSERT INTO blog_tenant (id, name, state, deleted_on) VALUES (1, ?, 1, 0)", setting.TenantSetting.Default).Error; err != nil {
+		return err
+	}
+	for username, role := range map[string]string{testUser: models.ROLE_ADMIN, testEditor: models.ROLE_EDITOR} {
+		if err := db.Exec("INSERT INTO blog_auth (tenant_id, username, password, role) VALUES (1, ?, ?, ?)", username, testPassword, role).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// response 统一的 code / msg / data 响应
+type response struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// client 以某个站点下某个用户的身份发送请求，tenant 为 X-Tenant 请求头，host 为请求的域名，为空时都使用默认站点
+type client struct {
+	t      *testing.T
+	token  string
+	apiKey string
+	tenant string
+	host   string
+}
+
+type result struct {
+	*httptest.ResponseRecorder
+	body response
+}
+
+// data 将响应的 data 解析到 v
+func (r *result) data(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.body.Data, v); err != nil {
+		t.Fatalf("decode data %s: %v", r.body.Data, err)
+	}
+}
+
+func (c *client) do(method, path string, body io.Reader, contentType string, header http.Header) *result {
+	c.t.Helper()
+
+	if c.token != "" {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + "token=" + url.QueryEscape(c.token)
+	}
+
+	req := httptest.NewRequest(method, path, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-Api-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant", c.tenant)
+	}
+	if c.host != "" {
+		req.Host = c.host
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	res := &result{ResponseRecorder: w}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(w.Body.Bytes(), &res.body); err != nil {
+			c.t.Fatalf("%s %s: decode response %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+
+	return res
+}
+
+func (c *client) get(path string, header http.Header) *result {
+	c.t.Helper()
+	return c.do(http.MethodGet, path, nil, "", header)
+}
+
+func (c *client) json(method, path string, v interface{}, header http.Header) *result {
+	c.t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.do(method, path, bytes.NewReader(data), "application/json", header)
+}
+
+// upload 以 multipart/form-data 上传一个文件
+func (c *client) upload(path, field, filename string, content []byte) *result {
+	c.t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	fw.Write(content)
+	mw.Close()
+
+	return c.do(http.MethodPost, path, &buf, mw.FormDataContentType(), nil)
+}
+
+// expect 检查 HTTP 状态码与业务码
+func expect(t *testing.T, r *result, status, code int) {
+	t.Helper()
+	if r.Code != status || r.body.Code != code {
+		t.Fatalf("got status %d code %d, want %d %d: %s", r.Code, r.body.Code, status, code, r.Body.String())
+	}
+}
+
+// login 获取默认站点管理员的 token
+func login(t *testing.T) *client {
+	t.Helper()
+	return loginAs(t, "", testUser, testPassword)
+}
+
+// loginAs 获取 tenant 站点下账号的 token，返回的 client 之后的请求都带上该站点的 X-Tenant 请求头
+func loginAs(t *testing.T, tenant, username, password string) *client {
+	t.Helper()
+
+	anon := &client{t: t, tenant: tenant}
+	r := anon.get(fmt.Sprintf("/auth?username=%s&password=%s", username, password), nil)
+	if r.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", r.Code, r.Body.String())
+	}
+
+	var data struct {
+		Token string `json:"token"`
+	}
+	r.data(t, &data)
+
+	return &client{t: t, token: data.Token, tenant: tenant}
+}
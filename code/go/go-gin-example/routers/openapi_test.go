@@ -0,0 +1,36 @@
+package routers_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/fzzv/go-gin-example/pkg/openapi"
+	"github.com/fzzv/go-gin-example/routers"
+)
+
+// TestOpenAPI 文档本身有效，且包含除以下接口外所有已注册的路由
+func TestOpenAPI(t *testing.T) {
+	doc := openapi.Document()
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid openapi document: %v", err)
+	}
+
+	// 文档与静态文件不按 OpenAPI 文档校验
+	undocumented := map[string]bool{
+		"/openapi.json":            true,
+		"/graphql":                 true,
+		"/swagger/*any":            true,
+		"/upload/images/*filepath": true,
+	}
+	param := regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+	for _, route := range routers.InitRouter().Routes() {
+		if undocumented[route.Path] {
+			continue
+		}
+		path := doc.Paths.Value(param.ReplaceAllString(route.Path, "{$1}"))
+		if path == nil || path.GetOperation(route.Method) == nil {
+			t.Errorf("%s %s is not in the openapi document", route.Method, route.Path)
+		}
+	}
+}
@@ -5,8 +5,17 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/fzzv/go-gin-example/middleware/cors"
+	"github.com/fzzv/go-gin-example/middleware/csrf"
+	"github.com/fzzv/go-gin-example/middleware/etag"
 	"github.com/fzzv/go-gin-example/middleware/jwt"
-	"github.com/fzzv/go-gin-example/pkg/export"
+	"github.com/fzzv/go-gin-example/middleware/ratelimit"
+	"github.com/fzzv/go-gin-example/middleware/secure"
+	"github.com/fzzv/go-gin-example/middleware/tenant"
+	"github.com/fzzv/go-gin-example/middleware/tracing"
+	"github.com/fzzv/go-gin-example/middleware/validator"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/logging"
 	"github.com/fzzv/go-gin-example/pkg/setting"
 	"github.com/fzzv/go-gin-example/pkg/upload"
 	"github.com/fzzv/go-gin-example/routers/api"
@@ -22,20 +31,70 @@ func InitRouter() *gin.Engine {
 
 	r.Use(gin.Recovery())
 
+	// 为每个请求创建 span，数据库与 Redis 的 span 是它的子 span
+	r.Use(tracing.Tracing())
+
+	// 安全相关的响应头
+	r.Use(secure.Headers())
+	// 按路由组区分跨域策略，需在注册路由前注册，没有对应路由的 OPTIONS 预检请求也会经过它
+	apiPolicy, publicPolicy := cors.Api(), cors.Public()
+	r.Use(cors.CORS(
+		cors.Rule{Prefix: "/api/v1/public/", Policy: publicPolicy},
+		cors.Rule{Prefix: "/feed.", Policy: publicPolicy},
+		cors.Rule{Prefix: "/tags/", Policy: publicPolicy},
+		cors.Rule{Prefix: "/sitemap.xml", Policy: publicPolicy},
+		cors.Rule{Prefix: "/upload/images/", Policy: publicPolicy},
+		cors.Rule{Prefix: "/auth", Policy: apiPolicy},
+		cors.Rule{Prefix: "/api/v1/", Policy: apiPolicy},
+		cors.Rule{Prefix: "/graphql", Policy: apiPolicy},
+		cors.Rule{Prefix: "/upload", Policy: apiPolicy},
+	))
+
 	gin.SetMode(setting.ServerSetting.RunMode)
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// 只信任配置的代理转发的 X-Forwarded-For，否则客户端可以伪造 IP 绕过按 IP 的限流
+	if err := r.SetTrustedProxies(setting.ServerSetting.TrustedProxies); err != nil {
+		logging.Fatal(err)
+	}
+	// OpenAPI 3 文档由各 handler 注册的请求结构体生成，swagger 页面直接展示该文档
+	r.GET("/openapi.json", api.GetOpenAPI)
+	r.GET("/swagger/*any", secure.CSP(secure.SWAGGER_CSP), ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/openapi.json")))
+
+	// 之后注册的路由都属于某个站点，按 X-Tenant 请求头或域名确定
+	r.Use(tenant.Resolve())
+
+	r.GET("/auth", ratelimit.Limit("auth"), validator.OpenAPI(), api.GetAuth)
+	// 清除 /auth?cookie=true 设置的登录 Cookie
+	r.DELETE("/auth", validator.OpenAPI(), api.Logout)
+	// 通过 OIDC 身份提供方登录，成功后与 /auth?cookie=true 一样写入登录 Cookie，未配置 [oidc] 时返回 404
+	r.GET("/auth/oidc/login", validator.OpenAPI(), api.OIDCLogin)
+	r.GET("/auth/oidc/callback", ratelimit.Limit("auth"), validator.OpenAPI(), api.OIDCCallback)
 
-	r.GET("/auth", api.GetAuth)
+	// 已发布文章的 RSS、Atom、JSON Feed 订阅源，无需鉴权
+	for _, format := range []string{"rss", "atom", "json"} {
+		r.GET("/feed."+format, validator.OpenAPI(), api.GetFeed)
+		r.GET("/tags/:id/feed."+format, validator.OpenAPI(), api.GetFeed)
+	}
+
+	r.GET("/sitemap.xml", etag.ETag(), api.GetSitemap)
+
+	// GraphQL 接口，与 /api/v1 使用相同的 token 鉴权，请求与响应格式由 GraphQL 规范定义，只读的 API Key 只能执行查询
+	r.POST("/graphql", jwt.JWT(), csrf.Protect(), jwt.RequireScope(models.SCOPE_READ, models.SCOPE_READ), api.GraphQL)
 
-	r.POST("/upload", api.UploadImage)
+	r.POST("/upload", validator.OpenAPI(), api.UploadImage)
 	// 当访问 $HOST/upload/images 时，会访问 upload.GetImageFullPath() 目录下的文件
 	r.StaticFS("/upload/images", http.Dir(upload.GetImageFullPath()))
-	// 当访问 $HOST/export 时，会访问 export.GetExcelFullPath() 目录下的文件
-	r.StaticFS("/export", http.Dir(export.GetExcelFullPath()))
 
 	apiv1 := r.Group("/api/v1")
 	// 将中间件接入到Gin的访问流程中
 	apiv1.Use(jwt.JWT())
+	// 通过 Cookie 登录时校验写请求的 CSRF token
+	apiv1.Use(csrf.Protect())
+	// 通过 API Key 鉴权时，读请求需 read 权限范围，写请求需 articles:write 权限范围
+	apiv1.Use(jwt.RequireScope(models.SCOPE_READ, models.SCOPE_ARTICLES_WRITE))
+	// 按用户限制写请求的频率
+	apiv1.Use(ratelimit.LimitWrites("write"))
+	// 拒绝不符合 OpenAPI 文档的请求
+	apiv1.Use(validator.OpenAPI())
 	{
 		//获取标签列表
 		apiv1.GET("/tags", v1.GetTags)
@@ -45,12 +104,18 @@ func InitRouter() *gin.Engine {
 		apiv1.PUT("/tags/:id", v1.EditTag)
 		//删除指定标签
 		apiv1.DELETE("/tags/:id", v1.DeleteTag)
+		//批量操作标签
+		apiv1.POST("/tags/bulk", v1.BulkTags)
+		//从回收站恢复标签
+		apiv1.POST("/tags/:id/restore", v1.RestoreTag)
 		//导出标签
 		apiv1.POST("/tags/export", v1.ExportTag)
 		// 导入标签
 		apiv1.POST("/tags/import", v1.ImportTag)
 		//获取文章列表
 		apiv1.GET("/articles", v1.GetArticles)
+		//获取热门文章
+		apiv1.GET("/articles/popular", v1.GetPopularArticles)
 		//获取指定文章
 		apiv1.GET("/articles/:id", v1.GetArticle)
 		//新建文章
@@ -59,10 +124,110 @@ func InitRouter() *gin.Engine {
 		apiv1.PUT("/articles/:id", v1.EditArticle)
 		//删除指定文章
 		apiv1.DELETE("/articles/:id", v1.DeleteArticle)
+		//批量操作文章
+		apiv1.POST("/articles/bulk", v1.BulkArticles)
+		//从回收站恢复文章
+		apiv1.POST("/articles/:id/restore", v1.RestoreArticle)
+		//获取相关文章
+		apiv1.GET("/articles/:id/related", v1.GetRelatedArticles)
+		//点赞文章
+		apiv1.POST("/articles/:id/like", v1.LikeArticle)
+		//取消点赞
+		apiv1.DELETE("/articles/:id/like", v1.UnlikeArticle)
 		//导出文章
 		apiv1.POST("/articles/export", v1.ExportArticle)
 		//导入文章
 		apiv1.POST("/articles/import", v1.ImportArticle)
+		//获取系列列表
+		apiv1.GET("/series", v1.GetSeriesList)
+		//获取指定系列及其文章
+		apiv1.GET("/series/:id", v1.GetSeries)
+		//新建系列
+		apiv1.POST("/series", v1.AddSeries)
+		//更新指定系列
+		apiv1.PUT("/series/:id", v1.EditSeries)
+		//删除指定系列
+		apiv1.DELETE("/series/:id", v1.DeleteSeries)
+		//设置系列中的文章及其顺序
+		apiv1.PUT("/series/:id/articles", v1.SetSeriesArticles)
+		//将文章加到系列末尾
+		apiv1.POST("/series/:id/articles", v1.AddSeriesArticle)
+		//将文章移出系列
+		apiv1.DELETE("/series/:id/articles/:article_id", v1.RemoveSeriesArticle)
+		//获取回收站
+		apiv1.GET("/trash", v1.GetTrash)
+	}
+
+	// 公开的只读接口，只返回已发布的文章与已启用的标签，无需鉴权
+	public := r.Group("/api/v1/public")
+	public.Use(validator.OpenAPI())
+	public.Use(etag.ETag())
+	{
+		//获取已发布的文章列表
+		public.GET("/articles", v1.GetPublicArticles)
+		//按 slug 获取已发布的文章
+		public.GET("/articles/:slug", v1.GetPublicArticle)
+		//获取已启用的标签列表
+		public.GET("/tags", v1.GetPublicTags)
+	}
+
+	// API Key 管理，通过 API Key 鉴权时需 admin 权限范围
+	apiKeys := apiv1.Group("/api-keys")
+	apiKeys.Use(jwt.RequireScope(models.SCOPE_ADMIN, models.SCOPE_ADMIN))
+	{
+		//获取 API Key 列表
+		apiKeys.GET("", v1.GetApiKeys)
+		//新建 API Key
+		apiKeys.POST("", v1.AddApiKey)
+		//吊销 API Key
+		apiKeys.DELETE("/:id", v1.DeleteApiKey)
+	}
+
+	// 管理接口，仅 admin 角色可访问，通过 API Key 鉴权时还需 admin 权限范围
+	admin := apiv1.Group("/admin")
+	admin.Use(jwt.RequireRole(models.ROLE_ADMIN))
+	admin.Use(jwt.RequireScope(models.SCOPE_ADMIN, models.SCOPE_ADMIN))
+	{
+		//获取审计日志
+		admin.GET("/audits", v1.GetAudits)
+		//导出审计日志
+		admin.POST("/audits/export", v1.ExportAudits)
+		//获取 webhook 订阅列表
+		admin.GET("/webhooks", v1.GetWebhooks)
+		//新建 webhook 订阅
+		admin.POST("/webhooks", v1.AddWebhook)
+		//更新 webhook 订阅
+		admin.PUT("/webhooks/:id", v1.EditWebhook)
+		//删除 webhook 订阅
+		admin.DELETE("/webhooks/:id", v1.DeleteWebhook)
+		//获取 webhook 投递记录
+		admin.GET("/webhooks/:id/deliveries", v1.GetWebhookDeliveries)
+		//重放一次投递
+		admin.POST("/webhooks/:id/deliveries/:delivery_id/replay", v1.ReplayWebhookDelivery)
+		//重放所有失败的投递
+		admin.POST("/webhooks/:id/replay", v1.ReplayFailedWebhookDeliveries)
+	}
+
+	// 定时任务与站点管理作用于所有站点，仅默认站点的 admin 可访问
+	global := admin.Group("")
+	global.Use(tenant.RequireDefault())
+	{
+		//获取定时任务列表
+		global.GET("/jobs", v1.GetJobs)
+		//立即执行定时任务
+		global.POST("/jobs/:name/run", v1.RunJob)
+		//暂停定时任务
+		global.POST("/jobs/:name/pause", v1.PauseJob)
+		//恢复定时任务
+		global.POST("/jobs/:name/resume", v1.ResumeJob)
+		//获取定时任务执行记录
+		global.GET("/jobs/:name/runs", v1.GetJobRuns)
+		//获取站点列表
+		global.GET("/tenants", v1.GetTenants)
+		//新建站点
+		global.POST("/tenants", v1.AddTenant)
+		//更新站点
+		global.PUT("/tenants/:id", v1.EditTenant)
 	}
 
 	return r
@@ -0,0 +1,1939 @@
+package routers_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/fzzv/go-gin-example/middleware/csrf"
+	"github.com/fzzv/go-gin-example/middleware/jwt"
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/models/memory"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/export"
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/pkg/scheduler"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/telemetry"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/auth_service"
+	"github.com/fzzv/go-gin-example/service/site_service"
+	"github.com/fzzv/go-gin-example/service/webhook_service"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// backends 同一组用例分别在 SQLite + Redis 与内存实现上运行，以保证内存实现与数据库的行为一致
+var backends = []struct {
+	name string
+	use  func() (restore func())
+}{
+	{"sqlite", func() func() { return func() {} }},
+	{"memory", func() func() {
+		store := memory.New()
+		return service.Use(service.Repositories{Tags: store.Tags(), Articles: store.Articles(), Series: store.Series(), Cache: gredis.NewMemoryCache(), Counter: gredis.NewMemoryCounter()})
+	}},
+}
+
+func TestAuth(t *testing.T) {
+	anon := &client{t: t}
+
+	r := anon.get("/api/v1/tags", nil)
+	expect(t, r, http.StatusUnauthorized, e.INVALID_PARAMS)
+
+	r = anon.get("/api/v1/tags?token=invalid", nil)
+	expect(t, r, http.StatusUnauthorized, e.ERROR_AUTH_CHECK_TOKEN_FAIL)
+
+	r = anon.get("/auth?username="+testUser+"&password=wrong", nil)
+	expect(t, r, http.StatusUnauthorized, e.ERROR_AUTH)
+
+	c := login(t)
+	r = c.get("/api/v1/admin/audits", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	anon := &client{t: t}
+	r := anon.get("/api/v1/public/tags", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	for name, want := range map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Content-Security-Policy": setting.SecuritySetting.ContentSecurityPolicy,
+	} {
+		if got := r.Header().Get(name); got != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if got := r.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Fatalf("HSTS over http: %q", got)
+	}
+
+	r = anon.get("/api/v1/public/tags", http.Header{"X-Forwarded-Proto": {"https"}})
+	if got := r.Header().Get("Strict-Transport-Security"); got != "max-age=31536000" {
+		t.Fatalf("HSTS over https = %q", got)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	anon := &client{t: t}
+	preflight := func(path, origin string) *result {
+		return anon.do(http.MethodOptions, path, nil, "", http.Header{
+			"Origin":                         {origin},
+			"Access-Control-Request-Method":  {http.MethodPut},
+			"Access-Control-Request-Headers": {"If-Match, X-CSRF-Token"},
+		})
+	}
+
+	// 公开接口允许任意来源读取，不携带 Cookie
+	r := preflight("/api/v1/public/articles", "https://reader.example.org")
+	if r.Code != http.StatusNoContent || r.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("public preflight: %d %v", r.Code, r.Header())
+	}
+	r = anon.get("/api/v1/public/tags", http.Header{"Origin": {"https://reader.example.org"}})
+	if r.Header().Get("Access-Control-Allow-Origin") != "*" || r.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("public: %v", r.Header())
+	}
+
+	// 管理接口只允许配置的来源，预检请求没有对应的路由也能得到响应
+	r = preflight("/api/v1/articles/1", "https://evil.example.org")
+	if r.Code != http.StatusForbidden || r.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("disallowed preflight: %d %v", r.Code, r.Header())
+	}
+	r = preflight("/api/v1/articles/1", testOrigin)
+	if r.Code != http.StatusNoContent ||
+		r.Header().Get("Access-Control-Allow-Origin") != testOrigin ||
+		r.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+		!strings.Contains(r.Header().Get("Access-Control-Allow-Methods"), http.MethodPut) ||
+		!strings.Contains(r.Header().Get("Access-Control-Allow-Headers"), "If-Match") {
+		t.Fatalf("allowed preflight: %d %v", r.Code, r.Header())
+	}
+
+	// 前端需要读取 ETag 作为 If-Match 发回
+	c := login(t)
+	r = c.get("/api/v1/tags", http.Header{"Origin": {testOrigin}})
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if !strings.Contains(r.Header().Get("Access-Control-Expose-Headers"), "ETag") {
+		t.Fatalf("expose headers: %v", r.Header())
+	}
+}
+
+func TestTracing(t *testing.T) {
+	c := login(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	restore := telemetry.Use(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer restore()
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	header := http.Header{http.CanonicalHeaderKey("traceparent"): {"00-" + traceID + "-" + spanID + "-01"}}
+	expect(t, c.get("/api/v1/articles", header), http.StatusOK, e.SUCCESS)
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	server := spans["GET /api/v1/articles"]
+	if len(server) != 1 {
+		t.Fatalf("server span: %v", spans)
+	}
+	// 沿用上游的 trace
+	if sc := server[0].SpanContext(); sc.TraceID().String() != traceID || server[0].Parent().SpanID().String() != spanID {
+		t.Fatalf("server span context: %v parent %v", sc, server[0].Parent())
+	}
+
+	// 数据库、Redis 与编码响应的 span 都是请求 span 的子 span
+	for _, name := range []string{"gorm select blog_article", "redis exists", "render json"} {
+		if len(spans[name]) == 0 {
+			t.Fatalf("no %q span in %v", name, spans)
+		}
+		for _, span := range spans[name] {
+			if span.Parent().SpanID() != server[0].SpanContext().SpanID() {
+				t.Fatalf("%q is not a child of the server span", name)
+			}
+		}
+	}
+
+	// 请求身份提供方时创建客户端 span 并透传 trace
+	issuer.SetUser(map[string]interface{}{"sub": "tracer-1", "preferred_username": "tracer", "groups": "blog-editors"})
+	if r := oidcLogin(t, "/", nil); r.Code != http.StatusFound {
+		t.Fatalf("oidc login: %d %s", r.Code, r.Body.String())
+	}
+	var callback, exchange sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "GET /auth/oidc/callback":
+			callback = span
+		case http.MethodPost:
+			exchange = span
+		}
+	}
+	if callback == nil || exchange == nil || exchange.Parent().SpanID() != callback.SpanContext().SpanID() {
+		t.Fatalf("token exchange span: %v callback %v", exchange, callback)
+	}
+
+	// 请求中另起的 goroutine 查询订阅时沿用请求的 trace
+	expect(t, c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "tracing", "state": 1}, nil), http.StatusOK, e.SUCCESS)
+	var create, webhooks sdktrace.ReadOnlySpan
+	for deadline := time.Now().Add(5 * time.Second); webhooks == nil && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, span := range recorder.Ended() {
+			switch span.Name() {
+			case "POST /api/v1/tags":
+				create = span
+			case "gorm select blog_webhook":
+				webhooks = span
+			}
+		}
+	}
+	if create == nil || webhooks == nil || webhooks.Parent().SpanID() != create.SpanContext().SpanID() {
+		t.Fatalf("webhook query span: %v request %v", webhooks, create)
+	}
+
+	// 不属于任何请求的查询不创建 span
+	before := len(recorder.Ended())
+	if _, err := models.GetAuth(context.Background(), 1, testUser); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Ended()) != before {
+		t.Fatalf("query outside a request created a span")
+	}
+}
+
+func TestCookieAuth(t *testing.T) {
+	anon := &client{t: t}
+	r := anon.get("/auth?username="+testUser+"&password="+testPassword+"&cookie=true", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	var data struct {
+		Token     string `json:"token"`
+		CSRFToken string `json:"csrf_token"`
+	}
+	r.data(t, &data)
+	if data.Token != "" || data.CSRFToken == "" {
+		t.Fatalf("cookie login data: %+v", data)
+	}
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range r.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	token, csrfToken := cookies[jwt.COOKIE_NAME], cookies[csrf.COOKIE_NAME]
+	if token == nil || !token.HttpOnly || token.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("token cookie: %+v", token)
+	}
+	if csrfToken == nil || csrfToken.HttpOnly || csrfToken.Value != data.CSRFToken {
+		t.Fatalf("csrf cookie: %+v", csrfToken)
+	}
+
+	cookie := http.Header{"Cookie": {token.String()}}
+	r = anon.get("/api/v1/tags", cookie)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	// 写请求缺少或携带错误的 CSRF token 时拒绝
+	tag := map[string]interface{}{"name": "cookie-auth", "state": 1}
+	r = anon.json(http.MethodPost, "/api/v1/tags", tag, cookie)
+	expect(t, r, http.StatusForbidden, e.ERROR_CSRF_TOKEN)
+	r = anon.json(http.MethodPost, "/api/v1/tags", tag, http.Header{"Cookie": {token.String()}, http.CanonicalHeaderKey(csrf.HEADER): {"wrong"}})
+	expect(t, r, http.StatusForbidden, e.ERROR_CSRF_TOKEN)
+	r = anon.json(http.MethodPost, "/graphql", map[string]string{"query": "{ tags { id } }"}, cookie)
+	expect(t, r, http.StatusForbidden, e.ERROR_CSRF_TOKEN)
+
+	r = anon.json(http.MethodPost, "/api/v1/tags", tag, http.Header{"Cookie": {token.String()}, http.CanonicalHeaderKey(csrf.HEADER): {data.CSRFToken}})
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	// 通过 token 参数鉴权的请求不需要 CSRF token
+	c := login(t)
+	r = c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "token-auth", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	r = anon.do(http.MethodDelete, "/auth", nil, "", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	for _, cookie := range r.Result().Cookies() {
+		if cookie.MaxAge >= 0 {
+			t.Fatalf("logout did not clear cookie %+v", cookie)
+		}
+	}
+}
+
+func TestApiKeys(t *testing.T) {
+	admin := login(t)
+	newKey := func(c *client, scope string, expiresIn int) (models.ApiKey, *client) {
+		t.Helper()
+		r := c.json(http.MethodPost, "/api/v1/api-keys", map[string]interface{}{"name": "ci " + scope, "scope": scope, "expires_in": expiresIn}, nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		var data struct {
+			ApiKey models.ApiKey `json:"api_key"`
+			Key    string        `json:"key"`
+		}
+		r.data(t, &data)
+		if !strings.HasPrefix(data.Key, data.ApiKey.Prefix) || data.ApiKey.Scope != scope {
+			t.Fatalf("new key: %+v", data)
+		}
+		return data.ApiKey, &client{t: t, apiKey: data.Key}
+	}
+
+	readKey, reader := newKey(admin, models.SCOPE_READ, 0)
+	_, writer := newKey(admin, models.SCOPE_ARTICLES_WRITE, 30)
+	_, adminKey := newKey(admin, models.SCOPE_ADMIN, 1)
+
+	// read 只能读取
+	r := reader.get("/api/v1/tags", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	tag := map[string]interface{}{"name": "api-key-tag", "state": 1}
+	r = reader.json(http.MethodPost, "/api/v1/tags", tag, nil)
+	expect(t, r, http.StatusForbidden, e.ERROR_API_KEY_SCOPE)
+	gql(t, reader, `{ tags { lists { id } } }`, nil, 0, nil)
+	gql(t, reader, `mutation { addTag(name: "api-key-gql", state: 1) { id } }`, nil, e.ERROR_API_KEY_SCOPE, nil)
+
+	// articles:write 可以修改内容，不能访问管理接口或管理 API Key
+	r = writer.json(http.MethodPost, "/api/v1/tags", tag, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = writer.get("/api/v1/admin/audits", nil)
+	expect(t, r, http.StatusForbidden, e.ERROR_API_KEY_SCOPE)
+	r = writer.json(http.MethodPost, "/api/v1/api-keys", map[string]interface{}{"name": "escalate", "scope": models.SCOPE_ADMIN}, nil)
+	expect(t, r, http.StatusForbidden, e.ERROR_API_KEY_SCOPE)
+
+	r = adminKey.get("/api/v1/admin/audits", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	r = admin.get("/api/v1/api-keys?username="+testUser, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if strings.Contains(r.Body.String(), "key_hash") || strings.Contains(r.Body.String(), reader.apiKey) {
+		t.Fatalf("list exposes keys: %s", r.Body.String())
+	}
+	var list struct {
+		Lists []models.ApiKey `json:"lists"`
+	}
+	r.data(t, &list)
+	if len(list.Lists) < 3 {
+		t.Fatalf("list: %+v", list.Lists)
+	}
+	for _, key := range list.Lists {
+		if key.ID == readKey.ID && key.LastUsedOn == 0 {
+			t.Fatalf("last used not tracked: %+v", key)
+		}
+	}
+
+	// 账号只能吊销自己的 key，不能创建 admin 权限范围的 key
+	editor := auth_service.Auth{TenantID: 1, Username: "key-editor", Password: "editor123", Role: models.ROLE_EDITOR}
+	if _, err := editor.Add(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	editorClient := loginAs(t, "", editor.Username, editor.Password)
+	r = editorClient.json(http.MethodPost, "/api/v1/api-keys", map[string]interface{}{"name": "mine", "scope": models.SCOPE_ADMIN}, nil)
+	expect(t, r, http.StatusForbidden, e.ERROR_AUTH_FORBIDDEN)
+	editorKey, _ := newKey(editorClient, models.SCOPE_READ, 0)
+	r = editorClient.do(http.MethodDelete, fmt.Sprintf("/api/v1/api-keys/%d", readKey.ID), nil, "", nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_API_KEY)
+	r = editorClient.get("/api/v1/api-keys", nil)
+	r.data(t, &list)
+	if len(list.Lists) != 1 || list.Lists[0].ID != editorKey.ID {
+		t.Fatalf("editor list: %+v", list.Lists)
+	}
+
+	r = admin.do(http.MethodDelete, fmt.Sprintf("/api/v1/api-keys/%d", readKey.ID), nil, "", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = reader.get("/api/v1/tags", nil)
+	expect(t, r, http.StatusUnauthorized, e.ERROR_AUTH_CHECK_API_KEY_FAIL)
+	r = (&client{t: t, apiKey: "blog_unknown"}).get("/api/v1/tags", nil)
+	expect(t, r, http.StatusUnauthorized, e.ERROR_AUTH_CHECK_API_KEY_FAIL)
+}
+
+// oidcLogin 以 issuer 当前设置的用户完成一次 OIDC 登录，返回回调的响应；tamper 用于在回调前修改参数
+func oidcLogin(t *testing.T, redirect string, tamper func(q url.Values)) *result {
+	t.Helper()
+
+	anon := &client{t: t}
+	r := anon.get("/auth/oidc/login?redirect="+url.QueryEscape(redirect), nil)
+	if r.Code != http.StatusFound {
+		t.Fatalf("oidc login: %d %s", r.Code, r.Body.String())
+	}
+	var state *http.Cookie
+	for _, cookie := range r.Result().Cookies() {
+		if cookie.Name == "oidc_state" {
+			state = cookie
+		}
+	}
+	if state == nil || !state.HttpOnly {
+		t.Fatalf("state cookie: %+v", state)
+	}
+
+	// 身份提供方直接同意授权并跳转到回调地址
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noFollow.Get(r.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound || !strings.HasPrefix(callback.String(), testOIDCCallback) {
+		t.Fatalf("authorize: %d %q %v", resp.StatusCode, callback, err)
+	}
+
+	q := callback.Query()
+	if tamper != nil {
+		tamper(q)
+	}
+	return anon.get(callback.Path+"?"+q.Encode(), http.Header{"Cookie": {state.String()}})
+}
+
+func TestOIDC(t *testing.T) {
+	issuer.SetUser(map[string]interface{}{"sub": "alice-1", "preferred_username": "alice", "groups": []string{"staff", "blog-admins"}})
+	r := oidcLogin(t, "/admin/articles", nil)
+	if r.Code != http.StatusFound || r.Header().Get("Location") != "/admin/articles" {
+		t.Fatalf("callback: %d %q %s", r.Code, r.Header().Get("Location"), r.Body.String())
+	}
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range r.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	token, csrfToken := cookies[jwt.COOKIE_NAME], cookies[csrf.COOKIE_NAME]
+	if token == nil || token.Value == "" || !token.HttpOnly || csrfToken == nil || csrfToken.Value != csrf.Token(token.Value) {
+		t.Fatalf("session cookies: %+v %+v", token, csrfToken)
+	}
+
+	// 首次登录时创建账号并绑定 sub，角色由 groups 确定
+	alice := &client{t: t, token: token.Value}
+	expect(t, alice.get("/api/v1/admin/audits", nil), http.StatusOK, e.SUCCESS)
+	auth, err := models.GetAuth(context.Background(), 1, "alice")
+	if err != nil || auth.Role != models.ROLE_ADMIN || auth.Subject != "alice-1" {
+		t.Fatalf("created user: %+v %v", auth, err)
+	}
+
+	// 之后的登录按 groups 同步角色
+	issuer.SetUser(map[string]interface{}{"sub": "alice-1", "preferred_username": "alice", "groups": "blog-editors"})
+	r = oidcLogin(t, "//evil.example.com", nil)
+	if r.Code != http.StatusFound || r.Header().Get("Location") != "/" {
+		t.Fatalf("callback: %d %q %s", r.Code, r.Header().Get("Location"), r.Body.String())
+	}
+	if auth, _ := models.GetAuth(context.Background(), 1, "alice"); auth.Role != models.ROLE_EDITOR {
+		t.Fatalf("role not synced: %+v", auth)
+	}
+
+	// 按 sub 匹配账号，身份提供方中的用户名修改后仍登录原账号
+	issuer.SetUser(map[string]interface{}{"sub": "alice-1", "preferred_username": "alice-renamed", "groups": "blog-editors"})
+	if r := oidcLogin(t, "/", nil); r.Code != http.StatusFound {
+		t.Fatalf("renamed user callback: %d %s", r.Code, r.Body.String())
+	}
+	if auth, _ := models.GetAuth(context.Background(), 1, "alice-renamed"); auth.ID != 0 {
+		t.Fatalf("renamed user was created: %+v", auth)
+	}
+
+	// 同名但 sub 不同的用户不能登录已绑定的账号
+	issuer.SetUser(map[string]interface{}{"sub": "mallory", "preferred_username": "alice", "groups": "blog-admins"})
+	expect(t, oidcLogin(t, "/", nil), http.StatusConflict, e.ERROR_OIDC_USER_MISMATCH)
+
+	// 用户名声明可以被用户自行修改，不能据此接管未绑定的本地账号，需先由管理员通过 user bind-oidc 绑定
+	dave := auth_service.Auth{TenantID: 1, Username: "oidc-dave", Password: "dave12345", Role: models.ROLE_ADMIN}
+	if _, err := dave.Add(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	issuer.SetUser(map[string]interface{}{"sub": "mallory", "preferred_username": "oidc-dave", "groups": "blog-editors"})
+	expect(t, oidcLogin(t, "/", nil), http.StatusForbidden, e.ERROR_OIDC_NOT_BOUND)
+	if auth, _ := models.GetAuth(context.Background(), 1, "oidc-dave"); auth.Subject != "" || auth.Role != models.ROLE_ADMIN {
+		t.Fatalf("unbound user was modified: %+v", auth)
+	}
+
+	dave.Subject = "alice-1"
+	if err := dave.BindSubject(context.Background()); err == nil {
+		t.Fatal("bind a subject bound to another user: want error")
+	}
+	dave.Subject = "dave-1"
+	if err := dave.BindSubject(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	issuer.SetUser(map[string]interface{}{"sub": "dave-1", "preferred_username": "dave", "groups": "blog-admins"})
+	r = oidcLogin(t, "/", nil)
+	if r.Code != http.StatusFound {
+		t.Fatalf("bound user callback: %d %s", r.Code, r.Body.String())
+	}
+	if auth, _ := models.GetAuth(context.Background(), 1, "dave"); auth.ID != 0 {
+		t.Fatalf("bound user login created %+v", auth)
+	}
+
+	issuer.SetUser(map[string]interface{}{"sub": "bob-1", "preferred_username": "bob", "groups": []string{"staff"}})
+	expect(t, oidcLogin(t, "/", nil), http.StatusForbidden, e.ERROR_OIDC_NO_ROLE)
+	if auth, _ := models.GetAuth(context.Background(), 1, "bob"); auth.ID != 0 {
+		t.Fatalf("user without role was created: %+v", auth)
+	}
+
+	issuer.SetUser(map[string]interface{}{"preferred_username": "carol", "groups": "blog-editors"})
+	expect(t, oidcLogin(t, "/", nil), http.StatusUnauthorized, e.ERROR_OIDC_ID_TOKEN)
+
+	issuer.Deny()
+	expect(t, oidcLogin(t, "/", nil), http.StatusForbidden, e.ERROR_OIDC_DENIED)
+
+	// state 与 Cookie 不一致，或授权码被篡改
+	issuer.SetUser(map[string]interface{}{"sub": "alice-1", "preferred_username": "alice", "groups": "blog-editors"})
+	expect(t, oidcLogin(t, "/", func(q url.Values) { q.Set("state", "forged") }), http.StatusBadRequest, e.ERROR_OIDC_STATE)
+	expect(t, oidcLogin(t, "/", func(q url.Values) { q.Set("code", "forged") }), http.StatusBadGateway, e.ERROR_OIDC_PROVIDER)
+
+	anon := &client{t: t}
+	expect(t, anon.get("/auth/oidc/callback?code=x&state=y", nil), http.StatusBadRequest, e.ERROR_OIDC_STATE)
+}
+
+// TestAuthService 覆盖 user 子命令使用的账号管理
+func TestAuthService(t *testing.T) {
+	editor := auth_service.Auth{TenantID: 1, Username: "cli-editor", Password: "editor123", Role: models.ROLE_EDITOR}
+	if _, err := editor.Add(context.Background()); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, err := editor.Add(context.Background()); err == nil {
+		t.Fatal("add existing user: want error")
+	}
+	if _, err := (&auth_service.Auth{TenantID: 1, Username: "cli-other", Password: "x", Role: "owner"}).Add(context.Background()); err == nil {
+		t.Fatal("add with unknown role: want error")
+	}
+
+	c := loginAs(t, "", "cli-editor", "editor123")
+	r := c.get("/api/v1/admin/audits", nil)
+	if r.Code != http.StatusForbidden {
+		t.Fatalf("editor audits: %d %s", r.Code, r.Body.String())
+	}
+
+	editor.Role = models.ROLE_ADMIN
+	if err := editor.SetRole(context.Background()); err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+	editor.Password = "changed123"
+	if err := editor.ResetPassword(context.Background()); err != nil {
+		t.Fatalf("reset password: %v", err)
+	}
+
+	anon := &client{t: t}
+	r = anon.get("/auth?username=cli-editor&password=editor123", nil)
+	expect(t, r, http.StatusUnauthorized, e.ERROR_AUTH)
+	c = loginAs(t, "", "cli-editor", "changed123")
+	r = c.get("/api/v1/admin/audits", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	if err := (&auth_service.Auth{TenantID: 1, Username: "missing", Role: models.ROLE_ADMIN}).SetRole(context.Background()); err == nil {
+		t.Fatal("set role of missing user: want error")
+	}
+}
+
+func TestUpload(t *testing.T) {
+	c := &client{t: t}
+
+	r := c.upload("/upload", "image", "cover.gif", []byte("GIF89a"))
+	expect(t, r, http.StatusBadRequest, e.ERROR_UPLOAD_CHECK_IMAGE_FORMAT)
+
+	r = c.upload("/upload", "image", "cover.jpg", []byte("\xff\xd8\xff\xe0 not really a jpeg"))
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	var data struct {
+		ImageURL     string `json:"image_url"`
+		ImageSaveURL string `json:"image_save_url"`
+	}
+	r.data(t, &data)
+	if _, err := os.Stat(setting.AppSetting.RuntimeRootPath + data.ImageSaveURL); err != nil {
+		t.Fatalf("uploaded image not saved: %v", err)
+	}
+
+	r = c.get("/"+strings.TrimPrefix(data.ImageURL, setting.AppSetting.PrefixUrl+"/"), nil)
+	if r.Code != http.StatusOK {
+		t.Fatalf("get uploaded image: %d", r.Code)
+	}
+}
+
+// TestValidationErrors 参数错误按参数名返回翻译后的信息，不返回校验库的原始错误
+func TestValidationErrors(t *testing.T) {
+	c := login(t)
+
+	tests := []struct {
+		method, path string
+		body         interface{}
+		want         map[string]string
+	}{
+		{http.MethodPost, "/api/v1/tags", map[string]interface{}{"state": 1}, map[string]string{"name": "name为必填字段"}},
+		{http.MethodPost, "/api/v1/tags?lang=en", map[string]interface{}{"state": 1}, map[string]string{"name": "name is a required field"}},
+		{http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": 123, "state": 1}, map[string]string{"name": "格式不正确"}},
+		{http.MethodGet, "/api/v1/tags?page=abc&lang=en", nil, map[string]string{"page": "is invalid"}},
+	}
+	for _, tt := range tests {
+		var r *result
+		if tt.body == nil {
+			r = c.get(tt.path, nil)
+		} else {
+			r = c.json(tt.method, tt.path, tt.body, nil)
+		}
+		expect(t, r, http.StatusBadRequest, e.INVALID_PARAMS)
+		var got map[string]string
+		r.data(t, &got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s: errors = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestProblemJSON X-Api-Version 为 2 时错误响应使用 problem+json，为 1 时保持 code / msg / data 格式
+func TestProblemJSON(t *testing.T) {
+	c := login(t)
+
+	requests := []struct {
+		name   string
+		send   func(header http.Header) *result
+		status int
+		code   int
+		detail string
+	}{
+		{"not found", func(header http.Header) *result {
+			return c.get("/api/v1/articles/999999?lang=en", header)
+		}, http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE, e.GetMsgByLang(e.ERROR_NOT_EXIST_ARTICLE, e.LANG_EN)},
+		{"invalid params", func(header http.Header) *result {
+			return c.json(http.MethodPost, "/api/v1/tags?lang=en", map[string]interface{}{"state": 1}, header)
+		}, http.StatusBadRequest, e.INVALID_PARAMS, "name: name is a required field"},
+	}
+	for _, req := range requests {
+		t.Run(req.name, func(t *testing.T) {
+			r := req.send(http.Header{"X-Api-Version": {"2"}})
+			if ct := r.Header().Get("Content-Type"); r.Code != req.status || !strings.HasPrefix(ct, "application/problem+json") {
+				t.Fatalf("v2: %d %s: %s", r.Code, ct, r.Body.String())
+			}
+			var problem struct {
+				Type   string            `json:"type"`
+				Title  string            `json:"title"`
+				Status int               `json:"status"`
+				Detail string            `json:"detail"`
+				Code   int               `json:"code"`
+				Errors map[string]string `json:"errors"`
+			}
+			if err := json.Unmarshal(r.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Type != fmt.Sprintf("urn:go-gin-example:error:%d", req.code) ||
+				problem.Title != e.GetMsgByLang(req.code, e.LANG_EN) ||
+				problem.Status != req.status || problem.Detail != req.detail || problem.Code != req.code {
+				t.Fatalf("v2 problem = %+v", problem)
+			}
+			if req.code == e.INVALID_PARAMS && problem.Errors["name"] == "" {
+				t.Fatalf("v2 problem has no field errors: %+v", problem)
+			}
+
+			r = req.send(http.Header{"X-Api-Version": {"1"}})
+			if ct := r.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Fatalf("v1 Content-Type = %s", ct)
+			}
+			expect(t, r, req.status, req.code)
+			if r.body.Msg != e.GetMsgByLang(req.code, e.LANG_EN) {
+				t.Fatalf("v1 msg = %q", r.body.Msg)
+			}
+		})
+	}
+}
+
+// TestJobs 覆盖定时任务的管理接口，任务在测试中注册，执行时阻塞到测试放行
+func TestJobs(t *testing.T) {
+	release := make(chan struct{})
+	scheduler.Register("clean_tags", func(context.Context) error {
+		<-release
+		return nil
+	})
+	c := login(t)
+
+	var jobs struct {
+		Lists    []scheduler.Status `json:"lists"`
+		Instance string             `json:"instance"`
+		Leader   bool               `json:"leader"`
+	}
+	job := func() scheduler.Status {
+		t.Helper()
+		r := c.get("/api/v1/admin/jobs", nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		r.data(t, &jobs)
+		for _, s := range jobs.Lists {
+			if s.Name == "clean_tags" {
+				return s
+			}
+		}
+		t.Fatalf("clean_tags is not listed: %+v", jobs.Lists)
+		return scheduler.Status{}
+	}
+	if s := job(); s.Paused || s.Running || jobs.Instance != scheduler.Instance {
+		t.Fatalf("job = %+v, instance %q", s, jobs.Instance)
+	}
+
+	r := c.json(http.MethodPost, "/api/v1/admin/jobs/clean_tags/run", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusAccepted, e.SUCCESS)
+	var run models.JobRun
+	r.data(t, &run)
+	if run.ID == 0 || run.Trigger != models.JOB_TRIGGER_MANUAL || run.TriggerBy != testUser || run.Status != models.JOB_RUN_RUNNING {
+		t.Fatalf("run = %+v", run)
+	}
+	if s := job(); !s.Running {
+		t.Fatalf("job = %+v, want running", s)
+	}
+	r = c.json(http.MethodPost, "/api/v1/admin/jobs/clean_tags/run", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusConflict, e.ERROR_JOB_RUNNING)
+	close(release)
+
+	// 等待后台执行结束
+	var runs struct {
+		Lists []models.JobRun `json:"lists"`
+		Total int             `json:"total"`
+	}
+	for i := 0; i < 100; i++ {
+		r = c.get("/api/v1/admin/jobs/clean_tags/runs", nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		r.data(t, &runs)
+		if runs.Total == 1 && runs.Lists[0].Status != models.JOB_RUN_RUNNING {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runs.Total != 1 || runs.Lists[0].ID != run.ID || runs.Lists[0].Status != models.JOB_RUN_SUCCESS {
+		t.Fatalf("runs = %+v", runs)
+	}
+
+	r = c.json(http.MethodPost, "/api/v1/admin/jobs/clean_tags/pause", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if s := job(); !s.Paused {
+		t.Fatalf("job = %+v, want paused", s)
+	}
+	r = c.json(http.MethodPost, "/api/v1/admin/jobs/clean_tags/resume", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if s := job(); s.Paused {
+		t.Fatalf("job = %+v, want resumed", s)
+	}
+
+	for _, path := range []string{"run", "pause", "resume"} {
+		r = c.json(http.MethodPost, "/api/v1/admin/jobs/missing/"+path, map[string]interface{}{}, nil)
+		expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_JOB)
+	}
+	r = c.get("/api/v1/admin/jobs/missing/runs", nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_JOB)
+
+	// 只有管理员可以管理定时任务
+	r = loginAs(t, "", testEditor, testPassword).json(http.MethodPost, "/api/v1/admin/jobs/clean_tags/run", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusForbidden, e.ERROR_AUTH_FORBIDDEN)
+}
+
+// TestWebhooks 订阅的事件经投递循环发送到接收方，接收方按密钥校验签名，重放以相同内容再次投递
+func TestWebhooks(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan request, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{r.Header, body}
+	}))
+	defer receiver.Close()
+
+	// httptest 监听在回环地址上
+	setting.WebhookSetting.AllowPrivateNetwork = true
+	defer func() { setting.WebhookSetting.AllowPrivateNetwork = false }()
+	webhook_service.Setup()
+	defer webhook_service.Stop()
+
+	c := login(t)
+	r := c.json(http.MethodPost, "/api/v1/admin/webhooks", map[string]interface{}{"url": receiver.URL, "events": "tag.created", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	var added struct {
+		Webhook models.Webhook `json:"webhook"`
+		Secret  string         `json:"secret"`
+	}
+	r.data(t, &added)
+	if added.Webhook.ID == 0 || added.Secret == "" {
+		t.Fatalf("added = %+v", added)
+	}
+	defer func() {
+		r := c.do(http.MethodDelete, fmt.Sprintf("/api/v1/admin/webhooks/%d", added.Webhook.ID), nil, "", nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+	}()
+
+	wait := func() request {
+		t.Helper()
+		select {
+		case req := <-received:
+			timestamp := req.header.Get("X-Webhook-Timestamp")
+			mac := hmac.New(sha256.New, []byte(added.Secret))
+			mac.Write([]byte(timestamp + "."))
+			mac.Write(req.body)
+			if got, want := req.header.Get("X-Webhook-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+				t.Fatalf("signature = %s, want %s", got, want)
+			}
+			return req
+		case <-time.After(5 * time.Second):
+			t.Fatal("no delivery received")
+			return request{}
+		}
+	}
+
+	r = c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "webhook-tag", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	first := wait()
+	var payload struct {
+		Event string     `json:"event"`
+		Data  models.Tag `json:"data"`
+	}
+	if err := json.Unmarshal(first.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != "tag.created" || payload.Data.Name != "webhook-tag" || first.header.Get("X-Webhook-Event") != "tag.created" {
+		t.Fatalf("payload = %s, headers = %v", first.body, first.header)
+	}
+
+	deliveries := func() []models.WebhookDelivery {
+		t.Helper()
+		var list struct {
+			Lists []models.WebhookDelivery `json:"lists"`
+		}
+		for i := 0; i < 100; i++ {
+			r := c.get(fmt.Sprintf("/api/v1/admin/webhooks/%d/deliveries", added.Webhook.ID), nil)
+			expect(t, r, http.StatusOK, e.SUCCESS)
+			r.data(t, &list)
+			if len(list.Lists) > 0 && list.Lists[0].Status == models.DELIVERY_SUCCESS {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return list.Lists
+	}
+	list := deliveries()
+	if len(list) != 1 || list[0].Status != models.DELIVERY_SUCCESS || list[0].Attempts != 1 || list[0].ResponseCode != http.StatusOK {
+		t.Fatalf("deliveries = %+v", list)
+	}
+	if id := first.header.Get("X-Webhook-Delivery"); id != strconv.Itoa(list[0].ID) {
+		t.Fatalf("delivery header = %s, want %d", id, list[0].ID)
+	}
+
+	r = c.json(http.MethodPost, fmt.Sprintf("/api/v1/admin/webhooks/%d/deliveries/%d/replay", added.Webhook.ID, list[0].ID), map[string]interface{}{}, nil)
+	expect(t, r, http.StatusAccepted, e.SUCCESS)
+	var replay models.WebhookDelivery
+	r.data(t, &replay)
+	if replay.ReplayOf != list[0].ID {
+		t.Fatalf("replay = %+v", replay)
+	}
+	second := wait()
+	if string(second.body) != string(first.body) || second.header.Get("X-Webhook-Delivery") != strconv.Itoa(replay.ID) {
+		t.Fatalf("replayed body = %s, headers = %v", second.body, second.header)
+	}
+
+	list = deliveries()
+	if len(list) != 2 || list[0].ID != replay.ID || list[0].Status != models.DELIVERY_SUCCESS || list[1].Replayed != 1 {
+		t.Fatalf("deliveries after replay = %+v", list)
+	}
+
+	r = c.get("/api/v1/admin/webhooks/999999/deliveries", nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_WEBHOOK)
+	select {
+	case req := <-received:
+		t.Fatalf("unexpected delivery %s", req.body)
+	default:
+	}
+}
+
+// brokenTags、brokenArticles 在 Edit 或 Restore 之后读取失败，模拟修改后重新读取时数据库出错
+type brokenTags struct {
+	models.TagRepository
+	editErr error
+	broken  bool
+}
+
+func (r *brokenTags) Edit(ctx context.Context, tenantID, id, version int, data map[string]interface{}) error {
+	r.broken = true
+	if r.editErr != nil {
+		return r.editErr
+	}
+	return r.TagRepository.Edit(ctx, tenantID, id, version, data)
+}
+
+func (r *brokenTags) Restore(ctx context.Context, tag *models.Tag, cascade bool) error {
+	r.broken = true
+	return r.TagRepository.Restore(ctx, tag, cascade)
+}
+
+func (r *brokenTags) Get(ctx context.Context, tenantID, id int) (*models.Tag, error) {
+	if r.broken {
+		return nil, errors.New("connection lost")
+	}
+	return r.TagRepository.Get(ctx, tenantID, id)
+}
+
+type brokenArticles struct {
+	models.ArticleRepository
+	editErr error
+	broken  bool
+}
+
+func (r *brokenArticles) Edit(ctx context.Context, tenantID, id, version int, data map[string]interface{}) error {
+	r.broken = true
+	if r.editErr != nil {
+		return r.editErr
+	}
+	return r.ArticleRepository.Edit(ctx, tenantID, id, version, data)
+}
+
+func (r *brokenArticles) Restore(ctx context.Context, tenantID, id int) error {
+	r.broken = true
+	return r.ArticleRepository.Restore(ctx, tenantID, id)
+}
+
+func (r *brokenArticles) Get(ctx context.Context, tenantID, id int) (*models.Article, error) {
+	if r.broken {
+		return nil, errors.New("connection lost")
+	}
+	return r.ArticleRepository.Get(ctx, tenantID, id)
+}
+
+// TestEditReloadFail 修改或恢复后重新读取失败时返回对应的失败，而不是 panic
+func TestEditReloadFail(t *testing.T) {
+	store := memory.New()
+	tags := &brokenTags{TagRepository: store.Tags()}
+	articles := &brokenArticles{ArticleRepository: store.Articles()}
+	defer service.Use(service.Repositories{Tags: tags, Articles: articles, Cache: gredis.NewMemoryCache()})()
+
+	c := login(t)
+	r := c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "reload", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	tagID := findTag(t, c, "reload").ID
+	article := map[string]interface{}{
+		"tag_id":          tagID,
+		"title":           "Reload",
+		"desc":            "desc",
+		"content":         "content",
+		"cover_image_url": "upload/images/cover.jpg",
+		"state":           1,
+	}
+	r = c.json(http.MethodPost, "/api/v1/articles", article, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	articleID := findArticle(t, c, "reload").ID
+
+	for _, editErr := range []error{nil, models.ErrVersionConflict} {
+		tags.broken, tags.editErr = false, editErr
+		r = c.json(http.MethodPut, fmt.Sprintf("/api/v1/tags/%d", tagID), map[string]interface{}{"name": "reload"}, nil)
+		expect(t, r, http.StatusInternalServerError, e.ERROR_EDIT_TAG_FAIL)
+
+		articles.broken, articles.editErr = false, editErr
+		r = c.json(http.MethodPut, fmt.Sprintf("/api/v1/articles/%d", articleID), article, nil)
+		expect(t, r, http.StatusInternalServerError, e.ERROR_EDIT_ARTICLE_FAIL)
+	}
+
+	// 恢复成功后重新读取失败
+	articles.broken, articles.editErr = false, nil
+	r = c.do(http.MethodDelete, fmt.Sprintf("/api/v1/articles/%d", articleID), nil, "", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.json(http.MethodPost, fmt.Sprintf("/api/v1/articles/%d/restore", articleID), map[string]interface{}{}, nil)
+	expect(t, r, http.StatusInternalServerError, e.ERROR_RESTORE_ARTICLE_FAIL)
+
+	tags.broken = false
+	r = c.do(http.MethodDelete, fmt.Sprintf("/api/v1/tags/%d?cascade=true", tagID), nil, "", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.json(http.MethodPost, fmt.Sprintf("/api/v1/tags/%d/restore", tagID), map[string]interface{}{}, nil)
+	expect(t, r, http.StatusInternalServerError, e.ERROR_RESTORE_TAG_FAIL)
+}
+
+func TestAPI(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			defer b.use()()
+			redis.FlushAll()
+
+			t.Run("tags", testTags)
+			t.Run("articles", testArticles)
+			t.Run("bulk", testBulk)
+			t.Run("import export", testImportExport)
+			t.Run("graphql", testGraphQL)
+			t.Run("engagement", testEngagement)
+			t.Run("related", testRelated)
+			t.Run("series", testSeries)
+		})
+	}
+}
+
+func testTags(t *testing.T) {
+	c := login(t)
+
+	r := c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "go", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "go", "state": 1}, nil)
+	expect(t, r, http.StatusConflict, e.ERROR_EXIST_TAG)
+	r = c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"state": 1}, nil)
+	expect(t, r, http.StatusBadRequest, e.INVALID_PARAMS)
+
+	id := findTag(t, c, "go").ID
+	path := fmt.Sprintf("/api/v1/tags/%d", id)
+
+	r = c.json(http.MethodPut, path, map[string]interface{}{"name": "golang"}, http.Header{"If-Match": {`"5"`}})
+	expect(t, r, http.StatusPreconditionFailed, e.ERROR_PRECONDITION_FAILED)
+
+	r = c.json(http.MethodPut, path, map[string]interface{}{"name": "golang"}, http.Header{"If-Match": {`"1"`}})
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if etag := r.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf("ETag after edit = %s, want \"2\"", etag)
+	}
+	if tag := findTag(t, c, "golang"); tag.ID != id || tag.Version != 2 {
+		t.Fatalf("edited tag = %+v", tag)
+	}
+
+	r = c.do(http.MethodDelete, path, nil, "", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.do(http.MethodDelete, path, nil, "", nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_TAG)
+
+	var trash struct {
+		Tags struct {
+			Total int `json:"total"`
+		} `json:"tags"`
+	}
+	r = c.get("/api/v1/trash?type=tag", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r.data(t, &trash)
+	if trash.Tags.Total != 1 {
+		t.Fatalf("trash has %d tags, want 1", trash.Tags.Total)
+	}
+
+	r = c.json(http.MethodPost, path+"/restore", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	findTag(t, c, "golang")
+}
+
+func testArticles(t *testing.T) {
+	c := login(t)
+
+	r := c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "notes", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	tagID := findTag(t, c, "notes").ID
+
+	article := map[string]interface{}{
+		"tag_id":          tagID,
+		"title":           "Hello World",
+		"desc":            "desc",
+		"content":         "content",
+		"cover_image_url": "upload/images/cover.jpg",
+		"state":           1,
+	}
+	r = c.json(http.MethodPost, "/api/v1/articles", article, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.json(http.MethodPost, "/api/v1/articles", article, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	article["tag_id"] = 99999
+	r = c.json(http.MethodPost, "/api/v1/articles", article, nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_TAG)
+
+	first, second := findArticle(t, c, "hello-world"), findArticle(t, c, "hello-world-2")
+	if first.ID == 0 || second.ID == 0 || first.Tag.Name != "notes" {
+		t.Fatalf("articles = %+v, %+v", first, second)
+	}
+
+	path := fmt.Sprintf("/api/v1/articles/%d", first.ID)
+	r = c.get(path, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	etag := r.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("ETag = %s, want \"1\"", etag)
+	}
+	if r = c.get(path, http.Header{"If-None-Match": {etag}}); r.Code != http.StatusNotModified {
+		t.Fatalf("conditional get: %d, want 304", r.Code)
+	}
+
+	edit := map[string]interface{}{
+		"tag_id":          tagID,
+		"title":           "Hello Again",
+		"desc":            "desc",
+		"cover_image_url": "upload/images/cover.jpg",
+	}
+	r = c.json(http.MethodPut, path, edit, http.Header{"If-Match": {etag}})
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.json(http.MethodPut, path, edit, http.Header{"If-Match": {etag}})
+	expect(t, r, http.StatusPreconditionFailed, e.ERROR_PRECONDITION_FAILED)
+
+	// 修改后单篇文章的缓存应失效
+	var got models.Article
+	r = c.get(path, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r.data(t, &got)
+	if got.Title != "Hello Again" || got.Version != 2 || r.Header().Get("ETag") != `"2"` {
+		t.Fatalf("edited article = %+v, ETag %s", got, r.Header().Get("ETag"))
+	}
+
+	r = c.do(http.MethodDelete, fmt.Sprintf("/api/v1/tags/%d", tagID), nil, "", nil)
+	expect(t, r, http.StatusConflict, e.ERROR_TAG_HAS_ARTICLES)
+
+	r = c.do(http.MethodDelete, path, nil, "", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.get(path, nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE)
+	r = c.get("/api/v1/public/articles/hello-world", nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE)
+
+	r = c.json(http.MethodPost, path+"/restore", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	findArticle(t, c, "hello-world")
+}
+
+func testBulk(t *testing.T) {
+	c := login(t)
+
+	ops := []map[string]interface{}{
+		{"op": "create", "name": "bulk-a", "state": 1},
+		{"op": "update", "id": 99999, "name": "missing"},
+	}
+	r := c.json(http.MethodPost, "/api/v1/tags/bulk", map[string]interface{}{"atomic": true, "operations": ops}, nil)
+	expect(t, r, http.StatusConflict, e.ERROR_BULK_ROLLED_BACK)
+	for _, tag := range enabledTags(t, c) {
+		if tag.Name == "bulk-a" {
+			t.Fatal("rolled back tag was created")
+		}
+	}
+
+	r = c.json(http.MethodPost, "/api/v1/tags/bulk", map[string]interface{}{"operations": ops}, nil)
+	expect(t, r, http.StatusMultiStatus, e.ERROR_BULK_PARTIAL)
+	findTag(t, c, "bulk-a")
+}
+
+func testImportExport(t *testing.T) {
+	c := login(t)
+
+	tags, articles := countTags(t, c), countArticles(t, c)
+	if tags == 0 || articles == 0 {
+		t.Fatalf("nothing to export: %d tags, %d articles", tags, articles)
+	}
+
+	for _, kind := range []string{"tags", "articles"} {
+		r := c.json(http.MethodPost, "/api/v1/"+kind+"/export", map[string]interface{}{"state": 1}, nil)
+		if r.Code != http.StatusOK || r.Header().Get("Content-Type") != export.CONTENT_TYPE ||
+			!strings.HasPrefix(r.Header().Get("Content-Disposition"), `attachment; filename="`+kind+"-") {
+			t.Fatalf("export %s: %d %s %s", kind, r.Code, r.Header().Get("Content-Type"), r.Header().Get("Content-Disposition"))
+		}
+		content := r.Body.Bytes()
+
+		r = c.upload("/api/v1/"+kind+"/import", "file", "import.xlsx", content)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+	}
+
+	// 导入的标签与文章都是已启用、已发布的，数量应翻倍
+	if got := countTags(t, c); got != 2*tags {
+		t.Fatalf("after import %d tags, want %d", got, 2*tags)
+	}
+	if got := countArticles(t, c); got != 2*articles {
+		t.Fatalf("after import %d articles, want %d", got, 2*articles)
+	}
+
+	r := c.upload("/api/v1/tags/import", "file", "import.xlsx", []byte("not a spreadsheet"))
+	expect(t, r, http.StatusInternalServerError, e.ERROR_IMPORT_TAG_FAIL)
+
+	r = c.get("/api/v1/admin/audits?action=import", nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	var audits struct {
+		Total int `json:"total"`
+	}
+	r.data(t, &audits)
+	if audits.Total < 2 {
+		t.Fatalf("import audits = %d, want at least 2", audits.Total)
+	}
+
+	r = c.json(http.MethodPost, "/api/v1/admin/audits/export", map[string]interface{}{"action": "import"}, nil)
+	if r.Code != http.StatusOK || r.Header().Get("Content-Type") != export.CONTENT_TYPE {
+		t.Fatalf("export audits: %d %s", r.Code, r.Header().Get("Content-Type"))
+	}
+	if !strings.HasPrefix(r.Header().Get("Content-Disposition"), "attachment;") {
+		t.Fatalf("export audits Content-Disposition = %q", r.Header().Get("Content-Disposition"))
+	}
+	f, err := excelize.OpenReader(r.Body)
+	if err != nil {
+		t.Fatalf("open audits export: %v", err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil || len(rows) != audits.Total+1 {
+		t.Fatalf("audits export has %d rows, want %d: %v", len(rows), audits.Total+1, err)
+	}
+	// 导出文件只在响应中下载，不写入导出目录，也没有公开访问的路由
+	for _, pattern := range []string{"*.xlsx", "*/*.xlsx"} {
+		if matches, _ := filepath.Glob(filepath.Join(export.GetExcelFullPath(), pattern)); len(matches) > 0 {
+			t.Fatalf("exported to disk: %v", matches)
+		}
+	}
+	r = c.get("/export/1/tags-1.xlsx", nil)
+	if r.Code != http.StatusNotFound {
+		t.Fatalf("GET /export = %d, want 404", r.Code)
+	}
+
+	r = loginAs(t, "", testEditor, testPassword).json(http.MethodPost, "/api/v1/admin/audits/export", map[string]interface{}{}, nil)
+	expect(t, r, http.StatusForbidden, e.ERROR_AUTH_FORBIDDEN)
+}
+
+// gqlResult GraphQL 响应，业务码在 errors[].extensions.code 中
+type gqlResult struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code int `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// gql 执行 GraphQL 请求，wantCode 为 0 时要求没有错误，否则要求第一个错误的业务码为 wantCode
+func gql(t *testing.T, c *client, query string, variables map[string]interface{}, wantCode int, v interface{}) {
+	t.Helper()
+
+	r := c.json(http.MethodPost, "/graphql", map[string]interface{}{"query": query, "variables": variables}, nil)
+	if r.Code != http.StatusOK {
+		t.Fatalf("graphql: %d %s", r.Code, r.Body.String())
+	}
+
+	var res gqlResult
+	if err := json.Unmarshal(r.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode graphql response %s: %v", r.Body.String(), err)
+	}
+	if wantCode == 0 && len(res.Errors) > 0 || wantCode != 0 && (len(res.Errors) == 0 || res.Errors[0].Extensions.Code != wantCode) {
+		t.Fatalf("graphql errors = %s, want code %d", r.Body.String(), wantCode)
+	}
+	if v != nil {
+		if err := json.Unmarshal(res.Data, v); err != nil {
+			t.Fatalf("decode graphql data %s: %v", res.Data, err)
+		}
+	}
+}
+
+// countingArticles 统计批量查询的次数，用于确认关联字段没有逐条查询
+type countingArticles struct {
+	models.ArticleRepository
+	byTags, byAuthors int
+}
+
+func (r *countingArticles) GetByTags(ctx context.Context, tenantID int, tagIDs []int) ([]*models.Article, error) {
+	r.byTags++
+	return r.ArticleRepository.GetByTags(ctx, tenantID, tagIDs)
+}
+
+func (r *countingArticles) GetByAuthors(ctx context.Context, tenantID int, usernames []string) ([]*models.Article, error) {
+	r.byAuthors++
+	return r.ArticleRepository.GetByAuthors(ctx, tenantID, usernames)
+}
+
+func testGraphQL(t *testing.T) {
+	anon := &client{t: t}
+	r := anon.json(http.MethodPost, "/graphql", map[string]interface{}{"query": "{ authors { username } }"}, nil)
+	expect(t, r, http.StatusUnauthorized, e.INVALID_PARAMS)
+
+	c := login(t)
+	articles := &countingArticles{ArticleRepository: service.Articles}
+	defer service.Use(service.Repositories{Articles: articles})()
+
+	const addTag = `mutation($name: String!) { addTag(name: $name, state: 1) { id name createdBy } }`
+	const addArticle = `mutation($input: ArticleInput!) { addArticle(input: $input) { id slug tag { name } author { username } } }`
+	for _, name := range []string{"gql-a", "gql-b"} {
+		var added struct {
+			AddTag struct {
+				ID        int    `json:"id"`
+				Name      string `json:"name"`
+				CreatedBy string `json:"createdBy"`
+			} `json:"addTag"`
+		}
+		gql(t, c, addTag, map[string]interface{}{"name": name}, 0, &added)
+		if added.AddTag.Name != name || added.AddTag.CreatedBy != testUser {
+			t.Fatalf("added tag = %+v", added.AddTag)
+		}
+
+		for i := 0; i < 2; i++ {
+			input := map[string]interface{}{
+				"tagId":         added.AddTag.ID,
+				"title":         name + " post",
+				"desc":          "desc",
+				"content":       "content",
+				"coverImageUrl": "upload/images/cover.jpg",
+				"state":         1,
+			}
+			var article struct {
+				AddArticle struct {
+					Tag    struct{ Name string }     `json:"tag"`
+					Author struct{ Username string } `json:"author"`
+				} `json:"addArticle"`
+			}
+			gql(t, c, addArticle, map[string]interface{}{"input": input}, 0, &article)
+			if article.AddArticle.Tag.Name != name || article.AddArticle.Author.Username != testUser {
+				t.Fatalf("added article = %+v", article.AddArticle)
+			}
+		}
+	}
+	gql(t, c, addTag, map[string]interface{}{"name": "gql-a"}, e.ERROR_EXIST_TAG, nil)
+	gql(t, c, addTag, map[string]interface{}{"name": strings.Repeat("x", 101)}, e.INVALID_PARAMS, nil)
+
+	// 两个标签下的文章与文章的作者各只查询一次
+	var tags struct {
+		A struct {
+			Lists []struct {
+				Name     string `json:"name"`
+				Articles []struct {
+					Title  string                    `json:"title"`
+					Tag    struct{ Name string }     `json:"tag"`
+					Author struct{ Username string } `json:"author"`
+				} `json:"articles"`
+			} `json:"lists"`
+		} `json:"a"`
+		B struct {
+			Lists []struct {
+				Articles []struct{ ID int } `json:"articles"`
+			} `json:"lists"`
+		} `json:"b"`
+	}
+	gql(t, c, `{
+		a: tags(name: "gql-a") { lists { name articles { title tag { name } author { username } } } }
+		b: tags(name: "gql-b") { lists { articles(limit: 1) { id } } }
+	}`, nil, 0, &tags)
+	if len(tags.A.Lists) != 1 || len(tags.A.Lists[0].Articles) != 2 || tags.A.Lists[0].Articles[0].Tag.Name != "gql-a" {
+		t.Fatalf("tags a = %+v", tags.A)
+	}
+	if len(tags.B.Lists) != 1 || len(tags.B.Lists[0].Articles) != 1 {
+		t.Fatalf("tags b = %+v", tags.B)
+	}
+	if articles.byTags != 1 {
+		t.Fatalf("articles of tags loaded %d times, want 1", articles.byTags)
+	}
+
+	var authors struct {
+		Authors []struct {
+			Username string `json:"username"`
+			Articles []struct {
+				Author struct{ Username string } `json:"author"`
+			} `json:"articles"`
+		} `json:"authors"`
+	}
+	gql(t, c, `{ authors { username articles(limit: 3) { author { username } } } }`, nil, 0, &authors)
+	if len(authors.Authors) != 1 || authors.Authors[0].Username != testUser || len(authors.Authors[0].Articles) != 3 {
+		t.Fatalf("authors = %+v", authors.Authors)
+	}
+	if articles.byAuthors != 1 {
+		t.Fatalf("articles of authors loaded %d times, want 1", articles.byAuthors)
+	}
+
+	var listed struct {
+		Articles struct {
+			Total int `json:"total"`
+			Lists []struct {
+				ID      int `json:"id"`
+				Version int `json:"version"`
+			} `json:"lists"`
+		} `json:"articles"`
+	}
+	gql(t, c, `{ articles(tagId: `+fmt.Sprint(findTag(t, c, "gql-b").ID)+`) { total lists { id version } } }`, nil, 0, &listed)
+	if listed.Articles.Total != 2 || len(listed.Articles.Lists) != 2 {
+		t.Fatalf("articles = %+v", listed.Articles)
+	}
+
+	id := listed.Articles.Lists[0].ID
+	const editArticle = `mutation($id: Int!, $version: Int) {
+		editArticle(id: $id, version: $version, input: {tagId: %d, title: "edited", desc: "desc", content: "content", coverImageUrl: "c.jpg"}) { title version }
+	}`
+	query := fmt.Sprintf(editArticle, findTag(t, c, "gql-b").ID)
+	var edited struct {
+		EditArticle struct {
+			Title   string `json:"title"`
+			Version int    `json:"version"`
+		} `json:"editArticle"`
+	}
+	gql(t, c, query, map[string]interface{}{"id": id, "version": 1}, 0, &edited)
+	if edited.EditArticle.Title != "edited" || edited.EditArticle.Version != 2 {
+		t.Fatalf("edited article = %+v", edited.EditArticle)
+	}
+	gql(t, c, query, map[string]interface{}{"id": id, "version": 1}, e.ERROR_VERSION_CONFLICT, nil)
+	gql(t, c, query, map[string]interface{}{"id": 99999}, e.ERROR_NOT_EXIST_ARTICLE, nil)
+
+	const deleteTag = `mutation($id: Int!, $cascade: Boolean) { deleteTag(id: $id, cascade: $cascade) }`
+	tagID := findTag(t, c, "gql-b").ID
+	gql(t, c, deleteTag, map[string]interface{}{"id": tagID}, e.ERROR_TAG_HAS_ARTICLES, nil)
+	gql(t, c, deleteTag, map[string]interface{}{"id": tagID, "cascade": true}, 0, nil)
+
+	var article struct {
+		Article *struct{ ID int } `json:"article"`
+	}
+	gql(t, c, `query($id: Int!) { article(id: $id) { id } }`, map[string]interface{}{"id": id}, 0, &article)
+	if article.Article != nil {
+		t.Fatalf("article of deleted tag = %+v", article.Article)
+	}
+
+	gql(t, c, `{ tags { lists { articles { tag { articles { tag { articles { tag { articles { id } } } } } } } } } }`, nil, e.ERROR_GRAPHQL_TOO_DEEP, nil)
+	gql(t, c, `{ tags { lists { articles(limit: 100) { tag { articles(limit: 100) { id } } } } } }`, nil, e.ERROR_GRAPHQL_TOO_COMPLEX, nil)
+	gql(t, c, `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`, nil, 0, nil)
+}
+
+// TestTenants 不同站点的数据、账号与缓存互不可见
+func testEngagement(t *testing.T) {
+	c := login(t)
+
+	r := c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "engage", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	tagID := findTag(t, c, "engage").ID
+	for _, title := range []string{"Engage A", "Engage B"} {
+		r = c.json(http.MethodPost, "/api/v1/articles", map[string]interface{}{
+			"tag_id":          tagID,
+			"title":           title,
+			"desc":            "desc",
+			"content":         "content",
+			"cover_image_url": "upload/images/cover.jpg",
+			"state":           1,
+		}, nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+	}
+	a, b := findArticle(t, c, "engage-a"), findArticle(t, c, "engage-b")
+
+	// 浏览数先在 Redis 中累计，写入数据库前文章的浏览数不变
+	for i := 0; i < 3; i++ {
+		expect(t, c.get(fmt.Sprintf("/api/v1/articles/%d", a.ID), nil), http.StatusOK, e.SUCCESS)
+	}
+	if got := findArticle(t, c, "engage-a"); got.ViewCount != 0 {
+		t.Fatalf("view count before flush = %d, want 0", got.ViewCount)
+	}
+	if err := article_service.FlushViews(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 两次 findArticle 与三次 GET 都计入浏览，浏览数的变化不改变版本号
+	if got := findArticle(t, c, "engage-a"); got.ViewCount != 5 || got.Version != 1 {
+		t.Fatalf("article after flush = %+v, want 5 views at version 1", got)
+	}
+
+	popular := func(query string) []int {
+		t.Helper()
+		r := c.get("/api/v1/articles/popular"+query, nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		var data struct {
+			Lists []struct {
+				ID    int     `json:"id"`
+				Score float64 `json:"score"`
+			} `json:"lists"`
+		}
+		r.data(t, &data)
+		var ids []int
+		for _, item := range data.Lists {
+			if item.ID == a.ID || item.ID == b.ID {
+				ids = append(ids, item.ID)
+			}
+		}
+		return ids
+	}
+	if got := popular("?window=7d&limit=100"); len(got) != 2 || got[0] != a.ID {
+		t.Fatalf("popular = %v, want %d first", got, a.ID)
+	}
+
+	like := func(method string, id int, wantLiked bool, wantCount int) {
+		t.Helper()
+		r := c.do(method, fmt.Sprintf("/api/v1/articles/%d/like", id), nil, "", nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		var data struct {
+			Liked     bool `json:"liked"`
+			LikeCount int  `json:"like_count"`
+		}
+		r.data(t, &data)
+		if data.Liked != wantLiked || data.LikeCount != wantCount {
+			t.Fatalf("%s like = %+v, want %v %d", method, data, wantLiked, wantCount)
+		}
+	}
+	like(http.MethodPost, b.ID, true, 1)
+	like(http.MethodPost, b.ID, true, 1)
+	// 一次点赞相当于 LikeWeight 次浏览
+	if got := popular("?limit=100"); len(got) != 2 || got[0] != b.ID {
+		t.Fatalf("popular after like = %v, want %d first", got, b.ID)
+	}
+	like(http.MethodDelete, b.ID, false, 0)
+	like(http.MethodDelete, b.ID, false, 0)
+	if got := popular("?limit=100"); got[0] != a.ID {
+		t.Fatalf("popular after unlike = %v, want %d first", got, a.ID)
+	}
+
+	r = c.do(http.MethodPost, "/api/v1/articles/99999/like", nil, "", nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE)
+	for _, window := range []string{"abc", "0d", "1000d"} {
+		r = c.get("/api/v1/articles/popular?window="+window, nil)
+		expect(t, r, http.StatusBadRequest, e.INVALID_PARAMS)
+	}
+}
+
+func testRelated(t *testing.T) {
+	c := login(t)
+
+	tags := make(map[string]int)
+	for _, name := range []string{"rel-go", "rel-cook"} {
+		r := c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": name, "state": 1}, nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		tags[name] = findTag(t, c, name).ID
+	}
+	add := func(tag, title, desc, content string, state int) {
+		t.Helper()
+		r := c.json(http.MethodPost, "/api/v1/articles", map[string]interface{}{
+			"tag_id":          tags[tag],
+			"title":           title,
+			"desc":            desc,
+			"content":         content,
+			"cover_image_url": "upload/images/cover.jpg",
+			"state":           state,
+		}, nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+	}
+	add("rel-go", "Go Concurrency Patterns", "goroutines and channels", "select over goroutines and channels for concurrency", 1)
+	add("rel-go", "Go Channels Deep Dive", "channels in depth", "buffered channels, goroutines and select", 1)
+	add("rel-go", "Go Modules", "dependency management", "go.mod, go.sum and semantic import versioning", 1)
+	add("rel-cook", "Baking Sourdough Bread", "a bread recipe", "flour, water, salt and a sourdough starter", 1)
+	add("rel-cook", "Sourdough Starter Care", "feeding the starter", "flour and water for the sourdough starter", 0)
+
+	concurrency, channels, modules := findArticle(t, c, "go-concurrency-patterns"), findArticle(t, c, "go-channels-deep-dive"), findArticle(t, c, "go-modules")
+	bread := findArticle(t, c, "baking-sourdough-bread")
+
+	related := func(id int) []int {
+		t.Helper()
+		r := c.get(fmt.Sprintf("/api/v1/articles/%d/related", id), nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		var data struct {
+			Lists []struct {
+				ID    int     `json:"id"`
+				Score float64 `json:"score"`
+			} `json:"lists"`
+		}
+		r.data(t, &data)
+		if len(data.Lists) > setting.RelatedSetting.Size {
+			t.Fatalf("related has %d articles, want at most %d", len(data.Lists), setting.RelatedSetting.Size)
+		}
+		ids := make([]int, len(data.Lists))
+		for i, item := range data.Lists {
+			ids[i] = item.ID
+		}
+		return ids
+	}
+
+	// 内容相近的排在前面，同一标签但内容无关的其次，草稿不会被推荐
+	if got := related(concurrency.ID); len(got) < 2 || got[0] != channels.ID || got[1] != modules.ID {
+		t.Fatalf("related to %d = %v, want %d then %d", concurrency.ID, got, channels.ID, modules.ID)
+	}
+	if got := related(bread.ID); len(got) != 0 {
+		t.Fatalf("related to bread = %v, want none while the starter article is a draft", got)
+	}
+
+	// 发布草稿后缓存失效，重新计算的结果包含该文章
+	var drafts struct {
+		Lists []models.Article `json:"lists"`
+	}
+	r := c.get(fmt.Sprintf("/api/v1/articles?state=0&tag_id=%d", tags["rel-cook"]), nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r.data(t, &drafts)
+	if len(drafts.Lists) != 1 {
+		t.Fatalf("drafts = %+v", drafts.Lists)
+	}
+	starter := drafts.Lists[0]
+	r = c.json(http.MethodPut, fmt.Sprintf("/api/v1/articles/%d", starter.ID), map[string]interface{}{
+		"tag_id":          starter.TagID,
+		"title":           starter.Title,
+		"desc":            starter.Desc,
+		"content":         starter.Content,
+		"cover_image_url": starter.CoverImageUrl,
+		"state":           1,
+	}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if got := related(bread.ID); len(got) != 1 || got[0] != starter.ID {
+		t.Fatalf("related to bread = %v, want [%d]", got, starter.ID)
+	}
+
+	r = c.get("/api/v1/articles/99999/related", nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE)
+}
+
+func testSeries(t *testing.T) {
+	c := login(t)
+
+	r := c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "series-tag", "state": 1}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	tagID := findTag(t, c, "series-tag").ID
+
+	ids := make([]int, 3)
+	for i := range ids {
+		r := c.json(http.MethodPost, "/api/v1/articles", map[string]interface{}{
+			"tag_id":          tagID,
+			"title":           fmt.Sprintf("Series Part %d", i+1),
+			"desc":            "desc",
+			"content":         "content",
+			"cover_image_url": "upload/images/cover.jpg",
+			"state":           1,
+		}, nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		ids[i] = findArticle(t, c, fmt.Sprintf("series-part-%d", i+1)).ID
+	}
+
+	var series models.Series
+	r = c.json(http.MethodPost, "/api/v1/series", map[string]interface{}{"title": "A Series"}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r.data(t, &series)
+	path := fmt.Sprintf("/api/v1/series/%d", series.ID)
+
+	r = c.json(http.MethodPut, path+"/articles", map[string]interface{}{"article_ids": ids}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.json(http.MethodPut, path+"/articles", map[string]interface{}{"article_ids": []int{ids[0], ids[0]}}, nil)
+	expect(t, r, http.StatusBadRequest, e.INVALID_PARAMS)
+	r = c.json(http.MethodPut, path+"/articles", map[string]interface{}{"article_ids": []int{99999}}, nil)
+	expect(t, r, http.StatusBadRequest, e.INVALID_PARAMS)
+
+	nav := func(id int) *models.SeriesNav {
+		t.Helper()
+		r := c.get(fmt.Sprintf("/api/v1/articles/%d", id), nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+		var article models.Article
+		r.data(t, &article)
+		return article.Series
+	}
+	itemID := func(item *models.SeriesItem) int {
+		if item == nil {
+			return 0
+		}
+		return item.ID
+	}
+
+	if n := nav(ids[1]); n == nil || n.ID != series.ID || n.Position != 2 || n.Total != 3 || itemID(n.Prev) != ids[0] || itemID(n.Next) != ids[2] {
+		t.Fatalf("nav of the second article = %+v", n)
+	}
+	if n := nav(ids[0]); n == nil || n.Prev != nil || itemID(n.Next) != ids[1] {
+		t.Fatalf("nav of the first article = %+v", n)
+	}
+
+	// 调整顺序后前后篇随之变化
+	r = c.json(http.MethodPut, path+"/articles", map[string]interface{}{"article_ids": []int{ids[2], ids[0], ids[1]}}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if n := nav(ids[0]); n == nil || n.Position != 2 || itemID(n.Prev) != ids[2] || itemID(n.Next) != ids[1] {
+		t.Fatalf("nav after reordering = %+v", n)
+	}
+
+	var detail struct {
+		Series   models.Series    `json:"series"`
+		Articles []models.Article `json:"articles"`
+	}
+	r = c.get(path, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r.data(t, &detail)
+	if detail.Series.Title != "A Series" || len(detail.Articles) != 3 || detail.Articles[0].ID != ids[2] {
+		t.Fatalf("series detail = %+v", detail)
+	}
+
+	// 一篇文章只能属于一个系列
+	var other models.Series
+	r = c.json(http.MethodPost, "/api/v1/series", map[string]interface{}{"title": "Another Series"}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r.data(t, &other)
+	r = c.json(http.MethodPost, fmt.Sprintf("/api/v1/series/%d/articles", other.ID), map[string]interface{}{"article_id": ids[0]}, nil)
+	expect(t, r, http.StatusConflict, e.ERROR_ARTICLE_IN_OTHER_SERIES)
+
+	r = c.json(http.MethodDelete, fmt.Sprintf("%s/articles/%d", path, ids[0]), nil, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.json(http.MethodDelete, fmt.Sprintf("%s/articles/%d", path, ids[0]), nil, nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE)
+	r = c.json(http.MethodPost, fmt.Sprintf("/api/v1/series/%d/articles", other.ID), map[string]interface{}{"article_id": ids[0]}, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	if n := nav(ids[0]); n == nil || n.ID != other.ID || n.Total != 1 || n.Prev != nil || n.Next != nil {
+		t.Fatalf("nav after moving to another series = %+v", n)
+	}
+	if n := nav(ids[2]); n == nil || n.Total != 2 || itemID(n.Next) != ids[1] {
+		t.Fatalf("nav after removing an article = %+v", n)
+	}
+
+	// 删除系列后文章不再有系列导航
+	r = c.json(http.MethodDelete, path, nil, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+	r = c.get(path, nil)
+	expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_SERIES)
+	if n := nav(ids[2]); n != nil {
+		t.Fatalf("nav after deleting the series = %+v", n)
+	}
+}
+
+func TestExportSite(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			defer b.use()()
+
+			c := login(t)
+			tagName := "export-" + b.name
+			r := c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": tagName, "state": 1}, nil)
+			expect(t, r, http.StatusOK, e.SUCCESS)
+			tag := findTag(t, c, tagName)
+
+			r = c.upload("/upload", "image", "export.jpg", []byte("\xff\xd8\xff\xe0 not really a jpeg"))
+			expect(t, r, http.StatusOK, e.SUCCESS)
+			var image struct {
+				ImageURL string `json:"image_url"`
+			}
+			r.data(t, &image)
+			name := strings.TrimPrefix(image.ImageURL, setting.AppSetting.PrefixUrl+"/")
+
+			// 前两篇发布，第三篇为草稿
+			for i, content := range []string{`<p>see <img src="` + image.ImageURL + `"></p>`, "<p>second</p>", "<p>draft</p>"} {
+				r := c.json(http.MethodPost, "/api/v1/articles", map[string]interface{}{
+					"tag_id":          tag.ID,
+					"title":           fmt.Sprintf("Export %s %d", b.name, i+1),
+					"desc":            "desc",
+					"content":         content,
+					"cover_image_url": name,
+					"state":           min(2-i, 1),
+				}, nil)
+				expect(t, r, http.StatusOK, e.SUCCESS)
+			}
+			slug := fmt.Sprintf("export-%s-1", b.name)
+
+			tenant, err := models.GetTenantByName(context.Background(), setting.TenantSetting.Default)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := filepath.Join(t.TempDir(), "site")
+			pageSize := setting.SiteSetting.PageSize
+			setting.SiteSetting.PageSize = 1
+			defer func() { setting.SiteSetting.PageSize = pageSize }()
+
+			result, err := site_service.Export(context.Background(), tenant, out, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, missing := range result.Missing {
+				if setting.AppSetting.ImageSavePath+missing == name {
+					t.Fatalf("result = %+v, want the uploaded image copied", result)
+				}
+			}
+
+			read := func(name string) string {
+				t.Helper()
+				data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return string(data)
+			}
+			article := read("articles/" + slug + "/index.html")
+			if !strings.Contains(article, `src="/`+name+`"`) || strings.Contains(article, setting.AppSetting.PrefixUrl) {
+				t.Fatalf("article page does not point at the exported image:\n%s", article)
+			}
+			if read(name) != "\xff\xd8\xff\xe0 not really a jpeg" {
+				t.Fatal("image not copied")
+			}
+			// 每页一篇，较早发布的第一篇在标签页的第二页
+			if tagPage := read(fmt.Sprintf("tags/%d/page/2/index.html", tag.ID)); !strings.Contains(tagPage, "/articles/"+slug+"/") {
+				t.Fatalf("tag page does not link to the article:\n%s", tagPage)
+			}
+			if _, err := os.Stat(filepath.Join(out, "articles", fmt.Sprintf("export-%s-3", b.name))); !os.IsNotExist(err) {
+				t.Fatalf("draft exported: %v", err)
+			}
+			if !strings.Contains(read("index.html"), `href="/page/2/"`) || !strings.Contains(read("page/2/index.html"), `href="/"`) {
+				t.Fatal("index is not paginated")
+			}
+			for _, name := range []string{"feed.rss", "feed.atom", "feed.json", fmt.Sprintf("tags/%d/feed.rss", tag.ID)} {
+				if !strings.Contains(read(name), slug) {
+					t.Fatalf("%s does not contain the article", name)
+				}
+			}
+			if !strings.Contains(read("sitemap.xml"), "/articles/"+slug) {
+				t.Fatal("sitemap does not contain the article")
+			}
+
+			// 自定义模板覆盖同名的内置模板，重新导出时替换之前导出的目录
+			templates := t.TempDir()
+			custom := `{{define "content"}}<h1 class="custom">{{.Article.Title}}</h1>{{end}}`
+			if err := os.WriteFile(filepath.Join(templates, "article.html"), []byte(custom), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := site_service.Export(context.Background(), tenant, out, templates); err != nil {
+				t.Fatal(err)
+			}
+			if article := read("articles/" + slug + "/index.html"); !strings.Contains(article, `<h1 class="custom">`) {
+				t.Fatalf("custom template not used:\n%s", article)
+			}
+
+			// 不覆盖不是由 export-site 生成的非空目录
+			other := t.TempDir()
+			if err := os.WriteFile(filepath.Join(other, "keep.txt"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := site_service.Export(context.Background(), tenant, other, ""); err == nil {
+				t.Fatal("export into a non-empty directory succeeded")
+			}
+		})
+	}
+}
+
+func TestTenants(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			defer b.use()()
+
+			name, host := "second-"+b.name, b.name+".example.com"
+			admin := login(t)
+			site := map[string]interface{}{
+				"name":           name,
+				"host":           host,
+				"state":          1,
+				"admin_username": "owner",
+				"admin_password": "owner123456",
+			}
+			r := admin.json(http.MethodPost, "/api/v1/admin/tenants", site, nil)
+			expect(t, r, http.StatusOK, e.SUCCESS)
+			r = admin.json(http.MethodPost, "/api/v1/admin/tenants", site, nil)
+			expect(t, r, http.StatusConflict, e.ERROR_EXIST_TENANT)
+
+			anon := &client{t: t, tenant: "missing"}
+			r = anon.get("/api/v1/public/tags", nil)
+			expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_TENANT)
+
+			// 账号只属于创建它的站点，token 也只能用于签发它的站点
+			anon = &client{t: t}
+			r = anon.get("/auth?username=owner&password=owner123456", nil)
+			expect(t, r, http.StatusUnauthorized, e.ERROR_AUTH)
+			r = (&client{t: t, token: admin.token, tenant: name}).get("/api/v1/tags", nil)
+			expect(t, r, http.StatusUnauthorized, e.ERROR_AUTH_CHECK_TOKEN_FAIL)
+
+			owner := loginAs(t, name, "owner", "owner123456")
+			r = owner.get("/api/v1/admin/tenants", nil)
+			expect(t, r, http.StatusForbidden, e.ERROR_TENANT_FORBIDDEN)
+
+			// 标签名称与 slug 只在站点内唯一
+			for _, c := range []*client{admin, owner} {
+				r = c.json(http.MethodPost, "/api/v1/tags", map[string]interface{}{"name": "shared", "state": 1}, nil)
+				expect(t, r, http.StatusOK, e.SUCCESS)
+				article := map[string]interface{}{
+					"tag_id":          findTag(t, c, "shared").ID,
+					"title":           "Shared Title",
+					"desc":            "desc",
+					"content":         "content",
+					"cover_image_url": "upload/images/cover.jpg",
+					"state":           1,
+				}
+				r = c.json(http.MethodPost, "/api/v1/articles", article, nil)
+				expect(t, r, http.StatusOK, e.SUCCESS)
+				findArticle(t, c, "shared-title")
+			}
+			if n := countArticles(t, owner); n != 1 {
+				t.Fatalf("second site has %d published articles, want 1", n)
+			}
+
+			// 列表缓存按站点区分
+			for _, c := range []*client{admin, owner} {
+				r = c.get("/api/v1/tags?state=1", nil)
+				expect(t, r, http.StatusOK, e.SUCCESS)
+			}
+			var tags struct {
+				Lists []models.Tag `json:"lists"`
+			}
+			r.data(t, &tags)
+			if len(tags.Lists) != 1 || tags.Lists[0].Name != "shared" {
+				t.Fatalf("second site tags = %+v", tags.Lists)
+			}
+
+			// 其他站点的标签与文章视为不存在
+			defaultTag := findTag(t, admin, "shared")
+			r = owner.do(http.MethodDelete, fmt.Sprintf("/api/v1/tags/%d", defaultTag.ID), nil, "", nil)
+			expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_TAG)
+			defaultArticle := findArticle(t, admin, "shared-title")
+			r = owner.get(fmt.Sprintf("/api/v1/articles/%d", defaultArticle.ID), nil)
+			expect(t, r, http.StatusNotFound, e.ERROR_NOT_EXIST_ARTICLE)
+
+			// 按域名确定站点
+			byHost := &client{t: t, host: host + ":8000"}
+			if n := countArticles(t, byHost); n != 1 {
+				t.Fatalf("site resolved by host has %d published articles, want 1", n)
+			}
+		})
+	}
+}
+
+// publicList 通过公开接口逐页读取，不经过缓存
+func publicList(t *testing.T, c *client, path string, item func(r *result) int) {
+	t.Helper()
+
+	for page, seen := 1, 0; ; page++ {
+		r := c.get(fmt.Sprintf("%s?page=%d", path, page), nil)
+		expect(t, r, http.StatusOK, e.SUCCESS)
+
+		var data struct {
+			Total int `json:"total"`
+		}
+		r.data(t, &data)
+		n := item(r)
+		seen += n
+		if n == 0 || seen >= data.Total {
+			return
+		}
+	}
+}
+
+func enabledTags(t *testing.T, c *client) []models.Tag {
+	t.Helper()
+
+	var tags []models.Tag
+	publicList(t, c, "/api/v1/public/tags", func(r *result) int {
+		var data struct {
+			Lists []models.Tag `json:"lists"`
+		}
+		r.data(t, &data)
+		tags = append(tags, data.Lists...)
+		return len(data.Lists)
+	})
+
+	return tags
+}
+
+func publishedArticles(t *testing.T, c *client) []models.Article {
+	t.Helper()
+
+	var articles []models.Article
+	publicList(t, c, "/api/v1/public/articles", func(r *result) int {
+		var data struct {
+			Lists []models.Article `json:"lists"`
+		}
+		r.data(t, &data)
+		articles = append(articles, data.Lists...)
+		return len(data.Lists)
+	})
+
+	return articles
+}
+
+func findTag(t *testing.T, c *client, name string) models.Tag {
+	t.Helper()
+
+	for _, tag := range enabledTags(t, c) {
+		if tag.Name == name {
+			return tag
+		}
+	}
+	t.Fatalf("tag %q not found", name)
+
+	return models.Tag{}
+}
+
+func findArticle(t *testing.T, c *client, slug string) models.Article {
+	t.Helper()
+
+	r := c.get("/api/v1/public/articles/"+slug, nil)
+	expect(t, r, http.StatusOK, e.SUCCESS)
+
+	var article models.Article
+	r.data(t, &article)
+
+	return article
+}
+
+func countTags(t *testing.T, c *client) int {
+	t.Helper()
+	return len(enabledTags(t, c))
+}
+
+func countArticles(t *testing.T, c *client) int {
+	t.Helper()
+	return len(publishedArticles(t, c))
+}
@@ -0,0 +1,136 @@
+package api_key_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+)
+
+const (
+	// KEY_PREFIX 生成的 key 均以此开头，便于在日志、代码仓库中识别泄露的 key
+	KEY_PREFIX = "blog_"
+	// TOUCH_INTERVAL 最近使用时间的精度，同一个 key 在此间隔内只更新一次，避免每个请求都写数据库
+	TOUCH_INTERVAL = time.Minute
+)
+
+// Scopes 可以授予的权限范围，从低到高排列，高的包含低的：
+// read 只能读取，articles:write 还可以修改文章、标签与系列，admin 还可以访问管理接口（账号本身也需是 admin）
+var Scopes = []string{models.SCOPE_READ, models.SCOPE_ARTICLES_WRITE, models.SCOPE_ADMIN}
+
+type ApiKey struct {
+	TenantID  int
+	ID        int
+	Username  string
+	Name      string
+	Scope     string
+	ExpiresOn int
+}
+
+// Add 生成新的 key，返回的 key 明文只在此时可见
+func (k *ApiKey) Add(ctx context.Context) (*models.ApiKey, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	key := KEY_PREFIX + base64.RawURLEncoding.EncodeToString(b)
+
+	apiKey := &models.ApiKey{
+		TenantID:  k.TenantID,
+		Username:  k.Username,
+		Name:      k.Name,
+		Prefix:    key[:len(KEY_PREFIX)+6],
+		KeyHash:   Hash(key),
+		Scope:     k.Scope,
+		ExpiresOn: k.ExpiresOn,
+	}
+	if err := models.AddApiKey(ctx, apiKey); err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+// Get 获取站点下未吊销的 key，不存在时返回 ID 为 0 的 key
+func (k *ApiKey) Get(ctx context.Context) (*models.ApiKey, error) {
+	return models.GetApiKey(ctx, k.TenantID, k.ID)
+}
+
+// GetAll 获取 k.Username 的 key，Username 为空时获取站点下所有账号的
+func (k *ApiKey) GetAll(ctx context.Context) ([]models.ApiKey, error) {
+	return models.GetApiKeys(ctx, k.TenantID, k.Username)
+}
+
+// Delete 吊销 key，之后使用它的请求立即失败
+func (k *ApiKey) Delete(ctx context.Context) error {
+	return models.DeleteApiKey(ctx, k.TenantID, k.ID)
+}
+
+// Authenticate 校验站点下的 key，返回 key 与所属账号；key 不存在、已吊销、已过期或账号已不存在时返回 nil
+func Authenticate(ctx context.Context, tenantID int, key string) (*models.ApiKey, *models.Auth, error) {
+	if !strings.HasPrefix(key, KEY_PREFIX) {
+		return nil, nil, nil
+	}
+
+	apiKey, err := models.GetApiKeyByHash(ctx, Hash(key))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if apiKey.ID == 0 || apiKey.TenantID != tenantID || (apiKey.ExpiresOn > 0 && int64(apiKey.ExpiresOn) <= now.Unix()) {
+		return nil, nil, nil
+	}
+
+	auth, err := models.GetAuth(ctx, tenantID, apiKey.Username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if auth.ID == 0 {
+		return nil, nil, nil
+	}
+
+	if now.Sub(time.Unix(int64(apiKey.LastUsedOn), 0)) >= TOUCH_INTERVAL {
+		apiKey.LastUsedOn = int(now.Unix())
+		if err := models.TouchApiKey(ctx, apiKey.ID, apiKey.LastUsedOn); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return apiKey, auth, nil
+}
+
+// Hash key 的 SHA-256，key 为随机生成的长字符串，无需加盐或使用慢哈希
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckScope 校验权限范围是否为 Scopes 之一
+func CheckScope(scope string) error {
+	if level(scope) < 0 {
+		return fmt.Errorf("unknown scope %q, must be one of %s", scope, strings.Join(Scopes, ", "))
+	}
+
+	return nil
+}
+
+// Allows 权限范围 granted 是否包含 required
+func Allows(granted, required string) bool {
+	return level(granted) >= 0 && level(granted) >= level(required)
+}
+
+func level(scope string) int {
+	for i, s := range Scopes {
+		if s == scope {
+			return i
+		}
+	}
+
+	return -1
+}
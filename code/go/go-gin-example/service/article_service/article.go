@@ -1,30 +1,36 @@
 package article_service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"time"
 
 	"github.com/fzzv/go-gin-example/models"
-	"github.com/fzzv/go-gin-example/pkg/export"
-	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/service"
+
 	"github.com/fzzv/go-gin-example/pkg/logging"
 	"github.com/fzzv/go-gin-example/service/cache_service"
+	"github.com/fzzv/go-gin-example/service/feed_service"
+	"github.com/fzzv/go-gin-example/service/related_service"
+	"github.com/fzzv/go-gin-example/service/webhook_service"
 	"github.com/unknwon/com"
 	"github.com/xuri/excelize/v2"
 )
 
 type Article struct {
+	TenantID      int
 	ID            int
 	TagID         int
 	Title         string
+	Slug          string // 为空时由标题生成，修改文章时为空表示不修改
 	Desc          string
 	Content       string
 	CoverImageUrl string
 	State         int
+	Version       int // 修改时期望的当前版本，为 0 时不检查
 	CreatedBy     string
 	ModifiedBy    string
 
@@ -32,10 +38,20 @@ type Article struct {
 	PageSize int
 }
 
-func (a *Article) Add() error {
+func (a *Article) Add(ctx context.Context) (*models.Article, error) {
+	slug := a.Slug
+	if slug == "" {
+		var err error
+		if slug, err = service.Articles.UniqueSlug(ctx, a.TenantID, a.Title); err != nil {
+			return nil, err
+		}
+	}
+
 	article := map[string]interface{}{
+		"tenant_id":       a.TenantID,
 		"tag_id":          a.TagID,
 		"title":           a.Title,
+		"slug":            slug,
 		"desc":            a.Desc,
 		"content":         a.Content,
 		"created_by":      a.CreatedBy,
@@ -43,31 +59,72 @@ func (a *Article) Add() error {
 		"state":           a.State,
 	}
 
-	if err := models.AddArticle(article); err != nil {
-		return err
+	created, err := service.Articles.Add(ctx, article)
+	if err != nil {
+		return nil, err
 	}
+	fireCreated(ctx, created)
 
-	return nil
+	return created, nil
 }
 
-func (a *Article) Edit() error {
-	return models.EditArticle(a.ID, map[string]interface{}{
+// Edit 修改文章，State 为 -1 时不修改状态，状态由 0 变为 1 时触发 article.published 事件
+func (a *Article) Edit(ctx context.Context) error {
+	before, err := service.Articles.Get(ctx, a.TenantID, a.ID)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
 		"tag_id":          a.TagID,
 		"title":           a.Title,
 		"desc":            a.Desc,
 		"content":         a.Content,
 		"cover_image_url": a.CoverImageUrl,
 		"modified_by":     a.ModifiedBy,
-	})
+	}
+	if a.State >= 0 {
+		data["state"] = a.State
+	}
+	if a.Slug != "" {
+		data["slug"] = a.Slug
+	}
+	if err := service.Articles.Edit(ctx, a.TenantID, a.ID, a.Version, data); err != nil {
+		return err
+	}
+	a.clearCache(ctx)
+	feed_service.Invalidate(ctx, a.TenantID)
+	related_service.Invalidate(ctx, a.TenantID)
+
+	after, err := service.Articles.Get(ctx, a.TenantID, a.ID)
+	if err != nil {
+		logging.Error(err)
+		return nil
+	}
+	webhook_service.Fire(ctx, a.TenantID, webhook_service.EVENT_ARTICLE_UPDATED, after)
+	if before.State != 1 && after.State == 1 {
+		webhook_service.Fire(ctx, a.TenantID, webhook_service.EVENT_ARTICLE_PUBLISHED, after)
+	}
+
+	return nil
+}
+
+func fireCreated(ctx context.Context, article *models.Article) {
+	feed_service.Invalidate(ctx, article.TenantID)
+	related_service.Invalidate(ctx, article.TenantID)
+	webhook_service.Fire(ctx, article.TenantID, webhook_service.EVENT_ARTICLE_CREATED, article)
+	if article.State == 1 {
+		webhook_service.Fire(ctx, article.TenantID, webhook_service.EVENT_ARTICLE_PUBLISHED, article)
+	}
 }
 
-func (a *Article) Get() (*models.Article, error) {
+func (a *Article) Get(ctx context.Context) (*models.Article, error) {
 	var cacheArticle *models.Article
 
-	cache := cache_service.Article{ID: a.ID}
+	cache := cache_service.Article{TenantID: a.TenantID, ID: a.ID}
 	key := cache.GetArticleKey()
-	if gredis.Exists(key) {
-		data, err := gredis.Get(key)
+	if service.Cache.Exists(ctx, key) {
+		data, err := service.Cache.Get(ctx, key)
 		if err != nil {
 			logging.Info(err)
 		} else {
@@ -76,31 +133,32 @@ func (a *Article) Get() (*models.Article, error) {
 		}
 	}
 
-	article, err := models.GetArticle(a.ID)
+	article, err := service.Articles.Get(ctx, a.TenantID, a.ID)
 	log.Print(article)
 	if err != nil {
 		return nil, err
 	}
 
-	gredis.Set(key, article, 3600)
+	service.Cache.Set(ctx, key, article, 3600)
 	return article, nil
 }
 
-func (a *Article) GetAll() ([]*models.Article, error) {
+func (a *Article) GetAll(ctx context.Context) ([]*models.Article, error) {
 	var (
 		articles, cacheArticles []*models.Article
 	)
 
 	cache := cache_service.Article{
-		TagID: a.TagID,
-		State: a.State,
+		TenantID: a.TenantID,
+		TagID:    a.TagID,
+		State:    a.State,
 
 		PageNum:  a.PageNum,
 		PageSize: a.PageSize,
 	}
 	key := cache.GetArticlesKey()
-	if gredis.Exists(key) {
-		data, err := gredis.Get(key)
+	if service.Cache.Exists(ctx, key) {
+		data, err := service.Cache.Get(ctx, key)
 		if err != nil {
 			logging.Info(err)
 		} else {
@@ -109,29 +167,109 @@ func (a *Article) GetAll() ([]*models.Article, error) {
 		}
 	}
 
-	articles, err := models.GetArticles(a.PageNum, a.PageSize, a.getMaps())
+	articles, err := service.Articles.GetAll(ctx, a.PageNum, a.PageSize, a.getMaps())
 	if err != nil {
 		return nil, err
 	}
 
-	gredis.Set(key, articles, 3600)
+	service.Cache.Set(ctx, key, articles, 3600)
 	return articles, nil
 }
 
-func (a *Article) Delete() error {
-	return models.DeleteArticle(a.ID)
+// GetByTags 批量获取多个标签下未删除的文章，不经过缓存
+func (a *Article) GetByTags(ctx context.Context, tagIDs []int) ([]*models.Article, error) {
+	return service.Articles.GetByTags(ctx, a.TenantID, tagIDs)
+}
+
+// GetByAuthors 批量获取多个作者未删除的文章，不经过缓存
+func (a *Article) GetByAuthors(ctx context.Context, usernames []string) ([]*models.Article, error) {
+	return service.Articles.GetByAuthors(ctx, a.TenantID, usernames)
+}
+
+// GetAuthors 获取站点内发表过文章的用户
+func (a *Article) GetAuthors(ctx context.Context) ([]string, error) {
+	return service.Articles.GetAuthors(ctx, a.TenantID)
+}
+
+func (a *Article) Delete(ctx context.Context) error {
+	if err := service.Articles.Delete(ctx, a.TenantID, a.ID); err != nil {
+		return err
+	}
+	a.clearCache(ctx)
+	feed_service.Invalidate(ctx, a.TenantID)
+	related_service.Invalidate(ctx, a.TenantID)
+	webhook_service.Fire(ctx, a.TenantID, webhook_service.EVENT_ARTICLE_DELETED, map[string]int{"id": a.ID})
+
+	return nil
+}
+
+// clearCache 删除单篇文章的缓存，文章修改后版本号随之变化，缓存的旧版本会导致 ETag 失效
+func (a *Article) clearCache(ctx context.Context) {
+	cache := cache_service.Article{TenantID: a.TenantID, ID: a.ID}
+	if _, err := service.Cache.Delete(ctx, cache.GetArticleKey()); err != nil {
+		logging.Warn(err)
+	}
 }
 
-func (a *Article) ExistByID() (bool, error) {
-	return models.ExistArticleByID(a.ID)
+// Load 直接从数据库读取文章，不经过缓存，不存在时返回 ID 为 0 的文章
+func (a *Article) Load(ctx context.Context) (*models.Article, error) {
+	return service.Articles.Get(ctx, a.TenantID, a.ID)
 }
 
-func (a *Article) Count() (int, error) {
-	return models.GetArticleTotal(a.getMaps())
+// GetDeleted 获取回收站中的文章，不存在时返回 ID 为 0 的文章
+func (a *Article) GetDeleted(ctx context.Context) (*models.Article, error) {
+	return service.Articles.GetDeleted(ctx, a.TenantID, a.ID)
+}
+
+func (a *Article) Restore(ctx context.Context) error {
+	if err := service.Articles.Restore(ctx, a.TenantID, a.ID); err != nil {
+		return err
+	}
+	a.clearCache(ctx)
+	feed_service.Invalidate(ctx, a.TenantID)
+	related_service.Invalidate(ctx, a.TenantID)
+
+	return nil
+}
+
+func (a *Article) GetTrash(ctx context.Context) ([]*models.Article, error) {
+	return service.Articles.GetDeletedAll(ctx, a.TenantID, a.PageNum, a.PageSize)
+}
+
+func (a *Article) CountTrash(ctx context.Context) (int, error) {
+	return service.Articles.CountDeleted(ctx, a.TenantID)
+}
+
+func (a *Article) ExistByID(ctx context.Context) (bool, error) {
+	return service.Articles.ExistByID(ctx, a.TenantID, a.ID)
+}
+
+// ExistBySlug 判断 a.Slug 是否已被站点内 a.ID 以外的文章使用
+func (a *Article) ExistBySlug(ctx context.Context) (bool, error) {
+	return service.Articles.ExistBySlug(ctx, a.TenantID, a.Slug, a.ID)
+}
+
+// GetPublished 获取已发布且所属标签已启用的文章，TagID 为 0 时不限标签，不经过缓存
+func (a *Article) GetPublished(ctx context.Context) ([]*models.Article, error) {
+	return service.Articles.GetPublished(ctx, a.TenantID, a.PageNum, a.PageSize, a.TagID)
+}
+
+func (a *Article) CountPublished(ctx context.Context) (int, error) {
+	return service.Articles.CountPublished(ctx, a.TenantID, a.TagID)
+}
+
+// GetPublishedBySlug 按 a.Slug 获取已发布的文章，不存在时返回 ID 为 0 的文章
+func (a *Article) GetPublishedBySlug(ctx context.Context) (*models.Article, error) {
+	return service.Articles.GetPublishedBySlug(ctx, a.TenantID, a.Slug)
+}
+
+func (a *Article) Count(ctx context.Context) (int, error) {
+	return service.Articles.Count(ctx, a.getMaps())
 }
 
 func (a *Article) getMaps() map[string]interface{} {
 	maps := make(map[string]interface{})
+	maps["tenant_id"] = a.TenantID
 	maps["deleted_on"] = 0
 	if a.State != -1 {
 		maps["state"] = a.State
@@ -143,7 +281,8 @@ func (a *Article) getMaps() map[string]interface{} {
 	return maps
 }
 
-func (a *Article) Export() (string, error) {
+// Export 导出站点内已发布的文章，Excel 文件写入 w，不落盘到公开访问的目录
+func (a *Article) Export(ctx context.Context, w io.Writer) error {
 	if a.PageSize == 0 {
 		a.PageSize = 10000
 	}
@@ -151,10 +290,10 @@ func (a *Article) Export() (string, error) {
 		a.TagID = -1
 	}
 	a.State = 1
-	articles, err := a.GetAll()
+	articles, err := a.GetAll(ctx)
 	fmt.Println("articles:", articles)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	f := excelize.NewFile()
@@ -165,7 +304,7 @@ func (a *Article) Export() (string, error) {
 
 	titles := []string{"ID", "标题", "描述", "内容", "封面图片", "状态", "创建人", "创建时间", "修改人", "修改时间", "标签ID"}
 	if err := f.SetSheetRow(sheetName, "A1", &titles); err != nil {
-		return "", fmt.Errorf("设置表头失败: %w", err)
+		return fmt.Errorf("设置表头失败: %w", err)
 	}
 
 	for i, v := range articles {
@@ -189,40 +328,37 @@ func (a *Article) Export() (string, error) {
 
 		cell := fmt.Sprintf("A%d", row)
 		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
-			return "", fmt.Errorf("写入第 %d 行数据失败: %w", row, err)
+			return fmt.Errorf("写入第 %d 行数据失败: %w", row, err)
 		}
 	}
 
-	filename := fmt.Sprintf("articles-%d.xlsx", time.Now().Unix())
-	fullPath := export.GetExcelFullPath() + filename
-
-	if err := os.MkdirAll(export.GetExcelFullPath(), 0755); err != nil {
-		return "", fmt.Errorf("创建导出目录失败: %w", err)
-	}
-	if err := f.SaveAs(fullPath); err != nil {
-		return "", fmt.Errorf("保存 Excel 文件失败: %w", err)
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("写入 Excel 文件失败: %w", err)
 	}
 
-	return filename, nil
+	return nil
 }
 
-func (a *Article) Import(r io.Reader) error {
+// Import 导入文章，创建人为 a.CreatedBy，返回导入的数量
+func (a *Article) Import(ctx context.Context, r io.Reader) (int, error) {
 	xlsx, err := excelize.OpenReader(r)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer xlsx.Close()
 
 	rows, err := xlsx.GetRows("文章信息")
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	count := 0
+
 	for irow, row := range rows {
 		if irow == 0 {
 			continue
 		}
-		if len(row) < 3 {
+		if len(row) < 11 {
 			continue
 		}
 		title := row[1]
@@ -230,23 +366,36 @@ func (a *Article) Import(r io.Reader) error {
 		content := row[3]
 		coverImageUrl := row[4]
 		state := row[5]
-		createdBy := row[6]
-		createdTime := row[7]
-		modifiedBy := row[8]
-		modifiedTime := row[9]
-		tagId := row[10]
-		models.AddArticle(map[string]interface{}{
-			"tag_id":          com.StrTo(tagId).MustInt(),
+		tagId := com.StrTo(row[10]).MustInt()
+		// 跳过标签不属于当前站点的行
+		exists, err := service.Tags.ExistByID(ctx, a.TenantID, tagId)
+		if err != nil {
+			return count, err
+		}
+		if !exists {
+			continue
+		}
+		slug, err := service.Articles.UniqueSlug(ctx, a.TenantID, title)
+		if err != nil {
+			return count, err
+		}
+		// 创建人为执行导入的用户，不取表格中的创建人
+		article, err := service.Articles.Add(ctx, map[string]interface{}{
+			"tenant_id":       a.TenantID,
+			"tag_id":          tagId,
 			"title":           title,
+			"slug":            slug,
 			"desc":            desc,
 			"content":         content,
 			"cover_image_url": coverImageUrl,
 			"state":           com.StrTo(state).MustInt(),
-			"created_by":      createdBy,
-			"created_time":    createdTime,
-			"modified_by":     modifiedBy,
-			"modified_time":   modifiedTime,
+			"created_by":      a.CreatedBy,
 		})
+		if err != nil {
+			return count, err
+		}
+		fireCreated(ctx, article)
+		count++
 	}
-	return nil
+	return count, nil
 }
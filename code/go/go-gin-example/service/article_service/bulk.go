@@ -0,0 +1,178 @@
+package article_service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/feed_service"
+	"github.com/fzzv/go-gin-example/service/related_service"
+	"github.com/fzzv/go-gin-example/service/webhook_service"
+)
+
+// BulkOperation 批量操作中的一项，修改时为空的字段、为 0 的 TagID 与为 -1 的 State 不修改
+type BulkOperation struct {
+	Op string
+	Article
+}
+
+// BulkResult 单项操作的结果，Applied 为 false 且 Err 为 nil 表示该项因其他项失败而被回滚
+type BulkResult struct {
+	Op      string
+	ID      int
+	Applied bool
+	Err     error
+	Before  interface{}
+	After   interface{}
+}
+
+// Bulk 批量执行站点内的文章操作，atomic 为 true 时任一项失败则全部不生效，username 作为创建人与修改人
+func Bulk(ctx context.Context, tenantID int, ops []BulkOperation, atomic bool, username string) ([]BulkResult, error) {
+	results := make([]BulkResult, len(ops))
+	var (
+		modelOps []models.BulkOp
+		indexes  []int
+	)
+	for i, op := range ops {
+		results[i] = BulkResult{Op: op.Op, ID: op.ID}
+		modelOp, err := op.toModel(username)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		modelOps = append(modelOps, modelOp)
+		indexes = append(indexes, i)
+	}
+
+	// 存在不合法的项时，原子操作直接放弃执行
+	if atomic && len(modelOps) < len(ops) {
+		return results, nil
+	}
+
+	modelResults, err := service.Articles.Bulk(ctx, tenantID, modelOps, atomic)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := false
+	for i, r := range modelResults {
+		result := &results[indexes[i]]
+		result.ID, result.Err, result.Before, result.After = r.ID, r.Err, r.Before, r.After
+		if r.Err != nil {
+			failed = true
+		}
+	}
+
+	applied := 0
+	for i := range results {
+		if results[i].Err != nil || (atomic && failed) {
+			continue
+		}
+		results[i].Applied = true
+		applied++
+		(&Article{TenantID: tenantID, ID: results[i].ID}).clearCache(ctx)
+		fireBulk(ctx, tenantID, &results[i])
+	}
+	if applied > 0 {
+		feed_service.Invalidate(ctx, tenantID)
+		related_service.Invalidate(ctx, tenantID)
+	}
+
+	return results, nil
+}
+
+func (op *BulkOperation) toModel(username string) (models.BulkOp, error) {
+	modelOp := models.BulkOp{Op: op.Op, ID: op.ID, Version: op.Version}
+
+	if op.Slug != "" {
+		slug := util.Slugify(op.Slug)
+		if slug == "" {
+			return modelOp, fmt.Errorf("%w: slug must contain letters or digits", models.ErrInvalidOperation)
+		}
+		op.Slug = slug
+	}
+
+	switch op.Op {
+	case models.BULK_CREATE:
+		if op.TagID <= 0 || op.Title == "" || op.Desc == "" || op.Content == "" || op.CoverImageUrl == "" {
+			return modelOp, fmt.Errorf("%w: tag_id, title, desc, content and cover_image_url are required", models.ErrInvalidOperation)
+		}
+		state := op.State
+		if state < 0 {
+			state = 0
+		}
+		modelOp.Data = map[string]interface{}{
+			"tag_id":          op.TagID,
+			"title":           op.Title,
+			"slug":            op.Slug,
+			"desc":            op.Desc,
+			"content":         op.Content,
+			"cover_image_url": op.CoverImageUrl,
+			"state":           state,
+			"created_by":      username,
+		}
+		return modelOp, nil
+	}
+
+	if op.ID <= 0 {
+		return modelOp, fmt.Errorf("%w: id is required", models.ErrInvalidOperation)
+	}
+
+	switch op.Op {
+	case models.BULK_UPDATE:
+		data := map[string]interface{}{"modified_by": username}
+		if op.TagID > 0 {
+			data["tag_id"] = op.TagID
+		}
+		for column, value := range map[string]string{
+			"title":           op.Title,
+			"slug":            op.Slug,
+			"desc":            op.Desc,
+			"content":         op.Content,
+			"cover_image_url": op.CoverImageUrl,
+		} {
+			if value != "" {
+				data[column] = value
+			}
+		}
+		if op.State >= 0 {
+			data["state"] = op.State
+		}
+		if len(data) == 1 {
+			return modelOp, fmt.Errorf("%w: nothing to update", models.ErrInvalidOperation)
+		}
+		modelOp.Data = data
+	case models.BULK_STATE:
+		if op.State < 0 {
+			return modelOp, fmt.Errorf("%w: state is required", models.ErrInvalidOperation)
+		}
+		modelOp.Data = map[string]interface{}{"state": op.State, "modified_by": username}
+	case models.BULK_DELETE:
+	default:
+		return modelOp, fmt.Errorf("%w: unknown op %q", models.ErrInvalidOperation, op.Op)
+	}
+
+	return modelOp, nil
+}
+
+// fireBulk 为已生效的操作触发 webhook 事件，与单项接口保持一致
+func fireBulk(ctx context.Context, tenantID int, result *BulkResult) {
+	switch result.Op {
+	case models.BULK_CREATE:
+		webhook_service.Fire(ctx, tenantID, webhook_service.EVENT_ARTICLE_CREATED, result.After)
+		if article, ok := result.After.(*models.Article); ok && article.State == 1 {
+			webhook_service.Fire(ctx, tenantID, webhook_service.EVENT_ARTICLE_PUBLISHED, result.After)
+		}
+	case models.BULK_UPDATE, models.BULK_STATE:
+		webhook_service.Fire(ctx, tenantID, webhook_service.EVENT_ARTICLE_UPDATED, result.After)
+		before, _ := result.Before.(*models.Article)
+		after, _ := result.After.(*models.Article)
+		if before != nil && after != nil && before.State != 1 && after.State == 1 {
+			webhook_service.Fire(ctx, tenantID, webhook_service.EVENT_ARTICLE_PUBLISHED, result.After)
+		}
+	case models.BULK_DELETE:
+		webhook_service.Fire(ctx, tenantID, webhook_service.EVENT_ARTICLE_DELETED, map[string]int{"id": result.ID})
+	}
+}
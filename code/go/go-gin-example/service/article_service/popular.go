@@ -0,0 +1,187 @@
+package article_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service"
+)
+
+// ScoredArticle 文章及其热度或相关度
+type ScoredArticle struct {
+	*models.Article
+	Score float64 `json:"score"`
+}
+
+// View 记录一次浏览，先在 Redis 中按 站点ID:文章ID 累计，由 FlushViews 定期写入数据库，记录失败只写日志
+func (a *Article) View(ctx context.Context) {
+	if err := service.Counter.Incr(ctx, e.CACHE_VIEWS, viewField(a.TenantID, a.ID), 1); err != nil {
+		logging.Warn(err)
+	}
+}
+
+func viewField(tenantID, id int) string {
+	return strconv.Itoa(tenantID) + ":" + strconv.Itoa(id)
+}
+
+// FlushViews 取出 Redis 中累计的浏览数写入数据库，计入写入时的当天，写入失败时放回计数等待下次写入
+func FlushViews(ctx context.Context) error {
+	counts, err := service.Counter.Take(ctx, e.CACHE_VIEWS)
+	if err != nil {
+		return err
+	}
+
+	today := day(time.Now())
+	views := make([]models.ArticleView, 0, len(counts))
+	for field, n := range counts {
+		var tenantID, id int
+		if _, err := fmt.Sscanf(field, "%d:%d", &tenantID, &id); err != nil || n <= 0 {
+			logging.Warn("invalid view counter", field, n)
+			continue
+		}
+		views = append(views, models.ArticleView{TenantID: tenantID, ArticleID: id, Day: today, Views: int(n)})
+	}
+	if len(views) == 0 {
+		return nil
+	}
+	// 按固定顺序更新，避免多个事务交错加锁
+	sort.Slice(views, func(i, j int) bool { return views[i].ArticleID < views[j].ArticleID })
+
+	if err := service.Articles.AddViews(ctx, views); err != nil {
+		for _, view := range views {
+			if err := service.Counter.Incr(ctx, e.CACHE_VIEWS, viewField(view.TenantID, view.ArticleID), int64(view.Views)); err != nil {
+				logging.Error(err)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// day 返回 t 所在那天 0 点（UTC）的时间戳
+func day(t time.Time) int {
+	return int(t.Unix() / 86400 * 86400)
+}
+
+// Like 记录 username 对文章的点赞，返回是否为新的点赞
+func (a *Article) Like(ctx context.Context, username string) (bool, error) {
+	liked, err := service.Articles.Like(ctx, a.TenantID, a.ID, username)
+	if liked {
+		a.clearCache(ctx)
+	}
+
+	return liked, err
+}
+
+// Unlike 取消 username 对文章的点赞，返回此前是否已点赞
+func (a *Article) Unlike(ctx context.Context, username string) (bool, error) {
+	unliked, err := service.Articles.Unlike(ctx, a.TenantID, a.ID, username)
+	if unliked {
+		a.clearCache(ctx)
+	}
+
+	return unliked, err
+}
+
+// ExistLike 判断 username 是否已点赞文章
+func (a *Article) ExistLike(ctx context.Context, username string) (bool, error) {
+	return service.Articles.ExistLike(ctx, a.TenantID, a.ID, username)
+}
+
+// ParseWindow 解析热门文章的统计时间范围，支持 7d 形式的天数与 time.ParseDuration 的写法，不能超过 [popularity] MaxWindow
+func ParseWindow(s string) (time.Duration, error) {
+	var (
+		window time.Duration
+		err    error
+	)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		window, err = time.ParseDuration(s)
+	}
+	if err != nil || window <= 0 {
+		return 0, errors.New("must be a positive duration such as 7d or 24h")
+	}
+	if window > setting.PopularitySetting.MaxWindow {
+		return 0, fmt.Errorf("must not exceed %s", setting.PopularitySetting.MaxWindow)
+	}
+
+	return window, nil
+}
+
+// Popular 获取 window 内热度最高的 limit 篇未删除文章
+//
+// 每天的浏览数按当天中午、每次点赞按点赞时间计算距今的时长，每经过 [popularity] HalfLife 贡献减半，
+// 一次点赞相当于 LikeWeight 次浏览，尚未写入数据库的浏览不计入
+func (a *Article) Popular(ctx context.Context, window time.Duration, limit int) ([]ScoredArticle, error) {
+	now := time.Now()
+	since := now.Add(-window)
+
+	views, err := service.Articles.GetViewsSince(ctx, a.TenantID, day(since))
+	if err != nil {
+		return nil, err
+	}
+	likes, err := service.Articles.GetLikesSince(ctx, a.TenantID, int(since.Unix()))
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[int]float64)
+	for _, view := range views {
+		noon := time.Unix(int64(view.Day), 0).Add(12 * time.Hour)
+		scores[view.ArticleID] += float64(view.Views) * decay(now.Sub(noon))
+	}
+	for _, like := range likes {
+		scores[like.ArticleID] += float64(setting.PopularitySetting.LikeWeight) * decay(now.Sub(time.Unix(int64(like.CreatedOn), 0)))
+	}
+	if len(scores) == 0 {
+		return []ScoredArticle{}, nil
+	}
+
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	articles, err := service.Articles.GetByIDs(ctx, a.TenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	popular := make([]ScoredArticle, 0, len(articles))
+	for _, article := range articles {
+		popular = append(popular, ScoredArticle{Article: article, Score: scores[article.ID]})
+	}
+	sort.SliceStable(popular, func(i, j int) bool {
+		if popular[i].Score != popular[j].Score {
+			return popular[i].Score > popular[j].Score
+		}
+		return popular[i].ID > popular[j].ID
+	})
+	if len(popular) > limit {
+		popular = popular[:limit]
+	}
+
+	return popular, nil
+}
+
+// decay 距今 age 的浏览或点赞的权重，未来的时间按 1 计算
+func decay(age time.Duration) float64 {
+	if age < 0 {
+		return 1
+	}
+
+	return math.Exp2(-age.Hours() / setting.PopularitySetting.HalfLife.Hours())
+}
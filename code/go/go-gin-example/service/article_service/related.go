@@ -0,0 +1,43 @@
+package article_service
+
+import (
+	"context"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/related_service"
+)
+
+// GetRelated 获取与文章相关的已发布文章，按相关度从高到低排列，相关度由 related_service 计算并缓存
+func (a *Article) GetRelated(ctx context.Context) ([]ScoredArticle, error) {
+	related, err := related_service.Get(ctx, a.TenantID, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(related) == 0 {
+		return []ScoredArticle{}, nil
+	}
+
+	ids := make([]int, len(related))
+	for i, r := range related {
+		ids[i] = r.ID
+	}
+	articles, err := service.Articles.GetByIDs(ctx, a.TenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*models.Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+
+	// 缓存的结果计算之后文章可能已被删除或取消发布
+	scored := make([]ScoredArticle, 0, len(related))
+	for _, r := range related {
+		if article, ok := byID[r.ID]; ok && article.State == 1 {
+			scored = append(scored, ScoredArticle{Article: article, Score: r.Score})
+		}
+	}
+
+	return scored, nil
+}
@@ -0,0 +1,136 @@
+package audit_service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/fzzv/go-gin-example/models"
+)
+
+type Audit struct {
+	TenantID   int
+	Username   string
+	Action     string
+	EntityType string
+	EntityID   string
+	StartTime  int
+	EndTime    int
+
+	PageNum  int
+	PageSize int
+}
+
+// Record 写入站点的一条审计记录，before、after 为操作前后的数据，为 nil 时不记录
+func Record(ctx context.Context, tenantID int, username, ip, action, entityType, entityID string, before, after interface{}) error {
+	audit := &models.Audit{
+		TenantID:   tenantID,
+		Username:   username,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         ip,
+	}
+
+	var err error
+	if audit.Before, err = marshal(before); err != nil {
+		return err
+	}
+	if audit.After, err = marshal(after); err != nil {
+		return err
+	}
+
+	return models.AddAudit(ctx, audit)
+}
+
+func marshal(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func (a *Audit) GetAll(ctx context.Context) ([]models.Audit, error) {
+	return models.GetAudits(ctx, a.PageNum, a.PageSize, a.getMaps(), a.StartTime, a.EndTime)
+}
+
+func (a *Audit) Count(ctx context.Context) (int, error) {
+	return models.GetAuditTotal(ctx, a.getMaps(), a.StartTime, a.EndTime)
+}
+
+func (a *Audit) getMaps() map[string]interface{} {
+	maps := make(map[string]interface{})
+	maps["tenant_id"] = a.TenantID
+	if a.Username != "" {
+		maps["username"] = a.Username
+	}
+	if a.Action != "" {
+		maps["action"] = a.Action
+	}
+	if a.EntityType != "" {
+		maps["entity_type"] = a.EntityType
+	}
+	if a.EntityID != "" {
+		maps["entity_id"] = a.EntityID
+	}
+
+	return maps
+}
+
+// Export 按当前条件导出审计记录，Excel 文件写入 w
+func (a *Audit) Export(ctx context.Context, w io.Writer) error {
+	if a.PageSize == 0 {
+		a.PageSize = 10000
+	}
+	audits, err := a.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "审计日志"
+	f.SetSheetName(f.GetSheetName(0), sheetName)
+
+	titles := []string{"ID", "用户", "操作", "对象类型", "对象ID", "IP", "操作前", "操作后", "时间"}
+	if err := f.SetSheetRow(sheetName, "A1", &titles); err != nil {
+		return fmt.Errorf("设置表头失败: %w", err)
+	}
+
+	for i, v := range audits {
+		row := i + 2
+		values := []interface{}{
+			v.ID,
+			v.Username,
+			v.Action,
+			v.EntityType,
+			v.EntityID,
+			v.IP,
+			v.Before,
+			v.After,
+			time.Unix(int64(v.CreatedOn), 0).Format("2006-01-02 15:04:05"),
+		}
+
+		cell := fmt.Sprintf("A%d", row)
+		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
+			return fmt.Errorf("写入第 %d 行数据失败: %w", row, err)
+		}
+	}
+
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("写入 Excel 文件失败: %w", err)
+	}
+
+	return nil
+}
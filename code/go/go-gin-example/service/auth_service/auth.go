@@ -0,0 +1,130 @@
+package auth_service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fzzv/go-gin-example/models"
+)
+
+// MAX_LEN 账号与密码的最大长度，与 /auth 接口的校验一致
+const MAX_LEN = 50
+
+// Roles 可以设置的角色
+var Roles = []string{models.ROLE_ADMIN, models.ROLE_EDITOR}
+
+type Auth struct {
+	TenantID int
+	Username string
+	Password string
+	Role     string
+	Subject  string
+}
+
+// Get 获取站点下的账号，不存在时返回 ID 为 0 的账号
+func (a *Auth) Get(ctx context.Context) (*models.Auth, error) {
+	return models.GetAuth(ctx, a.TenantID, a.Username)
+}
+
+// GetBySubject 获取站点下绑定了 Subject 的账号，不存在时返回 ID 为 0 的账号
+func (a *Auth) GetBySubject(ctx context.Context) (*models.Auth, error) {
+	return models.GetAuthBySubject(ctx, a.TenantID, a.Subject)
+}
+
+// Add 新建账号，账号已存在时返回 error
+func (a *Auth) Add(ctx context.Context) (*models.Auth, error) {
+	if err := checkLen("username", a.Username); err != nil {
+		return nil, err
+	}
+	if err := checkLen("password", a.Password); err != nil {
+		return nil, err
+	}
+	if err := CheckRole(a.Role); err != nil {
+		return nil, err
+	}
+
+	existing, err := a.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if existing.ID > 0 {
+		return nil, fmt.Errorf("user %q already exists", a.Username)
+	}
+
+	auth := models.Auth{TenantID: a.TenantID, Username: a.Username, Password: a.Password, Role: a.Role, Subject: a.Subject}
+	if err := models.AddAuth(ctx, &auth); err != nil {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+// ResetPassword 修改密码，已签发的 token 在过期前仍然有效
+func (a *Auth) ResetPassword(ctx context.Context) error {
+	if err := checkLen("password", a.Password); err != nil {
+		return err
+	}
+
+	return a.edit(ctx, map[string]interface{}{"password": a.Password})
+}
+
+// SetRole 修改角色，已签发的 token 在过期前仍按原角色鉴权
+func (a *Auth) SetRole(ctx context.Context) error {
+	if err := CheckRole(a.Role); err != nil {
+		return err
+	}
+
+	return a.edit(ctx, map[string]interface{}{"role": a.Role})
+}
+
+// BindSubject 将账号绑定到 OIDC 身份提供方的用户 sub，之后只有该用户可以通过 OIDC 登录此账号，
+// sub 已绑定站点下的其他账号时返回 error
+func (a *Auth) BindSubject(ctx context.Context) error {
+	if a.Subject == "" {
+		return fmt.Errorf("subject is required")
+	}
+
+	bound, err := a.GetBySubject(ctx)
+	if err != nil {
+		return err
+	}
+	if bound.ID > 0 && bound.Username != a.Username {
+		return fmt.Errorf("subject %q is already bound to user %q", a.Subject, bound.Username)
+	}
+
+	return a.edit(ctx, map[string]interface{}{"subject": a.Subject})
+}
+
+func (a *Auth) edit(ctx context.Context, data map[string]interface{}) error {
+	existing, err := a.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if existing.ID == 0 {
+		return fmt.Errorf("user %q does not exist", a.Username)
+	}
+
+	return models.EditAuth(ctx, a.TenantID, a.Username, data)
+}
+
+// CheckRole 校验角色是否为 Roles 之一
+func CheckRole(role string) error {
+	for _, r := range Roles {
+		if role == r {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown role %q, must be one of %v", role, Roles)
+}
+
+func checkLen(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+	if len(value) > MAX_LEN {
+		return fmt.Errorf("%s must not be longer than %d characters", name, MAX_LEN)
+	}
+
+	return nil
+}
@@ -8,20 +8,22 @@ import (
 )
 
 type Article struct {
-	ID    int
-	TagID int
-	State int
+	TenantID int
+	ID       int
+	TagID    int
+	State    int
 
 	PageNum  int
 	PageSize int
 }
 
 func (a *Article) GetArticleKey() string {
-	return e.CACHE_ARTICLE + "_" + strconv.Itoa(a.ID)
+	return Namespace(a.TenantID) + "_" + e.CACHE_ARTICLE + "_" + strconv.Itoa(a.ID)
 }
 
 func (a *Article) GetArticlesKey() string {
 	keys := []string{
+		Namespace(a.TenantID),
 		e.CACHE_ARTICLE,
 		"LIST",
 	}
@@ -8,9 +8,10 @@ import (
 )
 
 type Tag struct {
-	ID    int
-	Name  string
-	State int
+	TenantID int
+	ID       int
+	Name     string
+	State    int
 
 	PageNum  int
 	PageSize int
@@ -18,6 +19,7 @@ type Tag struct {
 
 func (t *Tag) GetTagsKey() string {
 	keys := []string{
+		Namespace(t.TenantID),
 		e.CACHE_TAG,
 		"LIST",
 	}
@@ -0,0 +1,12 @@
+package cache_service
+
+import (
+	"strconv"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+)
+
+// Namespace 站点的缓存键前缀，同一站点的缓存都以它开头，避免不同站点之间相互读取
+func Namespace(tenantID int) string {
+	return e.CACHE_TENANT + "_" + strconv.Itoa(tenantID)
+}
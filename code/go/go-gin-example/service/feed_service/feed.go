@@ -0,0 +1,174 @@
+package feed_service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/feed"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/cache_service"
+	"github.com/fzzv/go-gin-example/service/tenant_service"
+
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+const (
+	FORMAT_RSS  = "rss"
+	FORMAT_ATOM = "atom"
+	FORMAT_JSON = "json"
+)
+
+// Feed 站点的订阅源，TagID 为 0 时包含所有标签下的文章
+type Feed struct {
+	Tenant *models.Tenant
+	TagID  int
+	Format string
+}
+
+// Output 渲染后的订阅源，整体缓存在 Redis 中
+type Output struct {
+	Body         []byte `json:"body"`
+	ContentType  string `json:"content_type"`
+	ETag         string `json:"etag"`
+	LastModified int64  `json:"last_modified"`
+}
+
+func (f *Feed) key() string {
+	return strings.Join([]string{cache_service.Namespace(f.Tenant.ID), e.CACHE_FEED, strconv.Itoa(f.TagID), f.Format}, "_")
+}
+
+// Get 获取订阅源，优先读取缓存
+func (f *Feed) Get(ctx context.Context) (*Output, error) {
+	key := f.key()
+	if service.Cache.Exists(ctx, key) {
+		data, err := service.Cache.Get(ctx, key)
+		if err != nil {
+			logging.Info(err)
+		} else {
+			var cacheOutput Output
+			if err := json.Unmarshal(data, &cacheOutput); err == nil {
+				return &cacheOutput, nil
+			}
+		}
+	}
+
+	output, err := f.Render(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl := int(setting.FeedSetting.CacheTTL / time.Second); ttl > 0 {
+		service.Cache.Set(ctx, key, output, ttl)
+	}
+	return output, nil
+}
+
+// Render 生成订阅源，不读写缓存
+func (f *Feed) Render(ctx context.Context) (*Output, error) {
+	articles, err := service.Articles.GetPublished(ctx, f.Tenant.ID, 0, setting.FeedSetting.Limit, f.TagID)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := f.build(ctx, articles)
+	if err != nil {
+		return nil, err
+	}
+
+	output := Output{LastModified: src.Updated.Unix()}
+	switch f.Format {
+	case FORMAT_RSS:
+		output.ContentType = feed.RSS_CONTENT_TYPE
+		output.Body, err = src.RSS()
+	case FORMAT_ATOM:
+		output.ContentType = feed.ATOM_CONTENT_TYPE
+		output.Body, err = src.Atom()
+	case FORMAT_JSON:
+		output.ContentType = feed.JSON_CONTENT_TYPE
+		output.Body, err = src.JSON()
+	default:
+		return nil, fmt.Errorf("unknown feed format %q", f.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum(output.Body)
+	output.ETag = `"` + hex.EncodeToString(sum[:]) + `"`
+
+	return &output, nil
+}
+
+func (f *Feed) build(ctx context.Context, articles []*models.Article) (*feed.Feed, error) {
+	link := tenant_service.Link(f.Tenant)
+	src := feed.Feed{
+		Title:       setting.FeedSetting.Title,
+		Link:        link,
+		FeedURL:     link + "/feed." + f.Format,
+		Description: setting.FeedSetting.Description,
+	}
+	if f.Tenant.Title != "" {
+		src.Title = f.Tenant.Title
+	}
+	if f.Tenant.Description != "" {
+		src.Description = f.Tenant.Description
+	}
+
+	if f.TagID > 0 {
+		tag, err := service.Tags.Get(ctx, f.Tenant.ID, f.TagID)
+		if err != nil {
+			return nil, err
+		}
+		src.Title += " - " + tag.Name
+		src.FeedURL = fmt.Sprintf("%s/tags/%d/feed.%s", link, f.TagID, f.Format)
+	}
+
+	for _, article := range articles {
+		published := time.Unix(int64(article.CreatedOn), 0)
+		updated := published
+		if article.ModifiedOn > article.CreatedOn {
+			updated = time.Unix(int64(article.ModifiedOn), 0)
+		}
+		if updated.After(src.Updated) {
+			src.Updated = updated
+		}
+
+		articleLink := link + "/articles/" + url.PathEscape(article.Slug)
+		src.Items = append(src.Items, feed.Item{
+			ID:          "urn:go-gin-example:article:" + strconv.Itoa(article.ID), // slug 可修改，使用 ID 保证条目标识不变
+			Title:       article.Title,
+			Link:        articleLink,
+			Description: article.Desc,
+			Content:     article.Content,
+			Author:      article.CreatedBy,
+			Category:    article.Tag.Name,
+			Image:       article.CoverImageUrl,
+			Published:   published,
+			Updated:     updated,
+		})
+	}
+
+	// 没有文章时以生成时间作为更新时间，缓存期内保持不变
+	if src.Updated.IsZero() {
+		src.Updated = time.Now()
+	}
+
+	return &src, nil
+}
+
+// Invalidate 清除站点所有订阅源的缓存，在文章或标签变更后调用
+func Invalidate(ctx context.Context, tenantID int) {
+	if err := service.Cache.LikeDeletes(ctx, cache_service.Namespace(tenantID)+"_"+e.CACHE_FEED); err != nil {
+		logging.Warn(err)
+	}
+}
@@ -0,0 +1,154 @@
+package graphql_service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/service/audit_service"
+)
+
+// Viewer 发起请求的用户，解析字段时用于确定站点与记录审计日志
+type Viewer struct {
+	TenantID int
+	Username string
+	ReadOnly bool // 为 true 时只能执行查询，如通过只读的 API Key 鉴权
+	IP       string
+	Lang     string
+}
+
+// Request 一次 GraphQL 请求
+type Request struct {
+	Query         string
+	OperationName string
+	Variables     map[string]interface{}
+}
+
+type viewerKey struct{}
+
+var (
+	schemaOnce sync.Once
+	schema     graphql.Schema
+	schemaErr  error
+)
+
+// Do 解析并校验请求，嵌套层数与复杂度都在限制内时才执行
+func Do(ctx context.Context, viewer *Viewer, req Request) *graphql.Result {
+	schemaOnce.Do(func() {
+		schema, schemaErr = newSchema()
+	})
+	if schemaErr != nil {
+		logging.Error(schemaErr)
+		return errorResult(newError(viewer, e.ERROR, nil))
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&schema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	if viewer.ReadOnly && isMutation(doc, req.OperationName) {
+		return errorResult(newError(viewer, e.ERROR_API_KEY_SCOPE, nil))
+	}
+
+	if err := checkLimits(schema, doc, req.OperationName, req.Variables, viewer); err != nil {
+		return errorResult(err)
+	}
+
+	ctx = context.WithValue(ctx, viewerKey{}, viewer)
+	ctx = withLoaders(ctx, viewer.TenantID)
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+// isMutation 要执行的操作是否为修改，operationName 为空时只看第一个操作
+func isMutation(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok && (operationName == "" || (op.Name != nil && op.Name.Value == operationName)) {
+			return op.Operation == ast.OperationTypeMutation
+		}
+	}
+
+	return false
+}
+
+func getViewer(ctx context.Context) *Viewer {
+	if viewer, ok := ctx.Value(viewerKey{}).(*Viewer); ok {
+		return viewer
+	}
+
+	return &Viewer{}
+}
+
+// Error 带业务码的错误，code 与 REST 接口一致，通过 extensions 返回
+type Error struct {
+	Code   int
+	Msg    string
+	Errors map[string]string // 参数校验错误，参数名 → 错误信息
+}
+
+func newError(viewer *Viewer, code int, errs map[string]string) *Error {
+	return &Error{Code: code, Msg: e.GetMsgByLang(code, viewer.Lang), Errors: errs}
+}
+
+func (err *Error) Error() string {
+	return err.Msg
+}
+
+func (err *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": err.Code}
+	if len(err.Errors) > 0 {
+		extensions["errors"] = err.Errors
+	}
+
+	return extensions
+}
+
+// errorResult 执行前就失败的请求，没有对应的字段路径
+func errorResult(err *Error) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{{
+		Message:    err.Msg,
+		Locations:  []location.SourceLocation{},
+		Extensions: err.Extensions(),
+	}}}
+}
+
+// fail 记录内部错误并返回带业务码的错误，不向客户端暴露错误详情
+func fail(ctx context.Context, code int, err error) *Error {
+	logging.Error(err)
+	return newError(getViewer(ctx), code, nil)
+}
+
+// audit 记录当前用户的一次写操作，记录失败只写日志，不影响请求结果
+func audit(ctx context.Context, action, entityType string, entityID int, before, after interface{}) {
+	viewer := getViewer(ctx)
+	id := strconv.Itoa(entityID)
+
+	err := audit_service.Record(ctx, viewer.TenantID, viewer.Username, viewer.IP, action, entityType, id, before, after)
+	if err != nil {
+		logging.Error("audit", action, entityType, id, err)
+	}
+}
@@ -0,0 +1,189 @@
+package graphql_service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// checkLimits 在执行前计算要执行的操作的嵌套层数与复杂度，超过配置的限制时返回错误
+//
+// 每个字段计 1，返回列表的字段其子字段按 limit 参数（未指定时为 app.PageSize）倍计算，
+// 内省字段只计 1，不计算其子字段，以免客户端无法获取 schema
+func checkLimits(schema graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}, viewer *Viewer) *Error {
+	l := limiter{
+		schema:        schema,
+		fragments:     make(map[string]*ast.FragmentDefinition),
+		variables:     variables,
+		maxDepth:      setting.GraphQLSetting.MaxDepth,
+		maxComplexity: setting.GraphQLSetting.MaxComplexity,
+	}
+
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			l.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		}
+	}
+	// 找不到要执行的操作时由执行阶段返回错误
+	if operation == nil {
+		return nil
+	}
+
+	var root *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	}
+	if root == nil {
+		return nil
+	}
+
+	complexity := l.complexity(root, operation.SelectionSet, 0)
+	if l.depth > l.maxDepth {
+		return newError(viewer, e.ERROR_GRAPHQL_TOO_DEEP, map[string]string{"depth": "must not exceed " + strconv.Itoa(l.maxDepth)})
+	}
+	if complexity > l.maxComplexity {
+		return newError(viewer, e.ERROR_GRAPHQL_TOO_COMPLEX, map[string]string{"complexity": "must not exceed " + strconv.Itoa(l.maxComplexity)})
+	}
+
+	return nil
+}
+
+type limiter struct {
+	schema        graphql.Schema
+	fragments     map[string]*ast.FragmentDefinition
+	variables     map[string]interface{}
+	maxDepth      int
+	maxComplexity int
+
+	depth int // 已遇到的最大嵌套层数
+}
+
+// complexity 计算 parent 类型下 set 的复杂度，depth 为 set 所在的层数，
+// 超过任一限制后立即返回，片段被反复引用时也不会遍历过多节点
+func (l *limiter) complexity(parent *graphql.Object, set *ast.SelectionSet, depth int) int {
+	if set == nil {
+		return 0
+	}
+
+	total := 0
+	for _, selection := range set.Selections {
+		if total > l.maxComplexity || l.depth > l.maxDepth {
+			break
+		}
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			total++
+			if depth+1 > l.depth {
+				l.depth = depth + 1
+			}
+
+			name := selection.Name.Value
+			def, ok := parent.Fields()[name]
+			if strings.HasPrefix(name, "__") || !ok {
+				continue
+			}
+
+			child, list := unwrap(def.Type)
+			if child == nil {
+				continue
+			}
+			sub := l.complexity(child, selection.SelectionSet, depth+1)
+			if list {
+				sub = l.multiply(sub, l.listSize(selection))
+			}
+			total += sub
+		case *ast.InlineFragment:
+			total += l.complexity(l.typeCondition(parent, selection.TypeCondition), selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			if fragment, ok := l.fragments[selection.Name.Value]; ok {
+				total += l.complexity(l.typeCondition(parent, fragment.TypeCondition), fragment.SelectionSet, depth)
+			}
+		}
+	}
+
+	return total
+}
+
+func (l *limiter) typeCondition(parent *graphql.Object, condition *ast.Named) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	if object, ok := l.schema.Type(condition.Name.Value).(*graphql.Object); ok {
+		return object
+	}
+
+	return parent
+}
+
+// listSize 列表字段预计返回的条数，取 limit 参数，未指定时为 app.PageSize
+func (l *limiter) listSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := l.variables[value.Name.Value].(type) {
+			case int:
+				if n > 0 {
+					return n
+				}
+			case float64:
+				if n > float64(l.maxComplexity) {
+					return l.maxComplexity + 1
+				}
+				if n >= 1 {
+					return int(n)
+				}
+			}
+		}
+	}
+
+	return setting.AppSetting.PageSize
+}
+
+// multiply 超过复杂度上限后不再精确计算，避免 limit 很大时溢出
+func (l *limiter) multiply(sub, size int) int {
+	if sub > l.maxComplexity || size > l.maxComplexity || sub*size > l.maxComplexity {
+		return l.maxComplexity + 1
+	}
+
+	return sub * size
+}
+
+// unwrap 去掉 NonNull 与 List，返回字段的对象类型（标量时为 nil）及是否为列表
+func unwrap(t graphql.Type) (*graphql.Object, bool) {
+	list := false
+	for {
+		switch v := t.(type) {
+		case *graphql.NonNull:
+			t = v.OfType
+		case *graphql.List:
+			list = true
+			t = v.OfType
+		case *graphql.Object:
+			return v, list
+		default:
+			return nil, list
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package graphql_service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+// loader 收集同一层字段请求的 key，第一次取值时一次查询出所有 key 的结果，避免列表中的每一项各查询一次（N+1）
+//
+// 解析字段时只调用 Load 登记 key 并返回 thunk，graphql-go 在解析完同一层的所有字段后才依次调用 thunk
+type loader[K comparable, V any] struct {
+	mu      sync.Mutex
+	fetch   func(keys []K) (map[K]V, error)
+	pending []K
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, values: make(map[K]V), errs: make(map[K]error)}
+}
+
+// Load 登记 key，返回的函数在第一次调用时查询所有已登记的 key
+func (l *loader[K, V]) Load(key K) func() (V, error) {
+	l.mu.Lock()
+	if _, ok := l.values[key]; !ok {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			l.flush()
+		}
+
+		return l.values[key], l.errs[key]
+	}
+}
+
+func (l *loader[K, V]) flush() {
+	keys := make([]K, 0, len(l.pending))
+	seen := make(map[K]bool, len(l.pending))
+	for _, key := range l.pending {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	l.pending = nil
+
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+		}
+		// 查询不到的 key 也记录零值，之后不再重复查询
+		l.values[key] = values[key]
+	}
+}
+
+// loaders 单个请求内共用的 loader，结果只在该请求内有效
+type loaders struct {
+	tags             *loader[int, *models.Tag]
+	articlesByTag    *loader[int, []*models.Article]
+	articlesByAuthor *loader[string, []*models.Article]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, tenantID int) context.Context {
+	tagService := tag_service.Tag{TenantID: tenantID}
+	articleService := article_service.Article{TenantID: tenantID}
+
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		tags: newLoader(func(ids []int) (map[int]*models.Tag, error) {
+			tags, err := tagService.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, fail(ctx, e.ERROR_GET_TAGS_FAIL, err)
+			}
+			result := make(map[int]*models.Tag, len(tags))
+			for i := range tags {
+				result[tags[i].ID] = &tags[i]
+			}
+			return result, nil
+		}),
+		articlesByTag: newLoader(func(tagIDs []int) (map[int][]*models.Article, error) {
+			articles, err := articleService.GetByTags(ctx, tagIDs)
+			if err != nil {
+				return nil, fail(ctx, e.ERROR_GET_ARTICLES_FAIL, err)
+			}
+			result := make(map[int][]*models.Article)
+			for _, article := range articles {
+				result[article.TagID] = append(result[article.TagID], article)
+			}
+			return result, nil
+		}),
+		articlesByAuthor: newLoader(func(usernames []string) (map[string][]*models.Article, error) {
+			articles, err := articleService.GetByAuthors(ctx, usernames)
+			if err != nil {
+				return nil, fail(ctx, e.ERROR_GET_ARTICLES_FAIL, err)
+			}
+			result := make(map[string][]*models.Article)
+			for _, article := range articles {
+				result[article.CreatedBy] = append(result[article.CreatedBy], article)
+			}
+			return result, nil
+		}),
+	})
+}
+
+func getLoaders(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,391 @@
+package graphql_service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/util"
+	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+// newMutation 各修改操作与对应的 REST 接口使用相同的 service、校验规则与业务码，并同样记录审计日志
+func newMutation(tagType, articleType *graphql.Object) *graphql.Object {
+	articleInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ArticleInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"tagId":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"title":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"slug":          &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "为空时由标题生成，修改文章时为空表示不修改"},
+			"desc":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"content":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"coverImageUrl": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"state":         &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+	versionArg := &graphql.ArgumentConfig{Type: graphql.Int, Description: "期望的当前版本，与 REST 接口的 If-Match 相同，不指定时不检查"}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"addTag": &graphql.Field{
+				Type: graphql.NewNonNull(tagType),
+				Args: graphql.FieldConfigArgument{
+					"name":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"state": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: addTag,
+			},
+			"editTag": &graphql.Field{
+				Type: graphql.NewNonNull(tagType),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"name":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"state":   &graphql.ArgumentConfig{Type: graphql.Int},
+					"version": versionArg,
+				},
+				Resolve: editTag,
+			},
+			"deleteTag": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"cascade": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: deleteTag,
+			},
+			"addArticle": &graphql.Field{
+				Type:    graphql.NewNonNull(articleType),
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(articleInput)}},
+				Resolve: addArticle,
+			},
+			"editArticle": &graphql.Field{
+				Type: graphql.NewNonNull(articleType),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(articleInput)},
+					"version": versionArg,
+				},
+				Resolve: editArticle,
+			},
+			"deleteArticle": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: deleteArticle,
+			},
+		},
+	})
+}
+
+type addTagArgs struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	State int    `json:"state" binding:"oneof=0 1"`
+}
+
+func addTag(p graphql.ResolveParams) (interface{}, error) {
+	var args addTagArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+	viewer := getViewer(p.Context)
+
+	tagService := tag_service.Tag{
+		TenantID:  viewer.TenantID,
+		Name:      args.Name,
+		CreatedBy: viewer.Username,
+		State:     args.State,
+	}
+	exists, err := tagService.ExistByName(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_EXIST_TAG_FAIL, err)
+	}
+	if exists {
+		return nil, newError(viewer, e.ERROR_EXIST_TAG, nil)
+	}
+
+	tag, err := tagService.Add(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_ADD_TAG_FAIL, err)
+	}
+	audit(p.Context, models.AUDIT_CREATE, "tag", tag.ID, nil, tag)
+
+	return tag, nil
+}
+
+type editTagArgs struct {
+	ID      int    `json:"id" binding:"required,min=1"`
+	Name    string `json:"name" binding:"required,max=100"`
+	State   *int   `json:"state" binding:"omitempty,oneof=0 1"`
+	Version int    `json:"version" binding:"omitempty,min=1"`
+}
+
+func editTag(p graphql.ResolveParams) (interface{}, error) {
+	var args editTagArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+	viewer := getViewer(p.Context)
+
+	state := -1
+	if args.State != nil {
+		state = *args.State
+	}
+
+	tagService := tag_service.Tag{
+		TenantID:   viewer.TenantID,
+		ID:         args.ID,
+		Name:       args.Name,
+		ModifiedBy: viewer.Username,
+		State:      state,
+		Version:    args.Version,
+	}
+	before, err := tagService.Load(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_EXIST_TAG_FAIL, err)
+	}
+	if before.ID == 0 {
+		return nil, newError(viewer, e.ERROR_NOT_EXIST_TAG, nil)
+	}
+
+	err = tagService.Edit(p.Context)
+	if errors.Is(err, models.ErrVersionConflict) {
+		return nil, newError(viewer, e.ERROR_VERSION_CONFLICT, nil)
+	}
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_EDIT_TAG_FAIL, err)
+	}
+	after, err := tagService.Load(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_EXIST_TAG_FAIL, err)
+	}
+	audit(p.Context, models.AUDIT_EDIT, "tag", args.ID, before, after)
+
+	return after, nil
+}
+
+type deleteTagArgs struct {
+	ID      int  `json:"id" binding:"required,min=1"`
+	Cascade bool `json:"cascade"`
+}
+
+// deleteTag 标签下还有文章时，除非指定 cascade 一并删除，否则拒绝删除
+func deleteTag(p graphql.ResolveParams) (interface{}, error) {
+	var args deleteTagArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+	viewer := getViewer(p.Context)
+
+	tagService := tag_service.Tag{TenantID: viewer.TenantID, ID: args.ID, Cascade: args.Cascade}
+	before, err := tagService.Load(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_EXIST_TAG_FAIL, err)
+	}
+	if before.ID == 0 {
+		return nil, newError(viewer, e.ERROR_NOT_EXIST_TAG, nil)
+	}
+
+	if !args.Cascade {
+		count, err := tagService.CountArticles(p.Context)
+		if err != nil {
+			return nil, fail(p.Context, e.ERROR_COUNT_ARTICLE_FAIL, err)
+		}
+		if count > 0 {
+			return nil, newError(viewer, e.ERROR_TAG_HAS_ARTICLES, nil)
+		}
+	}
+
+	if err := tagService.Delete(p.Context); err != nil {
+		return nil, fail(p.Context, e.ERROR_DELETE_TAG_FAIL, err)
+	}
+	audit(p.Context, models.AUDIT_DELETE, "tag", args.ID, before, map[string]bool{"cascade": args.Cascade})
+
+	return true, nil
+}
+
+// articleInput 与 AddArticleForm 的校验规则一致，修改文章时 content 可以为空
+type articleInput struct {
+	TagID         int    `json:"tagId" binding:"required,min=1"`
+	Title         string `json:"title" binding:"required,max=100"`
+	Slug          string `json:"slug" binding:"omitempty,max=100"`
+	Desc          string `json:"desc" binding:"required,max=255"`
+	Content       string `json:"content" binding:"max=65535"`
+	CoverImageUrl string `json:"coverImageUrl" binding:"required,max=255"`
+	State         *int   `json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+type addArticleArgs struct {
+	Input articleInput `json:"input"`
+}
+
+func addArticle(p graphql.ResolveParams) (interface{}, error) {
+	var args addArticleArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+	viewer := getViewer(p.Context)
+	input := args.Input
+	if input.Content == "" {
+		return nil, newError(viewer, e.INVALID_PARAMS, map[string]string{"content": "is required"})
+	}
+
+	if err := checkTag(p.Context, input.TagID); err != nil {
+		return nil, err
+	}
+	slug, err := checkSlug(p.Context, input.Slug, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	state := 0
+	if input.State != nil {
+		state = *input.State
+	}
+
+	articleService := article_service.Article{
+		TenantID:      viewer.TenantID,
+		TagID:         input.TagID,
+		Title:         input.Title,
+		Slug:          slug,
+		Desc:          input.Desc,
+		Content:       input.Content,
+		CoverImageUrl: input.CoverImageUrl,
+		State:         state,
+		CreatedBy:     viewer.Username,
+	}
+	article, err := articleService.Add(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_ADD_ARTICLE_FAIL, err)
+	}
+	audit(p.Context, models.AUDIT_CREATE, "article", article.ID, nil, article)
+
+	return article, nil
+}
+
+type editArticleArgs struct {
+	ID      int          `json:"id" binding:"required,min=1"`
+	Input   articleInput `json:"input"`
+	Version int          `json:"version" binding:"omitempty,min=1"`
+}
+
+func editArticle(p graphql.ResolveParams) (interface{}, error) {
+	var args editArticleArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+	viewer := getViewer(p.Context)
+	input := args.Input
+
+	slug, err := checkSlug(p.Context, input.Slug, args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := -1
+	if input.State != nil {
+		state = *input.State
+	}
+
+	articleService := article_service.Article{
+		TenantID:      viewer.TenantID,
+		ID:            args.ID,
+		TagID:         input.TagID,
+		Title:         input.Title,
+		Slug:          slug,
+		Desc:          input.Desc,
+		Content:       input.Content,
+		CoverImageUrl: input.CoverImageUrl,
+		State:         state,
+		Version:       args.Version,
+		ModifiedBy:    viewer.Username,
+	}
+	before, err := articleService.Load(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, err)
+	}
+	if before.ID == 0 {
+		return nil, newError(viewer, e.ERROR_NOT_EXIST_ARTICLE, nil)
+	}
+	if err := checkTag(p.Context, input.TagID); err != nil {
+		return nil, err
+	}
+
+	err = articleService.Edit(p.Context)
+	if errors.Is(err, models.ErrVersionConflict) {
+		return nil, newError(viewer, e.ERROR_VERSION_CONFLICT, nil)
+	}
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_EDIT_ARTICLE_FAIL, err)
+	}
+	after, err := articleService.Load(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, err)
+	}
+	audit(p.Context, models.AUDIT_EDIT, "article", args.ID, before, after)
+
+	return after, nil
+}
+
+func deleteArticle(p graphql.ResolveParams) (interface{}, error) {
+	viewer := getViewer(p.Context)
+	id := p.Args["id"].(int)
+
+	articleService := article_service.Article{TenantID: viewer.TenantID, ID: id}
+	before, err := articleService.Load(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, err)
+	}
+	if before.ID == 0 {
+		return nil, newError(viewer, e.ERROR_NOT_EXIST_ARTICLE, nil)
+	}
+
+	if err := articleService.Delete(p.Context); err != nil {
+		return nil, fail(p.Context, e.ERROR_DELETE_ARTICLE_FAIL, err)
+	}
+	audit(p.Context, models.AUDIT_DELETE, "article", id, before, nil)
+
+	return true, nil
+}
+
+// checkTag 文章所属的标签必须存在于当前站点
+func checkTag(ctx context.Context, id int) error {
+	viewer := getViewer(ctx)
+	tagService := tag_service.Tag{TenantID: viewer.TenantID, ID: id}
+	exists, err := tagService.ExistByID(ctx)
+	if err != nil {
+		return fail(ctx, e.ERROR_EXIST_TAG_FAIL, err)
+	}
+	if !exists {
+		return newError(viewer, e.ERROR_NOT_EXIST_TAG, nil)
+	}
+
+	return nil
+}
+
+// checkSlug 规范化客户端指定的 slug 并检查是否已被 id 以外的文章使用，未指定时返回空字符串
+func checkSlug(ctx context.Context, raw string, id int) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	viewer := getViewer(ctx)
+	slug := util.Slugify(raw)
+	if slug == "" {
+		return "", newError(viewer, e.INVALID_PARAMS, map[string]string{"slug": "must contain letters or digits"})
+	}
+
+	articleService := article_service.Article{TenantID: viewer.TenantID, ID: id, Slug: slug}
+	exists, err := articleService.ExistBySlug(ctx)
+	if err != nil {
+		return "", fail(ctx, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, err)
+	}
+	if exists {
+		return "", newError(viewer, e.ERROR_EXIST_ARTICLE_SLUG, nil)
+	}
+
+	return slug, nil
+}
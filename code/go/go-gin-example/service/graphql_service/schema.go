@@ -0,0 +1,397 @@
+package graphql_service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/app"
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service/article_service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+// author 作者即站点内发表过文章的用户
+type author struct {
+	Username string
+}
+
+func newSchema() (graphql.Schema, error) {
+	var tagType, articleType, authorType *graphql.Object
+
+	limitArgs := graphql.FieldConfigArgument{
+		"limit": &graphql.ArgumentConfig{Type: graphql.Int, Description: "最多返回的条数，1 到 100，默认为 app.PageSize"},
+	}
+
+	tagType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Tag",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":         tagField(graphql.NewNonNull(graphql.Int), func(t *models.Tag) interface{} { return t.ID }),
+				"name":       tagField(graphql.NewNonNull(graphql.String), func(t *models.Tag) interface{} { return t.Name }),
+				"state":      tagField(graphql.NewNonNull(graphql.Int), func(t *models.Tag) interface{} { return t.State }),
+				"createdBy":  tagField(graphql.NewNonNull(graphql.String), func(t *models.Tag) interface{} { return t.CreatedBy }),
+				"modifiedBy": tagField(graphql.NewNonNull(graphql.String), func(t *models.Tag) interface{} { return t.ModifiedBy }),
+				"createdOn":  tagField(graphql.NewNonNull(graphql.Int), func(t *models.Tag) interface{} { return t.CreatedOn }),
+				"modifiedOn": tagField(graphql.NewNonNull(graphql.Int), func(t *models.Tag) interface{} { return t.ModifiedOn }),
+				"version":    tagField(graphql.NewNonNull(graphql.Int), func(t *models.Tag) interface{} { return t.Version }),
+				"articles": &graphql.Field{
+					Type:        listOf(articleType),
+					Description: "标签下未删除的文章",
+					Args:        limitArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadArticles(p, getLoaders(p.Context).articlesByTag.Load(p.Source.(*models.Tag).ID))
+					},
+				},
+			}
+		}),
+	})
+
+	articleType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Article",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":            articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.ID }),
+				"tagId":         articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.TagID }),
+				"title":         articleField(graphql.NewNonNull(graphql.String), func(a *models.Article) interface{} { return a.Title }),
+				"slug":          articleField(graphql.NewNonNull(graphql.String), func(a *models.Article) interface{} { return a.Slug }),
+				"desc":          articleField(graphql.NewNonNull(graphql.String), func(a *models.Article) interface{} { return a.Desc }),
+				"content":       articleField(graphql.NewNonNull(graphql.String), func(a *models.Article) interface{} { return a.Content }),
+				"coverImageUrl": articleField(graphql.NewNonNull(graphql.String), func(a *models.Article) interface{} { return a.CoverImageUrl }),
+				"state":         articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.State }),
+				"createdBy":     articleField(graphql.NewNonNull(graphql.String), func(a *models.Article) interface{} { return a.CreatedBy }),
+				"modifiedBy":    articleField(graphql.NewNonNull(graphql.String), func(a *models.Article) interface{} { return a.ModifiedBy }),
+				"createdOn":     articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.CreatedOn }),
+				"modifiedOn":    articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.ModifiedOn }),
+				"version":       articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.Version }),
+				"viewCount":     articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.ViewCount }),
+				"likeCount":     articleField(graphql.NewNonNull(graphql.Int), func(a *models.Article) interface{} { return a.LikeCount }),
+				"tag": &graphql.Field{
+					Type:    tagType,
+					Resolve: resolveArticleTag,
+				},
+				"author": &graphql.Field{
+					Type: authorType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if username := p.Source.(*models.Article).CreatedBy; username != "" {
+							return &author{Username: username}, nil
+						}
+						return nil, nil
+					},
+				},
+			}
+		}),
+	})
+
+	authorType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Author",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"username": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*author).Username, nil
+					},
+				},
+				"articles": &graphql.Field{
+					Type:        listOf(articleType),
+					Description: "作者未删除的文章",
+					Args:        limitArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadArticles(p, getLoaders(p.Context).articlesByAuthor.Load(p.Source.(*author).Username))
+					},
+				},
+			}
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"tags": &graphql.Field{
+				Type: page("TagPage", tagType),
+				Args: graphql.FieldConfigArgument{
+					"page":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"name":  &graphql.ArgumentConfig{Type: graphql.String},
+					"state": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: resolveTags,
+			},
+			"tag": &graphql.Field{
+				Type:    tagType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: resolveTag,
+			},
+			"articles": &graphql.Field{
+				Type: page("ArticlePage", articleType),
+				Args: graphql.FieldConfigArgument{
+					"page":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"tagId": &graphql.ArgumentConfig{Type: graphql.Int},
+					"state": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: resolveArticles,
+			},
+			"article": &graphql.Field{
+				Type:    articleType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: resolveArticle,
+			},
+			"authors": &graphql.Field{
+				Type:    listOf(authorType),
+				Resolve: resolveAuthors,
+			},
+			"author": &graphql.Field{
+				Type:    authorType,
+				Args:    graphql.FieldConfigArgument{"username": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: resolveAuthor,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: newMutation(tagType, articleType),
+	})
+}
+
+func listOf(t graphql.Type) graphql.Output {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(t)))
+}
+
+// page 分页查询的结果，与 REST 接口的 lists / total 一致
+func page(name string, t graphql.Type) graphql.Output {
+	return graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.Fields{
+			"lists": &graphql.Field{Type: listOf(t)},
+			"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	}))
+}
+
+func tagField(t graphql.Output, get func(tag *models.Tag) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*models.Tag)), nil
+	}}
+}
+
+func articleField(t graphql.Output, get func(article *models.Article) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(*models.Article)), nil
+	}}
+}
+
+// bind 将参数解析到 v 并按 binding 标签校验，与 REST 接口的参数校验规则一致
+func bind(ctx context.Context, args interface{}, v interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fail(ctx, e.INVALID_PARAMS, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return newError(getViewer(ctx), e.INVALID_PARAMS, nil)
+	}
+	if err := binding.Validator.ValidateStruct(v); err != nil {
+		return newError(getViewer(ctx), e.INVALID_PARAMS, app.TranslateErrors(err, getViewer(ctx).Lang))
+	}
+
+	return nil
+}
+
+// offset 页码对应的偏移量，与 util.GetPage 一致
+func offset(page int) int {
+	if page > 0 {
+		return (page - 1) * setting.AppSetting.PageSize
+	}
+
+	return 0
+}
+
+type limitArgs struct {
+	Limit int `json:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// loadArticles 等待 loader 批量查询后取前 limit 篇文章
+func loadArticles(p graphql.ResolveParams, load func() ([]*models.Article, error)) (interface{}, error) {
+	var args limitArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+	if args.Limit == 0 {
+		args.Limit = setting.AppSetting.PageSize
+	}
+
+	return func() (interface{}, error) {
+		articles, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if len(articles) > args.Limit {
+			articles = articles[:args.Limit]
+		}
+		return append([]*models.Article{}, articles...), nil
+	}, nil
+}
+
+// resolveArticleTag 列表与单篇文章查询时已带出标签，其余情况通过 loader 批量查询
+func resolveArticleTag(p graphql.ResolveParams) (interface{}, error) {
+	article := p.Source.(*models.Article)
+	if article.Tag.ID > 0 && article.Tag.ID == article.TagID {
+		return &article.Tag, nil
+	}
+
+	load := getLoaders(p.Context).tags.Load(article.TagID)
+	return func() (interface{}, error) {
+		tag, err := load()
+		if err != nil || tag == nil {
+			return nil, err
+		}
+		return tag, nil
+	}, nil
+}
+
+type tagsArgs struct {
+	Page  int    `json:"page" binding:"omitempty,min=1"`
+	Name  string `json:"name" binding:"max=100"`
+	State *int   `json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+func resolveTags(p graphql.ResolveParams) (interface{}, error) {
+	var args tagsArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+
+	state := -1
+	if args.State != nil {
+		state = *args.State
+	}
+
+	tagService := tag_service.Tag{
+		TenantID: getViewer(p.Context).TenantID,
+		Name:     args.Name,
+		State:    state,
+		PageNum:  offset(args.Page),
+		PageSize: setting.AppSetting.PageSize,
+	}
+	tags, err := tagService.GetAll(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_GET_TAGS_FAIL, err)
+	}
+	total, err := tagService.Count(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_COUNT_TAG_FAIL, err)
+	}
+
+	lists := make([]*models.Tag, len(tags))
+	for i := range tags {
+		lists[i] = &tags[i]
+	}
+
+	return map[string]interface{}{"lists": lists, "total": total}, nil
+}
+
+func resolveTag(p graphql.ResolveParams) (interface{}, error) {
+	tagService := tag_service.Tag{TenantID: getViewer(p.Context).TenantID, ID: p.Args["id"].(int)}
+	tag, err := tagService.Load(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_EXIST_TAG_FAIL, err)
+	}
+	if tag.ID == 0 {
+		return nil, nil
+	}
+
+	return tag, nil
+}
+
+type articlesArgs struct {
+	Page  int  `json:"page" binding:"omitempty,min=1"`
+	TagID *int `json:"tagId" binding:"omitempty,min=1"`
+	State *int `json:"state" binding:"omitempty,oneof=0 1"`
+}
+
+func resolveArticles(p graphql.ResolveParams) (interface{}, error) {
+	var args articlesArgs
+	if err := bind(p.Context, p.Args, &args); err != nil {
+		return nil, err
+	}
+
+	state := -1
+	if args.State != nil {
+		state = *args.State
+	}
+	tagID := -1
+	if args.TagID != nil {
+		tagID = *args.TagID
+	}
+
+	articleService := article_service.Article{
+		TenantID: getViewer(p.Context).TenantID,
+		TagID:    tagID,
+		State:    state,
+		PageNum:  offset(args.Page),
+		PageSize: setting.AppSetting.PageSize,
+	}
+	total, err := articleService.Count(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_COUNT_ARTICLE_FAIL, err)
+	}
+	articles, err := articleService.GetAll(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_GET_ARTICLES_FAIL, err)
+	}
+
+	return map[string]interface{}{"lists": append([]*models.Article{}, articles...), "total": total}, nil
+}
+
+func resolveArticle(p graphql.ResolveParams) (interface{}, error) {
+	articleService := article_service.Article{TenantID: getViewer(p.Context).TenantID, ID: p.Args["id"].(int)}
+	exists, err := articleService.ExistByID(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	article, err := articleService.Get(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_GET_ARTICLE_FAIL, err)
+	}
+
+	return article, nil
+}
+
+func resolveAuthors(p graphql.ResolveParams) (interface{}, error) {
+	articleService := article_service.Article{TenantID: getViewer(p.Context).TenantID}
+	usernames, err := articleService.GetAuthors(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_GET_ARTICLES_FAIL, err)
+	}
+
+	authors := make([]*author, len(usernames))
+	for i, username := range usernames {
+		authors[i] = &author{Username: username}
+	}
+
+	return authors, nil
+}
+
+func resolveAuthor(p graphql.ResolveParams) (interface{}, error) {
+	articleService := article_service.Article{TenantID: getViewer(p.Context).TenantID}
+	usernames, err := articleService.GetAuthors(p.Context)
+	if err != nil {
+		return nil, fail(p.Context, e.ERROR_GET_ARTICLES_FAIL, err)
+	}
+
+	for _, username := range usernames {
+		if username == p.Args["username"].(string) {
+			return &author{Username: username}, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,255 @@
+package oidc_service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/oidc"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service/auth_service"
+)
+
+const (
+	// STATE_COOKIE 登录开始时保存 state、nonce 与 code_verifier 的 Cookie，回调时取回校验
+	STATE_COOKIE = "oidc_state"
+	// STATE_TTL 从跳转到身份提供方到回调的最长时间
+	STATE_TTL = 10 * time.Minute
+)
+
+var (
+	ErrDisabled = errors.New("oidc login is not enabled")
+	// ErrProvider 无法获取发现文档或换取 token
+	ErrProvider = errors.New("identity provider request failed")
+	// ErrIDToken ID Token 校验失败或缺少必要的声明
+	ErrIDToken = errors.New("invalid id token")
+	// ErrNoRole RoleClaim 不包含 AdminValues 或 EditorValues 中的任何值
+	ErrNoRole = errors.New("no role for the blog")
+	// ErrNoUser 本地账号不存在且未开启 AutoCreate
+	ErrNoUser = errors.New("local user does not exist")
+	// ErrNotBound 同名的本地账号尚未绑定 sub，需先通过 user bind-oidc 绑定
+	ErrNotBound = errors.New("local user is not bound to a subject")
+	// ErrUserMismatch 本地账号已绑定身份提供方的其他用户
+	ErrUserMismatch = errors.New("local user is bound to another subject")
+)
+
+var (
+	mu       sync.Mutex
+	provider *oidc.Provider
+)
+
+// Enabled 是否配置了 [oidc] Issuer
+func Enabled() bool {
+	return setting.OIDCSetting.Issuer != ""
+}
+
+// getProvider 首次使用时按 [oidc] 创建客户端，发现文档与签名公钥由它缓存
+func getProvider() *oidc.Provider {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		provider = oidc.New(oidc.Config{
+			Issuer:       setting.OIDCSetting.Issuer,
+			ClientID:     setting.OIDCSetting.ClientID,
+			ClientSecret: setting.OIDCSetting.ClientSecret,
+			RedirectURL:  setting.OIDCSetting.RedirectURL,
+			Scopes:       setting.OIDCSetting.Scopes,
+		})
+	}
+
+	return provider
+}
+
+// Login 一次进行中的登录，编码后保存在 STATE_COOKIE 中
+type Login struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	TenantID  int    `json:"tenant_id"`
+	Redirect  string `json:"redirect"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// Begin 开始登录，返回登录状态与跳转到身份提供方的地址，redirect 为登录成功后跳回的站内路径
+func Begin(ctx context.Context, tenantID int, redirect string) (*Login, string, error) {
+	if !Enabled() {
+		return nil, "", ErrDisabled
+	}
+
+	login := &Login{TenantID: tenantID, Redirect: SafeRedirect(redirect), ExpiresAt: time.Now().Add(STATE_TTL).Unix()}
+	for _, s := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		v, err := oidc.RandomString()
+		if err != nil {
+			return nil, "", err
+		}
+		*s = v
+	}
+
+	authURL, err := getProvider().AuthCodeURL(ctx, login.State, login.Nonce, login.Verifier)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+
+	return login, authURL, nil
+}
+
+// SafeRedirect 只允许站内的绝对路径，避免登录后被跳转到其他站点，其余情况返回 /
+func SafeRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return "/"
+	}
+
+	return redirect
+}
+
+// Encode 编码为 Cookie 的值，以 JwtSecret 签名防止篡改
+func (l *Login) Encode() (string, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + sign(payload), nil
+}
+
+// Decode 解析 Encode 的结果，签名不符或已过期时返回 false
+func Decode(value string) (*Login, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 || !hmac.Equal([]byte(value[i+1:]), []byte(sign(value[:i]))) {
+		return nil, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil {
+		return nil, false
+	}
+
+	var login Login
+	if err := json.Unmarshal(data, &login); err != nil || time.Now().Unix() > login.ExpiresAt {
+		return nil, false
+	}
+
+	return &login, true
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(setting.AppSetting.JwtSecret))
+	mac.Write([]byte("oidc:" + payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Finish 用回调中的授权码换取并校验 ID Token，返回对应的本地账号，created 表示账号是此次新建的。
+// 按 sub 查找绑定的本地账号，没有时开启 AutoCreate 则以 UsernameClaim 为用户名新建并绑定；每次登录都按 RoleClaim 同步角色。
+// UsernameClaim 通常可以由用户自行修改，不会据此绑定已有账号，已有账号需先通过 user bind-oidc 绑定
+func (l *Login) Finish(ctx context.Context, code string) (auth *models.Auth, created bool, err error) {
+	if !Enabled() {
+		return nil, false, ErrDisabled
+	}
+
+	p := getProvider()
+	rawIDToken, err := p.Exchange(ctx, code, l.Verifier)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	claims, err := p.Verify(ctx, rawIDToken, l.Nonce)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrIDToken, err)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, false, fmt.Errorf("%w: missing sub claim", ErrIDToken)
+	}
+	role, ok := Role(claims[setting.OIDCSetting.RoleClaim])
+	if !ok {
+		return nil, false, ErrNoRole
+	}
+
+	authService := auth_service.Auth{TenantID: l.TenantID, Role: role, Subject: subject}
+	auth, err = authService.GetBySubject(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if auth.ID == 0 {
+		username, _ := claims[setting.OIDCSetting.UsernameClaim].(string)
+		if username == "" || len(username) > auth_service.MAX_LEN {
+			return nil, false, fmt.Errorf("%w: missing %s claim", ErrIDToken, setting.OIDCSetting.UsernameClaim)
+		}
+		authService.Username = username
+
+		existing, err := authService.Get(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing.ID > 0 && existing.Subject != "" {
+			return nil, false, ErrUserMismatch
+		}
+		if existing.ID > 0 {
+			return nil, false, ErrNotBound
+		}
+		if !setting.OIDCSetting.AutoCreate {
+			return nil, false, ErrNoUser
+		}
+		// 账号只能通过 OIDC 登录，密码随机生成且不会告知任何人
+		if authService.Password, err = oidc.RandomString(); err != nil {
+			return nil, false, err
+		}
+		auth, err = authService.Add(ctx)
+		return auth, err == nil, err
+	}
+
+	authService.Username = auth.Username
+	if auth.Role != role {
+		if err := authService.SetRole(ctx); err != nil {
+			return nil, false, err
+		}
+		auth.Role = role
+	}
+
+	return auth, false, nil
+}
+
+// Role 由 RoleClaim 的值确定本地角色，值可以是字符串或字符串数组
+func Role(claim interface{}) (string, bool) {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	if matchAny(values, setting.OIDCSetting.AdminValues) {
+		return models.ROLE_ADMIN, true
+	}
+	if len(setting.OIDCSetting.EditorValues) == 0 || matchAny(values, setting.OIDCSetting.EditorValues) {
+		return models.ROLE_EDITOR, true
+	}
+
+	return "", false
+}
+
+func matchAny(values, targets []string) bool {
+	for _, v := range values {
+		for _, t := range targets {
+			if v == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
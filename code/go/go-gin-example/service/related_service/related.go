@@ -0,0 +1,178 @@
+package related_service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/fzzv/go-gin-example/pkg/e"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/cache_service"
+)
+
+// Related 一篇相关文章及其相关度
+type Related struct {
+	ID    int     `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// 计算结果的缓存时长，文章变更时会提前失效
+const cacheTTL = 24 * 3600
+
+var (
+	mu      sync.Mutex
+	pending = make(map[int]bool) // 等待重新计算的站点
+	wake    = make(chan struct{}, 1)
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	// rebuilds 同一站点同时只进行一次计算，缓存失效后的并发请求共享同一次计算的结果
+	rebuilds singleflight.Group
+)
+
+// Setup 启动后台计算循环
+func Setup() {
+	stop = make(chan struct{})
+
+	wg.Add(1)
+	go loop()
+}
+
+// Stop 停止后台计算循环，等待正在进行的计算完成
+func Stop() {
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	wg.Wait()
+	stop = nil
+}
+
+// Invalidate 清除站点相关文章的缓存并安排在后台重新计算，在文章变更后调用，
+// 计算完成前的请求会同步计算，同一站点的并发请求只计算一次
+func Invalidate(ctx context.Context, tenantID int) {
+	if err := service.Cache.LikeDeletes(ctx, cache_service.Namespace(tenantID)+"_"+e.CACHE_RELATED); err != nil {
+		logging.Warn(err)
+	}
+
+	mu.Lock()
+	pending[tenantID] = true
+	mu.Unlock()
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Get 获取文章的相关文章，按相关度从高到低排列，未缓存时计算整个站点
+func Get(ctx context.Context, tenantID, id int) ([]Related, error) {
+	key := cacheKey(tenantID, id)
+	if service.Cache.Exists(ctx, key) {
+		data, err := service.Cache.Get(ctx, key)
+		if err != nil {
+			logging.Info(err)
+		} else {
+			var related []Related
+			if err := json.Unmarshal(data, &related); err == nil {
+				return related, nil
+			}
+		}
+	}
+
+	results, err := Rebuild(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if results[id] == nil {
+		return []Related{}, nil
+	}
+
+	return results[id], nil
+}
+
+// Rebuild 重新计算站点内所有未删除文章的相关文章并写入缓存，只推荐已发布的文章；
+// 站点正在计算时等待并返回该次计算的结果，返回的结果由调用方共享，不能修改
+func Rebuild(ctx context.Context, tenantID int) (map[int][]Related, error) {
+	results, err, _ := rebuilds.Do(strconv.Itoa(tenantID), func() (interface{}, error) {
+		// 计算结果由等待中的请求共享，不随发起计算的请求取消
+		return rebuild(context.WithoutCancel(ctx), tenantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results.(map[int][]Related), nil
+}
+
+func rebuild(ctx context.Context, tenantID int) (map[int][]Related, error) {
+	// pageNum、pageSize 为 -1 时不分页
+	articles, err := service.Articles.GetAll(ctx, -1, -1, map[string]interface{}{"tenant_id": tenantID, "deleted_on": 0})
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]document, 0, len(articles))
+	for _, article := range articles {
+		docs = append(docs, newDocument(article))
+	}
+	results := rank(docs, setting.RelatedSetting.Size, setting.RelatedSetting.TagWeight)
+
+	for id, related := range results {
+		if err := service.Cache.Set(ctx, cacheKey(tenantID, id), related, cacheTTL); err != nil {
+			logging.Warn(err)
+		}
+	}
+
+	return results, nil
+}
+
+func cacheKey(tenantID, id int) string {
+	return cache_service.Namespace(tenantID) + "_" + e.CACHE_RELATED + "_" + strconv.Itoa(id)
+}
+
+// loop 收到变更后等待 Debounce 再计算，合并短时间内的多次变更
+func loop() {
+	defer wg.Done()
+
+	ctx := context.Background()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-wake:
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(setting.RelatedSetting.Debounce):
+		}
+
+		for _, tenantID := range takePending() {
+			if _, err := Rebuild(ctx, tenantID); err != nil {
+				logging.Error("related", tenantID, err)
+			}
+		}
+	}
+}
+
+func takePending() []int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	tenants := make([]int, 0, len(pending))
+	for tenantID := range pending {
+		tenants = append(tenants, tenantID)
+	}
+	pending = make(map[int]bool)
+
+	return tenants
+}
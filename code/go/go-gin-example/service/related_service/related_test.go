@@ -0,0 +1,90 @@
+package related_service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/models/memory"
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service"
+)
+
+// slowArticles 记录读取全部文章的次数，读取在 release 关闭前阻塞，模拟耗时的计算
+type slowArticles struct {
+	models.ArticleRepository
+
+	calls   int32
+	release chan struct{}
+}
+
+func (r *slowArticles) GetAll(ctx context.Context, pageNum, pageSize int, maps map[string]interface{}) ([]*models.Article, error) {
+	atomic.AddInt32(&r.calls, 1)
+	<-r.release
+
+	return r.ArticleRepository.GetAll(ctx, pageNum, pageSize, maps)
+}
+
+// TestGetAfterInvalidate 缓存失效后同一站点的并发请求只计算一次
+func TestGetAfterInvalidate(t *testing.T) {
+	prev := *setting.RelatedSetting
+	defer func() { *setting.RelatedSetting = prev }()
+	setting.RelatedSetting.Size = 5
+	setting.RelatedSetting.TagWeight = 0.5
+
+	store := memory.New()
+	articles := &slowArticles{ArticleRepository: store.Articles(), release: make(chan struct{})}
+	defer service.Use(service.Repositories{Articles: articles, Cache: gredis.NewMemoryCache()})()
+
+	var ids []int
+	for _, title := range []string{"gin middleware", "gin router", "gorm callbacks"} {
+		article, err := store.Articles().Add(context.Background(), map[string]interface{}{
+			"tenant_id": 1, "tag_id": 1, "title": title, "slug": title, "desc": title, "content": title,
+			"created_by": "test", "state": 1, "cover_image_url": "",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, article.ID)
+	}
+
+	Invalidate(context.Background(), 1)
+
+	var wg sync.WaitGroup
+	results := make([][]Related, 10)
+	errs := make([]error, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = Get(context.Background(), 1, ids[0])
+		}(i)
+	}
+
+	// 等待所有请求都在等待同一次计算
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&articles.calls) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(articles.release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&articles.calls); calls != 1 {
+		t.Fatalf("rebuilt %d times, want 1", calls)
+	}
+	for i := range results {
+		if errs[i] != nil || len(results[i]) == 0 || results[i][0].ID != ids[1] {
+			t.Fatalf("request %d: related = %+v, %v", i, results[i], errs[i])
+		}
+	}
+
+	// 计算结果已写入缓存
+	if related, err := Get(context.Background(), 1, ids[1]); err != nil || len(related) == 0 || atomic.LoadInt32(&articles.calls) != 1 {
+		t.Fatalf("cached related = %+v, %v, %d rebuilds", related, err, articles.calls)
+	}
+}
@@ -0,0 +1,165 @@
+package related_service
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fzzv/go-gin-example/models"
+)
+
+// 标题与描述比正文更能代表文章的主题，其中的词按多次出现计算
+const (
+	titleWeight = 3
+	descWeight  = 2
+)
+
+// stopWords 几乎每篇文章都会出现、不能说明主题的词
+var stopWords = map[string]bool{
+	"an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true, "for": true,
+	"from": true, "has": true, "have": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "with": true,
+	"的": true, "了": true, "是": true, "在": true, "和": true, "与": true, "也": true, "就": true, "都": true,
+}
+
+// document 一篇文章的词频，计算完 TF-IDF 后为归一化的词向量
+type document struct {
+	id        int
+	tagID     int
+	published bool
+	terms     map[string]float64
+}
+
+func newDocument(article *models.Article) document {
+	terms := make(map[string]float64)
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{
+		{article.Title, titleWeight},
+		{article.Desc, descWeight},
+		{article.Content, 1},
+	} {
+		for _, term := range tokenize(field.text) {
+			terms[term] += field.weight
+		}
+	}
+
+	return document{id: article.ID, tagID: article.TagID, published: article.State == 1, terms: terms}
+}
+
+// tokenize 将文本切分为小写的词，汉字按单字切分，其他文字按连续的字母与数字切分，忽略单个字母或数字与 stopWords
+func tokenize(text string) []string {
+	var (
+		terms []string
+		word  strings.Builder
+	)
+	add := func(term string) {
+		if !stopWords[term] {
+			terms = append(terms, term)
+		}
+	}
+	flush := func() {
+		if len([]rune(word.String())) > 1 {
+			add(word.String())
+		}
+		word.Reset()
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			add(string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return terms
+}
+
+// vectorize 将 docs 的词频换算为 TF-IDF 并归一化，词频取对数以免长文中的高频词占比过大
+func vectorize(docs []document) {
+	df := make(map[string]int)
+	for _, doc := range docs {
+		for term := range doc.terms {
+			df[term]++
+		}
+	}
+
+	n := float64(len(docs))
+	for _, doc := range docs {
+		var norm float64
+		for term, tf := range doc.terms {
+			w := (1 + math.Log(tf)) * (math.Log((1+n)/(1+float64(df[term]))) + 1)
+			doc.terms[term] = w
+			norm += w * w
+		}
+		norm = math.Sqrt(norm)
+		for term := range doc.terms {
+			doc.terms[term] /= norm
+		}
+	}
+}
+
+// rank 计算每篇文章与其他已发布文章的相关度，相关度为词向量的余弦相似度，同一标签的再加上 tagWeight，
+// 每篇文章保留相关度最高的 size 篇
+func rank(docs []document, size int, tagWeight float64) map[int][]Related {
+	vectorize(docs)
+
+	type posting struct {
+		doc    int
+		weight float64
+	}
+	postings := make(map[string][]posting)
+	byTag := make(map[int][]int)
+	for i, doc := range docs {
+		if !doc.published {
+			continue
+		}
+		for term, w := range doc.terms {
+			postings[term] = append(postings[term], posting{i, w})
+		}
+		byTag[doc.tagID] = append(byTag[doc.tagID], i)
+	}
+
+	results := make(map[int][]Related, len(docs))
+	for i, doc := range docs {
+		scores := make(map[int]float64)
+		for term, w := range doc.terms {
+			for _, p := range postings[term] {
+				scores[p.doc] += w * p.weight
+			}
+		}
+		if tagWeight > 0 {
+			for _, j := range byTag[doc.tagID] {
+				scores[j] += tagWeight
+			}
+		}
+		delete(scores, i)
+
+		related := make([]Related, 0, len(scores))
+		for j, score := range scores {
+			if score > 0 {
+				related = append(related, Related{ID: docs[j].id, Score: math.Round(score*1e4) / 1e4})
+			}
+		}
+		sort.Slice(related, func(a, b int) bool {
+			if related[a].Score != related[b].Score {
+				return related[a].Score > related[b].Score
+			}
+			return related[a].ID > related[b].ID
+		})
+		if len(related) > size {
+			related = related[:size]
+		}
+		results[doc.id] = related
+	}
+
+	return results
+}
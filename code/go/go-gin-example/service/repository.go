@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/gredis"
+)
+
+// 各 service 通过这里的实现读写标签、文章、系列、缓存与计数，默认使用数据库与 Redis，测试时可通过 Use 替换为内存实现。
+// 这些变量在启动后视为只读，业务代码不应修改
+var (
+	Tags     models.TagRepository     = models.NewTagRepository()
+	Articles models.ArticleRepository = models.NewArticleRepository()
+	Series   models.SeriesRepository  = models.NewSeriesRepository()
+	Cache    gredis.Cache             = gredis.NewCache()
+	Counter  gredis.Counter           = gredis.NewCounter()
+)
+
+// Repositories 需要替换的实现，为 nil 的字段保持不变
+type Repositories struct {
+	Tags     models.TagRepository
+	Articles models.ArticleRepository
+	Series   models.SeriesRepository
+	Cache    gredis.Cache
+	Counter  gredis.Counter
+}
+
+// Use 替换各 service 使用的实现，返回的函数用于恢复替换前的实现。
+//
+// 只用于测试：替换的是包级变量，读取时不加锁，因此不能与处理中的请求或后台任务（定时任务、webhook 投递等）并发调用，
+// 也不能在 t.Parallel 的用例中使用。应在开始发出请求前调用，在所有请求结束、后台任务停止后再恢复
+func Use(r Repositories) (restore func()) {
+	tags, articles, series, cache, counter := Tags, Articles, Series, Cache, Counter
+	if r.Tags != nil {
+		Tags = r.Tags
+	}
+	if r.Articles != nil {
+		Articles = r.Articles
+	}
+	if r.Series != nil {
+		Series = r.Series
+	}
+	if r.Cache != nil {
+		Cache = r.Cache
+	}
+	if r.Counter != nil {
+		Counter = r.Counter
+	}
+
+	return func() {
+		Tags, Articles, Series, Cache, Counter = tags, articles, series, cache, counter
+	}
+}
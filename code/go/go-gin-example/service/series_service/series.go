@@ -0,0 +1,207 @@
+package series_service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/service"
+)
+
+type Series struct {
+	TenantID   int
+	ID         int
+	Title      string
+	Desc       string
+	CreatedBy  string
+	ModifiedBy string
+
+	PageNum  int
+	PageSize int
+}
+
+// Get 获取未删除的系列，不存在时返回 ID 为 0 的系列
+func (s *Series) Get(ctx context.Context) (*models.Series, error) {
+	return service.Series.Get(ctx, s.TenantID, s.ID)
+}
+
+func (s *Series) GetAll(ctx context.Context) ([]models.Series, error) {
+	return service.Series.GetAll(ctx, s.TenantID, s.PageNum, s.PageSize)
+}
+
+func (s *Series) Count(ctx context.Context) (int, error) {
+	return service.Series.Count(ctx, s.TenantID)
+}
+
+func (s *Series) Add(ctx context.Context) (*models.Series, error) {
+	series := models.Series{
+		TenantID:  s.TenantID,
+		Title:     s.Title,
+		Desc:      s.Desc,
+		CreatedBy: s.CreatedBy,
+	}
+	if err := service.Series.Add(ctx, &series); err != nil {
+		return nil, err
+	}
+
+	return &series, nil
+}
+
+func (s *Series) Edit(ctx context.Context) error {
+	return service.Series.Edit(ctx, s.TenantID, s.ID, map[string]interface{}{
+		"title":       s.Title,
+		"desc":        s.Desc,
+		"modified_by": s.ModifiedBy,
+	})
+}
+
+func (s *Series) Delete(ctx context.Context) error {
+	return service.Series.Delete(ctx, s.TenantID, s.ID)
+}
+
+// GetArticleIDs 按顺序获取系列中的文章 ID，包括回收站中的文章
+func (s *Series) GetArticleIDs(ctx context.Context) ([]int, error) {
+	return service.Series.GetArticleIDs(ctx, s.TenantID, s.ID)
+}
+
+// GetArticles 按顺序获取系列中未删除的文章
+func (s *Series) GetArticles(ctx context.Context) ([]*models.Article, error) {
+	ids, err := s.GetArticleIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return articles(ctx, s.TenantID, ids)
+}
+
+// CheckArticles 校验要放入系列的文章，文章重复、不存在或在回收站中时返回 error
+func (s *Series) CheckArticles(ctx context.Context, articleIDs []int) error {
+	seen := make(map[int]bool, len(articleIDs))
+	for _, id := range articleIDs {
+		if seen[id] {
+			return fmt.Errorf("article %d is listed more than once", id)
+		}
+		seen[id] = true
+	}
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	found, err := service.Articles.GetByIDs(ctx, s.TenantID, articleIDs)
+	if err != nil {
+		return err
+	}
+	for _, article := range found {
+		delete(seen, article.ID)
+	}
+	for _, id := range articleIDs {
+		if seen[id] {
+			return fmt.Errorf("article %d does not exist", id)
+		}
+	}
+
+	return nil
+}
+
+// SetArticles 按 articleIDs 的顺序设置系列中的文章，不在其中的文章移出系列，
+// 任一文章已属于其他系列时返回 models.ErrArticleInSeries
+func (s *Series) SetArticles(ctx context.Context, articleIDs []int) error {
+	return service.Series.SetArticles(ctx, s.TenantID, s.ID, articleIDs)
+}
+
+// AddArticle 将文章加到系列末尾，已在该系列中时不做修改
+func (s *Series) AddArticle(ctx context.Context, articleID int) error {
+	ids, err := s.GetArticleIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id == articleID {
+			return nil
+		}
+	}
+
+	return service.Series.SetArticles(ctx, s.TenantID, s.ID, append(ids, articleID))
+}
+
+// RemoveArticle 将文章移出系列，返回文章此前是否在该系列中
+func (s *Series) RemoveArticle(ctx context.Context, articleID int) (bool, error) {
+	ids, err := s.GetArticleIDs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	rest := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id != articleID {
+			rest = append(rest, id)
+		}
+	}
+	if len(rest) == len(ids) {
+		return false, nil
+	}
+
+	return true, service.Series.SetArticles(ctx, s.TenantID, s.ID, rest)
+}
+
+// Nav 获取文章所在系列及其前后篇，文章不在任何系列中时返回 nil
+func Nav(ctx context.Context, tenantID, articleID int) (*models.SeriesNav, error) {
+	series, err := service.Series.GetByArticle(ctx, tenantID, articleID)
+	if err != nil || series.ID == 0 {
+		return nil, err
+	}
+
+	ids, err := service.Series.GetArticleIDs(ctx, tenantID, series.ID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := articles(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	nav := &models.SeriesNav{ID: series.ID, Title: series.Title, Total: len(list)}
+	for i, article := range list {
+		if article.ID != articleID {
+			continue
+		}
+		nav.Position = i + 1
+		if i > 0 {
+			nav.Prev = item(list[i-1])
+		}
+		if i < len(list)-1 {
+			nav.Next = item(list[i+1])
+		}
+	}
+
+	return nav, nil
+}
+
+// articles 按 ids 的顺序获取未删除的文章
+func articles(ctx context.Context, tenantID int, ids []int) ([]*models.Article, error) {
+	if len(ids) == 0 {
+		return []*models.Article{}, nil
+	}
+
+	found, err := service.Articles.GetByIDs(ctx, tenantID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*models.Article, len(found))
+	for _, article := range found {
+		byID[article.ID] = article
+	}
+
+	list := make([]*models.Article, 0, len(found))
+	for _, id := range ids {
+		if article, ok := byID[id]; ok {
+			list = append(list, article)
+		}
+	}
+
+	return list, nil
+}
+
+func item(article *models.Article) *models.SeriesItem {
+	return &models.SeriesItem{ID: article.ID, Title: article.Title, Slug: article.Slug}
+}
@@ -0,0 +1,442 @@
+package site_service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/feed_service"
+	"github.com/fzzv/go-gin-example/service/sitemap_service"
+	"github.com/fzzv/go-gin-example/service/tenant_service"
+)
+
+// MARKER 导出目录中的标记文件，只有空目录或带有该文件的目录才会被覆盖，避免误删其他文件
+const MARKER = ".export-site"
+
+//go:embed templates/*.html
+var builtin embed.FS
+
+// 每个页面模板与 layout.html 组合渲染，页面模板通过 define 覆盖 layout 中的 title、content 等 block
+var pageTemplates = []string{"index.html", "tag.html", "article.html"}
+
+// Site 所有页面共用的站点信息
+type Site struct {
+	Title       string
+	Description string
+	Link        string
+	Tags        []models.Tag // 已启用的标签，用于导航
+}
+
+// ListPage 首页与标签页的一页
+type ListPage struct {
+	Site     *Site
+	Tag      *models.Tag // 首页时为 nil
+	Articles []*models.Article
+	Page     int
+	Pages    int
+	Prev     string // 上一页的地址，第一页时为空
+	Next     string // 下一页的地址，最后一页时为空
+}
+
+// ArticlePage 文章页，Content 为文章内容，与订阅源一样按 HTML 输出
+type ArticlePage struct {
+	Site    *Site
+	Article *models.Article
+	Content template.HTML
+}
+
+// Result 导出的统计，Missing 为文章引用但在 ImageSavePath 中找不到的图片
+type Result struct {
+	Pages    int
+	Articles int
+	Tags     int
+	Images   int
+	Missing  []string
+}
+
+type exporter struct {
+	tenant    *models.Tenant
+	dir       string // 渲染时写入的临时目录
+	site      *Site
+	templates map[string]*template.Template
+	images    map[string]bool // 引用的图片，相对于 ImageSavePath
+	result    Result
+}
+
+// Export 将站点已发布的文章与已启用的标签渲染为静态页面，连同订阅源、站点地图与引用的图片写入 dir，
+// templateDir 中的模板覆盖同名的内置模板，为空时只使用内置模板
+//
+// 先写入 dir 旁的临时目录，全部成功后再替换 dir，dir 已存在时必须为空或是之前导出的目录
+func Export(ctx context.Context, tenant *models.Tenant, dir, templateDir string) (*Result, error) {
+	dir = filepath.Clean(dir)
+	if err := checkOutput(dir); err != nil {
+		return nil, err
+	}
+
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+"-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	e := exporter{tenant: tenant, dir: tmp, images: make(map[string]bool)}
+	if e.templates, err = e.loadTemplates(templateDir); err != nil {
+		return nil, err
+	}
+	if err := e.export(ctx); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, err
+	}
+
+	return &e.result, nil
+}
+
+// checkOutput 确认 dir 不存在、为空或是之前导出的目录
+func checkOutput(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, MARKER)); err != nil {
+		return fmt.Errorf("%s is not empty and was not created by export-site", dir)
+	}
+
+	return nil
+}
+
+func (e *exporter) loadTemplates(dir string) (map[string]*template.Template, error) {
+	funcs := template.FuncMap{
+		"articleURL": articleURL,
+		"tagURL":     tagURL,
+		"image":      e.image,
+		"date": func(unix int) string {
+			return time.Unix(int64(unix), 0).UTC().Format("2006-01-02")
+		},
+	}
+
+	text, err := readTemplate(dir, "layout.html")
+	if err != nil {
+		return nil, err
+	}
+	layout, err := template.New("layout.html").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*template.Template, len(pageTemplates))
+	for _, name := range pageTemplates {
+		text, err := readTemplate(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		t, err := layout.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if templates[name], err = t.Parse(text); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return templates, nil
+}
+
+// readTemplate 优先读取 dir 中的模板，不存在时使用内置模板
+func readTemplate(dir, name string) (string, error) {
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	data, err := builtin.ReadFile("templates/" + name)
+	return string(data), err
+}
+
+func (e *exporter) export(ctx context.Context) error {
+	tags, err := service.Tags.GetAll(ctx, -1, -1, map[string]interface{}{"tenant_id": e.tenant.ID, "state": 1, "deleted_on": 0})
+	if err != nil {
+		return err
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	// 已发布的文章按发布时间从新到旧排列，首页与标签页都按此顺序分页
+	articles, err := service.Articles.GetPublished(ctx, e.tenant.ID, -1, -1, 0)
+	if err != nil {
+		return err
+	}
+
+	e.site = &Site{
+		Title:       setting.FeedSetting.Title,
+		Description: setting.FeedSetting.Description,
+		Link:        tenant_service.Link(e.tenant),
+		Tags:        tags,
+	}
+	if e.tenant.Title != "" {
+		e.site.Title = e.tenant.Title
+	}
+	if e.tenant.Description != "" {
+		e.site.Description = e.tenant.Description
+	}
+
+	if err := e.list("", nil, articles); err != nil {
+		return err
+	}
+	for i := range tags {
+		tag := &tags[i]
+		var tagged []*models.Article
+		for _, article := range articles {
+			if article.TagID == tag.ID {
+				tagged = append(tagged, article)
+			}
+		}
+		if err := e.list(strings.TrimPrefix(tagURL(tag.ID), "/"), tag, tagged); err != nil {
+			return err
+		}
+		e.result.Tags++
+	}
+
+	for _, article := range articles {
+		page := ArticlePage{Site: e.site, Article: article, Content: template.HTML(e.rewriteImages(article.Content))}
+		// 文件路径使用未转义的 slug，由静态文件服务器按解码后的路径查找
+		if err := e.render("article.html", "articles/"+article.Slug, page); err != nil {
+			return err
+		}
+		e.result.Articles++
+	}
+
+	if err := e.feeds(ctx, tags); err != nil {
+		return err
+	}
+	sitemap, err := sitemap_service.Get(ctx, e.tenant)
+	if err != nil {
+		return err
+	}
+	if err := e.write("sitemap.xml", sitemap); err != nil {
+		return err
+	}
+
+	if err := e.copyImages(); err != nil {
+		return err
+	}
+
+	return e.write(MARKER, nil)
+}
+
+// list 按 [site] PageSize 分页渲染首页或标签页，第一页写入 base，第 n 页写入 base/page/n
+func (e *exporter) list(base string, tag *models.Tag, articles []*models.Article) error {
+	name := "index.html"
+	if tag != nil {
+		name = "tag.html"
+	}
+
+	size := setting.SiteSetting.PageSize
+	pages := (len(articles) + size - 1) / size
+	if pages == 0 {
+		pages = 1
+	}
+	for n := 1; n <= pages; n++ {
+		page := ListPage{Site: e.site, Tag: tag, Page: n, Pages: pages}
+		page.Articles = articles[min((n-1)*size, len(articles)):min(n*size, len(articles))]
+		if n > 1 {
+			page.Prev = pageURL(base, n-1)
+		}
+		if n < pages {
+			page.Next = pageURL(base, n+1)
+		}
+		if err := e.render(name, strings.TrimPrefix(pageURL(base, n), "/"), page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// feeds 生成站点与每个标签的 RSS、Atom、JSON Feed 订阅源，路径与服务端的 /feed.rss、/tags/:id/feed.rss 等相同
+func (e *exporter) feeds(ctx context.Context, tags []models.Tag) error {
+	tagIDs := []int{0}
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	for _, tagID := range tagIDs {
+		for _, format := range []string{feed_service.FORMAT_RSS, feed_service.FORMAT_ATOM, feed_service.FORMAT_JSON} {
+			f := feed_service.Feed{Tenant: e.tenant, TagID: tagID, Format: format}
+			output, err := f.Render(ctx)
+			if err != nil {
+				return err
+			}
+			name := "feed." + format
+			if tagID > 0 {
+				name = fmt.Sprintf("tags/%d/%s", tagID, name)
+			}
+			if err := e.write(name, output.Body); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// render 渲染页面写入 dir/index.html，dir 为相对于输出目录的路径
+func (e *exporter) render(name, dir string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := e.templates[name].ExecuteTemplate(&buf, "layout.html", data); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	e.result.Pages++
+
+	return e.write(path.Join(dir, "index.html"), buf.Bytes())
+}
+
+// write 写入相对于输出目录的文件
+func (e *exporter) write(name string, data []byte) error {
+	name = filepath.Join(e.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, data, 0644)
+}
+
+// image 模板中使用的图片地址，本站上传的图片记录下来稍后复制，并改为站点根目录下的地址
+func (e *exporter) image(src string) string {
+	if name, ok := localImage(src); ok {
+		e.images[name] = true
+		return "/" + setting.AppSetting.ImageSavePath + name
+	}
+
+	return src
+}
+
+// imagePattern 匹配内容中引号、括号、等号或空白之后以 PrefixUrl/、/ 或直接以 ImageSavePath 开头的图片地址
+func imagePattern() *regexp.Regexp {
+	return regexp.MustCompile(`(^|["'(=\s])((?:` + regexp.QuoteMeta(setting.AppSetting.PrefixUrl+"/") + `|/)?` +
+		regexp.QuoteMeta(setting.AppSetting.ImageSavePath) + `[\w\-./]+)`)
+}
+
+// rewriteImages 将内容中本站上传的图片改为站点根目录下的地址
+func (e *exporter) rewriteImages(content string) string {
+	pattern := imagePattern()
+	return pattern.ReplaceAllStringFunc(content, func(match string) string {
+		m := pattern.FindStringSubmatch(match)
+		return m[1] + e.image(m[2])
+	})
+}
+
+// localImage 判断 src 是否为本站上传的图片，返回相对于 ImageSavePath 的路径
+func localImage(src string) (string, bool) {
+	src = strings.TrimPrefix(src, setting.AppSetting.PrefixUrl+"/")
+	src = strings.TrimPrefix(src, "/")
+	name, ok := strings.CutPrefix(src, setting.AppSetting.ImageSavePath)
+	if !ok || name == "" || path.Clean(name) != name || strings.HasPrefix(name, "../") || name == ".." {
+		return "", false
+	}
+
+	return name, true
+}
+
+// copyImages 将引用的图片从 RuntimeRootPath/ImageSavePath 复制到输出目录的 ImageSavePath 下
+func (e *exporter) copyImages() error {
+	names := make([]string, 0, len(e.images))
+	for name := range e.images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	src := setting.AppSetting.RuntimeRootPath + setting.AppSetting.ImageSavePath
+	for _, name := range names {
+		err := e.copy(filepath.Join(src, filepath.FromSlash(name)), path.Join(setting.AppSetting.ImageSavePath, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			e.result.Missing = append(e.result.Missing, name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		e.result.Images++
+	}
+
+	return nil
+}
+
+func (e *exporter) copy(src, name string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	name = filepath.Join(e.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+func articleURL(article *models.Article) string {
+	return "/articles/" + url.PathEscape(article.Slug) + "/"
+}
+
+func tagURL(id int) string {
+	return "/tags/" + strconv.Itoa(id) + "/"
+}
+
+// pageURL 列表第 n 页的地址，base 为第一页相对于站点根目录的路径
+func pageURL(base string, n int) string {
+	if n == 1 {
+		return "/" + base
+	}
+
+	return "/" + base + "page/" + strconv.Itoa(n) + "/"
+}
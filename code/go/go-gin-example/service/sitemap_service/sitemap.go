@@ -0,0 +1,56 @@
+package sitemap_service
+
+import (
+	"context"
+	"encoding/xml"
+	"net/url"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/tenant_service"
+)
+
+// MAX_URLS 单个站点地图文件最多包含的地址数
+const MAX_URLS = 50000
+
+const CONTENT_TYPE = "application/xml; charset=utf-8"
+
+type urlSet struct {
+	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []urlEntry
+}
+
+type urlEntry struct {
+	XMLName xml.Name `xml:"url"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+// Get 生成包含站点首页与所有已发布文章的站点地图
+func Get(ctx context.Context, tenant *models.Tenant) ([]byte, error) {
+	articles, err := service.Articles.GetPublishedIndex(ctx, tenant.ID, MAX_URLS-1)
+	if err != nil {
+		return nil, err
+	}
+
+	link := tenant_service.Link(tenant)
+	set := urlSet{URLs: []urlEntry{{Loc: link + "/"}}}
+	for _, article := range articles {
+		updated := article.CreatedOn
+		if article.ModifiedOn > updated {
+			updated = article.ModifiedOn
+		}
+		set.URLs = append(set.URLs, urlEntry{
+			Loc:     link + "/articles/" + url.PathEscape(article.Slug),
+			LastMod: time.Unix(int64(updated), 0).UTC().Format(time.RFC3339),
+		})
+	}
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
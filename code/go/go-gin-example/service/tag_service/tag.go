@@ -1,44 +1,66 @@
 package tag_service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"time"
 
 	"github.com/fzzv/go-gin-example/models"
-	"github.com/fzzv/go-gin-example/pkg/export"
-	"github.com/fzzv/go-gin-example/pkg/gredis"
+	"github.com/fzzv/go-gin-example/service"
+
 	"github.com/fzzv/go-gin-example/pkg/logging"
 	"github.com/fzzv/go-gin-example/service/cache_service"
+	"github.com/fzzv/go-gin-example/service/feed_service"
+	"github.com/fzzv/go-gin-example/service/related_service"
+	"github.com/fzzv/go-gin-example/service/webhook_service"
 	"github.com/xuri/excelize/v2"
 )
 
 type Tag struct {
+	TenantID   int
 	ID         int
 	Name       string
 	CreatedBy  string
 	ModifiedBy string
 	State      int
+	Version    int  // 修改时期望的当前版本，为 0 时不检查
+	Cascade    bool // 删除或恢复时是否同时处理该标签下的文章
 
 	PageNum  int
 	PageSize int
 }
 
-func (t *Tag) ExistByName() (bool, error) {
-	return models.ExistTagByName(t.Name)
+func (t *Tag) ExistByName(ctx context.Context) (bool, error) {
+	return service.Tags.ExistByName(ctx, t.TenantID, t.Name)
+}
+
+func (t *Tag) ExistByID(ctx context.Context) (bool, error) {
+	return service.Tags.ExistByID(ctx, t.TenantID, t.ID)
+}
+
+func (t *Tag) Add(ctx context.Context) (*models.Tag, error) {
+	tag, err := service.Tags.Add(ctx, t.TenantID, t.Name, t.State, t.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	webhook_service.Fire(ctx, t.TenantID, webhook_service.EVENT_TAG_CREATED, tag)
+
+	return tag, nil
 }
 
-func (t *Tag) ExistByID() (bool, error) {
-	return models.ExistTagByID(t.ID)
+// Load 直接从数据库读取标签，不存在时返回 ID 为 0 的标签
+func (t *Tag) Load(ctx context.Context) (*models.Tag, error) {
+	return service.Tags.Get(ctx, t.TenantID, t.ID)
 }
 
-func (t *Tag) Add() error {
-	return models.AddTag(t.Name, t.State, t.CreatedBy)
+// GetByIDs 批量获取站点内的标签，已删除的标签也会返回
+func (t *Tag) GetByIDs(ctx context.Context, ids []int) ([]models.Tag, error) {
+	return service.Tags.GetByIDs(ctx, t.TenantID, ids)
 }
 
-func (t *Tag) Edit() error {
+func (t *Tag) Edit(ctx context.Context) error {
 	data := make(map[string]interface{})
 	data["modified_by"] = t.ModifiedBy
 	data["name"] = t.Name
@@ -46,31 +68,80 @@ func (t *Tag) Edit() error {
 		data["state"] = t.State
 	}
 
-	return models.EditTag(t.ID, data)
+	if err := service.Tags.Edit(ctx, t.TenantID, t.ID, t.Version, data); err != nil {
+		return err
+	}
+	feed_service.Invalidate(ctx, t.TenantID)
+
+	tag, err := service.Tags.Get(ctx, t.TenantID, t.ID)
+	if err != nil {
+		logging.Error(err)
+		return nil
+	}
+	webhook_service.Fire(ctx, t.TenantID, webhook_service.EVENT_TAG_UPDATED, tag)
+
+	return nil
+}
+
+func (t *Tag) Delete(ctx context.Context) error {
+	if err := service.Tags.Delete(ctx, t.TenantID, t.ID, t.Cascade); err != nil {
+		return err
+	}
+	feed_service.Invalidate(ctx, t.TenantID)
+	related_service.Invalidate(ctx, t.TenantID)
+	webhook_service.Fire(ctx, t.TenantID, webhook_service.EVENT_TAG_DELETED, map[string]interface{}{"id": t.ID, "cascade": t.Cascade})
+
+	return nil
 }
 
-func (t *Tag) Delete() error {
-	return models.DeleteTag(t.ID)
+// CountArticles 统计站点内标签下未删除的文章数
+func (t *Tag) CountArticles(ctx context.Context) (int, error) {
+	return service.Tags.CountArticles(ctx, t.TenantID, t.ID)
 }
 
-func (t *Tag) Count() (int, error) {
-	return models.GetTagTotal(t.getMaps())
+// GetDeleted 获取回收站中的标签，不存在时返回 ID 为 0 的标签
+func (t *Tag) GetDeleted(ctx context.Context) (*models.Tag, error) {
+	return service.Tags.GetDeleted(ctx, t.TenantID, t.ID)
 }
 
-func (t *Tag) GetAll() ([]models.Tag, error) {
+func (t *Tag) Restore(ctx context.Context, tag *models.Tag) error {
+	if err := service.Tags.Restore(ctx, tag, t.Cascade); err != nil {
+		return err
+	}
+	feed_service.Invalidate(ctx, t.TenantID)
+	related_service.Invalidate(ctx, t.TenantID)
+
+	return nil
+}
+
+func (t *Tag) GetTrash(ctx context.Context) ([]models.Tag, error) {
+	return service.Tags.GetDeletedAll(ctx, t.TenantID, t.PageNum, t.PageSize)
+}
+
+func (t *Tag) CountTrash(ctx context.Context) (int, error) {
+	return service.Tags.CountDeleted(ctx, t.TenantID)
+}
+
+func (t *Tag) Count(ctx context.Context) (int, error) {
+	return service.Tags.Count(ctx, t.getMaps())
+}
+
+func (t *Tag) GetAll(ctx context.Context) ([]models.Tag, error) {
 	var (
 		tags, cacheTags []models.Tag
 	)
 
 	cache := cache_service.Tag{
-		State: t.State,
+		TenantID: t.TenantID,
+		Name:     t.Name,
+		State:    t.State,
 
 		PageNum:  t.PageNum,
 		PageSize: t.PageSize,
 	}
 	key := cache.GetTagsKey()
-	if gredis.Exists(key) {
-		data, err := gredis.Get(key)
+	if service.Cache.Exists(ctx, key) {
+		data, err := service.Cache.Get(ctx, key)
 		if err != nil {
 			logging.Info(err)
 		} else {
@@ -79,17 +150,27 @@ func (t *Tag) GetAll() ([]models.Tag, error) {
 		}
 	}
 
-	tags, err := models.GetTags(t.PageNum, t.PageSize, t.getMaps())
+	tags, err := service.Tags.GetAll(ctx, t.PageNum, t.PageSize, t.getMaps())
 	if err != nil {
 		return nil, err
 	}
 
-	gredis.Set(key, tags, 3600)
+	service.Cache.Set(ctx, key, tags, 3600)
 	return tags, nil
 }
 
+// GetEnabled 获取已启用的标签，不经过缓存
+func (t *Tag) GetEnabled(ctx context.Context) ([]models.Tag, error) {
+	return service.Tags.GetAll(ctx, t.PageNum, t.PageSize, map[string]interface{}{"tenant_id": t.TenantID, "state": 1, "deleted_on": 0})
+}
+
+func (t *Tag) CountEnabled(ctx context.Context) (int, error) {
+	return service.Tags.Count(ctx, map[string]interface{}{"tenant_id": t.TenantID, "state": 1, "deleted_on": 0})
+}
+
 func (t *Tag) getMaps() map[string]interface{} {
 	maps := make(map[string]interface{})
+	maps["tenant_id"] = t.TenantID
 	maps["deleted_on"] = 0
 
 	if t.Name != "" {
@@ -102,15 +183,16 @@ func (t *Tag) getMaps() map[string]interface{} {
 	return maps
 }
 
-func (t *Tag) Export() (string, error) {
+// Export 导出站点内的标签，Excel 文件写入 w，不落盘到公开访问的目录
+func (t *Tag) Export(ctx context.Context, w io.Writer) error {
 	// 导出时需要获取所有数据，设置一个很大的 PageSize
 	if t.PageSize == 0 {
 		t.PageSize = 10000 // 设置一个足够大的值，或者改为 -1 表示不限制
 	}
-	tags, err := t.GetAll()
+	tags, err := t.GetAll(ctx)
 	fmt.Println("tags:", tags)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	// 创建 Excel 文件
@@ -124,7 +206,7 @@ func (t *Tag) Export() (string, error) {
 	// 表头
 	titles := []string{"ID", "名称", "创建人", "创建时间", "修改人", "修改时间"}
 	if err := f.SetSheetRow(sheetName, "A1", &titles); err != nil {
-		return "", fmt.Errorf("设置表头失败: %w", err)
+		return fmt.Errorf("设置表头失败: %w", err)
 	}
 
 	// 填充数据
@@ -145,40 +227,34 @@ func (t *Tag) Export() (string, error) {
 
 		cell := fmt.Sprintf("A%d", row)
 		if err := f.SetSheetRow(sheetName, cell, &values); err != nil {
-			return "", fmt.Errorf("写入第 %d 行数据失败: %w", row, err)
+			return fmt.Errorf("写入第 %d 行数据失败: %w", row, err)
 		}
 	}
 
-	// 生成文件名
-	filename := fmt.Sprintf("tags-%d.xlsx", time.Now().Unix())
-	fullPath := export.GetExcelFullPath() + filename
-
-	// 创建导出目录
-	if err := os.MkdirAll(export.GetExcelFullPath(), 0755); err != nil {
-		return "", fmt.Errorf("创建导出目录失败: %w", err)
-	}
-	// 保存文件
-	if err := f.SaveAs(fullPath); err != nil {
-		return "", fmt.Errorf("保存 Excel 文件失败: %w", err)
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("写入 Excel 文件失败: %w", err)
 	}
 
-	return filename, nil
+	return nil
 }
 
-func (t *Tag) Import(r io.Reader) error {
+// Import 导入标签，创建人为 t.CreatedBy，返回导入的数量
+func (t *Tag) Import(ctx context.Context, r io.Reader) (int, error) {
 	// 1. 打开 Excel 文件
 	xlsx, err := excelize.OpenReader(r)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer xlsx.Close() // 推荐：释放内部资源
 
 	// 2. 读取 "标签信息" 工作表的所有行（注意：GetRows 会返回错误！）
 	rows, err := xlsx.GetRows("标签信息")
 	if err != nil {
-		return err // 工作表不存在或损坏
+		return 0, err // 工作表不存在或损坏
 	}
 
+	count := 0
+
 	// 3. 遍历行，跳过表头（irow == 0）
 	for irow, row := range rows {
 		if irow == 0 {
@@ -190,10 +266,9 @@ func (t *Tag) Import(r io.Reader) error {
 			continue // 跳过不完整行（或可返回错误）
 		}
 
-		// 5. 提取字段（根据 models.AddTag 参数）
-		name := row[1]      // 第2列：名称
-		state := 1          // 状态为1
-		createdBy := row[2] // 第3列：创建人
+		// 5. 提取字段（根据 models.AddTag 参数），创建人为执行导入的用户，不取表格中的第3列
+		name := row[1] // 第2列：名称
+		state := 1     // 状态为1
 
 		// 可选：跳过空名称
 		if name == "" {
@@ -201,8 +276,13 @@ func (t *Tag) Import(r io.Reader) error {
 		}
 
 		// 6. 调用业务逻辑
-		models.AddTag(name, state, createdBy)
+		tag, err := service.Tags.Add(ctx, t.TenantID, name, state, t.CreatedBy)
+		if err != nil {
+			return count, err
+		}
+		webhook_service.Fire(ctx, t.TenantID, webhook_service.EVENT_TAG_CREATED, tag)
+		count++
 	}
 
-	return nil
+	return count, nil
 }
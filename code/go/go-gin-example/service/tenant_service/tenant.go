@@ -0,0 +1,120 @@
+package tenant_service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+type Tenant struct {
+	ID          int
+	Name        string
+	Host        string
+	Title       string
+	Description string
+	Link        string
+	State       int
+	CreatedBy   string
+	ModifiedBy  string
+
+	// AdminUsername、AdminPassword 不为空时新建站点的同时创建该站点的管理员
+	AdminUsername string
+	AdminPassword string
+
+	PageNum  int
+	PageSize int
+}
+
+// Link 站点的访问地址，站点未设置时使用 [feed] Link 或 PrefixUrl，末尾不带 /
+func Link(tenant *models.Tenant) string {
+	if tenant.Link != "" {
+		return strings.TrimRight(tenant.Link, "/")
+	}
+
+	return setting.SiteLink()
+}
+
+// Resolve 按 X-Tenant 请求头中的名称、请求的域名、默认站点的顺序查找启用的站点，都不存在时返回 ID 为 0 的站点
+func Resolve(ctx context.Context, name, host string) (*models.Tenant, error) {
+	if name != "" {
+		return models.GetTenantByName(ctx, name)
+	}
+
+	if host != "" {
+		tenant, err := models.GetTenantByHost(ctx, host)
+		if err != nil || tenant.ID > 0 {
+			return tenant, err
+		}
+	}
+
+	if setting.TenantSetting.Default == "" {
+		return &models.Tenant{}, nil
+	}
+	return models.GetTenantByName(ctx, setting.TenantSetting.Default)
+}
+
+// IsDefault 是否为默认站点，只有默认站点的管理员可以管理站点与全局任务
+func IsDefault(tenant *models.Tenant) bool {
+	return tenant.Name != "" && tenant.Name == setting.TenantSetting.Default
+}
+
+func (t *Tenant) Exist(ctx context.Context) (bool, error) {
+	return models.ExistTenant(ctx, t.Name, t.Host, t.ID)
+}
+
+func (t *Tenant) Get(ctx context.Context) (*models.Tenant, error) {
+	return models.GetTenant(ctx, t.ID)
+}
+
+func (t *Tenant) GetAll(ctx context.Context) ([]models.Tenant, error) {
+	return models.GetTenants(ctx, t.PageNum, t.PageSize)
+}
+
+func (t *Tenant) Count(ctx context.Context) (int, error) {
+	return models.GetTenantTotal(ctx)
+}
+
+func (t *Tenant) Add(ctx context.Context) (*models.Tenant, error) {
+	tenant := &models.Tenant{
+		Name:        t.Name,
+		Host:        t.Host,
+		Title:       t.Title,
+		Description: t.Description,
+		Link:        t.Link,
+		State:       t.State,
+		CreatedBy:   t.CreatedBy,
+	}
+
+	var admin *models.Auth
+	if t.AdminUsername != "" {
+		admin = &models.Auth{
+			Username: t.AdminUsername,
+			Password: t.AdminPassword,
+			Role:     models.ROLE_ADMIN,
+		}
+	}
+
+	if err := models.AddTenant(ctx, tenant, admin); err != nil {
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+func (t *Tenant) Edit(ctx context.Context) error {
+	data := map[string]interface{}{
+		"name":        t.Name,
+		"host":        t.Host,
+		"title":       t.Title,
+		"description": t.Description,
+		"link":        t.Link,
+		"modified_by": t.ModifiedBy,
+	}
+	if t.State >= 0 {
+		data["state"] = t.State
+	}
+
+	return models.EditTenant(ctx, t.ID, data)
+}
@@ -0,0 +1,357 @@
+package webhook_service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/logging"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+	"github.com/fzzv/go-gin-example/pkg/telemetry"
+)
+
+var (
+	ErrDeliveryPending  = errors.New("delivery is still pending")
+	ErrForbiddenAddress = errors.New("destination is a loopback, private or link-local address")
+)
+
+// Payload 投递的请求体
+type Payload struct {
+	Event     string      `json:"event"`
+	CreatedOn int64       `json:"created_on"`
+	Data      interface{} `json:"data"`
+}
+
+var (
+	client *http.Client
+	wake   = make(chan struct{}, 1)
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	fires  sync.WaitGroup
+)
+
+// Setup 启动投递循环，投递记录保存在数据库中，多个实例同时运行时通过抢占记录避免重复投递
+func Setup() {
+	client = newClient()
+	stop = make(chan struct{})
+
+	wg.Add(1)
+	go loop()
+}
+
+// Stop 等待 Fire 写入投递记录，然后停止投递循环，等待正在进行的投递完成
+func Stop() {
+	fires.Wait()
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	wg.Wait()
+	stop = nil
+}
+
+// Fire 为站点内订阅了该事件的所有订阅创建投递记录，查询订阅与写入记录都在后台进行，不阻塞当前请求，失败只写日志
+func Fire(ctx context.Context, tenantID int, event string, data interface{}) {
+	// 立即序列化，调用方之后修改 data 不影响投递内容
+	payload, err := json.Marshal(Payload{Event: event, CreatedOn: time.Now().Unix(), Data: data})
+	if err != nil {
+		logging.Error("webhook", event, err)
+		return
+	}
+
+	// 请求结束后 ctx 会被取消，后台写入只沿用其中的 trace
+	ctx = context.WithoutCancel(ctx)
+	fires.Add(1)
+	go func() {
+		defer fires.Done()
+		fire(ctx, tenantID, event, payload)
+	}()
+}
+
+func fire(ctx context.Context, tenantID int, event string, payload []byte) {
+	webhooks, err := models.GetEnabledWebhooks(ctx, tenantID)
+	if err != nil {
+		logging.Error("webhook", event, err)
+		return
+	}
+
+	for i := range webhooks {
+		if !Subscribed(&webhooks[i], event) {
+			continue
+		}
+
+		delivery := &models.WebhookDelivery{WebhookID: webhooks[i].ID, Event: event, Payload: string(payload)}
+		if err := enqueue(ctx, delivery); err != nil {
+			logging.Error("webhook", webhooks[i].ID, event, err)
+		}
+	}
+}
+
+// enqueue 写入待投递的记录，记录 ctx 所属的 trace，投递时接收方可以与触发事件的请求串联
+func enqueue(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.Status = models.DELIVERY_PENDING
+	delivery.Traceparent = telemetry.Traceparent(ctx)
+	delivery.NextRetryOn = int(time.Now().Unix())
+	if err := models.AddWebhookDelivery(ctx, delivery); err != nil {
+		return err
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Replay 以原始内容重新投递，生成新的投递记录，原记录标记为已重放
+func Replay(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	if delivery.Status == models.DELIVERY_PENDING || delivery.Status == models.DELIVERY_SENDING {
+		return nil, ErrDeliveryPending
+	}
+
+	replay := &models.WebhookDelivery{
+		WebhookID: delivery.WebhookID,
+		Event:     delivery.Event,
+		Payload:   delivery.Payload,
+		ReplayOf:  delivery.ID,
+	}
+	if err := enqueue(ctx, replay); err != nil {
+		return nil, err
+	}
+
+	return replay, models.EditWebhookDelivery(ctx, delivery.ID, map[string]interface{}{"replayed": 1})
+}
+
+// ReplayFailed 重放订阅下所有尚未重放过的失败投递，返回重放的数量
+func ReplayFailed(ctx context.Context, webhookID int) (int, error) {
+	maps := map[string]interface{}{"webhook_id": webhookID, "status": models.DELIVERY_FAILED, "replayed": 0}
+	deliveries, err := models.GetWebhookDeliveries(ctx, 0, 1000, maps)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		if _, err := Replay(ctx, &deliveries[i]); err != nil {
+			return i, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func loop() {
+	defer wg.Done()
+
+	ctx := context.Background()
+
+	ticker := time.NewTicker(setting.WebhookSetting.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		deliverDue(ctx)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+func deliverDue(ctx context.Context) {
+	deliveries, err := models.GetDueWebhookDeliveries(ctx, int(time.Now().Unix()), 100)
+	if err != nil {
+		logging.Error("webhook", err)
+		return
+	}
+
+	for i := range deliveries {
+		deliver(ctx, &deliveries[i])
+	}
+}
+
+// deliver 投递一次，失败后按指数退避安排下次重试
+func deliver(ctx context.Context, d *models.WebhookDelivery) {
+	now := time.Now()
+	lease := now.Add(setting.WebhookSetting.Timeout + 5*time.Second)
+	ok, err := models.ClaimWebhookDelivery(ctx, d.ID, int(now.Unix()), int(lease.Unix()))
+	if err != nil {
+		logging.Error("webhook delivery", d.ID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	webhook, err := models.GetWebhook(ctx, d.WebhookID)
+	if err != nil {
+		logging.Error("webhook delivery", d.ID, err)
+		return
+	}
+
+	attempts := d.Attempts + 1
+	data := map[string]interface{}{"attempts": attempts}
+
+	var code int
+	var body string
+	if webhook.ID == 0 || webhook.State != 1 {
+		err = errors.New("webhook is deleted or disabled")
+		attempts = setting.WebhookSetting.MaxAttempts
+	} else {
+		code, body, err = send(telemetry.WithTraceparent(ctx, d.Traceparent), webhook, d)
+		// 目标地址被拒绝时重试也不会成功
+		if errors.Is(err, ErrForbiddenAddress) {
+			attempts = setting.WebhookSetting.MaxAttempts
+		}
+	}
+	data["response_code"] = code
+	data["response_body"] = body
+
+	switch {
+	case err == nil:
+		data["status"] = models.DELIVERY_SUCCESS
+		data["error"] = ""
+	case attempts >= setting.WebhookSetting.MaxAttempts:
+		data["status"] = models.DELIVERY_FAILED
+		data["error"] = err.Error()
+		logging.Warn("webhook delivery", d.ID, "failed", err)
+	default:
+		data["status"] = models.DELIVERY_PENDING
+		data["error"] = err.Error()
+		data["next_retry_on"] = int(time.Now().Add(backoff(attempts)).Unix())
+	}
+
+	if err := models.EditWebhookDelivery(ctx, d.ID, data); err != nil {
+		logging.Error("webhook delivery", d.ID, err)
+	}
+}
+
+// newClient 投递使用的客户端，不使用环境变量中的代理，连接前校验解析后的地址，重定向与 DNS 重绑定同样受限制。
+// 请求属于某个 trace 时创建客户端 span 并写入 traceparent 请求头
+func newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: setting.WebhookSetting.Timeout, Control: dialControl}
+
+	return &http.Client{
+		Timeout: setting.WebhookSetting.Timeout,
+		Transport: telemetry.Transport(&http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: setting.WebhookSetting.Timeout,
+			MaxIdleConnsPerHost: 2,
+		}),
+	}
+}
+
+// dialControl 拒绝连接回环、内网与链路本地地址，webhook.AllowPrivateNetwork 开启时不限制
+func dialControl(network, address string, _ syscall.RawConn) error {
+	if setting.WebhookSetting.AllowPrivateNetwork {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbidden(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
+
+func forbidden(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// send 发送请求，2xx 视为成功，响应内容最多保留 1KB
+func send(ctx context.Context, webhook *models.Webhook, d *models.WebhookDelivery) (int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewBufferString(d.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-gin-example-webhook")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(webhook.Secret, timestamp, []byte(d.Payload)))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, string(body), nil
+}
+
+// Sign 计算签名，签名内容为 时间戳.请求体，接收方应校验签名并拒绝时间戳过旧的请求
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff 第 n 次失败后的等待时间
+func backoff(attempts int) time.Duration {
+	d := setting.WebhookSetting.RetryBase
+	for i := 1; i < attempts && d < setting.WebhookSetting.RetryMax; i++ {
+		d *= 2
+	}
+	if d > setting.WebhookSetting.RetryMax {
+		d = setting.WebhookSetting.RetryMax
+	}
+
+	return d
+}
+
+// GetDeliveries 分页获取订阅的投递记录，status 为空时不限状态
+func GetDeliveries(ctx context.Context, webhookID int, status string, pageNum, pageSize int) ([]models.WebhookDelivery, int, error) {
+	maps := map[string]interface{}{"webhook_id": webhookID}
+	if status != "" {
+		maps["status"] = status
+	}
+
+	deliveries, err := models.GetWebhookDeliveries(ctx, pageNum, pageSize, maps)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := models.GetWebhookDeliveryTotal(ctx, maps)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// GetDelivery 获取投递记录，不存在时返回 ID 为 0 的记录
+func GetDelivery(ctx context.Context, webhookID, id int) (*models.WebhookDelivery, error) {
+	return models.GetWebhookDelivery(ctx, webhookID, id)
+}
@@ -0,0 +1,374 @@
+package webhook_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/pkg/setting"
+)
+
+// TestMain 投递记录写入临时目录中的 SQLite
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "webhook")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := func() int {
+		defer os.RemoveAll(dir)
+
+		*setting.WebhookSetting = setting.Webhook{
+			Timeout:      5 * time.Second,
+			MaxAttempts:  3,
+			RetryBase:    time.Minute,
+			RetryMax:     10 * time.Minute,
+			PollInterval: time.Second,
+		}
+		client = newClient()
+
+		if err := models.Open("sqlite3", filepath.Join(dir, "blog.db")); err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer models.CloseDB()
+		if err := models.Migrate(); err != nil {
+			log.Println(err)
+			return 1
+		}
+
+		return m.Run()
+	}()
+	os.Exit(code)
+}
+
+// receiver 记录收到的投递并校验签名，status 为返回的状态码
+type receiver struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	status       int
+	received     []string
+	traceparents []string
+}
+
+func newReceiver(t *testing.T, secret string) *receiver {
+	r := &receiver{status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		timestamp := req.Header.Get("X-Webhook-Timestamp")
+		if got, want := req.Header.Get("X-Webhook-Signature"), "sha256="+Sign(secret, timestamp, body); got != want {
+			t.Errorf("signature = %s, want %s", got, want)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.received = append(r.received, req.Header.Get("X-Webhook-Event"))
+		r.traceparents = append(r.traceparents, req.Header.Get("traceparent"))
+		w.WriteHeader(r.status)
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func (r *receiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+// allowPrivate 允许投递到 httptest 监听的回环地址
+func allowPrivate(t *testing.T) {
+	setting.WebhookSetting.AllowPrivateNetwork = true
+	t.Cleanup(func() { setting.WebhookSetting.AllowPrivateNetwork = false })
+}
+
+func addWebhook(t *testing.T, tenantID int, url, events string) *models.Webhook {
+	t.Helper()
+
+	webhook := &models.Webhook{TenantID: tenantID, URL: url, Secret: "secret", Events: events, State: 1}
+	if err := models.AddWebhook(context.Background(), webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	return webhook
+}
+
+func addDelivery(t *testing.T, webhookID int) *models.WebhookDelivery {
+	t.Helper()
+
+	delivery := &models.WebhookDelivery{WebhookID: webhookID, Event: EVENT_TAG_CREATED, Payload: `{"event":"tag.created"}`}
+	if err := enqueue(context.Background(), delivery); err != nil {
+		t.Fatal(err)
+	}
+
+	return delivery
+}
+
+func getDelivery(t *testing.T, webhookID, id int) *models.WebhookDelivery {
+	t.Helper()
+
+	delivery, err := models.GetWebhookDelivery(context.Background(), webhookID, id)
+	if err != nil || delivery.ID == 0 {
+		t.Fatalf("delivery %d = %+v, %v", id, delivery, err)
+	}
+
+	return delivery
+}
+
+// due 使等待重试的记录立即到期
+func due(t *testing.T, id int) {
+	t.Helper()
+
+	if err := models.EditWebhookDelivery(context.Background(), id, map[string]interface{}{"next_retry_on": 0}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	got := Sign("secret", "1700000000", []byte(`{"event":"tag.created"}`))
+	if want := "5a0f57ee693fdf7da614328547e96639bdf30c4a3c0caf95defa9a8935e99d00"; got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+
+	// 密钥、时间戳与请求体任一不同签名都不同
+	for _, other := range []string{
+		Sign("other", "1700000000", []byte(`{"event":"tag.created"}`)),
+		Sign("secret", "1700000001", []byte(`{"event":"tag.created"}`)),
+		Sign("secret", "1700000000", []byte(`{"event":"tag.updated"}`)),
+	} {
+		if other == got {
+			t.Error("signature does not depend on all inputs")
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{30, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"0.0.0.0:80", false},
+	}
+	for _, tt := range tests {
+		err := dialControl("tcp", tt.address, nil)
+		if tt.allowed != (err == nil) {
+			t.Errorf("dialControl(%s) = %v, allowed %v", tt.address, err, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("dialControl(%s) = %v, want ErrForbiddenAddress", tt.address, err)
+		}
+	}
+
+	allowPrivate(t)
+	if err := dialControl("tcp", "127.0.0.1:80", nil); err != nil {
+		t.Errorf("dialControl with AllowPrivateNetwork = %v", err)
+	}
+}
+
+// TestForbiddenDestination 目标为内部地址时不重试，直接标记为失败
+func TestForbiddenDestination(t *testing.T) {
+	r := newReceiver(t, "secret")
+	webhook := addWebhook(t, 1, r.URL, EVENT_ALL)
+	d := addDelivery(t, webhook.ID)
+
+	deliver(context.Background(), d)
+	got := getDelivery(t, webhook.ID, d.ID)
+	if got.Status != models.DELIVERY_FAILED || got.Attempts != 1 || !strings.Contains(got.Error, ErrForbiddenAddress.Error()) {
+		t.Fatalf("delivery = %+v", got)
+	}
+	if r.count() != 0 {
+		t.Fatal("request reached a loopback address")
+	}
+}
+
+// TestRetry 失败后按退避时间重试，次数用完后标记为失败，重放成功后原记录不再重放
+func TestRetry(t *testing.T) {
+	allowPrivate(t)
+	r := newReceiver(t, "secret")
+	r.setStatus(http.StatusInternalServerError)
+	webhook := addWebhook(t, 2, r.URL, EVENT_ALL)
+	d := addDelivery(t, webhook.ID)
+
+	for attempts := 1; attempts < setting.WebhookSetting.MaxAttempts; attempts++ {
+		deliver(context.Background(), d)
+		got := getDelivery(t, webhook.ID, d.ID)
+		retry := time.Now().Add(backoff(attempts)).Unix()
+		if got.Status != models.DELIVERY_PENDING || got.Attempts != attempts || got.ResponseCode != 500 || got.Error != "unexpected status 500" {
+			t.Fatalf("attempt %d: delivery = %+v", attempts, got)
+		}
+		if diff := retry - int64(got.NextRetryOn); diff < 0 || diff > 2 {
+			t.Fatalf("attempt %d: next retry on %d, want about %d", attempts, got.NextRetryOn, retry)
+		}
+
+		// 未到期前不会被再次投递
+		deliver(context.Background(), got)
+		if r.count() != attempts {
+			t.Fatalf("attempt %d: delivered before the retry is due", attempts)
+		}
+		due(t, d.ID)
+		d = getDelivery(t, webhook.ID, d.ID)
+	}
+
+	deliver(context.Background(), d)
+	failed := getDelivery(t, webhook.ID, d.ID)
+	if failed.Status != models.DELIVERY_FAILED || failed.Attempts != setting.WebhookSetting.MaxAttempts {
+		t.Fatalf("delivery after the last attempt = %+v", failed)
+	}
+
+	r.setStatus(http.StatusOK)
+	count, err := ReplayFailed(context.Background(), webhook.ID)
+	if err != nil || count != 1 {
+		t.Fatalf("ReplayFailed = %d, %v", count, err)
+	}
+	if got := getDelivery(t, webhook.ID, d.ID); got.Replayed != 1 {
+		t.Fatalf("original delivery = %+v, want replayed", got)
+	}
+	if count, err := ReplayFailed(context.Background(), webhook.ID); err != nil || count != 0 {
+		t.Fatalf("second ReplayFailed = %d, %v", count, err)
+	}
+
+	replays, _, err := GetDeliveries(context.Background(), webhook.ID, models.DELIVERY_PENDING, 0, 10)
+	if err != nil || len(replays) != 1 || replays[0].ReplayOf != d.ID || replays[0].Payload != d.Payload || replays[0].Attempts != 0 {
+		t.Fatalf("replays = %+v, %v", replays, err)
+	}
+	if _, err := Replay(context.Background(), &replays[0]); err != ErrDeliveryPending {
+		t.Fatalf("replay a pending delivery: %v", err)
+	}
+
+	deliver(context.Background(), &replays[0])
+	if got := getDelivery(t, webhook.ID, replays[0].ID); got.Status != models.DELIVERY_SUCCESS || got.ResponseBody != "ok" || got.Error != "" {
+		t.Fatalf("replayed delivery = %+v", got)
+	}
+}
+
+// TestFire 只为订阅了该事件的启用的订阅创建投递记录，内容为调用时的数据
+func TestFire(t *testing.T) {
+	all := addWebhook(t, 3, "https://example.com/all", EVENT_ALL)
+	tags := addWebhook(t, 3, "https://example.com/tags", EVENT_TAG_CREATED+","+EVENT_TAG_DELETED)
+	articles := addWebhook(t, 3, "https://example.com/articles", EVENT_ARTICLE_CREATED)
+	disabled := addWebhook(t, 3, "https://example.com/disabled", EVENT_ALL)
+	if err := models.EditWebhook(context.Background(), disabled.ID, map[string]interface{}{"state": 0}); err != nil {
+		t.Fatal(err)
+	}
+	other := addWebhook(t, 4, "https://example.com/other", EVENT_ALL)
+
+	data := map[string]string{"name": "go"}
+	Fire(context.Background(), 3, EVENT_TAG_CREATED, data)
+	data["name"] = "changed"
+	fires.Wait()
+
+	for _, tt := range []struct {
+		webhook *models.Webhook
+		want    int
+	}{{all, 1}, {tags, 1}, {articles, 0}, {disabled, 0}, {other, 0}} {
+		deliveries, total, err := GetDeliveries(context.Background(), tt.webhook.ID, "", 0, 10)
+		if err != nil || total != tt.want {
+			t.Fatalf("%s: %d deliveries, %v, want %d", tt.webhook.URL, total, err, tt.want)
+		}
+		if total == 0 {
+			continue
+		}
+
+		var payload struct {
+			Event string            `json:"event"`
+			Data  map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(deliveries[0].Payload), &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Event != EVENT_TAG_CREATED || payload.Data["name"] != "go" || deliveries[0].Status != models.DELIVERY_PENDING {
+			t.Fatalf("%s: delivery = %+v", tt.webhook.URL, deliveries[0])
+		}
+	}
+}
+
+// TestTraceparent 投递记录保存触发事件的请求的 trace，接收方收到的 traceparent 属于同一个 trace
+func TestTraceparent(t *testing.T) {
+	allowPrivate(t)
+	r := newReceiver(t, "secret")
+	webhook := addWebhook(t, 5, r.URL, EVENT_ALL)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+	Fire(ctx, 5, EVENT_TAG_CREATED, nil)
+	fires.Wait()
+
+	deliveries, _, err := GetDeliveries(context.Background(), webhook.ID, "", 0, 10)
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	if err != nil || len(deliveries) != 1 || deliveries[0].Traceparent != want {
+		t.Fatalf("deliveries = %+v, %v, want traceparent %s", deliveries, err, want)
+	}
+
+	// 投递循环不属于任何请求，仍沿用投递记录中的 trace
+	deliver(context.Background(), &deliveries[0])
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.traceparents) != 1 || !strings.HasPrefix(r.traceparents[0], "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Fatalf("traceparent headers = %q", r.traceparents)
+	}
+
+	// 不属于任何 trace 的投递不带 traceparent
+	d := addDelivery(t, webhook.ID)
+	if d.Traceparent != "" {
+		t.Fatalf("traceparent without a trace = %q", d.Traceparent)
+	}
+}
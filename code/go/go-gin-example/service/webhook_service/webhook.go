@@ -0,0 +1,160 @@
+package webhook_service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/fzzv/go-gin-example/models"
+)
+
+const (
+	EVENT_ARTICLE_CREATED   = "article.created"
+	EVENT_ARTICLE_UPDATED   = "article.updated"
+	EVENT_ARTICLE_DELETED   = "article.deleted"
+	EVENT_ARTICLE_PUBLISHED = "article.published"
+	EVENT_TAG_CREATED       = "tag.created"
+	EVENT_TAG_UPDATED       = "tag.updated"
+	EVENT_TAG_DELETED       = "tag.deleted"
+
+	// EVENT_ALL 订阅所有事件
+	EVENT_ALL = "*"
+)
+
+// Events 所有可订阅的事件
+var Events = []string{
+	EVENT_ARTICLE_CREATED,
+	EVENT_ARTICLE_UPDATED,
+	EVENT_ARTICLE_DELETED,
+	EVENT_ARTICLE_PUBLISHED,
+	EVENT_TAG_CREATED,
+	EVENT_TAG_UPDATED,
+	EVENT_TAG_DELETED,
+}
+
+type Webhook struct {
+	TenantID   int
+	ID         int
+	URL        string
+	Secret     string
+	Events     string
+	State      int
+	CreatedBy  string
+	ModifiedBy string
+
+	PageNum  int
+	PageSize int
+}
+
+// ParseEvents 校验并规范化逗号分隔的事件列表
+func ParseEvents(events string) (string, error) {
+	var list []string
+	for _, event := range strings.Split(events, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if event != EVENT_ALL && !contains(Events, event) {
+			return "", fmt.Errorf("unknown event %q, must be * or one of %s", event, strings.Join(Events, ", "))
+		}
+		list = append(list, event)
+	}
+	if len(list) == 0 {
+		return "", fmt.Errorf("at least one event is required")
+	}
+
+	return strings.Join(list, ","), nil
+}
+
+// Subscribed 订阅是否包含该事件
+func Subscribed(webhook *models.Webhook, event string) bool {
+	events := strings.Split(webhook.Events, ",")
+	return contains(events, EVENT_ALL) || contains(events, event)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add 新建订阅，未指定密钥时随机生成
+func (w *Webhook) Add(ctx context.Context) (*models.Webhook, error) {
+	if w.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		w.Secret = secret
+	}
+
+	webhook := &models.Webhook{
+		TenantID:  w.TenantID,
+		URL:       w.URL,
+		Secret:    w.Secret,
+		Events:    w.Events,
+		State:     w.State,
+		CreatedBy: w.CreatedBy,
+	}
+	if err := models.AddWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (w *Webhook) Edit(ctx context.Context) error {
+	data := map[string]interface{}{
+		"url":         w.URL,
+		"events":      w.Events,
+		"modified_by": w.ModifiedBy,
+	}
+	if w.State >= 0 {
+		data["state"] = w.State
+	}
+	if w.Secret != "" {
+		data["secret"] = w.Secret
+	}
+
+	return models.EditWebhook(ctx, w.ID, data)
+}
+
+func (w *Webhook) Delete(ctx context.Context) error {
+	return models.DeleteWebhook(ctx, w.ID)
+}
+
+// Get 获取站点的订阅，不存在或属于其他站点时返回 ID 为 0 的订阅
+func (w *Webhook) Get(ctx context.Context) (*models.Webhook, error) {
+	webhook, err := models.GetWebhook(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.TenantID != w.TenantID {
+		return &models.Webhook{}, nil
+	}
+
+	return webhook, nil
+}
+
+func (w *Webhook) GetAll(ctx context.Context) ([]models.Webhook, error) {
+	return models.GetWebhooks(ctx, w.TenantID, w.PageNum, w.PageSize)
+}
+
+func (w *Webhook) Count(ctx context.Context) (int, error) {
+	return models.GetWebhookTotal(ctx, w.TenantID)
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
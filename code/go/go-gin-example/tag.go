@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/service"
+	"github.com/fzzv/go-gin-example/service/tag_service"
+)
+
+const tagUsage = `usage:
+  go-gin-example tag list [--tenant name] [--state -1|0|1]
+  go-gin-example tag add [--tenant name] [--state 0|1] <name>`
+
+// runTag 处理 tag 子命令，管理站点下的标签
+func runTag(args []string) int {
+	return subcommand(tagUsage, map[string]func([]string) int{
+		"list": runTagList,
+		"add":  runTagAdd,
+	}, args)
+}
+
+func runTagList(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("tag list", flag.ExitOnError)
+	flags.bind(fs)
+	state := fs.Int("state", -1, "只列出该状态的标签，-1 为全部")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, tagUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(false)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	// 直接读取数据库，不经过缓存
+	maps := map[string]interface{}{"tenant_id": tenant.ID, "deleted_on": 0}
+	if *state >= 0 {
+		maps["state"] = *state
+	}
+	tags, err := service.Tags.GetAll(context.Background(), -1, -1, maps)
+	if err != nil {
+		return fail(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATE\tCREATED BY")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", tag.ID, tag.Name, tag.State, tag.CreatedBy)
+	}
+	w.Flush()
+	return 0
+}
+
+func runTagAdd(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("tag add", flag.ExitOnError)
+	flags.bind(fs)
+	state := fs.Int("state", 1, "状态 0 禁用 1 启用")
+	fs.Parse(args)
+	if fs.NArg() != 1 || (*state != 0 && *state != 1) {
+		fmt.Fprintln(os.Stderr, tagUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(true)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	tagService := tag_service.Tag{TenantID: tenant.ID, Name: fs.Arg(0), State: *state, CreatedBy: CLI_USER}
+	exists, err := tagService.ExistByName(context.Background())
+	if err != nil {
+		return fail(err)
+	}
+	if exists {
+		return fail(fmt.Errorf("tag %q already exists on site %s", tagService.Name, tenant.Name))
+	}
+
+	tag, err := tagService.Add(context.Background())
+	if err != nil {
+		return fail(err)
+	}
+	audit(tenant.ID, models.AUDIT_CREATE, "tag", tag.ID, nil, tag)
+
+	fmt.Printf("created tag %s (id %d) on site %s\n", tag.Name, tag.ID, tenant.Name)
+	return 0
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fzzv/go-gin-example/models"
+	"github.com/fzzv/go-gin-example/service/auth_service"
+)
+
+const userUsage = `usage:
+  go-gin-example user create [--tenant name] [--role admin|editor] [--password password] <username>
+  go-gin-example user reset-password [--tenant name] [--password password] <username>
+  go-gin-example user set-role [--tenant name] <username> <admin|editor>
+  go-gin-example user bind-oidc [--tenant name] <username> <sub>
+
+未指定 --password 时从标准输入读取密码；已有账号需先通过 bind-oidc 绑定身份提供方的用户 sub 才能通过 OIDC 登录`
+
+// runUser 处理 user 子命令，管理站点下的登录账号
+func runUser(args []string) int {
+	return subcommand(userUsage, map[string]func([]string) int{
+		"create":         runUserCreate,
+		"reset-password": runUserResetPassword,
+		"set-role":       runUserSetRole,
+		"bind-oidc":      runUserBindOIDC,
+	}, args)
+}
+
+func runUserCreate(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("user create", flag.ExitOnError)
+	flags.bind(fs)
+	role := fs.String("role", models.ROLE_EDITOR, "角色 admin 或 editor")
+	password := fs.String("password", "", "密码，留空时从标准输入读取")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(false)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	authService := auth_service.Auth{TenantID: tenant.ID, Username: fs.Arg(0), Role: *role}
+	if authService.Password, err = readPassword(*password); err != nil {
+		return fail(err)
+	}
+	auth, err := authService.Add(context.Background())
+	if err != nil {
+		return fail(err)
+	}
+	audit(tenant.ID, models.AUDIT_CREATE, "user", auth.Username, nil, map[string]string{"role": auth.Role})
+
+	fmt.Printf("created %s user %s (id %d) on site %s\n", auth.Role, auth.Username, auth.ID, tenant.Name)
+	return 0
+}
+
+func runUserResetPassword(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("user reset-password", flag.ExitOnError)
+	flags.bind(fs)
+	password := fs.String("password", "", "新密码，留空时从标准输入读取")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(false)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	authService := auth_service.Auth{TenantID: tenant.ID, Username: fs.Arg(0)}
+	if authService.Password, err = readPassword(*password); err != nil {
+		return fail(err)
+	}
+	if err := authService.ResetPassword(context.Background()); err != nil {
+		return fail(err)
+	}
+	// 不记录密码本身
+	audit(tenant.ID, models.AUDIT_EDIT, "user", authService.Username, nil, map[string]bool{"password_reset": true})
+
+	fmt.Printf("reset the password of %s on site %s\n", authService.Username, tenant.Name)
+	return 0
+}
+
+func runUserSetRole(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("user set-role", flag.ExitOnError)
+	flags.bind(fs)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(false)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	authService := auth_service.Auth{TenantID: tenant.ID, Username: fs.Arg(0), Role: fs.Arg(1)}
+	before, err := authService.Get(context.Background())
+	if err != nil {
+		return fail(err)
+	}
+	if err := authService.SetRole(context.Background()); err != nil {
+		return fail(err)
+	}
+	audit(tenant.ID, models.AUDIT_EDIT, "user", authService.Username, map[string]string{"role": before.Role}, map[string]string{"role": authService.Role})
+
+	fmt.Printf("set the role of %s on site %s to %s\n", authService.Username, tenant.Name, authService.Role)
+	return 0
+}
+
+func runUserBindOIDC(args []string) int {
+	var flags commandFlags
+	fs := flag.NewFlagSet("user bind-oidc", flag.ExitOnError)
+	flags.bind(fs)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, userUsage)
+		return 2
+	}
+
+	tenant, err := flags.setup(false)
+	if err != nil {
+		return fail(err)
+	}
+	defer models.CloseDB()
+
+	authService := auth_service.Auth{TenantID: tenant.ID, Username: fs.Arg(0), Subject: fs.Arg(1)}
+	before, err := authService.Get(context.Background())
+	if err != nil {
+		return fail(err)
+	}
+	if err := authService.BindSubject(context.Background()); err != nil {
+		return fail(err)
+	}
+	audit(tenant.ID, models.AUDIT_EDIT, "user", authService.Username, map[string]string{"subject": before.Subject}, map[string]string{"subject": authService.Subject})
+
+	fmt.Printf("bound %s on site %s to OIDC subject %s\n", authService.Username, tenant.Name, authService.Subject)
+	return 0
+}